@@ -9,8 +9,11 @@ type DB interface {
 }
 
 // Tx is a common interface implemented by all transactions.
+//
+// Operations called with a canceled or expired context fail with the error of the context.
 type Tx interface {
 	// Commit applies all changes made in the transaction.
+	// If the context is already canceled, the transaction is rolled back and the error of the context is returned.
 	Commit(ctx context.Context) error
 	// Close rolls back the transaction.
 	// Committed transactions will not be affected by calling Close.
@@ -20,6 +23,7 @@ type Tx interface {
 // Iterator is a common interface implemented by all iterators.
 type Iterator interface {
 	// Next advances an iterator.
+	// If the context is canceled, iteration stops and Err returns the error of the context.
 	Next(ctx context.Context) bool
 	// Err returns a last encountered error.
 	Err() error
@@ -0,0 +1,71 @@
+package base
+
+import "strings"
+
+// Capabilities is a set of optional features supported by a database.
+type Capabilities uint32
+
+const (
+	// CapTx indicates that transactions are isolated and changes can be rolled back.
+	CapTx Capabilities = 1 << iota
+	// CapConcurrentWrites indicates that the database is safe for concurrent writers.
+	CapConcurrentWrites
+	// CapSeek indicates that iterators seek natively, without scanning from the beginning.
+	CapSeek
+	// CapPrefixScan indicates that prefix scans are executed natively.
+	CapPrefixScan
+	// CapBatchGet indicates that multiple keys are fetched in a single operation.
+	CapBatchGet
+	// CapConflicts indicates that conflicting concurrent writes are detected and reported as ErrConflict.
+	CapConflicts
+
+	capLast
+)
+
+// AllCapabilities is a set of all known capabilities.
+const AllCapabilities = capLast - 1
+
+var capNames = []string{
+	"tx",
+	"concurrent-writes",
+	"seek",
+	"prefix-scan",
+	"batch-get",
+	"conflicts",
+}
+
+// Has checks if all capabilities from the set are supported.
+func (c Capabilities) Has(caps Capabilities) bool {
+	return c&caps == caps
+}
+
+func (c Capabilities) String() string {
+	if c == 0 {
+		return "none"
+	}
+	var names []string
+	for i, name := range capNames {
+		if c.Has(1 << uint(i)) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Capable is implemented by databases that report their capabilities at runtime.
+//
+// Runtime capabilities may differ from the ones in the driver registration,
+// for example when they depend on the database configuration or on the remote server.
+type Capable interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns capabilities of an opened database.
+// It returns false if the database doesn't report its capabilities.
+func CapabilitiesOf(db interface{}) (Capabilities, bool) {
+	c, ok := db.(Capable)
+	if !ok {
+		return 0, false
+	}
+	return c.Capabilities(), true
+}
@@ -9,4 +9,6 @@ type Registration struct {
 	Title    string // human-readable name
 	Local    bool   // stores data on local disk or keeps it in-memory
 	Volatile bool   // not persistent
+	// Capabilities lists features supported by the driver. Opened databases may report more precise set (see Capable).
+	Capabilities Capabilities
 }
@@ -0,0 +1,175 @@
+// Package chaos provides fault-injecting wrappers for key-value and tuple stores.
+//
+// Wrappers can be configured to inject errors, conflicts, latency, iterator failures and failed commits,
+// which allows to test retry and error handling code without a real backend failure.
+//
+// Faults are decided by a random source with a fixed seed. Given the same seed and the same sequence of operations,
+// wrappers inject exactly the same faults. Concurrent use makes the sequence of operations (and thus faults)
+// dependent on the scheduling.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+)
+
+// ErrInjected is the default error returned by injected faults.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Op is a type of operation performed on the store.
+type Op string
+
+const (
+	OpTx       = Op("tx")     // opening a transaction
+	OpGet      = Op("get")    // Get and GetTuple
+	OpGetBatch = Op("batch")  // GetBatch and GetTupleBatch
+	OpPut      = Op("put")    // Put, InsertTuple and UpdateTuple
+	OpDel      = Op("del")    // Del and DeleteTuples
+	OpScan     = Op("scan")   // starting an iterator
+	OpNext     = Op("next")   // advancing an iterator
+	OpCommit   = Op("commit") // committing a transaction
+	OpTable    = Op("table")  // opening, listing, creating, dropping and clearing tables
+)
+
+// Options configures faults injected by the wrappers.
+//
+// Rates are probabilities in [0, 1] range; zero disables a specific fault.
+type Options struct {
+	// Seed for the random source.
+	Seed int64
+	// Error is returned by injected faults. Default is ErrInjected.
+	Error error
+	// ErrorRate is a probability of failing any operation before it reaches the store.
+	ErrorRate float64
+	// ConflictRate is a probability of failing the commit of a read-write transaction with kv.ErrConflict.
+	// Changes made by the transaction are discarded.
+	ConflictRate float64
+	// CommitErrorRate is a probability of failing the commit of a read-write transaction after it was applied.
+	// This simulates a lost acknowledgement: the caller gets an error, but changes are persisted.
+	CommitErrorRate float64
+	// IterErrorRate is a probability of failing each step of the iterator after the first item was returned.
+	IterErrorRate float64
+	// Latency is added to each operation.
+	Latency time.Duration
+	// Jitter is the maximal random latency added on top of Latency.
+	Jitter time.Duration
+	// Context makes every operation fail with the context error if the context is done,
+	// even if the store itself ignores the context.
+	Context bool
+	// Inject is called before each operation. A non-nil error is returned to the caller instead of performing the operation.
+	// It allows to deterministically inject faults into specific operations.
+	Inject func(op Op) error
+}
+
+// Faults decides which faults to inject. It can be shared between multiple wrappers. It's safe for concurrent use.
+type Faults struct {
+	opts Options
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// New creates a fault injector with given options.
+func New(opts Options) *Faults {
+	if opts.Error == nil {
+		opts.Error = ErrInjected
+	}
+	return &Faults{opts: opts, rnd: rand.New(rand.NewSource(opts.Seed))}
+}
+
+// capabilities returns capabilities of the wrapped database. Conflicts are reported if they are injected.
+func (f *Faults) capabilities(db interface{}) base.Capabilities {
+	c, _ := base.CapabilitiesOf(db)
+	if f.opts.ConflictRate > 0 {
+		c |= base.CapConflicts
+	}
+	return c
+}
+
+// roll returns true with a given probability.
+func (f *Faults) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rnd.Float64() < rate
+}
+
+func (f *Faults) delay() time.Duration {
+	d := f.opts.Latency
+	if f.opts.Jitter > 0 {
+		f.mu.Lock()
+		d += time.Duration(f.rnd.Int63n(int64(f.opts.Jitter) + 1))
+		f.mu.Unlock()
+	}
+	return d
+}
+
+// before is called before each operation and returns an error if the operation must fail.
+func (f *Faults) before(ctx context.Context, op Op) error {
+	if d := f.delay(); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			// stopped timer never fires, so it must not be drained
+			t.Stop()
+			if f.opts.Context {
+				return ctx.Err()
+			}
+			// store will see the context error by itself
+		}
+	}
+	if f.opts.Context {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if f.opts.Inject != nil {
+		if err := f.opts.Inject(op); err != nil {
+			return err
+		}
+	}
+	if f.roll(f.opts.ErrorRate) {
+		return f.opts.Error
+	}
+	return nil
+}
+
+// next is called before each iterator step. The n is the number of items already returned by the iterator.
+func (f *Faults) next(ctx context.Context, n int) error {
+	if err := f.before(ctx, OpNext); err != nil {
+		return err
+	}
+	if n > 0 && f.roll(f.opts.IterErrorRate) {
+		return f.opts.Error
+	}
+	return nil
+}
+
+// commit runs the commit function and injects commit faults.
+// The rollback function is called instead of commit if the conflict is injected.
+func (f *Faults) commit(ctx context.Context, rw bool, commit func(ctx context.Context) error, rollback func() error) error {
+	if err := f.before(ctx, OpCommit); err != nil {
+		_ = rollback()
+		return err
+	}
+	if rw && f.roll(f.opts.ConflictRate) {
+		_ = rollback()
+		return kv.ErrConflict
+	}
+	if err := commit(ctx); err != nil {
+		return err
+	}
+	if rw && f.roll(f.opts.CommitErrorRate) {
+		return f.opts.Error
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package chaos_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/chaos"
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/flat"
+	"github.com/hidal-go/hidalgo/kv/flat/btree"
+	"github.com/hidal-go/hidalgo/kv/kvtest"
+	"github.com/hidal-go/hidalgo/kv/mem"
+	"github.com/hidal-go/hidalgo/kv/options"
+	"github.com/hidal-go/hidalgo/tuple"
+	tuplekv "github.com/hidal-go/hidalgo/tuple/kv"
+	"github.com/hidal-go/hidalgo/tuple/tupletest"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+// Wrappers without faults must pass the standard test suites.
+
+func TestKV(t *testing.T) {
+	kvtest.RunTest(t, func(t testing.TB) kv.KV {
+		return chaos.WrapKV(mem.New(), chaos.New(chaos.Options{}))
+	}, nil)
+}
+
+func TestFlat(t *testing.T) {
+	kvtest.RunTest(t, func(t testing.TB) kv.KV {
+		return flat.Upgrade(chaos.WrapFlat(btree.New(), chaos.New(chaos.Options{})))
+	}, nil)
+}
+
+func TestTuple(t *testing.T) {
+	tupletest.RunTest(t, func(t testing.TB) tuple.Store {
+		return chaos.WrapTuple(tuplekv.New(flat.Upgrade(btree.New())), chaos.New(chaos.Options{}))
+	}, nil)
+}
+
+// run performs the same sequence of writes and returns the outcome of each operation.
+func run(t *testing.T, seed int64) []error {
+	ctx := context.Background()
+	db := chaos.WrapKV(mem.New(), chaos.New(chaos.Options{Seed: seed, ErrorRate: 0.3}))
+	defer db.Close()
+
+	var errs []error
+	for i := 0; i < 50; i++ {
+		err := db.Update(ctx, func(tx kv.Tx) error {
+			return tx.Put(ctx, kv.SKey("a", string(rune('a'+i%26))), kv.Value("v"))
+		})
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func TestDeterministic(t *testing.T) {
+	e1, e2 := run(t, 1), run(t, 1)
+	require.Equal(t, e1, e2)
+
+	failed := 0
+	for _, err := range e1 {
+		if err != nil {
+			require.True(t, errors.Is(err, chaos.ErrInjected), "%v", err)
+			failed++
+		}
+	}
+	require.True(t, failed > 0 && failed < len(e1), "failed: %d", failed)
+	require.NotEqual(t, e1, run(t, 2))
+}
+
+func TestInject(t *testing.T) {
+	ctx := context.Background()
+	errPut := errors.New("put failed")
+	db := chaos.WrapKV(mem.New(), chaos.New(chaos.Options{
+		Inject: func(op chaos.Op) error {
+			if op == chaos.OpPut {
+				return errPut
+			}
+			return nil
+		},
+	}))
+	defer db.Close()
+
+	err := db.Update(ctx, func(tx kv.Tx) error {
+		return tx.Put(ctx, kv.SKey("a"), kv.Value("1"))
+	})
+	require.Equal(t, errPut, err)
+
+	err = db.View(ctx, func(tx kv.Tx) error {
+		_, err := tx.Get(ctx, kv.SKey("a"))
+		return err
+	})
+	require.Equal(t, kv.ErrNotFound, err)
+}
+
+func TestConflict(t *testing.T) {
+	ctx := context.Background()
+	db := chaos.WrapKV(mem.New(), chaos.New(chaos.Options{Seed: 1, ConflictRate: 0.5}))
+	defer db.Close()
+
+	conflicts := 0
+	for i := 0; i < 20; i++ {
+		key := kv.SKey("a", string(rune('A'+i)))
+		tx, err := db.Tx(ctx, true)
+		require.NoError(t, err)
+		err = tx.Put(ctx, key, kv.Value("1"))
+		require.NoError(t, err)
+		cerr := tx.Commit(ctx)
+		require.NoError(t, tx.Close())
+
+		err = db.View(ctx, func(tx kv.Tx) error {
+			_, err := tx.Get(ctx, key)
+			return err
+		})
+		if cerr == nil {
+			require.NoError(t, err)
+			continue
+		}
+		require.Equal(t, kv.ErrConflict, cerr)
+		conflicts++
+		// changes must be discarded
+		require.Equal(t, kv.ErrNotFound, err)
+	}
+	require.True(t, conflicts > 0 && conflicts < 20, "conflicts: %d", conflicts)
+
+	// kv.Update retries on conflicts
+	calls := 0
+	for i := 0; i < 20; i++ {
+		err := db.Update(ctx, func(tx kv.Tx) error {
+			calls++
+			return tx.Put(ctx, kv.SKey("b"), kv.Value("1"))
+		})
+		require.NoError(t, err)
+	}
+	require.True(t, calls > 20, "calls: %d", calls)
+
+	// read-only transactions never conflict
+	for i := 0; i < 20; i++ {
+		err := db.View(ctx, func(tx kv.Tx) error { return nil })
+		require.NoError(t, err)
+	}
+}
+
+func TestCommitError(t *testing.T) {
+	ctx := context.Background()
+	db := chaos.WrapFlat(btree.New(), chaos.New(chaos.Options{CommitErrorRate: 1}))
+	defer db.Close()
+
+	err := db.Update(ctx, func(tx flat.Tx) error {
+		return tx.Put(ctx, flat.Key("a"), flat.Value("1"))
+	})
+	require.Equal(t, chaos.ErrInjected, err)
+
+	// changes are persisted anyway
+	err = db.View(ctx, func(tx flat.Tx) error {
+		v, err := tx.Get(ctx, flat.Key("a"))
+		require.Equal(t, flat.Value("1"), v)
+		return err
+	})
+	require.NoError(t, err)
+}
+
+func TestIterError(t *testing.T) {
+	ctx := context.Background()
+	db := chaos.WrapKV(mem.New(), chaos.New(chaos.Options{Seed: 1, IterErrorRate: 0.2}))
+	defer db.Close()
+
+	for i := 0; i < 50; i++ {
+		err := db.KV.Update(ctx, func(tx kv.Tx) error {
+			return tx.Put(ctx, kv.SKey("a", string(rune('A'+i))), kv.Value("v"))
+		})
+		require.NoError(t, err)
+	}
+
+	n := 0
+	err := db.View(ctx, func(tx kv.Tx) error {
+		it := tx.Scan(ctx, options.WithPrefixKV(kv.SKey("a")))
+		defer it.Close()
+		for it.Next(ctx) {
+			n++
+		}
+		return it.Err()
+	})
+	require.Equal(t, chaos.ErrInjected, err)
+	require.True(t, n > 0 && n < 50, "returned: %d", n)
+}
+
+func TestTupleFaults(t *testing.T) {
+	ctx := context.Background()
+	var fail bool
+	db := chaos.WrapTuple(tuplekv.New(flat.Upgrade(btree.New())), chaos.New(chaos.Options{
+		Inject: func(op chaos.Op) error {
+			if fail && op == chaos.OpGet {
+				return chaos.ErrInjected
+			}
+			return nil
+		},
+	}))
+	defer db.Close()
+
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "test",
+			Key:  []tuple.KeyField{{Name: "k", Type: values.StringType{}}},
+			Data: []tuple.Field{{Name: "v", Type: values.IntType{}}},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+			Key:  tuple.SKey("a"),
+			Data: tuple.Data{values.Int(1)},
+		})
+		return err
+	})
+	require.NoError(t, err)
+
+	info, err := db.Table(ctx, "test")
+	require.NoError(t, err)
+
+	fail = true
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := info.Open(ctx, tx)
+		if err != nil {
+			return err
+		}
+		_, err = tbl.GetTuple(ctx, tuple.SKey("a"))
+		return err
+	})
+	require.Equal(t, chaos.ErrInjected, err)
+}
+
+func TestContext(t *testing.T) {
+	db := chaos.WrapKV(mem.New(), chaos.New(chaos.Options{
+		Latency: time.Hour,
+		Context: true,
+	}))
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := db.Tx(ctx, false)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.True(t, time.Since(start) < time.Minute)
+}
+
+func TestContextLatency(t *testing.T) {
+	// the context error is left to the store
+	db := chaos.WrapKV(mem.New(), chaos.New(chaos.Options{
+		Latency: time.Hour,
+	}))
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if tx, err := db.Tx(ctx, false); err == nil {
+			_ = tx.Close()
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Fatal("operation is blocked after the context is done")
+	}
+}
+
+func TestCommitInjected(t *testing.T) {
+	ctx := context.Background()
+	db := chaos.WrapKV(mem.New(), chaos.New(chaos.Options{
+		Inject: func(op chaos.Op) error {
+			if op == chaos.OpCommit {
+				return chaos.ErrInjected
+			}
+			return nil
+		},
+	}))
+	defer db.Close()
+
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	require.NoError(t, tx.Put(ctx, kv.SKey("a"), kv.Value("1")))
+
+	// the same fault is returned by later commits
+	require.Equal(t, chaos.ErrInjected, tx.Commit(ctx))
+	require.Equal(t, chaos.ErrInjected, tx.Commit(ctx))
+	require.NoError(t, tx.Close())
+}
+
+func TestScanReset(t *testing.T) {
+	ctx := context.Background()
+	db := chaos.WrapKV(mem.New(), chaos.New(chaos.Options{
+		Inject: func(op chaos.Op) error {
+			if op == chaos.OpScan {
+				return chaos.ErrInjected
+			}
+			return nil
+		},
+	}))
+	defer db.Close()
+
+	err := db.KV.Update(ctx, func(tx kv.Tx) error {
+		return tx.Put(ctx, kv.SKey("a"), kv.Value("1"))
+	})
+	require.NoError(t, err)
+
+	err = db.View(ctx, func(tx kv.Tx) error {
+		it := tx.Scan(ctx)
+		defer it.Close()
+		require.False(t, it.Next(ctx))
+		require.Equal(t, chaos.ErrInjected, it.Err())
+
+		// fault injected on scan is not cleared by Reset
+		it.Reset()
+		require.False(t, it.Next(ctx))
+		return it.Err()
+	})
+	require.Equal(t, chaos.ErrInjected, err)
+}
@@ -0,0 +1,177 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv/flat"
+)
+
+var _ flat.KV = (*Flat)(nil)
+
+// WrapFlat wraps a flat key-value store and injects faults into its operations.
+func WrapFlat(db flat.KV, f *Faults) *Flat {
+	return &Flat{KV: db, f: f}
+}
+
+// Flat is a flat key-value store with fault injection.
+type Flat struct {
+	f  *Faults
+	KV flat.KV
+}
+
+func (d *Flat) Close() error {
+	return d.KV.Close()
+}
+
+// Capabilities implements base.Capable.
+func (d *Flat) Capabilities() base.Capabilities {
+	return d.f.capabilities(d.KV)
+}
+
+func (d *Flat) Tx(ctx context.Context, rw bool) (flat.Tx, error) {
+	if err := d.f.before(ctx, OpTx); err != nil {
+		return nil, err
+	}
+	tx, err := d.KV.Tx(ctx, rw)
+	if err != nil {
+		return nil, err
+	}
+	return &flatTx{f: d.f, tx: tx, rw: rw}, nil
+}
+
+func (d *Flat) View(ctx context.Context, fn func(tx flat.Tx) error) error {
+	return flat.View(ctx, d, fn)
+}
+
+func (d *Flat) Update(ctx context.Context, fn func(tx flat.Tx) error) error {
+	return flat.Update(ctx, d, fn)
+}
+
+type flatTx struct {
+	f    *Faults
+	tx   flat.Tx
+	rw   bool
+	done bool  // rolled back by the injected fault
+	err  error // injected fault; returned by later commits
+}
+
+func (tx *flatTx) Commit(ctx context.Context) error {
+	if tx.done {
+		return tx.err
+	}
+	err := tx.f.commit(ctx, tx.rw, tx.tx.Commit, func() error {
+		tx.done = true
+		return tx.tx.Close()
+	})
+	if tx.done {
+		tx.err = err
+	}
+	return err
+}
+
+func (tx *flatTx) Close() error {
+	if tx.done {
+		return nil
+	}
+	return tx.tx.Close()
+}
+
+func (tx *flatTx) Get(ctx context.Context, k flat.Key) (flat.Value, error) {
+	if err := tx.f.before(ctx, OpGet); err != nil {
+		return nil, err
+	}
+	return tx.tx.Get(ctx, k)
+}
+
+func (tx *flatTx) GetBatch(ctx context.Context, keys []flat.Key) ([]flat.Value, error) {
+	if err := tx.f.before(ctx, OpGetBatch); err != nil {
+		return nil, err
+	}
+	return tx.tx.GetBatch(ctx, keys)
+}
+
+func (tx *flatTx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
+	if err := tx.f.before(ctx, OpPut); err != nil {
+		return err
+	}
+	return tx.tx.Put(ctx, k, v)
+}
+
+func (tx *flatTx) Del(ctx context.Context, k flat.Key) error {
+	if err := tx.f.before(ctx, OpDel); err != nil {
+		return err
+	}
+	return tx.tx.Del(ctx, k)
+}
+
+func (tx *flatTx) Scan(ctx context.Context, opts ...flat.IteratorOption) flat.Iterator {
+	it := &flatIterator{f: tx.f, it: tx.tx.Scan(ctx, opts...)}
+	it.serr = tx.f.before(ctx, OpScan)
+	it.err = it.serr
+	return it
+}
+
+type flatIterator struct {
+	f    *Faults
+	it   flat.Iterator
+	n    int   // items returned since reset
+	serr error // injected when the scan was started; kept across Reset
+	err  error
+}
+
+func (it *flatIterator) Reset() {
+	it.n = 0
+	it.err = it.serr
+	it.it.Reset()
+}
+
+// check is called before each iterator step.
+func (it *flatIterator) check(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	it.err = it.f.next(ctx, it.n)
+	return it.err == nil
+}
+
+func (it *flatIterator) Next(ctx context.Context) bool {
+	if !it.check(ctx) || !it.it.Next(ctx) {
+		return false
+	}
+	it.n++
+	return true
+}
+
+func (it *flatIterator) Seek(ctx context.Context, key flat.Key) bool {
+	if !it.check(ctx) || !flat.Seek(ctx, it.it, key) {
+		return false
+	}
+	it.n++
+	return true
+}
+
+func (it *flatIterator) Key() flat.Key {
+	if it.err != nil {
+		return nil
+	}
+	return it.it.Key()
+}
+
+func (it *flatIterator) Val() flat.Value {
+	if it.err != nil {
+		return nil
+	}
+	return it.it.Val()
+}
+
+func (it *flatIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err()
+}
+
+func (it *flatIterator) Close() error {
+	return it.it.Close()
+}
@@ -0,0 +1,177 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+)
+
+var _ kv.KV = (*KV)(nil)
+
+// WrapKV wraps a hierarchical key-value store and injects faults into its operations.
+func WrapKV(db kv.KV, f *Faults) *KV {
+	return &KV{KV: db, f: f}
+}
+
+// KV is a hierarchical key-value store with fault injection.
+type KV struct {
+	f  *Faults
+	KV kv.KV
+}
+
+func (d *KV) Close() error {
+	return d.KV.Close()
+}
+
+// Capabilities implements base.Capable.
+func (d *KV) Capabilities() base.Capabilities {
+	return d.f.capabilities(d.KV)
+}
+
+func (d *KV) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
+	if err := d.f.before(ctx, OpTx); err != nil {
+		return nil, err
+	}
+	tx, err := d.KV.Tx(ctx, rw)
+	if err != nil {
+		return nil, err
+	}
+	return &kvTx{f: d.f, tx: tx, rw: rw}, nil
+}
+
+func (d *KV) View(ctx context.Context, fn func(tx kv.Tx) error) error {
+	return kv.View(ctx, d, fn)
+}
+
+func (d *KV) Update(ctx context.Context, fn func(tx kv.Tx) error) error {
+	return kv.Update(ctx, d, fn)
+}
+
+type kvTx struct {
+	f    *Faults
+	tx   kv.Tx
+	rw   bool
+	done bool  // rolled back by the injected fault
+	err  error // injected fault; returned by later commits
+}
+
+func (tx *kvTx) Commit(ctx context.Context) error {
+	if tx.done {
+		return tx.err
+	}
+	err := tx.f.commit(ctx, tx.rw, tx.tx.Commit, func() error {
+		tx.done = true
+		return tx.tx.Close()
+	})
+	if tx.done {
+		tx.err = err
+	}
+	return err
+}
+
+func (tx *kvTx) Close() error {
+	if tx.done {
+		return nil
+	}
+	return tx.tx.Close()
+}
+
+func (tx *kvTx) Get(ctx context.Context, k kv.Key) (kv.Value, error) {
+	if err := tx.f.before(ctx, OpGet); err != nil {
+		return nil, err
+	}
+	return tx.tx.Get(ctx, k)
+}
+
+func (tx *kvTx) GetBatch(ctx context.Context, keys []kv.Key) ([]kv.Value, error) {
+	if err := tx.f.before(ctx, OpGetBatch); err != nil {
+		return nil, err
+	}
+	return tx.tx.GetBatch(ctx, keys)
+}
+
+func (tx *kvTx) Put(ctx context.Context, k kv.Key, v kv.Value) error {
+	if err := tx.f.before(ctx, OpPut); err != nil {
+		return err
+	}
+	return tx.tx.Put(ctx, k, v)
+}
+
+func (tx *kvTx) Del(ctx context.Context, k kv.Key) error {
+	if err := tx.f.before(ctx, OpDel); err != nil {
+		return err
+	}
+	return tx.tx.Del(ctx, k)
+}
+
+func (tx *kvTx) Scan(ctx context.Context, opts ...kv.IteratorOption) kv.Iterator {
+	it := &kvIterator{f: tx.f, it: tx.tx.Scan(ctx, opts...)}
+	it.serr = tx.f.before(ctx, OpScan)
+	it.err = it.serr
+	return it
+}
+
+type kvIterator struct {
+	f    *Faults
+	it   kv.Iterator
+	n    int   // items returned since reset
+	serr error // injected when the scan was started; kept across Reset
+	err  error
+}
+
+func (it *kvIterator) Reset() {
+	it.n = 0
+	it.err = it.serr
+	it.it.Reset()
+}
+
+// check is called before each iterator step.
+func (it *kvIterator) check(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	it.err = it.f.next(ctx, it.n)
+	return it.err == nil
+}
+
+func (it *kvIterator) Next(ctx context.Context) bool {
+	if !it.check(ctx) || !it.it.Next(ctx) {
+		return false
+	}
+	it.n++
+	return true
+}
+
+func (it *kvIterator) Seek(ctx context.Context, key kv.Key) bool {
+	if !it.check(ctx) || !kv.Seek(ctx, it.it, key) {
+		return false
+	}
+	it.n++
+	return true
+}
+
+func (it *kvIterator) Key() kv.Key {
+	if it.err != nil {
+		return nil
+	}
+	return it.it.Key()
+}
+
+func (it *kvIterator) Val() kv.Value {
+	if it.err != nil {
+		return nil
+	}
+	return it.it.Val()
+}
+
+func (it *kvIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err()
+}
+
+func (it *kvIterator) Close() error {
+	return it.it.Close()
+}
@@ -0,0 +1,309 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/tuple"
+)
+
+var _ tuple.Store = (*Tuple)(nil)
+
+// WrapTuple wraps a tuple store and injects faults into its operations.
+//
+// Injected conflicts are reported as kv.ErrConflict.
+func WrapTuple(db tuple.Store, f *Faults) *Tuple {
+	return &Tuple{Store: db, f: f}
+}
+
+// Tuple is a tuple store with fault injection.
+type Tuple struct {
+	f     *Faults
+	Store tuple.Store
+}
+
+func (d *Tuple) Close() error {
+	return d.Store.Close()
+}
+
+// Capabilities implements base.Capable.
+func (d *Tuple) Capabilities() base.Capabilities {
+	return d.f.capabilities(d.Store)
+}
+
+func (d *Tuple) Tx(ctx context.Context, rw bool) (tuple.Tx, error) {
+	if err := d.f.before(ctx, OpTx); err != nil {
+		return nil, err
+	}
+	tx, err := d.Store.Tx(ctx, rw)
+	if err != nil {
+		return nil, err
+	}
+	return &tupleTx{f: d.f, tx: tx, rw: rw}, nil
+}
+
+func (d *Tuple) View(ctx context.Context, view func(tx tuple.Tx) error) error {
+	return tuple.View(ctx, d, view)
+}
+
+func (d *Tuple) Update(ctx context.Context, update func(tx tuple.Tx) error) error {
+	return tuple.Update(ctx, d, update)
+}
+
+func (d *Tuple) Table(ctx context.Context, name string) (tuple.TableInfo, error) {
+	if err := d.f.before(ctx, OpTable); err != nil {
+		return nil, err
+	}
+	info, err := d.Store.Table(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &tableInfo{f: d.f, info: info}, nil
+}
+
+func (d *Tuple) ListTables(ctx context.Context) ([]tuple.TableInfo, error) {
+	if err := d.f.before(ctx, OpTable); err != nil {
+		return nil, err
+	}
+	list, err := d.Store.ListTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]tuple.TableInfo, 0, len(list))
+	for _, info := range list {
+		out = append(out, &tableInfo{f: d.f, info: info})
+	}
+	return out, nil
+}
+
+type tupleTx struct {
+	f    *Faults
+	tx   tuple.Tx
+	rw   bool
+	done bool  // rolled back by the injected fault
+	err  error // injected fault; returned by later commits
+}
+
+func (tx *tupleTx) Commit(ctx context.Context) error {
+	if tx.done {
+		return tx.err
+	}
+	err := tx.f.commit(ctx, tx.rw, tx.tx.Commit, func() error {
+		tx.done = true
+		return tx.tx.Close()
+	})
+	if tx.done {
+		tx.err = err
+	}
+	return err
+}
+
+func (tx *tupleTx) Close() error {
+	if tx.done {
+		return nil
+	}
+	return tx.tx.Close()
+}
+
+func (tx *tupleTx) table(t tuple.Table) tuple.Table {
+	return &table{f: tx.f, tbl: t}
+}
+
+func (tx *tupleTx) Table(ctx context.Context, name string) (tuple.Table, error) {
+	if err := tx.f.before(ctx, OpTable); err != nil {
+		return nil, err
+	}
+	t, err := tx.tx.Table(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return tx.table(t), nil
+}
+
+func (tx *tupleTx) ListTables(ctx context.Context) ([]tuple.Table, error) {
+	if err := tx.f.before(ctx, OpTable); err != nil {
+		return nil, err
+	}
+	list, err := tx.tx.ListTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]tuple.Table, 0, len(list))
+	for _, t := range list {
+		out = append(out, tx.table(t))
+	}
+	return out, nil
+}
+
+func (tx *tupleTx) CreateTable(ctx context.Context, h tuple.Header) (tuple.Table, error) {
+	if err := tx.f.before(ctx, OpTable); err != nil {
+		return nil, err
+	}
+	t, err := tx.tx.CreateTable(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	return tx.table(t), nil
+}
+
+func (tx *tupleTx) AlterTable(ctx context.Context, name string, changes ...tuple.Alteration) (tuple.Table, error) {
+	if err := tx.f.before(ctx, OpTable); err != nil {
+		return nil, err
+	}
+	t, err := tx.tx.AlterTable(ctx, name, changes...)
+	if err != nil {
+		return nil, err
+	}
+	return tx.table(t), nil
+}
+
+// open binds the table info to a transaction, unwrapping the transaction if it was created by the wrapper.
+func open(ctx context.Context, f *Faults, info tuple.TableInfo, tx tuple.Tx) (tuple.Table, error) {
+	if err := f.before(ctx, OpTable); err != nil {
+		return nil, err
+	}
+	wtx, ok := tx.(*tupleTx)
+	if !ok {
+		return info.Open(ctx, tx)
+	}
+	t, err := info.Open(ctx, wtx.tx)
+	if err != nil {
+		return nil, err
+	}
+	return wtx.table(t), nil
+}
+
+type tableInfo struct {
+	f    *Faults
+	info tuple.TableInfo
+}
+
+func (t *tableInfo) Header() tuple.Header {
+	return t.info.Header()
+}
+
+func (t *tableInfo) Open(ctx context.Context, tx tuple.Tx) (tuple.Table, error) {
+	return open(ctx, t.f, t.info, tx)
+}
+
+type table struct {
+	f   *Faults
+	tbl tuple.Table
+}
+
+func (t *table) Header() tuple.Header {
+	return t.tbl.Header()
+}
+
+func (t *table) Open(ctx context.Context, tx tuple.Tx) (tuple.Table, error) {
+	return open(ctx, t.f, t.tbl, tx)
+}
+
+func (t *table) Drop(ctx context.Context) error {
+	if err := t.f.before(ctx, OpTable); err != nil {
+		return err
+	}
+	return t.tbl.Drop(ctx)
+}
+
+func (t *table) Clear(ctx context.Context) error {
+	if err := t.f.before(ctx, OpTable); err != nil {
+		return err
+	}
+	return t.tbl.Clear(ctx)
+}
+
+func (t *table) GetTuple(ctx context.Context, key tuple.Key) (tuple.Data, error) {
+	if err := t.f.before(ctx, OpGet); err != nil {
+		return nil, err
+	}
+	return t.tbl.GetTuple(ctx, key)
+}
+
+func (t *table) GetTupleBatch(ctx context.Context, keys []tuple.Key) ([]tuple.Data, error) {
+	if err := t.f.before(ctx, OpGetBatch); err != nil {
+		return nil, err
+	}
+	return t.tbl.GetTupleBatch(ctx, keys)
+}
+
+func (t *table) InsertTuple(ctx context.Context, v tuple.Tuple) (tuple.Key, error) {
+	if err := t.f.before(ctx, OpPut); err != nil {
+		return nil, err
+	}
+	return t.tbl.InsertTuple(ctx, v)
+}
+
+func (t *table) UpdateTuple(ctx context.Context, v tuple.Tuple, opt *tuple.UpdateOpt) error {
+	if err := t.f.before(ctx, OpPut); err != nil {
+		return err
+	}
+	return t.tbl.UpdateTuple(ctx, v, opt)
+}
+
+func (t *table) DeleteTuples(ctx context.Context, f *tuple.Filter) error {
+	if err := t.f.before(ctx, OpDel); err != nil {
+		return err
+	}
+	return t.tbl.DeleteTuples(ctx, f)
+}
+
+func (t *table) Scan(ctx context.Context, opt *tuple.ScanOptions) tuple.Iterator {
+	it := &tupleIterator{f: t.f, it: t.tbl.Scan(ctx, opt)}
+	it.serr = t.f.before(ctx, OpScan)
+	it.err = it.serr
+	return it
+}
+
+type tupleIterator struct {
+	f    *Faults
+	it   tuple.Iterator
+	n    int   // items returned since reset
+	serr error // injected when the scan was started; kept across Reset
+	err  error
+}
+
+func (it *tupleIterator) Reset() {
+	it.n = 0
+	it.err = it.serr
+	it.it.Reset()
+}
+
+func (it *tupleIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if it.err = it.f.next(ctx, it.n); it.err != nil {
+		return false
+	}
+	if !it.it.Next(ctx) {
+		return false
+	}
+	it.n++
+	return true
+}
+
+func (it *tupleIterator) Key() tuple.Key {
+	if it.err != nil {
+		return nil
+	}
+	return it.it.Key()
+}
+
+func (it *tupleIterator) Data() tuple.Data {
+	if it.err != nil {
+		return nil
+	}
+	return it.it.Data()
+}
+
+func (it *tupleIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err()
+}
+
+func (it *tupleIterator) Close() error {
+	return it.it.Close()
+}
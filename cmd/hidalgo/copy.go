@@ -0,0 +1,530 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/tuple"
+)
+
+func init() {
+	registerCommand(command{
+		name: "dump", db: true,
+		help: "write all data from the database to a dump",
+		setup: func(fs *flag.FlagSet) runFunc {
+			out := fs.String("o", "", "output file (default stdout)")
+			enc := fs.String("enc", encJSON, "dump encoding: jsonl or bin")
+			tables := fs.Bool("tuple", false, "dump tables instead of key-value pairs for key-value stores")
+			every := fs.Duration("progress", 10*time.Second, "progress reporting interval; 0 disables it")
+			return func(ctx context.Context, e *env, args []string) error {
+				if len(args) != 0 {
+					return errUsage
+				}
+				return cmdDump(ctx, e, *out, *enc, *tables, *every)
+			}
+		},
+	})
+	registerCommand(command{
+		name: "load", db: true,
+		help: "write all data from a dump to the database",
+		setup: func(fs *flag.FlagSet) runFunc {
+			in := fs.String("i", "", "input file (default stdin)")
+			opts := loadFlags(fs)
+			return func(ctx context.Context, e *env, args []string) error {
+				if len(args) != 0 {
+					return errUsage
+				}
+				return cmdLoad(ctx, e, *in, *opts)
+			}
+		},
+	})
+	registerCommand(command{
+		name: "copy",
+		help: "copy all data from one database to another",
+		setup: func(fs *flag.FlagSet) runFunc {
+			from := fs.String("from", "", "source database URL")
+			to := fs.String("to", "", "destination database URL")
+			tables := fs.Bool("tuple", false, "copy tables instead of key-value pairs for key-value stores")
+			opts := loadFlags(fs)
+			return func(ctx context.Context, e *env, args []string) error {
+				if len(args) != 0 || *from == "" || *to == "" {
+					return errUsage
+				}
+				return cmdCopy(ctx, e, *from, *to, *tables, *opts)
+			}
+		},
+	})
+}
+
+// loadOptions are common options for commands that write to the database.
+type loadOptions struct {
+	batch  int           // number of records per transaction
+	resume string        // checkpoint file
+	every  time.Duration // progress reporting interval
+}
+
+func loadFlags(fs *flag.FlagSet) *loadOptions {
+	opts := &loadOptions{}
+	fs.IntVar(&opts.batch, "batch", 1000, "number of records written in a single transaction")
+	fs.StringVar(&opts.resume, "resume", "", "checkpoint file used to resume an interrupted run; existing tables and tuples are overwritten")
+	fs.DurationVar(&opts.every, "progress", 10*time.Second, "progress reporting interval; 0 disables it")
+	return opts
+}
+
+// source produces dump records in a stable order.
+type source interface {
+	kind() string
+	each(ctx context.Context, fnc func(r *record) error) error
+}
+
+// newSource creates a source for the database. Tuple stores and kv stores with asTuple flag are read table by table.
+func newSource(db *database, asTuple bool) source {
+	if db.kv != nil && !asTuple {
+		return kvSource{db: db.kv}
+	}
+	return tupleSource{db: db.tuple}
+}
+
+type kvSource struct {
+	db kv.KV
+}
+
+func (kvSource) kind() string {
+	return dumpKV
+}
+
+func (s kvSource) each(ctx context.Context, fnc func(r *record) error) error {
+	return s.db.View(ctx, func(tx kv.Tx) error {
+		it := tx.Scan(ctx)
+		defer it.Close()
+		for it.Next(ctx) {
+			r := &record{key: it.Key().Clone(), val: append([]byte{}, it.Val()...)}
+			if err := fnc(r); err != nil {
+				return err
+			}
+		}
+		return it.Err()
+	})
+}
+
+type tupleSource struct {
+	db tuple.Store
+}
+
+func (tupleSource) kind() string {
+	return dumpTuple
+}
+
+func (s tupleSource) each(ctx context.Context, fnc func(r *record) error) error {
+	return s.db.View(ctx, func(tx tuple.Tx) error {
+		list, err := tx.ListTables(ctx)
+		if err != nil {
+			return err
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Header().Name < list[j].Header().Name
+		})
+		for _, t := range list {
+			h := t.Header()
+			if err := fnc(&record{table: &h}); err != nil {
+				return err
+			}
+			it := t.Scan(ctx, &tuple.ScanOptions{Sort: tuple.SortAsc})
+			for it.Next(ctx) {
+				r := &record{name: h.Name, tuple: &tuple.Tuple{Key: it.Key(), Data: it.Data()}}
+				if err = fnc(r); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = it.Err()
+			}
+			it.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type readerSource struct {
+	r recordReader
+}
+
+func (s readerSource) kind() string {
+	return s.r.kind()
+}
+
+func (s readerSource) each(ctx context.Context, fnc func(r *record) error) error {
+	for {
+		r, err := s.r.readRecord()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = fnc(r); err != nil {
+			return err
+		}
+	}
+}
+
+// sink consumes dump records.
+type sink interface {
+	begin(kind string) error
+	write(ctx context.Context, r *record) error
+	// finish flushes all pending writes.
+	finish(ctx context.Context) error
+	// abort discards all pending writes.
+	abort()
+}
+
+type streamSink struct {
+	w   io.Writer
+	enc string
+	rw  recordWriter
+}
+
+func (s *streamSink) begin(kind string) (err error) {
+	s.rw, err = newRecordWriter(s.w, s.enc, kind)
+	return err
+}
+
+func (s *streamSink) write(_ context.Context, r *record) error {
+	return s.rw.writeRecord(r)
+}
+
+func (s *streamSink) finish(_ context.Context) error {
+	return s.rw.flush()
+}
+
+func (s *streamSink) abort() {
+	_ = s.rw.flush()
+}
+
+// dbSink writes records to the database in batches.
+type dbSink struct {
+	db     *database
+	batch  int
+	resume bool // reuse existing tables and overwrite existing tuples
+	// onCommit is called after each committed batch with the number of data records in it.
+	onCommit func(n int) error
+
+	kind    string
+	kvtx    kv.Tx
+	ttx     tuple.Tx
+	tables  map[string]tuple.Table // tables opened in the current transaction
+	pending int                    // data records in the current transaction
+}
+
+func (s *dbSink) begin(kind string) error {
+	if kind == dumpKV && s.db.kv == nil {
+		return fmt.Errorf("cannot write key-value pairs to a tuple store %q", s.db.name)
+	}
+	s.kind = kind
+	return nil
+}
+
+func (s *dbSink) open(ctx context.Context) error {
+	var err error
+	if s.kind == dumpKV {
+		if s.kvtx == nil {
+			s.kvtx, err = s.db.kv.Tx(ctx, true)
+		}
+	} else if s.ttx == nil {
+		s.ttx, err = s.db.tuple.Tx(ctx, true)
+		s.tables = make(map[string]tuple.Table)
+	}
+	return err
+}
+
+func (s *dbSink) table(ctx context.Context, name string) (tuple.Table, error) {
+	if t := s.tables[name]; t != nil {
+		return t, nil
+	}
+	t, err := s.ttx.Table(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	s.tables[name] = t
+	return t, nil
+}
+
+func (s *dbSink) write(ctx context.Context, r *record) error {
+	if err := s.open(ctx); err != nil {
+		return err
+	}
+	switch {
+	case r.table != nil:
+		t, err := s.ttx.Table(ctx, r.table.Name)
+		if err == tuple.ErrTableNotFound {
+			t, err = s.ttx.CreateTable(ctx, *r.table)
+		} else if err == nil && !s.resume {
+			err = fmt.Errorf("%w: %q", tuple.ErrTableExists, r.table.Name)
+		}
+		if err != nil {
+			return err
+		}
+		s.tables[r.table.Name] = t
+		return nil
+	case r.tuple != nil:
+		t, err := s.table(ctx, r.name)
+		if err != nil {
+			return err
+		}
+		if s.resume {
+			err = t.UpdateTuple(ctx, *r.tuple, &tuple.UpdateOpt{Upsert: true})
+		} else {
+			_, err = t.InsertTuple(ctx, *r.tuple)
+		}
+		if err != nil {
+			return fmt.Errorf("table %q: %w", r.name, err)
+		}
+	default:
+		if err := s.kvtx.Put(ctx, r.key, r.val); err != nil {
+			return err
+		}
+	}
+	s.pending++
+	if s.batch > 0 && s.pending >= s.batch {
+		return s.finish(ctx)
+	}
+	return nil
+}
+
+func (s *dbSink) finish(ctx context.Context) error {
+	var tx interface {
+		Commit(ctx context.Context) error
+		Close() error
+	}
+	if s.kvtx != nil {
+		tx, s.kvtx = s.kvtx, nil
+	} else if s.ttx != nil {
+		tx, s.ttx, s.tables = s.ttx, nil, nil
+	} else {
+		return nil
+	}
+	err := tx.Commit(ctx)
+	if err2 := tx.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return err
+	}
+	n := s.pending
+	s.pending = 0
+	if s.onCommit != nil {
+		return s.onCommit(n)
+	}
+	return nil
+}
+
+func (s *dbSink) abort() {
+	if s.kvtx != nil {
+		_ = s.kvtx.Close()
+		s.kvtx = nil
+	}
+	if s.ttx != nil {
+		_ = s.ttx.Close()
+		s.ttx, s.tables = nil, nil
+	}
+	s.pending = 0
+}
+
+// checkpoint is saved after each committed batch to allow resuming an interrupted load.
+type checkpoint struct {
+	Records int64 `json:"records"` // number of committed data records
+}
+
+func readCheckpoint(path string) (int64, error) {
+	p, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	var c checkpoint
+	if err := json.Unmarshal(p, &c); err != nil {
+		return 0, fmt.Errorf("invalid checkpoint file %q: %w", path, err)
+	}
+	return c.Records, nil
+}
+
+// writeCheckpoint atomically replaces the checkpoint file.
+func writeCheckpoint(path string, n int64) error {
+	p, err := json.Marshal(checkpoint{Records: n})
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, p, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// progress periodically reports the number of processed records.
+type progress struct {
+	w     io.Writer
+	verb  string
+	every time.Duration
+	start time.Time
+	last  time.Time
+	n     int64
+}
+
+func newProgress(w io.Writer, verb string, every time.Duration) *progress {
+	now := time.Now()
+	return &progress{w: w, verb: verb, every: every, start: now, last: now}
+}
+
+func (p *progress) add(n int64) {
+	p.n += n
+	if p.every <= 0 {
+		return
+	}
+	if now := time.Now(); now.Sub(p.last) >= p.every {
+		p.last = now
+		p.print(now)
+	}
+}
+
+func (p *progress) print(now time.Time) {
+	dt := now.Sub(p.start)
+	rate := float64(p.n) / dt.Seconds()
+	fmt.Fprintf(p.w, "%s %d records in %v (%.0f/s)\n", p.verb, p.n, dt.Round(time.Millisecond), rate)
+}
+
+func (p *progress) done() {
+	if p.every > 0 {
+		p.print(time.Now())
+	}
+}
+
+// pump copies all records from the source to the sink, skipping the first skip data records.
+func pump(ctx context.Context, src source, dst sink, skip int64, p *progress) error {
+	if err := dst.begin(src.kind()); err != nil {
+		return err
+	}
+	var n int64
+	err := src.each(ctx, func(r *record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if r.isData() {
+			if n++; n <= skip {
+				return nil
+			}
+		}
+		if err := dst.write(ctx, r); err != nil {
+			return err
+		}
+		if r.isData() {
+			p.add(1)
+		}
+		return nil
+	})
+	if err == nil {
+		err = dst.finish(ctx)
+	}
+	if err != nil {
+		dst.abort()
+		return err
+	}
+	p.done()
+	return nil
+}
+
+// load copies the source to the database, tracking progress in the checkpoint file if it is set.
+func (e *env) load(ctx context.Context, src source, db *database, opts loadOptions) error {
+	var done int64
+	if opts.resume != "" {
+		var err error
+		if done, err = readCheckpoint(opts.resume); err != nil {
+			return err
+		}
+		if done != 0 {
+			fmt.Fprintf(e.stderr, "resuming after %d records\n", done)
+		}
+	}
+	skip := done
+	dst := &dbSink{db: db, batch: opts.batch, resume: opts.resume != ""}
+	if opts.resume != "" {
+		dst.onCommit = func(n int) error {
+			done += int64(n)
+			return writeCheckpoint(opts.resume, done)
+		}
+	}
+	err := pump(ctx, src, dst, skip, newProgress(e.stderr, "loaded", opts.every))
+	if err != nil {
+		return err
+	}
+	if opts.resume != "" {
+		// completed successfully; the next run should start from scratch
+		if err := os.Remove(opts.resume); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func dump(ctx context.Context, e *env, db *database, w io.Writer, enc string, asTuple bool, every time.Duration) error {
+	dst := &streamSink{w: w, enc: enc}
+	return pump(ctx, newSource(db, asTuple), dst, 0, newProgress(e.stderr, "dumped", every))
+}
+
+func cmdDump(ctx context.Context, e *env, out, enc string, asTuple bool, every time.Duration) error {
+	return e.withDB(func(db *database) error {
+		if out == "" {
+			return dump(ctx, e, db, e.stdout, enc, asTuple, every)
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		err = dump(ctx, e, db, f, enc, asTuple, every)
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+		return err
+	})
+}
+
+func cmdLoad(ctx context.Context, e *env, in string, opts loadOptions) error {
+	var r io.Reader = e.stdin
+	if in != "" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	rr, err := newRecordReader(r)
+	if err != nil {
+		return err
+	}
+	return e.withDB(func(db *database) error {
+		return e.load(ctx, readerSource{r: rr}, db, opts)
+	})
+}
+
+func cmdCopy(ctx context.Context, e *env, from, to string, asTuple bool, opts loadOptions) error {
+	if from == to {
+		return errors.New("source and destination must be different")
+	}
+	return e.withDBURL(from, func(src *database) error {
+		return e.withDBURL(to, func(dst *database) error {
+			return e.load(ctx, newSource(src, asTuple), dst, opts)
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+	_ "github.com/hidal-go/hidalgo/kv/all"
+	"github.com/hidal-go/hidalgo/kv/flat"
+	"github.com/hidal-go/hidalgo/tuple"
+	tuplekv "github.com/hidal-go/hidalgo/tuple/kv"
+	sqltuple "github.com/hidal-go/hidalgo/tuple/sql"
+	_ "github.com/hidal-go/hidalgo/tuple/sql/all"
+)
+
+// sqlPrefix is a prefix used for SQL drivers in database URLs.
+const sqlPrefix = "sql" + base.RegistrySep
+
+// database is an opened key-value or tuple store.
+type database struct {
+	name  string
+	kv    kv.KV       // nil for tuple stores
+	tuple tuple.Store // tuple store, or key-value store with tuple layer on top of it
+}
+
+// openDB opens a database given its URL in the form of "driver:path".
+//
+// Drivers are looked up in the kv registry first, then in the flat registry.
+// SQL drivers use "sql." prefix, and the path is expected to be in the form of "address/database".
+func openDB(url string) (*database, error) {
+	i := strings.Index(url, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid database URL %q: expected driver:path", url)
+	}
+	name, path := url[:i], url[i+1:]
+	if strings.HasPrefix(name, sqlPrefix) {
+		sname := strings.TrimPrefix(name, sqlPrefix)
+		if sqltuple.ByName(sname) == nil {
+			return nil, fmt.Errorf("unknown driver: %q", name)
+		}
+		addr, ns := path, ""
+		if j := strings.LastIndex(path, "/"); j >= 0 {
+			addr, ns = path[:j], path[j+1:]
+		}
+		if ns == "" {
+			return nil, fmt.Errorf("database name is required for SQL drivers: %q", url)
+		}
+		db, err := sqltuple.Open(sname, addr, ns)
+		if err != nil {
+			return nil, err
+		}
+		return &database{name: name, tuple: db}, nil
+	}
+	var open kv.OpenPathFunc
+	if r := kv.ByName(name); r != nil {
+		open = r.OpenPath
+	} else if r := flat.ByName(name); r != nil {
+		open = flat.UpgradeOpenPath(r.OpenPath)
+	} else {
+		return nil, fmt.Errorf("unknown driver: %q", name)
+	}
+	db, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &database{name: name, kv: db, tuple: tuplekv.New(db)}, nil
+}
+
+// KV returns a key-value store or an error if the driver is a tuple store.
+func (db *database) KV() (kv.KV, error) {
+	if db.kv == nil {
+		return nil, fmt.Errorf("driver %q is not a key-value store", db.name)
+	}
+	return db.kv, nil
+}
+
+func (db *database) Close() error {
+	return db.tuple.Close()
+}
+
+// driverInfo describes a registered driver.
+type driverInfo struct {
+	base.Registration
+	Kind string
+}
+
+// listDrivers lists drivers from all registries.
+func listDrivers() []driverInfo {
+	var out []driverInfo
+	for _, r := range kv.List() {
+		kind := "kv"
+		if strings.HasPrefix(r.Name, "flat"+base.RegistrySep) {
+			kind = "flat"
+		}
+		out = append(out, driverInfo{Registration: r.Registration, Kind: kind})
+	}
+	for _, r := range sqltuple.List() {
+		r.Registration.Name = sqlPrefix + r.Name
+		out = append(out, driverInfo{Registration: r.Registration, Kind: "sql"})
+	}
+	return out
+}
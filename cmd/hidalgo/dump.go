@@ -0,0 +1,698 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/tuple/tuplepb"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+// Kinds of dumps.
+const (
+	dumpKV    = "kv"    // raw key-value pairs
+	dumpTuple = "tuple" // table schemas and tuples
+)
+
+// Dump encodings.
+const (
+	encJSON   = "jsonl" // JSON Lines
+	encBinary = "bin"   // length-prefixed binary frames
+)
+
+const (
+	dumpMagic   = "hidalgo-dump"
+	dumpVersion = 1
+)
+
+// record is a single entry of the dump.
+//
+// Table records carry the schema and always precede tuples of that table.
+type record struct {
+	table *tuple.Header // table schema
+
+	key kv.Key // key-value pair
+	val []byte
+
+	name  string       // table name of the tuple
+	tuple *tuple.Tuple // tuple
+}
+
+// isData reports if the record contains data rather than a schema.
+func (r *record) isData() bool {
+	return r.table == nil
+}
+
+// recordWriter encodes dump records.
+type recordWriter interface {
+	writeRecord(r *record) error
+	flush() error
+}
+
+// recordReader decodes dump records. It returns io.EOF when there are no more records.
+type recordReader interface {
+	kind() string
+	readRecord() (*record, error)
+}
+
+func checkKind(kind string) error {
+	switch kind {
+	case dumpKV, dumpTuple:
+		return nil
+	}
+	return fmt.Errorf("unsupported dump kind: %q", kind)
+}
+
+// newRecordWriter creates a dump writer with a given encoding and writes the dump header.
+func newRecordWriter(w io.Writer, enc, kind string) (recordWriter, error) {
+	switch enc {
+	case encJSON:
+		return newJSONWriter(w, kind)
+	case encBinary:
+		return newBinaryWriter(w, kind)
+	}
+	return nil, fmt.Errorf("unsupported encoding: %q", enc)
+}
+
+// newRecordReader reads the dump header and creates a dump reader. The encoding is detected automatically.
+func newRecordReader(r io.Reader) (recordReader, error) {
+	br := bufio.NewReader(r)
+	p, err := br.Peek(len(dumpMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if string(p) == dumpMagic {
+		return newBinaryReader(br)
+	}
+	return newJSONReader(br)
+}
+
+// headers tracks table schemas seen in the dump.
+type headers map[string]*tuple.Header
+
+func (m headers) add(h *tuple.Header) error {
+	if err := h.Validate(); err != nil {
+		return fmt.Errorf("invalid table %q: %w", h.Name, err)
+	}
+	m[h.Name] = h
+	return nil
+}
+
+func (m headers) get(name string) (*tuple.Header, error) {
+	h := m[name]
+	if h == nil {
+		return nil, fmt.Errorf("tuple for table %q before its schema", name)
+	}
+	return h, nil
+}
+
+// checkTuple verifies that the tuple matches the schema.
+func checkTuple(h *tuple.Header, t *tuple.Tuple) error {
+	if err := h.ValidateKey(t.Key, false); err != nil {
+		return fmt.Errorf("table %q: %w", h.Name, err)
+	}
+	if err := h.ValidateData(t.Data); err != nil {
+		return fmt.Errorf("table %q: %w", h.Name, err)
+	}
+	return nil
+}
+
+// JSON Lines encoding.
+//
+// The first line is a header, followed by one record per line:
+//
+//	{"hidalgo-dump":1,"kind":"tuple"}
+//	{"table":{"name":"users","key":[{"name":"id","type":"string"}],"data":[{"name":"age","type":"int"}]}}
+//	{"t":"users","k":["alice"],"d":[30]}
+//
+// Key-value pairs are encoded as {"k":[base64...],"v":base64}.
+
+type jsonHeader struct {
+	Version int    `json:"hidalgo-dump"`
+	Kind    string `json:"kind"`
+}
+
+type jsonField struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Auto     bool            `json:"auto,omitempty"`
+	Nullable bool            `json:"nullable,omitempty"`
+	Default  json.RawMessage `json:"default,omitempty"`
+}
+
+type jsonIndex struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+	Unique bool     `json:"unique,omitempty"`
+}
+
+type jsonTable struct {
+	Name    string      `json:"name"`
+	Key     []jsonField `json:"key"`
+	Data    []jsonField `json:"data,omitempty"`
+	Indexes []jsonIndex `json:"indexes,omitempty"`
+}
+
+type jsonRecord struct {
+	Table *jsonTable        `json:"table,omitempty"`
+	Name  string            `json:"t,omitempty"`
+	Key   json.RawMessage   `json:"k,omitempty"`
+	Val   []byte            `json:"v,omitempty"`
+	Data  []json.RawMessage `json:"d,omitempty"`
+}
+
+type jsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONWriter(w io.Writer, kind string) (*jsonWriter, error) {
+	if err := checkKind(kind); err != nil {
+		return nil, err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(jsonHeader{Version: dumpVersion, Kind: kind}); err != nil {
+		return nil, err
+	}
+	return &jsonWriter{w: bw, enc: enc}, nil
+}
+
+func (w *jsonWriter) writeRecord(r *record) error {
+	var (
+		out jsonRecord
+		err error
+	)
+	switch {
+	case r.table != nil:
+		t := &jsonTable{Name: r.table.Name}
+		for _, f := range r.table.Key {
+			t.Key = append(t.Key, jsonField{Name: f.Name, Type: typeName(f.Type), Auto: f.Auto})
+		}
+		for _, f := range r.table.Data {
+			jf := jsonField{Name: f.Name, Type: typeName(f.Type), Nullable: f.Nullable}
+			if f.Default != nil {
+				if jf.Default, err = encodeJSONValue(f.Default); err != nil {
+					return err
+				}
+			}
+			t.Data = append(t.Data, jf)
+		}
+		for _, ind := range r.table.Indexes {
+			t.Indexes = append(t.Indexes, jsonIndex{Name: ind.Name, Fields: ind.Fields, Unique: ind.Unique})
+		}
+		out.Table = t
+	case r.tuple != nil:
+		out.Name = r.name
+		key := make([]json.RawMessage, len(r.tuple.Key))
+		for i, v := range r.tuple.Key {
+			if key[i], err = encodeJSONValue(v); err != nil {
+				return err
+			}
+		}
+		if out.Key, err = json.Marshal(key); err != nil {
+			return err
+		}
+		out.Data = make([]json.RawMessage, len(r.tuple.Data))
+		for i, v := range r.tuple.Data {
+			if out.Data[i], err = encodeJSONValue(v); err != nil {
+				return err
+			}
+		}
+	default:
+		if out.Key, err = json.Marshal([][]byte(r.key)); err != nil {
+			return err
+		}
+		out.Val = r.val
+	}
+	return w.enc.Encode(out)
+}
+
+func (w *jsonWriter) flush() error {
+	return w.w.Flush()
+}
+
+func encodeJSONValue(v values.Value) (json.RawMessage, error) {
+	var x interface{}
+	switch v := v.(type) {
+	case nil:
+	case values.Bytes:
+		x = []byte(v)
+	case values.String:
+		x = string(v)
+	case values.Int:
+		x = int64(v)
+	case values.UInt:
+		x = uint64(v)
+	case values.Float:
+		x = float64(v)
+	case values.Bool:
+		x = bool(v)
+	case values.Time:
+		x = time.Time(v)
+	default:
+		return nil, fmt.Errorf("unsupported value type: %T", v)
+	}
+	return json.Marshal(x)
+}
+
+func decodeJSONValue(tp values.Type, p json.RawMessage) (values.Value, error) {
+	if len(p) == 0 || string(p) == "null" {
+		return nil, nil
+	}
+	var (
+		v   values.Value
+		err error
+	)
+	switch tp.(type) {
+	case values.BytesType:
+		var x []byte
+		err = json.Unmarshal(p, &x)
+		v = values.Bytes(x)
+	case values.StringType:
+		var x string
+		err = json.Unmarshal(p, &x)
+		v = values.String(x)
+	case values.IntType:
+		var x int64
+		err = json.Unmarshal(p, &x)
+		v = values.Int(x)
+	case values.UIntType:
+		var x uint64
+		err = json.Unmarshal(p, &x)
+		v = values.UInt(x)
+	case values.FloatType:
+		var x float64
+		err = json.Unmarshal(p, &x)
+		v = values.Float(x)
+	case values.BoolType:
+		var x bool
+		err = json.Unmarshal(p, &x)
+		v = values.Bool(x)
+	case values.TimeType:
+		var x time.Time
+		err = json.Unmarshal(p, &x)
+		v = values.AsTime(x)
+	default:
+		return nil, fmt.Errorf("unsupported type: %T", tp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+type jsonReader struct {
+	dec    *json.Decoder
+	k      string
+	tables headers
+}
+
+func newJSONReader(r io.Reader) (*jsonReader, error) {
+	dec := json.NewDecoder(r)
+	var h jsonHeader
+	if err := dec.Decode(&h); err == io.EOF {
+		return nil, errors.New("empty dump")
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read dump header: %w", err)
+	}
+	if h.Version != dumpVersion {
+		return nil, fmt.Errorf("unsupported dump version: %d", h.Version)
+	}
+	if err := checkKind(h.Kind); err != nil {
+		return nil, err
+	}
+	return &jsonReader{dec: dec, k: h.Kind, tables: make(headers)}, nil
+}
+
+func (r *jsonReader) kind() string {
+	return r.k
+}
+
+func (r *jsonReader) readRecord() (*record, error) {
+	var in jsonRecord
+	if err := r.dec.Decode(&in); err != nil {
+		return nil, err
+	}
+	switch {
+	case in.Table != nil:
+		h := &tuple.Header{Name: in.Table.Name}
+		for _, f := range in.Table.Key {
+			tp, err := parseType(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			st, ok := tp.(values.SortableType)
+			if !ok {
+				return nil, fmt.Errorf("type %q cannot be used in the key", f.Type)
+			}
+			h.Key = append(h.Key, tuple.KeyField{Name: f.Name, Type: st, Auto: f.Auto})
+		}
+		for _, f := range in.Table.Data {
+			tp, err := parseType(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			def, err := decodeJSONValue(tp, f.Default)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: default: %w", f.Name, err)
+			}
+			h.Data = append(h.Data, tuple.Field{Name: f.Name, Type: tp, Nullable: f.Nullable, Default: def})
+		}
+		for _, ind := range in.Table.Indexes {
+			h.Indexes = append(h.Indexes, tuple.Index{Name: ind.Name, Fields: ind.Fields, Unique: ind.Unique})
+		}
+		if err := r.tables.add(h); err != nil {
+			return nil, err
+		}
+		return &record{table: h}, nil
+	case r.k == dumpTuple:
+		h, err := r.tables.get(in.Name)
+		if err != nil {
+			return nil, err
+		}
+		var key []json.RawMessage
+		if err := json.Unmarshal(in.Key, &key); err != nil {
+			return nil, err
+		} else if len(key) != len(h.Key) {
+			return nil, fmt.Errorf("table %q: wrong key size: %d vs %d", h.Name, len(h.Key), len(key))
+		} else if len(in.Data) != len(h.Data) {
+			return nil, fmt.Errorf("table %q: wrong payload size: %d vs %d", h.Name, len(h.Data), len(in.Data))
+		}
+		t := &tuple.Tuple{Key: make(tuple.Key, len(key)), Data: make(tuple.Data, len(in.Data))}
+		for i, p := range key {
+			v, err := decodeJSONValue(h.Key[i].Type, p)
+			if err != nil {
+				return nil, fmt.Errorf("table %q: key %q: %w", h.Name, h.Key[i].Name, err)
+			}
+			s, _ := v.(values.Sortable)
+			t.Key[i] = s
+		}
+		for i, p := range in.Data {
+			v, err := decodeJSONValue(h.Data[i].Type, p)
+			if err != nil {
+				return nil, fmt.Errorf("table %q: field %q: %w", h.Name, h.Data[i].Name, err)
+			}
+			t.Data[i] = v
+		}
+		if err := checkTuple(h, t); err != nil {
+			return nil, err
+		}
+		return &record{name: h.Name, tuple: t}, nil
+	}
+	var key [][]byte
+	if err := json.Unmarshal(in.Key, &key); err != nil {
+		return nil, err
+	} else if len(key) == 0 {
+		return nil, errors.New("key cannot be empty")
+	}
+	val := in.Val
+	if val == nil {
+		val = []byte{}
+	}
+	return &record{key: kv.Key(key), val: val}, nil
+}
+
+// Binary encoding.
+//
+// The dump starts with dumpMagic, followed by a version byte and the kind as a length-prefixed string.
+// Each record is a frame: a tag byte, the payload length as uvarint and the payload.
+//
+// All byte strings in payloads are prefixed with uvarint length. Tuple values use the length+1 prefix,
+// so zero length indicates nil value. Table schemas are encoded with tuplepb.
+
+const (
+	tagKV    = 'k'
+	tagTable = 'T'
+	tagTuple = 't'
+)
+
+type binaryWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func newBinaryWriter(w io.Writer, kind string) (*binaryWriter, error) {
+	if err := checkKind(kind); err != nil {
+		return nil, err
+	}
+	bw := &binaryWriter{w: bufio.NewWriter(w)}
+	bw.buf = append(bw.buf, dumpMagic...)
+	bw.buf = append(bw.buf, dumpVersion)
+	bw.putBytes([]byte(kind))
+	if _, err := bw.w.Write(bw.buf); err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
+
+func (w *binaryWriter) putUvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	w.buf = append(w.buf, tmp[:n]...)
+}
+
+func (w *binaryWriter) putBytes(p []byte) {
+	w.putUvarint(uint64(len(p)))
+	w.buf = append(w.buf, p...)
+}
+
+func (w *binaryWriter) putValue(v values.Value) error {
+	if v == nil {
+		w.putUvarint(0)
+		return nil
+	}
+	p, err := v.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	w.putUvarint(uint64(len(p)) + 1)
+	w.buf = append(w.buf, p...)
+	return nil
+}
+
+func (w *binaryWriter) writeRecord(r *record) error {
+	w.buf = w.buf[:0]
+	var tag byte
+	switch {
+	case r.table != nil:
+		tag = tagTable
+		p, err := tuplepb.MarshalTable(r.table)
+		if err != nil {
+			return err
+		}
+		w.buf = append(w.buf, p...)
+	case r.tuple != nil:
+		tag = tagTuple
+		w.putBytes([]byte(r.name))
+		for _, v := range r.tuple.Key {
+			if err := w.putValue(v); err != nil {
+				return err
+			}
+		}
+		w.putUvarint(uint64(len(r.tuple.Data)))
+		for _, v := range r.tuple.Data {
+			if err := w.putValue(v); err != nil {
+				return err
+			}
+		}
+	default:
+		tag = tagKV
+		w.putUvarint(uint64(len(r.key)))
+		for _, p := range r.key {
+			w.putBytes(p)
+		}
+		w.putBytes(r.val)
+	}
+	var hdr [1 + binary.MaxVarintLen64]byte
+	hdr[0] = tag
+	n := 1 + binary.PutUvarint(hdr[1:], uint64(len(w.buf)))
+	if _, err := w.w.Write(hdr[:n]); err != nil {
+		return err
+	}
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+func (w *binaryWriter) flush() error {
+	return w.w.Flush()
+}
+
+type binaryReader struct {
+	r      *bufio.Reader
+	k      string
+	tables headers
+	buf    []byte
+}
+
+// errTruncated is returned when the binary dump ends in the middle of a record.
+var errTruncated = errors.New("truncated dump")
+
+func newBinaryReader(r *bufio.Reader) (*binaryReader, error) {
+	hdr := make([]byte, len(dumpMagic)+1)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("cannot read dump header: %w", err)
+	}
+	if v := hdr[len(dumpMagic)]; v != dumpVersion {
+		return nil, fmt.Errorf("unsupported dump version: %d", v)
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read dump header: %w", err)
+	}
+	kind := make([]byte, n)
+	if _, err := io.ReadFull(r, kind); err != nil {
+		return nil, fmt.Errorf("cannot read dump header: %w", err)
+	}
+	if err := checkKind(string(kind)); err != nil {
+		return nil, err
+	}
+	return &binaryReader{r: r, k: string(kind), tables: make(headers)}, nil
+}
+
+func (r *binaryReader) kind() string {
+	return r.k
+}
+
+func (r *binaryReader) readRecord() (*record, error) {
+	tag, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err // io.EOF at the record boundary
+	}
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, errTruncated
+	}
+	if uint64(cap(r.buf)) < n {
+		r.buf = make([]byte, n)
+	}
+	r.buf = r.buf[:n]
+	if _, err = io.ReadFull(r.r, r.buf); err != nil {
+		return nil, errTruncated
+	}
+	// copy the payload, since decoded records may reference it
+	d := &frameDecoder{p: append([]byte{}, r.buf...)}
+	switch tag {
+	case tagTable:
+		h, err := tuplepb.UnmarshalTable(d.p)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.tables.add(h); err != nil {
+			return nil, err
+		}
+		return &record{table: h}, nil
+	case tagTuple:
+		h, err := r.tables.get(string(d.bytes()))
+		if err != nil {
+			return nil, err
+		}
+		t := &tuple.Tuple{Key: make(tuple.Key, len(h.Key))}
+		for i, f := range h.Key {
+			p, ok := d.value()
+			if !ok {
+				continue
+			}
+			v := f.Type.NewSortable()
+			if err := v.UnmarshalBinary(p); err != nil {
+				return nil, fmt.Errorf("table %q: key %q: %w", h.Name, f.Name, err)
+			}
+			t.Key[i] = v.Sortable()
+		}
+		if cnt := d.uvarint(); d.err == nil && cnt != uint64(len(h.Data)) {
+			return nil, fmt.Errorf("table %q: wrong payload size: %d vs %d", h.Name, len(h.Data), cnt)
+		}
+		t.Data = make(tuple.Data, len(h.Data))
+		for i, f := range h.Data {
+			p, ok := d.value()
+			if !ok {
+				continue
+			}
+			v := f.Type.New()
+			if err := v.UnmarshalBinary(p); err != nil {
+				return nil, fmt.Errorf("table %q: field %q: %w", h.Name, f.Name, err)
+			}
+			t.Data[i] = v.Value()
+		}
+		if err := d.done(); err != nil {
+			return nil, err
+		}
+		if err := checkTuple(h, t); err != nil {
+			return nil, err
+		}
+		return &record{name: h.Name, tuple: t}, nil
+	case tagKV:
+		key := make(kv.Key, d.uvarint())
+		for i := range key {
+			key[i] = d.bytes()
+		}
+		val := d.bytes()
+		if err := d.done(); err != nil {
+			return nil, err
+		} else if len(key) == 0 {
+			return nil, errors.New("key cannot be empty")
+		}
+		return &record{key: key, val: val}, nil
+	}
+	return nil, fmt.Errorf("unknown record type: %q", tag)
+}
+
+// frameDecoder decodes a single frame payload. The first error is sticky.
+type frameDecoder struct {
+	p   []byte
+	err error
+}
+
+func (d *frameDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.p)
+	if n <= 0 {
+		d.err = errTruncated
+		return 0
+	}
+	d.p = d.p[n:]
+	return v
+}
+
+func (d *frameDecoder) take(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.p)) < n {
+		d.err = errTruncated
+		return nil
+	}
+	p := d.p[:n:n]
+	d.p = d.p[n:]
+	return p
+}
+
+func (d *frameDecoder) bytes() []byte {
+	return d.take(d.uvarint())
+}
+
+// value returns an encoded tuple value. It returns false for nil values.
+func (d *frameDecoder) value() ([]byte, bool) {
+	n := d.uvarint()
+	if n == 0 {
+		return nil, false
+	}
+	return d.take(n - 1), d.err == nil
+}
+
+func (d *frameDecoder) done() error {
+	if d.err == nil && len(d.p) != 0 {
+		d.err = fmt.Errorf("%d unexpected bytes at the end of the record", len(d.p))
+	}
+	return d.err
+}
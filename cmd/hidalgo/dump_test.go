@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+var dumpTable = tuple.Header{
+	Name: "items",
+	Key: []tuple.KeyField{
+		{Name: "id", Type: values.UIntType{}, Auto: true},
+	},
+	Data: []tuple.Field{
+		{Name: "name", Type: values.StringType{}},
+		{Name: "data", Type: values.BytesType{}, Nullable: true},
+		{Name: "score", Type: values.FloatType{}, Default: values.Float(0.5)},
+		{Name: "ok", Type: values.BoolType{}},
+		{Name: "ts", Type: values.TimeType{}},
+		{Name: "n", Type: values.IntType{}},
+	},
+	Indexes: []tuple.Index{
+		{Name: "by_name", Fields: []string{"name", "n"}, Unique: true},
+	},
+}
+
+func dumpTuples() []tuple.Tuple {
+	ts := values.AsTime(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC))
+	return []tuple.Tuple{
+		{Key: tuple.Key{values.UInt(1)}, Data: tuple.Data{values.String("a\n\"b"), values.Bytes{0, 1, 0xff}, values.Float(1.5), values.Bool(true), ts, values.Int(-3)}},
+		{Key: tuple.Key{values.UInt(2)}, Data: tuple.Data{values.String(""), values.Bytes{}, values.Float(0), values.Bool(false), ts, values.Int(0)}},
+		{Key: tuple.Key{values.UInt(3)}, Data: tuple.Data{values.String("c"), nil, values.Float(-2), values.Bool(true), ts, values.Int(1 << 60)}},
+	}
+}
+
+// newDumpDB creates a database with a test table and returns its URL.
+func newDumpDB(t testing.TB) string {
+	ctx := context.Background()
+	url := "bolt:" + filepath.Join(t.TempDir(), "src.db")
+	db, err := openDB(url)
+	require.NoError(t, err)
+	defer db.Close()
+	err = db.tuple.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, dumpTable)
+		if err != nil {
+			return err
+		}
+		for _, v := range dumpTuples() {
+			if _, err = tbl.InsertTuple(ctx, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return url
+}
+
+func readKV(t testing.TB, url string) map[string][]byte {
+	ctx := context.Background()
+	db, err := openDB(url)
+	require.NoError(t, err)
+	defer db.Close()
+	out := make(map[string][]byte)
+	err = db.kv.View(ctx, func(tx kv.Tx) error {
+		it := tx.Scan(ctx)
+		defer it.Close()
+		for it.Next(ctx) {
+			out[string(bytes.Join(it.Key(), []byte{0}))] = append([]byte{}, it.Val()...)
+		}
+		return it.Err()
+	})
+	require.NoError(t, err)
+	return out
+}
+
+func readTuples(t testing.TB, url string) []tuple.Tuple {
+	ctx := context.Background()
+	db, err := openDB(url)
+	require.NoError(t, err)
+	defer db.Close()
+	var out []tuple.Tuple
+	err = db.tuple.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, dumpTable.Name)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, dumpTable, tbl.Header())
+		it := tbl.Scan(ctx, &tuple.ScanOptions{Sort: tuple.SortAsc})
+		defer it.Close()
+		for it.Next(ctx) {
+			out = append(out, tuple.Tuple{Key: it.Key(), Data: it.Data()})
+		}
+		return it.Err()
+	})
+	require.NoError(t, err)
+	return out
+}
+
+func TestDumpLoad(t *testing.T) {
+	src := newDumpDB(t)
+	dir := t.TempDir()
+	for _, enc := range []string{encJSON, encBinary} {
+		for _, asTuple := range []bool{false, true} {
+			name := enc
+			if asTuple {
+				name += "-tuple"
+			}
+			t.Run(name, func(t *testing.T) {
+				file := filepath.Join(dir, name+".dump")
+				args := []string{"dump", "-db", src, "-enc", enc, "-o", file, "-progress", "0"}
+				if asTuple {
+					args = append(args, "-tuple")
+				}
+				runCmd(t, args...)
+
+				dst := "bolt:" + filepath.Join(dir, name+".db")
+				runCmd(t, "load", "-db", dst, "-i", file, "-batch", "2", "-progress", "0")
+				require.Equal(t, dumpTuples(), readTuples(t, dst))
+				require.Equal(t, readKV(t, src), readKV(t, dst))
+
+				// key-value pairs are overwritten, but tables cannot be loaded twice
+				var stdout, stderr bytes.Buffer
+				code := run(context.Background(), []string{"load", "-db", dst, "-i", file, "-progress", "0"}, nil, &stdout, &stderr)
+				if asTuple {
+					require.Equal(t, 1, code)
+					require.Contains(t, stderr.String(), "already exists")
+				} else {
+					require.Equal(t, 0, code, stderr.String())
+				}
+			})
+		}
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	src := newDumpDB(t)
+	out := runCmd(t, "dump", "-db", src, "-tuple", "-progress", "0")
+	require.Equal(t, []string{
+		`{"hidalgo-dump":1,"kind":"tuple"}`,
+		`{"table":{"name":"items","key":[{"name":"id","type":"uint","auto":true}],"data":[{"name":"name","type":"string"},{"name":"data","type":"bytes","nullable":true},{"name":"score","type":"float","default":0.5},{"name":"ok","type":"bool"},{"name":"ts","type":"time"},{"name":"n","type":"int"}],"indexes":[{"name":"by_name","fields":["name","n"],"unique":true}]}}`,
+		`{"t":"items","k":[1],"d":["a\n\"b","AAH/",1.5,true,"2020-01-02T03:04:05.000000006Z",-3]}`,
+		`{"t":"items","k":[2],"d":["","",0,false,"2020-01-02T03:04:05.000000006Z",0]}`,
+		`{"t":"items","k":[3],"d":["c",null,-2,true,"2020-01-02T03:04:05.000000006Z",1152921504606846976]}`,
+	}, trimLines(out))
+}
+
+func TestCopyResume(t *testing.T) {
+	ctx := context.Background()
+	src := newDumpDB(t)
+	dir := t.TempDir()
+	dst := "bolt:" + filepath.Join(dir, "dst.db")
+	ckpt := filepath.Join(dir, "copy.ckpt")
+
+	// simulate an interrupted copy: the first batch was committed, and the checkpoint was saved
+	db, err := openDB(dst)
+	require.NoError(t, err)
+	err = db.tuple.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, dumpTable)
+		if err != nil {
+			return err
+		}
+		_, err = tbl.InsertTuple(ctx, dumpTuples()[0])
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	require.NoError(t, writeCheckpoint(ckpt, 1))
+
+	var stdout, stderr bytes.Buffer
+	code := run(ctx, []string{"copy", "-from", src, "-to", dst, "-tuple", "-batch", "1", "-resume", ckpt, "-progress", "1ns"}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	require.Contains(t, stderr.String(), "resuming after 1 records")
+	require.Contains(t, stderr.String(), "loaded 2 records")
+	require.Equal(t, dumpTuples(), readTuples(t, dst))
+
+	_, err = os.Stat(ckpt)
+	require.True(t, os.IsNotExist(err))
+
+	// without the resume flag existing tables are not overwritten
+	code = run(ctx, []string{"copy", "-from", src, "-to", dst, "-tuple"}, nil, &stdout, &stderr)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr.String(), "already exists")
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	dst := "bolt:" + filepath.Join(dir, "dst.db")
+	for _, c := range []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"version", `{"hidalgo-dump":2,"kind":"kv"}`},
+		{"kind", `{"hidalgo-dump":1,"kind":"docs"}`},
+		{"no schema", "{\"hidalgo-dump\":1,\"kind\":\"tuple\"}\n{\"t\":\"a\",\"k\":[1],\"d\":[]}"},
+		{"empty key", "{\"hidalgo-dump\":1,\"kind\":\"kv\"}\n{\"k\":[],\"v\":\"\"}"},
+		{"truncated", dumpMagic + "\x01\x02kvk\x05\x01"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			file := filepath.Join(dir, "in.dump")
+			require.NoError(t, os.WriteFile(file, []byte(c.data), 0644))
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), []string{"load", "-db", dst, "-i", file, "-progress", "0"}, nil, &stdout, &stderr)
+			require.Equal(t, 1, code, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+// keyFormat controls how binary keys and values are rendered and parsed.
+type keyFormat struct {
+	mode string // escaped, utf8 or hex
+	sep  string // separator for hierarchical key parts
+}
+
+const (
+	fmtEscaped = "escaped"
+	fmtUTF8    = "utf8"
+	fmtHex     = "hex"
+)
+
+func (f keyFormat) validate() error {
+	switch f.mode {
+	case fmtEscaped, fmtUTF8, fmtHex:
+	default:
+		return fmt.Errorf("unsupported format: %q", f.mode)
+	}
+	if f.sep == "" {
+		return fmt.Errorf("key separator cannot be empty")
+	}
+	return nil
+}
+
+// escape renders non-printable bytes, backslashes and the separator as \xNN escapes.
+func (f keyFormat) escape(p []byte) string {
+	var buf strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '\\':
+			buf.WriteString(`\\`)
+		case c < 0x20 || c >= 0x7f || (f.sep != "" && strings.HasPrefix(string(p[i:]), f.sep)):
+			fmt.Fprintf(&buf, `\x%02x`, c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// unescape reverses escape.
+func unescape(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			out = append(out, c)
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\\' {
+			out = append(out, '\\')
+			i++
+			continue
+		}
+		if i+3 < len(s) && s[i+1] == 'x' {
+			b, err := hex.DecodeString(s[i+2 : i+4])
+			if err == nil {
+				out = append(out, b[0])
+				i += 3
+				continue
+			}
+		}
+		return nil, fmt.Errorf("invalid escape sequence at %d in %q", i, s)
+	}
+	return out, nil
+}
+
+// formatBytes renders a single key part or a value.
+func (f keyFormat) formatBytes(p []byte) string {
+	switch f.mode {
+	case fmtHex:
+		return hex.EncodeToString(p)
+	case fmtUTF8:
+		return string(p)
+	}
+	return f.escape(p)
+}
+
+// parseBytes parses a single key part or a value.
+func (f keyFormat) parseBytes(s string) ([]byte, error) {
+	switch f.mode {
+	case fmtHex:
+		return hex.DecodeString(s)
+	case fmtUTF8:
+		return []byte(s), nil
+	}
+	return unescape(s)
+}
+
+// formatKey renders all parts of a hierarchical key, joined with a separator.
+func (f keyFormat) formatKey(k kv.Key) string {
+	parts := make([]string, 0, len(k))
+	for _, p := range k {
+		parts = append(parts, f.formatBytes(p))
+	}
+	return strings.Join(parts, f.sep)
+}
+
+// parseKey splits the string into hierarchical key parts and parses each of them.
+func (f keyFormat) parseKey(s string) (kv.Key, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var k kv.Key
+	for _, ps := range strings.Split(s, f.sep) {
+		p, err := f.parseBytes(ps)
+		if err != nil {
+			return nil, err
+		}
+		k = append(k, p)
+	}
+	return k, nil
+}
+
+// formatValue renders a tuple value.
+func (f keyFormat) formatValue(v values.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case values.Bytes:
+		return f.formatBytes(v)
+	case values.String:
+		if f.mode == fmtEscaped {
+			return keyFormat{mode: f.mode}.escape([]byte(v))
+		}
+		return string(v)
+	case values.Int:
+		return strconv.FormatInt(int64(v), 10)
+	case values.UInt:
+		return strconv.FormatUint(uint64(v), 10)
+	case values.Float:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case values.Bool:
+		return strconv.FormatBool(bool(v))
+	case values.Time:
+		return time.Time(v).Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v.Native())
+}
+
+// parseValue parses a tuple value of a given type.
+func (f keyFormat) parseValue(tp values.Type, s string) (values.Value, error) {
+	switch tp.(type) {
+	case values.BytesType:
+		p, err := f.parseBytes(s)
+		if err != nil {
+			return nil, err
+		}
+		return values.Bytes(p), nil
+	case values.StringType:
+		if f.mode == fmtEscaped {
+			p, err := unescape(s)
+			if err != nil {
+				return nil, err
+			}
+			return values.String(p), nil
+		}
+		return values.String(s), nil
+	case values.IntType:
+		v, err := strconv.ParseInt(s, 10, 64)
+		return values.Int(v), err
+	case values.UIntType:
+		v, err := strconv.ParseUint(s, 10, 64)
+		return values.UInt(v), err
+	case values.FloatType:
+		v, err := strconv.ParseFloat(s, 64)
+		return values.Float(v), err
+	case values.BoolType:
+		v, err := strconv.ParseBool(s)
+		return values.Bool(v), err
+	case values.TimeType:
+		v, err := time.Parse(time.RFC3339Nano, s)
+		return values.AsTime(v), err
+	}
+	return nil, fmt.Errorf("unsupported type: %T", tp)
+}
+
+// typeName returns a human-readable name of the value type.
+func typeName(tp values.Type) string {
+	switch tp.(type) {
+	case values.BytesType:
+		return "bytes"
+	case values.StringType:
+		return "string"
+	case values.IntType:
+		return "int"
+	case values.UIntType:
+		return "uint"
+	case values.FloatType:
+		return "float"
+	case values.BoolType:
+		return "bool"
+	case values.TimeType:
+		return "time"
+	}
+	return fmt.Sprintf("%T", tp)
+}
+
+// parseType is the reverse of typeName.
+func parseType(name string) (values.Type, error) {
+	switch name {
+	case "bytes":
+		return values.BytesType{}, nil
+	case "string":
+		return values.StringType{}, nil
+	case "int":
+		return values.IntType{}, nil
+	case "uint":
+		return values.UIntType{}, nil
+	case "float":
+		return values.FloatType{}, nil
+	case "bool":
+		return values.BoolType{}, nil
+	case "time":
+		return values.TimeType{}, nil
+	}
+	return nil, fmt.Errorf("unsupported type: %q", name)
+}
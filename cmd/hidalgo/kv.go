@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/options"
+)
+
+func init() {
+	registerCommand(command{
+		name: "get", usage: "<key> [key...]", db: true,
+		help: "print values for the keys",
+		setup: func(fs *flag.FlagSet) runFunc {
+			return cmdGet
+		},
+	})
+	registerCommand(command{
+		name: "put", usage: "<key> <value>", db: true,
+		help: "write a value for the key",
+		setup: func(fs *flag.FlagSet) runFunc {
+			return cmdPut
+		},
+	})
+	registerCommand(command{
+		name: "del", usage: "<key> [key...]", db: true,
+		help: "delete the keys",
+		setup: func(fs *flag.FlagSet) runFunc {
+			return cmdDel
+		},
+	})
+	registerCommand(command{
+		name: "scan", db: true,
+		help: "list key-value pairs",
+		setup: func(fs *flag.FlagSet) runFunc {
+			pref := fs.String("prefix", "", "only list keys with this prefix")
+			limit := fs.Int("limit", 0, "maximal number of keys to list")
+			keysOnly := fs.Bool("keys", false, "only list keys")
+			return func(ctx context.Context, e *env, args []string) error {
+				if len(args) != 0 {
+					return errUsage
+				}
+				return cmdScan(ctx, e, *pref, *limit, *keysOnly)
+			}
+		},
+	})
+}
+
+func (e *env) parseKeys(args []string) ([]kv.Key, error) {
+	keys := make([]kv.Key, 0, len(args))
+	for _, s := range args {
+		k, err := e.fmt.parseKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse key %q: %w", s, err)
+		} else if len(k) == 0 {
+			return nil, fmt.Errorf("key cannot be empty")
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// withKV opens a key-value store for the duration of the function.
+func (e *env) withKV(fnc func(db kv.KV) error) error {
+	return e.withDB(func(db *database) error {
+		kdb, err := db.KV()
+		if err != nil {
+			return err
+		}
+		return fnc(kdb)
+	})
+}
+
+func cmdGet(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	keys, err := e.parseKeys(args)
+	if err != nil {
+		return err
+	}
+	return e.withKV(func(db kv.KV) error {
+		return db.View(ctx, func(tx kv.Tx) error {
+			vals, err := tx.GetBatch(ctx, keys)
+			if err != nil {
+				return err
+			}
+			for i, v := range vals {
+				if v == nil {
+					return fmt.Errorf("%w: %s", kv.ErrNotFound, e.fmt.formatKey(keys[i]))
+				}
+				if len(keys) == 1 {
+					fmt.Fprintln(e.stdout, e.fmt.formatBytes(v))
+				} else {
+					fmt.Fprintf(e.stdout, "%s\t%s\n", e.fmt.formatKey(keys[i]), e.fmt.formatBytes(v))
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func cmdPut(ctx context.Context, e *env, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	keys, err := e.parseKeys(args[:1])
+	if err != nil {
+		return err
+	}
+	val, err := e.fmt.parseBytes(args[1])
+	if err != nil {
+		return fmt.Errorf("cannot parse value: %w", err)
+	}
+	return e.withKV(func(db kv.KV) error {
+		return db.Update(ctx, func(tx kv.Tx) error {
+			return tx.Put(ctx, keys[0], val)
+		})
+	})
+}
+
+func cmdDel(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	keys, err := e.parseKeys(args)
+	if err != nil {
+		return err
+	}
+	return e.withKV(func(db kv.KV) error {
+		return db.Update(ctx, func(tx kv.Tx) error {
+			for _, k := range keys {
+				if err := tx.Del(ctx, k); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func cmdScan(ctx context.Context, e *env, prefix string, limit int, keysOnly bool) error {
+	pref, err := e.fmt.parseKey(prefix)
+	if err != nil {
+		return fmt.Errorf("cannot parse prefix: %w", err)
+	}
+	return e.withKV(func(db kv.KV) error {
+		return db.View(ctx, func(tx kv.Tx) error {
+			var opts []kv.IteratorOption
+			if len(pref) != 0 {
+				opts = append(opts, options.WithPrefixKV(pref))
+			}
+			it := tx.Scan(ctx, opts...)
+			defer it.Close()
+			for n := 0; (limit <= 0 || n < limit) && it.Next(ctx); n++ {
+				if keysOnly {
+					fmt.Fprintln(e.stdout, e.fmt.formatKey(it.Key()))
+				} else {
+					fmt.Fprintf(e.stdout, "%s\t%s\n", e.fmt.formatKey(it.Key()), e.fmt.formatBytes(it.Val()))
+				}
+			}
+			return it.Err()
+		})
+	})
+}
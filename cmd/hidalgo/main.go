@@ -0,0 +1,208 @@
+// Command hidalgo inspects and edits databases supported by HiDAL-Go.
+//
+// Databases are addressed by URLs in the form of "driver:path", for example "bolt:./data.db" or "flat.pebble:./data".
+// SQL databases use "sql." driver prefix and "address/database" path: "sql.postgres:postgres://user@localhost/db".
+// Key-value stores can be queried as tuple stores as well.
+//
+// Usage:
+//
+//	hidalgo list-drivers
+//	hidalgo get -db bolt:./data.db users/alice
+//	hidalgo scan -db bolt:./data.db -prefix users
+//	hidalgo select -db bolt:./data.db -where 'age>=18' users
+//	hidalgo shell -db bolt:./data.db
+//	hidalgo dump -db bolt:./data.db -enc bin -o data.dump
+//	hidalgo copy -from bolt:./data.db -to flat.leveldb:./data -resume copy.ckpt
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// env is a shared state of a single command invocation.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	dbURL string
+	fmt   keyFormat
+}
+
+// runFunc executes the command with positional arguments.
+type runFunc func(ctx context.Context, e *env, args []string) error
+
+type command struct {
+	name  string
+	usage string // positional arguments
+	help  string
+	db    bool // requires a database
+	// setup registers command-specific flags and returns a function to run the command.
+	setup func(fs *flag.FlagSet) runFunc
+}
+
+var commands []command
+
+func registerCommand(c command) {
+	commands = append(commands, c)
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+var errUsage = errors.New("invalid usage")
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: hidalgo <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	list := append([]command{}, commands...)
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+	for _, c := range list {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'hidalgo <command> -h' for command flags.")
+}
+
+// run executes the command and returns the exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	c := findCommand(args[0])
+	if c == nil {
+		fmt.Fprintf(stderr, "unknown command: %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if c.db {
+		fs.StringVar(&e.dbURL, "db", os.Getenv("HIDALGO_DB"), "database URL in the form of driver:path (default $HIDALGO_DB)")
+	}
+	fs.StringVar(&e.fmt.mode, "format", fmtEscaped, "binary data format: escaped, utf8 or hex")
+	fs.StringVar(&e.fmt.sep, "sep", "/", "separator for hierarchical key parts")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: hidalgo %s [flags] %s\n\n%s\n\nFlags:\n", c.name, c.usage, c.help)
+		fs.PrintDefaults()
+	}
+	fnc := c.setup(fs)
+	if err := fs.Parse(args[1:]); err == flag.ErrHelp {
+		return 0
+	} else if err != nil {
+		return 2
+	}
+	if err := e.fmt.validate(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if c.db && e.dbURL == "" {
+		fmt.Fprintln(stderr, "database URL is required: set -db flag or $HIDALGO_DB")
+		return 2
+	}
+	if err := fnc(ctx, e, fs.Args()); err == errUsage {
+		fs.Usage()
+		return 2
+	} else if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// withDB opens the database for the duration of the function.
+func (e *env) withDB(fnc func(db *database) error) error {
+	return e.withDBURL(e.dbURL, fnc)
+}
+
+// withDBURL opens the database with a given URL for the duration of the function.
+func (e *env) withDBURL(url string, fnc func(db *database) error) error {
+	db, err := openDB(url)
+	if err != nil {
+		return err
+	}
+	err = fnc(db)
+	if err2 := db.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// stringsFlag is a flag that can be specified multiple times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func init() {
+	registerCommand(command{
+		name: "list-drivers",
+		help: "list all supported database drivers",
+		setup: func(fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, e *env, args []string) error {
+				if len(args) != 0 {
+					return errUsage
+				}
+				w := newTable(e.stdout)
+				w.row("NAME", "KIND", "TITLE", "LOCAL", "VOLATILE", "CAPABILITIES")
+				for _, d := range listDrivers() {
+					w.row(d.Name, d.Kind, d.Title, yesNo(d.Local), yesNo(d.Volatile), d.Capabilities.String())
+				}
+				return w.flush()
+			}
+		},
+	})
+}
+
+// table writes aligned columns.
+type table struct {
+	w *tabwriter.Writer
+}
+
+func newTable(w io.Writer) *table {
+	return &table{w: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}
+}
+
+func (t *table) row(cols ...string) {
+	fmt.Fprintln(t.w, strings.Join(cols, "\t"))
+}
+
+func (t *table) flush() error {
+	return t.w.Flush()
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
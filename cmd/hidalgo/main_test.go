@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+func TestKeyFormat(t *testing.T) {
+	key := kv.Key{[]byte("a/b"), []byte{0, '\\', 'x', 0xff}}
+	for _, c := range []struct {
+		f   keyFormat
+		exp string
+	}{
+		{keyFormat{mode: fmtEscaped, sep: "/"}, `a\x2fb/\x00\\x\xff`},
+		{keyFormat{mode: fmtHex, sep: "/"}, `612f62/005c78ff`},
+		{keyFormat{mode: fmtHex, sep: ":"}, `612f62:005c78ff`},
+	} {
+		s := c.f.formatKey(key)
+		require.Equal(t, c.exp, s)
+		k, err := c.f.parseKey(s)
+		require.NoError(t, err)
+		require.Equal(t, key, k)
+	}
+
+	_, err := unescape(`a\x1`)
+	require.Error(t, err)
+	_, err = unescape(`a\n`)
+	require.Error(t, err)
+}
+
+// runCmd runs the command and returns its output.
+func runCmd(t *testing.T, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, "%q: %s", args, stderr.String())
+	return stdout.String()
+}
+
+func TestCommands(t *testing.T) {
+	url := "bolt:" + filepath.Join(t.TempDir(), "bolt.db")
+
+	runCmd(t, "put", "-db", url, "a/b", "1")
+	runCmd(t, "put", "-db", url, "a/c", `2\x00`)
+	runCmd(t, "put", "-db", url, "d", "3")
+
+	require.Equal(t, "1\n", runCmd(t, "get", "-db", url, "a/b"))
+	require.Equal(t, "a/b\t1\nd\t3\n", runCmd(t, "get", "-db", url, "a/b", "d"))
+	require.Equal(t, "a/b\t1\na/c\t2\\x00\n", runCmd(t, "scan", "-db", url, "-prefix", "a"))
+	require.Equal(t, "61:62\n61:63\n", runCmd(t, "scan", "-db", url, "-format", "hex", "-sep", ":", "-keys", "-prefix", "61"))
+	require.Equal(t, "a/b\t1\n", runCmd(t, "scan", "-db", url, "-limit", "1"))
+
+	runCmd(t, "del", "-db", url, "a/b", "d")
+	require.Equal(t, "a/c\t2\\x00\n", runCmd(t, "scan", "-db", url))
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"get", "-db", url, "a/b"}, nil, &stdout, &stderr)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr.String(), "not found")
+
+	code = run(context.Background(), []string{"get", "-db", url}, nil, &stdout, &stderr)
+	require.Equal(t, 2, code)
+}
+
+func TestTupleCommands(t *testing.T) {
+	ctx := context.Background()
+	url := "bolt:" + filepath.Join(t.TempDir(), "bolt.db")
+
+	db, err := openDB(url)
+	require.NoError(t, err)
+	err = db.tuple.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "users",
+			Key:  []tuple.KeyField{{Name: "name", Type: values.StringType{}}},
+			Data: []tuple.Field{
+				{Name: "age", Type: values.IntType{}},
+				{Name: "admin", Type: values.BoolType{}},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		for _, u := range []struct {
+			name  string
+			age   int
+			admin bool
+		}{
+			{"alice", 30, true},
+			{"bob", 17, false},
+			{"carol", 45, false},
+		} {
+			_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+				Key:  tuple.SKey(u.name),
+				Data: tuple.Data{values.Int(u.age), values.Bool(u.admin)},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.Equal(t, "users\n", runCmd(t, "tables", "-db", url))
+
+	schema := runCmd(t, "schema", "-db", url, "users")
+	require.Equal(t, []string{
+		"FIELD  TYPE    KEY",
+		"name   string  yes",
+		"age    int",
+		"admin  bool",
+	}, trimLines(schema))
+
+	out := runCmd(t, "select", "-db", url, "-where", "age>=18", "users")
+	require.Equal(t, []string{
+		"name   age  admin",
+		"alice  30   true",
+		"carol  45   false",
+	}, trimLines(out))
+
+	out = runCmd(t, "select", "-db", url, "-where", "name^=b", "-where", "admin=false", "users")
+	require.Equal(t, []string{
+		"name  age  admin",
+		"bob   17   false",
+	}, trimLines(out))
+
+	out = runCmd(t, "select", "-db", url, "-where", "name!=alice", "-limit", "1", "users")
+	require.Equal(t, []string{
+		"name  age  admin",
+		"bob   17   false",
+	}, trimLines(out))
+}
+
+func trimLines(s string) []string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	return lines
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+func init() {
+	registerCommand(command{
+		name: "shell", db: true,
+		help: "start an interactive shell for tuple stores",
+		setup: func(fs *flag.FlagSet) runFunc {
+			prompt := fs.String("prompt", "hidalgo> ", "prompt printed before each command")
+			return func(ctx context.Context, e *env, args []string) error {
+				if len(args) != 0 {
+					return errUsage
+				}
+				return e.withDB(func(db *database) error {
+					sh := &shell{e: e, db: db.tuple, prompt: *prompt}
+					return sh.run(ctx, e.stdin)
+				})
+			}
+		},
+	})
+}
+
+// shell is an interactive session over a tuple store.
+//
+// Each command runs in its own transaction, unless a transaction was started explicitly with "begin".
+type shell struct {
+	e      *env
+	db     tuple.Store
+	prompt string
+
+	tx tuple.Tx // explicit transaction; nil if none
+	rw bool
+}
+
+type shellCommand struct {
+	name  string
+	usage string
+	help  string
+	run   func(s *shell, ctx context.Context, args []string) error
+}
+
+var shellCommands []shellCommand
+
+func init() {
+	shellCommands = []shellCommand{
+		{"help", "", "print this help", (*shell).cmdHelp},
+		{"tables", "", "list tables", (*shell).cmdTables},
+		{"schema", "<table>", "print the table schema", (*shell).cmdSchema},
+		{"scan", "<table> [field<op>value...] [limit N]", "print tuples matching all filters; ops are =, !=, <, <=, >, >=, ^=", (*shell).cmdScan},
+		{"get", "<table> key=value...", "print a tuple with a given key", (*shell).cmdGet},
+		{"insert", "<table> field=value...", "insert a new tuple; auto-increment keys can be omitted", (*shell).cmdInsert},
+		{"update", "<table> key=value... field=value...", "change fields of an existing tuple", (*shell).cmdUpdate},
+		{"upsert", "<table> field=value...", "insert or replace a tuple", (*shell).cmdUpsert},
+		{"delete", "<table> field<op>value...", "delete tuples matching all filters", (*shell).cmdDelete},
+		{"begin", "[ro]", "start a read-write or read-only transaction", (*shell).cmdBegin},
+		{"commit", "", "commit the transaction", (*shell).cmdCommit},
+		{"rollback", "", "discard the transaction", (*shell).cmdRollback},
+		{"quit", "", "exit the shell, discarding an open transaction", nil},
+	}
+}
+
+var errQuit = errors.New("quit")
+
+// run reads and executes commands until the end of the input.
+func (s *shell) run(ctx context.Context, r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(s.e.stdout, s.prompt)
+		if !sc.Scan() {
+			break
+		}
+		err := s.exec(ctx, sc.Text())
+		if err == errQuit {
+			break
+		} else if err != nil {
+			fmt.Fprintln(s.e.stderr, "error:", err)
+		}
+	}
+	if s.tx != nil {
+		fmt.Fprintln(s.e.stderr, "rolling back the open transaction")
+		if err := s.closeTx(); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// exec executes a single line. Empty lines and lines starting with # are ignored.
+func (s *shell) exec(ctx context.Context, line string) error {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return nil
+	}
+	args, err := splitArgs(line)
+	if err != nil || len(args) == 0 {
+		return err
+	}
+	name := strings.ToLower(args[0])
+	if name == "quit" || name == "exit" {
+		return errQuit
+	}
+	for _, c := range shellCommands {
+		if c.name == name {
+			return c.run(s, ctx, args[1:])
+		}
+	}
+	return fmt.Errorf("unknown command: %q; type \"help\" for the list of commands", args[0])
+}
+
+// splitArgs splits the line into words. Single and double quotes can be used to group words;
+// backslashes are preserved so the values can use the escaped format.
+func splitArgs(line string) ([]string, error) {
+	var (
+		out   []string
+		cur   strings.Builder
+		quote rune
+		word  bool
+	)
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, word = r, true
+		case r == ' ' || r == '\t':
+			if word {
+				out = append(out, cur.String())
+				cur.Reset()
+				word = false
+			}
+		default:
+			cur.WriteRune(r)
+			word = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if word {
+		out = append(out, cur.String())
+	}
+	return out, nil
+}
+
+func (s *shell) cmdHelp(_ context.Context, args []string) error {
+	w := newTable(s.e.stdout)
+	for _, c := range shellCommands {
+		w.row(strings.TrimSpace(c.name+" "+c.usage), c.help)
+	}
+	return w.flush()
+}
+
+// withTx runs the function in the explicit transaction, or in a new one if there is no explicit transaction.
+func (s *shell) withTx(ctx context.Context, rw bool, fnc func(tx tuple.Tx) error) error {
+	if s.tx != nil {
+		if rw && !s.rw {
+			return errors.New("current transaction is read-only")
+		}
+		return fnc(s.tx)
+	}
+	if rw {
+		return s.db.Update(ctx, fnc)
+	}
+	return s.db.View(ctx, fnc)
+}
+
+// withTable opens a table named by the first argument and passes the rest of arguments to the function.
+func (s *shell) withTable(ctx context.Context, rw bool, args []string, fnc func(t tuple.Table, args []string) error) error {
+	if len(args) == 0 {
+		return errors.New("table name is required")
+	}
+	return s.withTx(ctx, rw, func(tx tuple.Tx) error {
+		t, err := tx.Table(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return fnc(t, args[1:])
+	})
+}
+
+func (s *shell) cmdTables(ctx context.Context, args []string) error {
+	return s.withTx(ctx, false, func(tx tuple.Tx) error {
+		list, err := tx.ListTables(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range list {
+			fmt.Fprintln(s.e.stdout, t.Header().Name)
+		}
+		return nil
+	})
+}
+
+func (s *shell) cmdSchema(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: schema <table>")
+	}
+	return s.withTable(ctx, false, args, func(t tuple.Table, _ []string) error {
+		return s.e.printSchema(t.Header())
+	})
+}
+
+func (s *shell) cmdScan(ctx context.Context, args []string) error {
+	return s.withTable(ctx, false, args, func(t tuple.Table, args []string) error {
+		limit := 0
+		var where []string
+		for i := 0; i < len(args); i++ {
+			if strings.ToLower(args[i]) != "limit" {
+				where = append(where, args[i])
+				continue
+			}
+			if i+1 >= len(args) {
+				return errors.New("limit value is required")
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return fmt.Errorf("invalid limit: %w", err)
+			}
+			limit = n
+			i++
+		}
+		h := t.Header()
+		f, err := s.e.buildFilter(h, where)
+		if err != nil {
+			return err
+		}
+		it := t.Scan(ctx, &tuple.ScanOptions{Filter: f, Limit: limit})
+		defer it.Close()
+		return s.e.printTuples(ctx, h, it, limit)
+	})
+}
+
+// assignment is a set of field values parsed from field=value arguments.
+type assignment struct {
+	key     tuple.Key
+	data    tuple.Data
+	hasKey  []bool
+	hasData []bool
+}
+
+func (s *shell) parseAssign(h tuple.Header, args []string) (*assignment, error) {
+	a := &assignment{
+		key: make(tuple.Key, len(h.Key)), hasKey: make([]bool, len(h.Key)),
+		data: make(tuple.Data, len(h.Data)), hasData: make([]bool, len(h.Data)),
+	}
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("expected field=value, got %q", arg)
+		}
+		name, lit := arg[:i], arg[i+1:]
+		if f, j := h.KeyByName(name); f != nil {
+			if a.hasKey[j] {
+				return nil, fmt.Errorf("field %q is set twice", name)
+			}
+			v, err := s.e.fmt.parseValue(f.Type, lit)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse value for %q: %w", name, err)
+			}
+			a.key[j], a.hasKey[j] = v.(values.Sortable), true
+		} else if f, j := h.DataByName(name); f != nil {
+			if a.hasData[j] {
+				return nil, fmt.Errorf("field %q is set twice", name)
+			}
+			v, err := s.e.fmt.parseValue(f.Type, lit)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse value for %q: %w", name, err)
+			}
+			a.data[j], a.hasData[j] = v, true
+		} else {
+			return nil, fmt.Errorf("unknown field: %q", name)
+		}
+	}
+	return a, nil
+}
+
+// checkKey verifies that all key fields are set. Auto-increment fields can be omitted if auto is set.
+func (a *assignment) checkKey(h tuple.Header, auto bool) error {
+	for i, f := range h.Key {
+		if !a.hasKey[i] && !(auto && f.Auto) {
+			return fmt.Errorf("key field %q is required", f.Name)
+		}
+	}
+	return nil
+}
+
+// checkData verifies that all payload fields are set.
+func (a *assignment) checkData(h tuple.Header) error {
+	for i, f := range h.Data {
+		if !a.hasData[i] {
+			return fmt.Errorf("field %q is required", f.Name)
+		}
+	}
+	return nil
+}
+
+// formatKey renders the key as a list of key=value pairs.
+func (s *shell) formatKey(h tuple.Header, key tuple.Key) string {
+	parts := make([]string, len(key))
+	for i, v := range key {
+		parts[i] = h.Key[i].Name + "=" + s.e.fmt.formatValue(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *shell) cmdGet(ctx context.Context, args []string) error {
+	return s.withTable(ctx, false, args, func(t tuple.Table, args []string) error {
+		h := t.Header()
+		a, err := s.parseAssign(h, args)
+		if err != nil {
+			return err
+		}
+		if err = a.checkKey(h, false); err != nil {
+			return err
+		}
+		for i, f := range h.Data {
+			if a.hasData[i] {
+				return fmt.Errorf("%q is not a key field", f.Name)
+			}
+		}
+		data, err := t.GetTuple(ctx, a.key)
+		if err != nil {
+			return err
+		}
+		w := s.e.newTupleTable(h)
+		w.add(a.key, data)
+		return w.flush()
+	})
+}
+
+func (s *shell) cmdInsert(ctx context.Context, args []string) error {
+	return s.withTable(ctx, true, args, func(t tuple.Table, args []string) error {
+		h := t.Header()
+		a, err := s.parseAssign(h, args)
+		if err != nil {
+			return err
+		}
+		if err = a.checkKey(h, true); err != nil {
+			return err
+		} else if err = a.checkData(h); err != nil {
+			return err
+		}
+		key, err := t.InsertTuple(ctx, tuple.Tuple{Key: a.key, Data: a.data})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(s.e.stdout, "inserted", s.formatKey(h, key))
+		return nil
+	})
+}
+
+func (s *shell) cmdUpdate(ctx context.Context, args []string) error {
+	return s.withTable(ctx, true, args, func(t tuple.Table, args []string) error {
+		h := t.Header()
+		a, err := s.parseAssign(h, args)
+		if err != nil {
+			return err
+		}
+		if err = a.checkKey(h, false); err != nil {
+			return err
+		}
+		data, err := t.GetTuple(ctx, a.key)
+		if err != nil {
+			return err
+		}
+		data = append(tuple.Data{}, data...)
+		for i, ok := range a.hasData {
+			if ok {
+				data[i] = a.data[i]
+			}
+		}
+		return t.UpdateTuple(ctx, tuple.Tuple{Key: a.key, Data: data}, nil)
+	})
+}
+
+func (s *shell) cmdUpsert(ctx context.Context, args []string) error {
+	return s.withTable(ctx, true, args, func(t tuple.Table, args []string) error {
+		h := t.Header()
+		a, err := s.parseAssign(h, args)
+		if err != nil {
+			return err
+		}
+		if err = a.checkKey(h, false); err != nil {
+			return err
+		} else if err = a.checkData(h); err != nil {
+			return err
+		}
+		return t.UpdateTuple(ctx, tuple.Tuple{Key: a.key, Data: a.data}, &tuple.UpdateOpt{Upsert: true})
+	})
+}
+
+func (s *shell) cmdDelete(ctx context.Context, args []string) error {
+	return s.withTable(ctx, true, args, func(t tuple.Table, args []string) error {
+		if len(args) == 0 {
+			return errors.New("at least one filter is required")
+		}
+		f, err := s.e.buildFilter(t.Header(), args)
+		if err != nil {
+			return err
+		}
+		return t.DeleteTuples(ctx, f)
+	})
+}
+
+func (s *shell) cmdBegin(ctx context.Context, args []string) error {
+	rw := true
+	switch {
+	case len(args) == 1 && strings.ToLower(args[0]) == "ro":
+		rw = false
+	case len(args) != 0:
+		return errors.New("usage: begin [ro]")
+	}
+	if s.tx != nil {
+		return errors.New("transaction is already open")
+	}
+	tx, err := s.db.Tx(ctx, rw)
+	if err != nil {
+		return err
+	}
+	s.tx, s.rw = tx, rw
+	return nil
+}
+
+func (s *shell) cmdCommit(ctx context.Context, args []string) error {
+	if s.tx == nil {
+		return errors.New("no open transaction")
+	} else if !s.rw {
+		// nothing to commit in read-only transactions
+		return s.closeTx()
+	}
+	err := s.tx.Commit(ctx)
+	if err2 := s.closeTx(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (s *shell) cmdRollback(_ context.Context, args []string) error {
+	if s.tx == nil {
+		return errors.New("no open transaction")
+	}
+	return s.closeTx()
+}
+
+func (s *shell) closeTx() error {
+	tx := s.tx
+	s.tx, s.rw = nil, false
+	return tx.Close()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(` insert  users name="bob smith" note='a "b"' tag=x\x00 `)
+	require.NoError(t, err)
+	require.Equal(t, []string{"insert", "users", "name=bob smith", `note=a "b"`, `tag=x\x00`}, args)
+
+	_, err = splitArgs(`insert "users`)
+	require.Error(t, err)
+}
+
+func TestShell(t *testing.T) {
+	ctx := context.Background()
+	url := "bolt:" + filepath.Join(t.TempDir(), "bolt.db")
+	db, err := openDB(url)
+	require.NoError(t, err)
+	err = db.tuple.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "events",
+			Key:  []tuple.KeyField{{Name: "id", Type: values.UIntType{}, Auto: true}},
+			Data: []tuple.Field{
+				{Name: "name", Type: values.StringType{}},
+				{Name: "payload", Type: values.BytesType{}},
+				{Name: "n", Type: values.IntType{}},
+				{Name: "score", Type: values.FloatType{}},
+				{Name: "ok", Type: values.BoolType{}},
+				{Name: "at", Type: values.TimeType{}},
+			},
+		})
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	shell := func(script string) (string, string) {
+		var stdout, stderr bytes.Buffer
+		in := strings.NewReader(strings.TrimSpace(script))
+		code := run(ctx, []string{"shell", "-db", url, "-prompt", ""}, in, &stdout, &stderr)
+		require.Equal(t, 0, code, stderr.String())
+		return stdout.String(), stderr.String()
+	}
+
+	out, errs := shell(`
+tables
+schema events
+# auto-increment key is assigned on insert
+insert events name="first event" payload=\x00\x01 n=-5 score=1.5 ok=true at=2020-01-02T03:04:05Z
+insert events name=second payload=ab n=7 score=-2 ok=false at=2021-01-01T00:00:00.5Z
+update events id=1 n=10
+upsert events id=5 name=fifth payload= n=0 score=0 ok=true at=2022-01-01T00:00:00Z
+get events id=1
+scan events n>=7 limit 1
+`)
+	require.Empty(t, errs)
+	require.Equal(t, []string{
+		"events",
+		"FIELD    TYPE    KEY",
+		"id       uint    auto",
+		"name     string",
+		"payload  bytes",
+		"n        int",
+		"score    float",
+		"ok       bool",
+		"at       time",
+		"inserted id=1",
+		"inserted id=2",
+		"id  name         payload   n   score  ok    at",
+		"1   first event  \\x00\\x01  10  1.5    true  2020-01-02T03:04:05Z",
+		"id  name         payload   n   score  ok    at",
+		"1   first event  \\x00\\x01  10  1.5    true  2020-01-02T03:04:05Z",
+	}, trimLines(out))
+
+	// explicit transactions
+	out, errs = shell(`
+begin
+delete events name=second
+scan events
+rollback
+begin ro
+delete events id>1
+commit
+begin
+delete events ok=true
+commit
+scan events
+begin
+insert events name=lost payload= n=0 score=0 ok=false at=2020-01-01T00:00:00Z
+`)
+	require.Equal(t, []string{
+		"id  name         payload   n   score  ok    at",
+		"1   first event  \\x00\\x01  10  1.5    true  2020-01-02T03:04:05Z",
+		"5   fifth                  0   0      true  2022-01-01T00:00:00Z",
+		"id  name    payload  n  score  ok     at",
+		"2   second  ab       7  -2     false  2021-01-01T00:00:00.5Z",
+		"inserted id=3",
+	}, trimLines(out))
+	require.Equal(t, []string{
+		"error: current transaction is read-only",
+		"rolling back the open transaction",
+	}, trimLines(errs))
+
+	out, errs = shell(`
+scan events
+get events id=3
+insert events n=1
+update events id=2 n=x
+frobnicate
+quit
+scan events
+`)
+	require.Equal(t, []string{
+		"id  name    payload  n  score  ok     at",
+		"2   second  ab       7  -2     false  2021-01-01T00:00:00.5Z",
+	}, trimLines(out))
+	errLines := trimLines(errs)
+	require.Len(t, errLines, 4)
+	require.Contains(t, errLines[0], "not found")
+	require.Contains(t, errLines[1], `field "name" is required`)
+	require.Contains(t, errLines[2], `cannot parse value for "n"`)
+	require.Contains(t, errLines[3], "unknown command")
+}
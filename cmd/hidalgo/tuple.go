@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hidal-go/hidalgo/filter"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+func init() {
+	registerCommand(command{
+		name: "tables", db: true,
+		help: "list tables",
+		setup: func(fs *flag.FlagSet) runFunc {
+			return cmdTables
+		},
+	})
+	registerCommand(command{
+		name: "schema", usage: "<table>", db: true,
+		help: "print the table schema",
+		setup: func(fs *flag.FlagSet) runFunc {
+			return cmdSchema
+		},
+	})
+	registerCommand(command{
+		name: "select", usage: "<table>", db: true,
+		help: "print tuples from the table",
+		setup: func(fs *flag.FlagSet) runFunc {
+			var where stringsFlag
+			fs.Var(&where, "where", "filter in the form of field<op>value, where op is one of =, !=, <, <=, >, >=, ^= (prefix); can be repeated")
+			limit := fs.Int("limit", 0, "maximal number of tuples to print")
+			return func(ctx context.Context, e *env, args []string) error {
+				if len(args) != 1 {
+					return errUsage
+				}
+				return cmdSelect(ctx, e, args[0], where, *limit)
+			}
+		},
+	})
+}
+
+func cmdTables(ctx context.Context, e *env, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	return e.withDB(func(db *database) error {
+		list, err := db.tuple.ListTables(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range list {
+			fmt.Fprintln(e.stdout, t.Header().Name)
+		}
+		return nil
+	})
+}
+
+func cmdSchema(ctx context.Context, e *env, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	return e.withDB(func(db *database) error {
+		t, err := db.tuple.Table(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return e.printSchema(t.Header())
+	})
+}
+
+// printSchema prints fields of the table header.
+func (e *env) printSchema(h tuple.Header) error {
+	w := newTable(e.stdout)
+	w.row("FIELD", "TYPE", "KEY")
+	for _, f := range h.Key {
+		key := "yes"
+		if f.Auto {
+			key = "auto"
+		}
+		w.row(f.Name, typeName(f.Type), key)
+	}
+	for _, f := range h.Data {
+		w.row(f.Name, typeName(f.Type), "")
+	}
+	return w.flush()
+}
+
+// whereOps lists filter operators. Longer operators must go first.
+var whereOps = []string{"!=", "<=", ">=", "^=", "=", "<", ">"}
+
+// parseWhere parses a filter expression for a single field.
+func (e *env) parseWhere(h tuple.Header, expr string) (int, bool, filter.ValueFilter, error) {
+	i := strings.IndexAny(expr, "=!<>^")
+	if i <= 0 {
+		return 0, false, nil, fmt.Errorf("invalid filter: %q", expr)
+	}
+	name, rest := expr[:i], expr[i:]
+	op := ""
+	for _, o := range whereOps {
+		if strings.HasPrefix(rest, o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		return 0, false, nil, fmt.Errorf("invalid filter operator: %q", expr)
+	}
+	var (
+		tp    values.Type
+		isKey bool
+	)
+	kf, ki := h.KeyByName(name)
+	df, di := h.DataByName(name)
+	switch {
+	case kf != nil:
+		tp, isKey = kf.Type, true
+	case df != nil:
+		tp = df.Type
+	default:
+		return 0, false, nil, fmt.Errorf("unknown field: %q", name)
+	}
+	v, err := e.fmt.parseValue(tp, rest[len(op):])
+	if err != nil {
+		return 0, false, nil, fmt.Errorf("cannot parse value for %q: %w", name, err)
+	}
+	idx := di
+	if isKey {
+		idx = ki
+	}
+	switch op {
+	case "=":
+		return idx, isKey, filter.EQ(v), nil
+	case "!=":
+		return idx, isKey, filter.Not{Filter: filter.EQ(v)}, nil
+	}
+	s, ok := v.(values.Sortable)
+	if !ok {
+		return 0, false, nil, fmt.Errorf("field %q is not sortable", name)
+	}
+	switch op {
+	case "<":
+		return idx, isKey, *filter.LT(s), nil
+	case "<=":
+		return idx, isKey, *filter.LTE(s), nil
+	case ">":
+		return idx, isKey, *filter.GT(s), nil
+	case ">=":
+		return idx, isKey, *filter.GTE(s), nil
+	}
+	b, ok := v.(values.BinaryString)
+	if !ok {
+		return 0, false, nil, fmt.Errorf("prefix filter is not supported for field %q", name)
+	}
+	return idx, isKey, filter.Prefix(b), nil
+}
+
+// buildFilter builds a tuple filter from multiple filter expressions. All expressions must match.
+func (e *env) buildFilter(h tuple.Header, where []string) (*tuple.Filter, error) {
+	if len(where) == 0 {
+		return nil, nil
+	}
+	keys := make([]filter.And, len(h.Key))
+	data := make([]filter.And, len(h.Data))
+	nkeys, ndata := 0, 0
+	for _, expr := range where {
+		i, isKey, f, err := e.parseWhere(h, expr)
+		if err != nil {
+			return nil, err
+		}
+		if isKey {
+			keys[i] = append(keys[i], f)
+			if i+1 > nkeys {
+				nkeys = i + 1
+			}
+		} else {
+			data[i] = append(data[i], f)
+			ndata++
+		}
+	}
+	// use a single filter for each field if possible, so backends can optimize it
+	one := func(arr filter.And) filter.ValueFilter {
+		switch len(arr) {
+		case 0:
+			return nil
+		case 1:
+			return arr[0]
+		}
+		return arr
+	}
+	f := &tuple.Filter{}
+	if nkeys != 0 {
+		kf := make(tuple.KeyFilters, nkeys)
+		for i := range kf {
+			kf[i] = one(keys[i])
+			if kf[i] == nil {
+				kf[i] = filter.Any{}
+			}
+		}
+		f.KeyFilter = kf
+	}
+	if ndata != 0 {
+		df := make(tuple.DataFilters, len(data))
+		for i := range df {
+			df[i] = one(data[i])
+		}
+		f.DataFilter = df
+	}
+	return f, nil
+}
+
+func cmdSelect(ctx context.Context, e *env, name string, where []string, limit int) error {
+	return e.withDB(func(db *database) error {
+		return db.tuple.View(ctx, func(tx tuple.Tx) error {
+			t, err := tx.Table(ctx, name)
+			if err != nil {
+				return err
+			}
+			h := t.Header()
+			f, err := e.buildFilter(h, where)
+			if err != nil {
+				return err
+			}
+			it := t.Scan(ctx, &tuple.ScanOptions{Filter: f, Limit: limit})
+			defer it.Close()
+			return e.printTuples(ctx, h, it, limit)
+		})
+	})
+}
+
+// printTuples prints tuples from the iterator as a table with a header.
+func (e *env) printTuples(ctx context.Context, h tuple.Header, it tuple.Iterator, limit int) error {
+	w := e.newTupleTable(h)
+	for n := 0; (limit <= 0 || n < limit) && it.Next(ctx); n++ {
+		w.add(it.Key(), it.Data())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+// tupleTable prints tuples as aligned columns.
+type tupleTable struct {
+	*table
+	f    keyFormat
+	cols []string
+}
+
+// newTupleTable creates a tuple table and writes the header.
+func (e *env) newTupleTable(h tuple.Header) *tupleTable {
+	w := &tupleTable{table: newTable(e.stdout), f: e.fmt}
+	w.cols = make([]string, 0, len(h.Key)+len(h.Data))
+	for _, c := range h.Key {
+		w.cols = append(w.cols, c.Name)
+	}
+	for _, c := range h.Data {
+		w.cols = append(w.cols, c.Name)
+	}
+	w.row(w.cols...)
+	return w
+}
+
+func (w *tupleTable) add(key tuple.Key, data tuple.Data) {
+	w.cols = w.cols[:0]
+	for _, v := range key {
+		w.cols = append(w.cols, w.f.formatValue(v))
+	}
+	for _, v := range data {
+		w.cols = append(w.cols, w.f.formatValue(v))
+	}
+	w.row(w.cols...)
+}
@@ -4,4 +4,6 @@ import (
 	_ "github.com/hidal-go/hidalgo/kv/bbolt"
 	_ "github.com/hidal-go/hidalgo/kv/bolt"
 	_ "github.com/hidal-go/hidalgo/kv/flat/all"
+	_ "github.com/hidal-go/hidalgo/kv/mem"
+	_ "github.com/hidal-go/hidalgo/kv/remote"
 )
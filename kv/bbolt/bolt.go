@@ -29,11 +29,14 @@ const (
 	Name = "bbolt"
 )
 
+// caps are the capabilities of the driver.
+const caps = base.CapTx | base.CapConcurrentWrites | base.CapSeek | base.CapPrefixScan | base.CapBatchGet
+
 func init() {
 	kv.Register(kv.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "BBoltDB",
-			Local: true,
+			Local: true, Capabilities: caps,
 		},
 		OpenPath: OpenPath,
 	})
@@ -75,7 +78,15 @@ func (db *DB) Close() error {
 	return db.db.Close()
 }
 
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
 func (db *DB) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	tx, err := db.db.Begin(rw)
 	if err != nil {
 		return nil, err
@@ -115,6 +126,9 @@ func (tx *Tx) bucket(key kv.Key) (*bolt.Bucket, kv.Key) {
 }
 
 func (tx *Tx) Get(ctx context.Context, key kv.Key) (kv.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	b, k := tx.bucket(key)
 	if b == nil || len(k) != 1 {
 		return nil, kv.ErrNotFound
@@ -127,6 +141,9 @@ func (tx *Tx) Get(ctx context.Context, key kv.Key) (kv.Value, error) {
 }
 
 func (tx *Tx) GetBatch(ctx context.Context, keys []kv.Key) ([]kv.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	vals := make([]kv.Value, len(keys))
 	for i, key := range keys {
 		if b, k := tx.bucket(key); b != nil && len(k) == 1 {
@@ -137,14 +154,26 @@ func (tx *Tx) GetBatch(ctx context.Context, keys []kv.Key) ([]kv.Value, error) {
 }
 
 func (tx *Tx) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		_ = tx.tx.Rollback()
+		return err
+	}
 	return tx.tx.Commit()
 }
 
 func (tx *Tx) Close() error {
-	return tx.tx.Rollback()
+	err := tx.tx.Rollback()
+	if err == bolt.ErrTxClosed {
+		// committed transactions are not affected by Close
+		return nil
+	}
+	return err
 }
 
 func (tx *Tx) Put(ctx context.Context, k kv.Key, v kv.Value) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	var (
 		b   *bolt.Bucket
 		err error
@@ -172,6 +201,9 @@ func (tx *Tx) Put(ctx context.Context, k kv.Key, v kv.Value) error {
 }
 
 func (tx *Tx) Del(ctx context.Context, k kv.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	b, k := tx.bucket(k)
 	if b == nil || len(k) != 1 {
 		return nil
@@ -208,11 +240,15 @@ type Iterator struct {
 		c []*bolt.Cursor
 	}
 	k, v []byte // inside the current bucket
+	cur  bool   // current key was positioned by Seek and not yet checked
+	err  error
 }
 
 func (it *Iterator) Reset() {
+	it.err = nil
 	it.k = nil
 	it.v = nil
+	it.cur = false
 	it.stack.c = nil
 	it.stack.b = nil
 	if cap(it.stack.k) >= len(it.rootk) {
@@ -258,6 +294,8 @@ func (it *Iterator) next(pref kv.Key) bool {
 			} else {
 				it.k, it.v = c.Seek(pref[i])
 			}
+		} else if it.cur {
+			it.cur = false
 		} else {
 			c := it.stack.c[i]
 			it.k, it.v = c.Next()
@@ -291,15 +329,82 @@ func (it *Iterator) next(pref kv.Key) bool {
 	return false
 }
 
+// stop stops the iterator if the context is canceled.
+func (it *Iterator) stop(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		it.stack.b, it.stack.c = nil, nil
+		it.k, it.v = nil, nil
+		return true
+	}
+	return false
+}
+
 func (it *Iterator) Seek(ctx context.Context, key kv.Key) bool {
 	it.Reset()
-	if !it.next(key) {
+	if len(it.stack.b) == 0 || it.stop(ctx) {
 		return false
 	}
-	return it.Key().HasPrefix(it.pref)
+	// make the key relative to the root bucket
+	n := len(it.rootk)
+	if len(key) < n {
+		n = len(key)
+	}
+	switch key[:n].Compare(it.rootk[:n]) {
+	case -1:
+		key = nil
+	case +1:
+		return false
+	default:
+		if len(key) < len(it.rootk) {
+			key = nil
+		} else {
+			key = key[len(it.rootk):]
+		}
+	}
+	if key.Compare(it.pref) < 0 {
+		key = it.pref
+	}
+	it.seek(key)
+	return it.next(it.pref)
+}
+
+// seek positions cursors at the first key that is greater or equal to a given one.
+// The key is relative to the root bucket.
+func (it *Iterator) seek(key kv.Key) {
+	for i := 0; ; i++ {
+		b := it.stack.b[i]
+		c := b.Cursor()
+		it.stack.c = append(it.stack.c, c)
+		it.cur = true
+		if i >= len(key) {
+			// key points to the bucket itself, all keys inside it are greater
+			it.k, it.v = c.First()
+			return
+		}
+		it.k, it.v = c.Seek(key[i])
+		if it.k == nil || !bytes.Equal(it.k, key[i]) {
+			return
+		}
+		if it.v == nil {
+			if sub := b.Bucket(it.k); sub != nil {
+				it.stack.b = append(it.stack.b, sub)
+				it.stack.k = append(it.stack.k, it.k)
+				continue
+			}
+		}
+		if i < len(key)-1 {
+			// a value that is a prefix of the key is less than the key
+			it.k, it.v = c.Next()
+		}
+		return
+	}
 }
 
 func (it *Iterator) Next(ctx context.Context) bool {
+	if it.stop(ctx) {
+		return false
+	}
 	return it.next(it.pref)
 }
 
@@ -317,7 +422,7 @@ func (it *Iterator) Val() kv.Value {
 }
 
 func (it *Iterator) Err() error {
-	return nil
+	return it.err
 }
 
 func (it *Iterator) Close() error {
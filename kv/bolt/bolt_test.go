@@ -1,9 +1,12 @@
 package bolt
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/hidal-go/hidalgo/kv"
 	"github.com/hidal-go/hidalgo/kv/kvtest"
 )
@@ -14,3 +17,80 @@ func TestBolt(t *testing.T) {
 		return OpenPath(path)
 	}, nil)
 }
+
+func BenchmarkBolt(b *testing.B) {
+	kvtest.RunBenchmarksLocal(b, func(path string) (kv.KV, error) {
+		path = filepath.Join(path, "bolt.db")
+		return OpenPath(path)
+	}, nil)
+}
+
+// TestSeekMissing checks that Seek to a key that does not exist stops at the next key.
+// Previously the key was used as a prefix, so such seeks returned no results.
+func TestSeekMissing(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenPath(filepath.Join(t.TempDir(), "bolt.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	td := kvtest.NewTest(t, db)
+	for _, k := range []kv.Key{kv.SKey("a", "x"), kv.SKey("b", "y"), kv.SKey("b", "z"), kv.SKey("c")} {
+		td.Put(k, kv.Value("v"))
+	}
+	for _, c := range []struct {
+		seek, exp kv.Key
+	}{
+		{seek: kv.SKey("0"), exp: kv.SKey("a", "x")},
+		{seek: kv.SKey("a"), exp: kv.SKey("a", "x")},
+		{seek: kv.SKey("a", "z"), exp: kv.SKey("b", "y")},
+		{seek: kv.SKey("b", "y0"), exp: kv.SKey("b", "z")},
+		{seek: kv.SKey("bb"), exp: kv.SKey("c")},
+		{seek: kv.SKey("d")},
+	} {
+		err = kv.View(ctx, db, func(tx kv.Tx) error {
+			it := tx.Scan(ctx)
+			defer it.Close()
+			if !kv.Seek(ctx, it, c.seek) {
+				require.Nil(t, c.exp, "seek %q", c.seek)
+				return it.Err()
+			}
+			require.Equal(t, c.exp, it.Key(), "seek %q", c.seek)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
+
+// TestDelNextToBucket checks that deleting a missing key is a no-op, even if the next key is a bucket.
+// Deleting a bucket key must still fail, the same as putting a value to it does.
+func TestDelNextToBucket(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenPath(filepath.Join(t.TempDir(), "bolt.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	td := kvtest.NewTest(t, db)
+	td.Put(kv.SKey("b", "x"), kv.Value("v"))
+
+	del := func(k kv.Key) error {
+		return kv.Update(ctx, db, func(tx kv.Tx) error {
+			return tx.Del(ctx, k)
+		})
+	}
+	require.NoError(t, del(kv.SKey("a")))
+	require.Error(t, del(kv.SKey("b")))
+	td.Expect(kv.SKey("b", "x"), kv.Value("v"))
+}
+
+// TestCloseAfterCommit checks that closing a committed transaction is a no-op, as required by base.Tx.
+// Bolt returns ErrTxClosed from Rollback in this case.
+func TestCloseAfterCommit(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenPath(filepath.Join(t.TempDir(), "bolt.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	require.NoError(t, tx.Put(ctx, kv.SKey("a"), kv.Value("1")))
+	require.NoError(t, tx.Commit(ctx))
+	require.NoError(t, tx.Close())
+	kvtest.NewTest(t, db).Expect(kv.SKey("a"), kv.Value("1"))
+}
@@ -2,6 +2,7 @@ package all
 
 import (
 	_ "github.com/hidal-go/hidalgo/kv/flat/badger"
+	_ "github.com/hidal-go/hidalgo/kv/flat/bitcask"
 	_ "github.com/hidal-go/hidalgo/kv/flat/btree"
 	_ "github.com/hidal-go/hidalgo/kv/flat/leveldb"
 )
@@ -13,11 +13,14 @@ const (
 	Name = "badger"
 )
 
+// caps are the capabilities of the driver.
+const caps = base.CapTx | base.CapConcurrentWrites | base.CapSeek | base.CapPrefixScan | base.CapConflicts
+
 func init() {
 	flat.Register(flat.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "Badger",
-			Local: true,
+			Local: true, Capabilities: caps,
 		},
 		OpenPath: OpenPath,
 	})
@@ -66,7 +69,15 @@ func (db *DB) Close() error {
 	return db.db.Close()
 }
 
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
 func (db *DB) Tx(ctx context.Context, rw bool) (flat.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	tx := db.db.NewTransaction(rw)
 	return &Tx{tx: tx}, nil
 }
@@ -84,6 +95,10 @@ type Tx struct {
 }
 
 func (tx *Tx) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		tx.tx.Discard()
+		return err
+	}
 	err := tx.tx.Commit()
 	if err == badger.ErrConflict {
 		err = flat.ErrConflict
@@ -97,7 +112,9 @@ func (tx *Tx) Close() error {
 }
 
 func (tx *Tx) Get(ctx context.Context, key flat.Key) (flat.Value, error) {
-	if len(key) == 0 {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if len(key) == 0 {
 		return nil, flat.ErrNotFound
 	}
 	item, err := tx.tx.Get(key)
@@ -114,6 +131,9 @@ func (tx *Tx) GetBatch(ctx context.Context, keys []flat.Key) ([]flat.Value, erro
 }
 
 func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	err := tx.tx.Set(k, v)
 	if err == badger.ErrConflict {
 		err = flat.ErrConflict
@@ -122,6 +142,9 @@ func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
 }
 
 func (tx *Tx) Del(ctx context.Context, k flat.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	err := tx.tx.Delete(k)
 	if err == badger.ErrConflict {
 		err = flat.ErrConflict
@@ -170,14 +193,30 @@ func (it *Iterator) next() bool {
 	return it.valid
 }
 
+// stop stops the iterator if the context is canceled.
+func (it *Iterator) stop(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		it.valid = false
+		it.err = err
+		return true
+	}
+	return false
+}
+
 func (it *Iterator) Seek(ctx context.Context, key flat.Key) bool {
 	it.Reset()
+	if it.stop(ctx) {
+		return false
+	}
 	it.first = false
 	it.it.Seek(key)
 	return it.next()
 }
 
 func (it *Iterator) Next(ctx context.Context) bool {
+	if it.err != nil || it.stop(ctx) {
+		return false
+	}
 	if it.first {
 		it.first = false
 		it.it.Seek(it.pref)
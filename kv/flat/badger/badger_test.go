@@ -10,3 +10,7 @@ import (
 func TestBadger(t *testing.T) {
 	kvtest.RunTestLocal(t, flat.UpgradeOpenPath(OpenPath), nil)
 }
+
+func BenchmarkBadger(b *testing.B) {
+	kvtest.RunBenchmarksLocal(b, flat.UpgradeOpenPath(OpenPath), nil)
+}
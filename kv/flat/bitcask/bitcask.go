@@ -0,0 +1,723 @@
+// Package bitcask implements a persistent flat key-value store based on an append-only log.
+//
+// The design follows Bitcask: all writes are appended to log segments on disk, while an ordered index
+// of all keys with locations of their values is kept in memory. The index is rebuilt by replaying
+// the log on open. Stale records are removed by periodic compaction that merges all segments into one.
+//
+// Only one process may open the database directory at a time.
+package bitcask
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv/flat"
+)
+
+const (
+	Name = "bitcask"
+)
+
+// caps are the capabilities of the driver.
+const caps = base.CapTx | base.CapConcurrentWrites | base.CapSeek | base.CapPrefixScan
+
+func init() {
+	flat.Register(flat.Registration{
+		Registration: base.Registration{
+			Name: Name, Title: "Append-only log",
+			Local: true, Capabilities: caps,
+		},
+		OpenPath: OpenPath,
+	})
+}
+
+const (
+	defaultMaxSegmentSize = 64 << 20
+	defaultCompactRatio   = 0.5
+	defaultMinCompactSize = 1 << 20
+
+	// maxRecordSize limits the size of records written by compaction.
+	maxRecordSize = 1 << 20
+)
+
+var (
+	// ErrTxClosed is returned when using a transaction after Commit or Close.
+	ErrTxClosed = errors.New("bitcask: transaction is closed")
+	// ErrClosed is returned when using a closed database.
+	ErrClosed = errors.New("bitcask: database is closed")
+)
+
+// Options for the append-only log store.
+type Options struct {
+	// MaxSegmentSize is a size of the log segment after which a new segment is started. Default is 64 MB.
+	MaxSegmentSize int64
+	// CompactRatio is a fraction of stale data in the log that triggers compaction on commit.
+	// Default is 0.5. Negative value disables automatic compaction.
+	CompactRatio float64
+	// MinCompactSize is a minimal size of the log for automatic compaction. Default is 1 MB.
+	MinCompactSize int64
+	// NoSync disables fsync on each commit. Committed data may be lost on crash, but the log remains consistent.
+	NoSync bool
+}
+
+func (o *Options) defaults() {
+	if o.MaxSegmentSize <= 0 {
+		o.MaxSegmentSize = defaultMaxSegmentSize
+	}
+	if o.CompactRatio == 0 {
+		o.CompactRatio = defaultCompactRatio
+	}
+	if o.MinCompactSize <= 0 {
+		o.MinCompactSize = defaultMinCompactSize
+	}
+}
+
+var _ flat.KV = (*DB)(nil)
+
+// loc is a location of the value in the log.
+type loc struct {
+	seg *segment
+	off int64
+	n   uint32
+}
+
+// entry is a single key in the index.
+type entry struct {
+	key []byte
+	loc loc
+}
+
+// entrySize estimates the size of the put operation in the log.
+func entrySize(k []byte, n uint32) int64 {
+	return 1 + 2*4 + int64(len(k)) + int64(n)
+}
+
+// Open opens or creates a database in a given directory. Options can be nil.
+func Open(dir string, opts *Options) (*DB, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	o.defaults()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	db := &DB{dir: dir, opts: o}
+	db.cond = sync.NewCond(&db.mu)
+	if err := db.load(); err != nil {
+		closeSegments(db.segs)
+		return nil, err
+	}
+	return db, nil
+}
+
+// OpenPath opens or creates a database in a given directory with default options.
+func OpenPath(path string) (flat.KV, error) {
+	db, err := Open(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// DB is a persistent key-value store based on an append-only log.
+type DB struct {
+	dir  string
+	opts Options
+
+	wmu sync.Mutex // single writer; held for the lifetime of read-write transaction
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signalled when there are no readers
+	idx     []entry    // sorted by key; never modified in place
+	segs    []*segment // last segment is active
+	readers int        // number of open read-only transactions
+	total   int64      // size of all segments
+	live    int64      // estimated size of live data
+	closed  bool
+}
+
+// load replays the log and builds the index.
+func (db *DB) load() error {
+	segs, err := listSegments(db.dir)
+	if err != nil {
+		return err
+	}
+	db.segs = segs
+	var (
+		base = 0 // first segment that is not obsolete
+		idx  = make(map[string]loc)
+	)
+	for i, s := range segs {
+		size, err := s.replay(func(flags byte, ops []op) error {
+			if flags&flagBase != 0 {
+				base = i
+				idx = make(map[string]loc)
+			}
+			for _, o := range ops {
+				if o.del {
+					delete(idx, string(o.key))
+				} else {
+					idx[string(o.key)] = loc{seg: s, off: o.voff, n: o.vlen}
+				}
+			}
+			return nil
+		})
+		if err == errTorn && i == len(segs)-1 {
+			// unfinished write at the tail of the log - discard it
+			if err = s.f.Truncate(size); err != nil {
+				return err
+			}
+		} else if err == errTorn {
+			return ErrCorrupted
+		} else if err != nil {
+			return err
+		}
+		s.size = size
+	}
+	if base > 0 {
+		// compaction finished, but obsolete segments were not removed
+		for _, s := range segs[:base] {
+			s.f.Close()
+			if err := os.Remove(s.path); err != nil {
+				return err
+			}
+		}
+		db.segs = segs[base:]
+	}
+	if len(db.segs) == 0 {
+		s, err := createSegment(db.dir, 1)
+		if err != nil {
+			return err
+		}
+		db.segs = []*segment{s}
+	}
+	db.idx = make([]entry, 0, len(idx))
+	for k, l := range idx {
+		db.idx = append(db.idx, entry{key: []byte(k), loc: l})
+		db.live += entrySize([]byte(k), l.n)
+	}
+	sort.Slice(db.idx, func(i, j int) bool {
+		return bytes.Compare(db.idx[i].key, db.idx[j].key) < 0
+	})
+	for _, s := range db.segs {
+		db.total += s.size
+	}
+	return nil
+}
+
+func (db *DB) active() *segment {
+	return db.segs[len(db.segs)-1]
+}
+
+func (db *DB) Close() error {
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+	var last error
+	for _, s := range db.segs {
+		if err := s.f.Close(); err != nil {
+			last = err
+		}
+	}
+	db.idx = nil
+	return last
+}
+
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
+func (db *DB) Tx(ctx context.Context, rw bool) (flat.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if rw {
+		db.wmu.Lock()
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		if rw {
+			db.wmu.Unlock()
+		}
+		return nil, ErrClosed
+	}
+	if !rw {
+		db.readers++
+	}
+	return &Tx{db: db, rw: rw, idx: db.idx}, nil
+}
+
+func (db *DB) View(ctx context.Context, fn func(tx flat.Tx) error) error {
+	return flat.View(ctx, db, fn)
+}
+
+func (db *DB) Update(ctx context.Context, fn func(tx flat.Tx) error) error {
+	return flat.Update(ctx, db, fn)
+}
+
+// Compact merges all log segments into one, removing stale records.
+// It waits for all read-only transactions to finish, so it must not be called while holding one.
+func (db *DB) Compact(ctx context.Context) error {
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for db.readers > 0 && !db.closed {
+		db.cond.Wait()
+	}
+	if db.closed {
+		return ErrClosed
+	}
+	return db.compact()
+}
+
+func (db *DB) needsCompaction() bool {
+	if db.opts.CompactRatio < 0 || db.total < db.opts.MinCompactSize {
+		return false
+	}
+	return float64(db.total-db.live) >= float64(db.total)*db.opts.CompactRatio
+}
+
+// compact writes all live data into a new segment and removes all other segments.
+// Caller must hold both locks and make sure that there are no open transactions.
+func (db *DB) compact() error {
+	id := db.active().id + 1
+	path := filepath.Join(db.dir, segmentName(id))
+	tmp := path + tmpExt
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	seg := &segment{id: id, path: path, f: f}
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	idx := make([]entry, len(db.idx))
+	var (
+		live int64
+		buf  []byte
+	)
+	rec := newRecord(flagBase)
+	flush := func() error {
+		p := rec.Bytes()
+		if _, err := f.WriteAt(p, seg.size); err != nil {
+			return err
+		}
+		seg.size += int64(len(p))
+		rec = newRecord(0)
+		return nil
+	}
+	for i, e := range db.idx {
+		if rec.Size() >= maxRecordSize {
+			if err = flush(); err != nil {
+				return fail(err)
+			}
+		}
+		if uint32(cap(buf)) < e.loc.n {
+			buf = make([]byte, e.loc.n)
+		}
+		buf = buf[:e.loc.n]
+		if _, err = e.loc.seg.f.ReadAt(buf, e.loc.off); err != nil {
+			return fail(err)
+		}
+		off := rec.Put(e.key, buf)
+		idx[i] = entry{key: e.key, loc: loc{seg: seg, off: seg.size + off, n: e.loc.n}}
+		live += entrySize(e.key, e.loc.n)
+	}
+	if err = flush(); err != nil {
+		return fail(err)
+	}
+	if err = f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fail(err)
+	}
+	if err = syncDir(db.dir); err != nil {
+		// the new segment is in place, so we cannot remove it
+		f.Close()
+		return err
+	}
+	old := db.segs
+	db.segs = []*segment{seg}
+	db.idx = idx
+	db.total, db.live = seg.size, live
+	var last error
+	for _, s := range old {
+		s.f.Close()
+		if err := os.Remove(s.path); err != nil {
+			last = err
+		}
+	}
+	return last
+}
+
+// write appends the record to the log. Caller must hold the write lock.
+func (db *DB) write(rec *record) (*segment, int64, error) {
+	s := db.active()
+	if s.size > 0 && s.size+rec.Size() > db.opts.MaxSegmentSize {
+		ns, err := createSegment(db.dir, s.id+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		db.mu.Lock()
+		db.segs = append(db.segs, ns)
+		db.mu.Unlock()
+		s = ns
+	}
+	off := s.size
+	p := rec.Bytes()
+	if _, err := s.f.WriteAt(p, off); err != nil {
+		// don't leave partial records behind
+		_ = s.f.Truncate(off)
+		return nil, 0, err
+	}
+	if !db.opts.NoSync {
+		if err := s.f.Sync(); err != nil {
+			_ = s.f.Truncate(off)
+			return nil, 0, err
+		}
+	}
+	s.size += int64(len(p))
+	return s, off, nil
+}
+
+// pending is an uncommitted write in a transaction.
+type pending struct {
+	key []byte
+	val flat.Value
+	del bool
+}
+
+type Tx struct {
+	db   *DB
+	rw   bool
+	done bool
+	idx  []entry   // index snapshot
+	pend []pending // sorted by key
+}
+
+// search returns the position of the first key in the index that is greater or equal to a given one.
+func search(idx []entry, key []byte) int {
+	return sort.Search(len(idx), func(i int) bool {
+		return bytes.Compare(idx[i].key, key) >= 0
+	})
+}
+
+func (tx *Tx) searchPending(key []byte) int {
+	return sort.Search(len(tx.pend), func(i int) bool {
+		return bytes.Compare(tx.pend[i].key, key) >= 0
+	})
+}
+
+func (tx *Tx) read(l loc) (flat.Value, error) {
+	v := make(flat.Value, l.n)
+	if _, err := l.seg.f.ReadAt(v, l.off); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (tx *Tx) Get(ctx context.Context, key flat.Key) (flat.Value, error) {
+	if tx.done {
+		return nil, ErrTxClosed
+	} else if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if i := tx.searchPending(key); i < len(tx.pend) && bytes.Equal(tx.pend[i].key, key) {
+		if p := tx.pend[i]; !p.del {
+			return p.val.Clone(), nil
+		}
+		return nil, flat.ErrNotFound
+	}
+	if i := search(tx.idx, key); i < len(tx.idx) && bytes.Equal(tx.idx[i].key, key) {
+		return tx.read(tx.idx[i].loc)
+	}
+	return nil, flat.ErrNotFound
+}
+
+func (tx *Tx) GetBatch(ctx context.Context, keys []flat.Key) ([]flat.Value, error) {
+	return flat.GetBatch(ctx, tx, keys)
+}
+
+func (tx *Tx) set(k flat.Key, v flat.Value, del bool) error {
+	if tx.done {
+		return ErrTxClosed
+	} else if !tx.rw {
+		return flat.ErrReadOnly
+	}
+	p := pending{key: k.Clone(), del: del}
+	if !del {
+		p.val = append(flat.Value{}, v...)
+	}
+	i := tx.searchPending(k)
+	if i < len(tx.pend) && bytes.Equal(tx.pend[i].key, k) {
+		tx.pend[i] = p
+		return nil
+	}
+	tx.pend = append(tx.pend, pending{})
+	copy(tx.pend[i+1:], tx.pend[i:])
+	tx.pend[i] = p
+	return nil
+}
+
+func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return tx.set(k, v, false)
+}
+
+func (tx *Tx) Del(ctx context.Context, k flat.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return tx.set(k, nil, true)
+}
+
+func (tx *Tx) Commit(ctx context.Context) error {
+	if tx.done {
+		return ErrTxClosed
+	}
+	if err := ctx.Err(); err != nil {
+		_ = tx.Close()
+		return err
+	}
+	if !tx.rw {
+		return tx.Close()
+	}
+	defer tx.Close()
+	if len(tx.pend) == 0 {
+		return nil
+	}
+	db := tx.db
+	rec := newRecord(0)
+	offs := make([]int64, len(tx.pend))
+	for i, p := range tx.pend {
+		if p.del {
+			rec.Del(p.key)
+		} else {
+			offs[i] = rec.Put(p.key, p.val)
+		}
+	}
+	s, off, err := db.write(rec)
+	if err != nil {
+		return err
+	}
+
+	// merge pending writes into a new index
+	idx := make([]entry, 0, len(tx.idx)+len(tx.pend))
+	live := db.live
+	i := 0
+	for j, p := range tx.pend {
+		for ; i < len(tx.idx) && bytes.Compare(tx.idx[i].key, p.key) < 0; i++ {
+			idx = append(idx, tx.idx[i])
+		}
+		if i < len(tx.idx) && bytes.Equal(tx.idx[i].key, p.key) {
+			live -= entrySize(p.key, tx.idx[i].loc.n)
+			i++
+		}
+		if !p.del {
+			n := uint32(len(p.val))
+			idx = append(idx, entry{key: p.key, loc: loc{seg: s, off: off + offs[j], n: n}})
+			live += entrySize(p.key, n)
+		}
+	}
+	idx = append(idx, tx.idx[i:]...)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.idx = idx
+	db.live = live
+	db.total += rec.Size()
+	if db.readers == 0 && db.needsCompaction() {
+		// the commit already succeeded, thus compaction errors are not reported;
+		// the log stays consistent and compaction will be retried on the next commit
+		_ = db.compact()
+	}
+	return nil
+}
+
+func (tx *Tx) Close() error {
+	if tx.done {
+		return nil
+	}
+	tx.done = true
+	tx.idx, tx.pend = nil, nil
+	if tx.rw {
+		tx.db.wmu.Unlock()
+		return nil
+	}
+	db := tx.db
+	db.mu.Lock()
+	db.readers--
+	if db.readers == 0 {
+		db.cond.Broadcast()
+	}
+	db.mu.Unlock()
+	return nil
+}
+
+func (tx *Tx) Scan(ctx context.Context, opts ...flat.IteratorOption) flat.Iterator {
+	var it flat.Iterator = &Iterator{tx: tx}
+	it = flat.ApplyIteratorOptions(it, opts)
+	return it
+}
+
+var (
+	_ flat.Seeker         = &Iterator{}
+	_ flat.PrefixIterator = &Iterator{}
+)
+
+// Iterator over a transaction snapshot merged with pending writes.
+// It tracks the position by the current key, so it's safe to modify the transaction while iterating.
+type Iterator struct {
+	tx   *Tx
+	pref []byte
+	done bool
+	key  []byte
+	cur  *entry   // current committed value
+	pend *pending // current pending value
+	val  flat.Value
+	err  error
+}
+
+func (it *Iterator) Reset() {
+	it.done = false
+	it.key = nil
+	it.cur, it.pend = nil, nil
+	it.val = nil
+	it.err = nil
+}
+
+func (it *Iterator) WithPrefix(pref flat.Key) flat.Iterator {
+	it.Reset()
+	it.pref = pref
+	return it
+}
+
+// next moves to the first key that is greater than (or equal to, if inclusive is set) a given key.
+func (it *Iterator) next(key []byte, inclusive bool) bool {
+	tx := it.tx
+	it.cur, it.pend, it.val = nil, nil, nil
+	if it.done || tx.done {
+		it.done = true
+		return false
+	}
+	if bytes.Compare(key, it.pref) < 0 {
+		key, inclusive = it.pref, true
+	}
+	for {
+		i := search(tx.idx, key)
+		if !inclusive && i < len(tx.idx) && bytes.Equal(tx.idx[i].key, key) {
+			i++
+		}
+		j := tx.searchPending(key)
+		if !inclusive && j < len(tx.pend) && bytes.Equal(tx.pend[j].key, key) {
+			j++
+		}
+		var e *entry
+		if i < len(tx.idx) {
+			e = &tx.idx[i]
+		}
+		if j < len(tx.pend) {
+			p := &tx.pend[j]
+			if e == nil || bytes.Compare(p.key, e.key) <= 0 {
+				if p.del {
+					// deleted in this transaction - skip it
+					key, inclusive = p.key, false
+					continue
+				}
+				e, it.pend = nil, p
+			}
+		}
+		var k []byte
+		if it.pend != nil {
+			k = it.pend.key
+		} else if e != nil {
+			k = e.key
+		}
+		if k == nil || !bytes.HasPrefix(k, it.pref) {
+			it.done = true
+			it.key = nil
+			return false
+		}
+		it.key, it.cur = k, e
+		return true
+	}
+}
+
+// stop stops the iterator if the context is canceled.
+func (it *Iterator) stop(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		it.done = true
+		it.key, it.cur, it.pend = nil, nil, nil
+		it.err = err
+		return true
+	}
+	return false
+}
+
+func (it *Iterator) Next(ctx context.Context) bool {
+	if it.stop(ctx) {
+		return false
+	} else if it.key == nil {
+		return it.next(it.pref, true)
+	}
+	return it.next(it.key, false)
+}
+
+func (it *Iterator) Seek(ctx context.Context, key flat.Key) bool {
+	it.Reset()
+	if it.stop(ctx) {
+		return false
+	}
+	return it.next(key, true)
+}
+
+func (it *Iterator) Key() flat.Key {
+	return it.key
+}
+
+func (it *Iterator) Val() flat.Value {
+	if it.pend != nil {
+		return it.pend.val
+	} else if it.cur == nil {
+		return nil
+	}
+	if it.val == nil {
+		v, err := it.tx.read(it.cur.loc)
+		if err != nil {
+			it.err = err
+			return nil
+		}
+		it.val = v
+	}
+	return it.val
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Close() error {
+	it.done = true
+	it.cur, it.pend = nil, nil
+	return it.err
+}
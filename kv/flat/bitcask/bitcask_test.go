@@ -0,0 +1,157 @@
+package bitcask
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/kv/flat"
+	"github.com/hidal-go/hidalgo/kv/kvtest"
+)
+
+func TestBitcask(t *testing.T) {
+	kvtest.RunTestLocal(t, flat.UpgradeOpenPath(OpenPath), nil)
+}
+
+func BenchmarkBitcask(b *testing.B) {
+	kvtest.RunBenchmarksLocal(b, flat.UpgradeOpenPath(OpenPath), nil)
+}
+
+func put(t testing.TB, db flat.KV, kvs ...string) {
+	err := db.Update(context.Background(), func(tx flat.Tx) error {
+		for i := 0; i < len(kvs); i += 2 {
+			if err := tx.Put(context.Background(), flat.Key(kvs[i]), flat.Value(kvs[i+1])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func scan(t testing.TB, db flat.KV) []string {
+	var out []string
+	err := db.View(context.Background(), func(tx flat.Tx) error {
+		return flat.Each(context.Background(), tx, func(k flat.Key, v flat.Value) error {
+			out = append(out, string(k), string(v))
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	return out
+}
+
+func segments(t testing.TB, dir string) []string {
+	names, err := filepath.Glob(filepath.Join(dir, "*"+segExt))
+	require.NoError(t, err)
+	return names
+}
+
+func TestRecovery(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, nil)
+	require.NoError(t, err)
+	put(t, db, "a", "1", "b", "2")
+	put(t, db, "c", "3")
+	require.NoError(t, db.Close())
+
+	// simulate a torn write at the end of the log
+	names := segments(t, dir)
+	require.Len(t, names, 1)
+	st, err := os.Stat(names[0])
+	require.NoError(t, err)
+	f, err := os.OpenFile(names[0], os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0x01, 0x02, 0x03, 0x04, 0xff, 0x00, 0x00, 0x00, 0x00})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	db, err = Open(dir, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "1", "b", "2", "c", "3"}, scan(t, db))
+	put(t, db, "d", "4")
+	require.NoError(t, db.Close())
+
+	st2, err := os.Stat(names[0])
+	require.NoError(t, err)
+	require.True(t, st2.Size() > st.Size())
+
+	db, err = Open(dir, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Equal(t, []string{"a", "1", "b", "2", "c", "3", "d", "4"}, scan(t, db))
+}
+
+func TestCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, &Options{MaxSegmentSize: 1})
+	require.NoError(t, err)
+	put(t, db, "a", "1")
+	put(t, db, "b", "2")
+	require.NoError(t, db.Close())
+
+	names := segments(t, dir)
+	require.Len(t, names, 2)
+	f, err := os.OpenFile(names[0], os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("x"), headerSize+1)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = Open(dir, nil)
+	require.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := Open(dir, &Options{MaxSegmentSize: 64, CompactRatio: -1})
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		put(t, db, "a", "old", "b", "old")
+	}
+	put(t, db, "a", "1", "c", "3")
+	err = db.Update(ctx, func(tx flat.Tx) error {
+		return tx.Del(ctx, flat.Key("b"))
+	})
+	require.NoError(t, err)
+	require.True(t, len(segments(t, dir)) > 1)
+
+	// snapshot must remain readable until compaction
+	tx, err := db.Tx(ctx, false)
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- db.Compact(ctx)
+	}()
+	v, err := tx.Get(ctx, flat.Key("a"))
+	require.NoError(t, err)
+	require.Equal(t, flat.Value("1"), v)
+	require.NoError(t, tx.Close())
+	require.NoError(t, <-done)
+
+	require.Len(t, segments(t, dir), 1)
+	require.Equal(t, []string{"a", "1", "c", "3"}, scan(t, db))
+	put(t, db, "d", "4")
+	require.NoError(t, db.Close())
+
+	db, err = Open(dir, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Equal(t, []string{"a", "1", "c", "3", "d", "4"}, scan(t, db))
+}
+
+func TestAutoCompact(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, &Options{MinCompactSize: 256, NoSync: true})
+	require.NoError(t, err)
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		put(t, db, "a", "value", "b", "value")
+	}
+	require.True(t, db.total < 512, "log was not compacted: %d", db.total)
+	require.Equal(t, []string{"a", "value", "b", "value"}, scan(t, db))
+}
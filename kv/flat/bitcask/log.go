@@ -0,0 +1,283 @@
+package bitcask
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Log record layout:
+//
+//	crc32c(payload) uint32 | len(payload) uint32 | payload
+//
+// Payload starts with a flags byte, followed by a sequence of operations:
+//
+//	opPut | uvarint(len(key)) | key | uvarint(len(val)) | val
+//	opDel | uvarint(len(key)) | key
+//
+// Each committed transaction is written as a single record, thus it's either applied completely or not at all.
+
+const (
+	segExt = ".log"
+	tmpExt = ".tmp"
+
+	headerSize = 8
+)
+
+const (
+	// flagBase marks the first record of a compacted segment. All previous segments are obsolete.
+	flagBase = 1 << iota
+)
+
+const (
+	opPut = 1
+	opDel = 2
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrCorrupted is returned when one of the log segments (except the tail of the last one) cannot be decoded.
+	ErrCorrupted = errors.New("bitcask: log is corrupted")
+)
+
+// segment is a single log file.
+type segment struct {
+	id   uint64
+	path string
+	f    *os.File
+	size int64
+}
+
+func segmentName(id uint64) string {
+	return fmt.Sprintf("%016x", id) + segExt
+}
+
+func createSegment(dir string, id uint64) (*segment, error) {
+	path := filepath.Join(dir, segmentName(id))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = syncDir(dir); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &segment{id: id, path: path, f: f}, nil
+}
+
+// listSegments opens all log segments in the directory, sorted by id. It also removes temporary files.
+func listSegments(dir string) ([]*segment, error) {
+	names, err := readDirNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []*segment
+	for _, name := range names {
+		if strings.HasSuffix(name, tmpExt) {
+			// unfinished compaction
+			if err = os.Remove(filepath.Join(dir, name)); err != nil {
+				closeSegments(segs)
+				return nil, err
+			}
+			continue
+		}
+		if !strings.HasSuffix(name, segExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segExt), 16, 64)
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_RDWR, 0644)
+		if err != nil {
+			closeSegments(segs)
+			return nil, err
+		}
+		segs = append(segs, &segment{id: id, path: path, f: f})
+	}
+	sort.Slice(segs, func(i, j int) bool {
+		return segs[i].id < segs[j].id
+	})
+	return segs, nil
+}
+
+func readDirNames(dir string) ([]string, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	return d.Readdirnames(-1)
+}
+
+func closeSegments(segs []*segment) {
+	for _, s := range segs {
+		s.f.Close()
+	}
+}
+
+// syncDir makes sure that file creation and renames are persisted. It's a best effort on platforms
+// that doesn't allow to sync directories.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err = d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) && !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+	return nil
+}
+
+// op is a single decoded operation from the log.
+type op struct {
+	del  bool
+	key  []byte
+	voff int64 // offset of the value in the segment
+	vlen uint32
+}
+
+// record is a buffer for encoding a single log record.
+type record struct {
+	buf []byte
+}
+
+func newRecord(flags byte) *record {
+	r := &record{buf: make([]byte, headerSize, 256)}
+	r.buf = append(r.buf, flags)
+	return r
+}
+
+// Put adds a put operation and returns an offset of the value relative to the record start.
+func (r *record) Put(k, v []byte) int64 {
+	r.buf = append(r.buf, opPut)
+	r.buf = appendUvarint(r.buf, uint64(len(k)))
+	r.buf = append(r.buf, k...)
+	r.buf = appendUvarint(r.buf, uint64(len(v)))
+	off := int64(len(r.buf))
+	r.buf = append(r.buf, v...)
+	return off
+}
+
+func (r *record) Del(k []byte) {
+	r.buf = append(r.buf, opDel)
+	r.buf = appendUvarint(r.buf, uint64(len(k)))
+	r.buf = append(r.buf, k...)
+}
+
+func appendUvarint(dst []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(dst, buf[:n]...)
+}
+
+// Size returns the size of the encoded record.
+func (r *record) Size() int64 {
+	return int64(len(r.buf))
+}
+
+// Bytes finalizes the record and returns its binary representation.
+func (r *record) Bytes() []byte {
+	payload := r.buf[headerSize:]
+	binary.LittleEndian.PutUint32(r.buf[0:], crc32.Checksum(payload, crcTable))
+	binary.LittleEndian.PutUint32(r.buf[4:], uint32(len(payload)))
+	return r.buf
+}
+
+// replay reads all records from the segment and calls fn for each of them.
+// It returns the size of the valid part of the segment. If the record at this offset is incomplete
+// or has invalid checksum, errTorn is returned alongside with the offset.
+func (s *segment) replay(fn func(flags byte, ops []op) error) (int64, error) {
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	st, err := s.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	fsize := st.Size()
+	r := bufio.NewReader(s.f)
+	var (
+		off     int64
+		hdr     [headerSize]byte
+		payload []byte
+		ops     []op
+	)
+	for off < fsize {
+		if _, err = io.ReadFull(r, hdr[:]); err != nil {
+			return off, errTorn
+		}
+		crc := binary.LittleEndian.Uint32(hdr[0:])
+		n := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		if n == 0 || off+headerSize+n > fsize {
+			return off, errTorn
+		}
+		if int64(cap(payload)) < n {
+			payload = make([]byte, n)
+		}
+		payload = payload[:n]
+		if _, err = io.ReadFull(r, payload); err != nil {
+			return off, errTorn
+		}
+		if crc32.Checksum(payload, crcTable) != crc {
+			return off, errTorn
+		}
+		ops, err = decodeOps(ops[:0], payload[1:], off+headerSize+1)
+		if err != nil {
+			return off, err
+		}
+		if err = fn(payload[0], ops); err != nil {
+			return off, err
+		}
+		off += headerSize + n
+	}
+	return off, nil
+}
+
+var errTorn = errors.New("bitcask: torn write")
+
+func decodeOps(dst []op, p []byte, base int64) ([]op, error) {
+	var pos int64
+	readLen := func() (int64, error) {
+		v, n := binary.Uvarint(p[pos:])
+		if n <= 0 || v > uint64(int64(len(p))-pos-int64(n)) {
+			return 0, ErrCorrupted
+		}
+		pos += int64(n)
+		return int64(v), nil
+	}
+	for pos < int64(len(p)) {
+		code := p[pos]
+		pos++
+		if code != opPut && code != opDel {
+			return nil, fmt.Errorf("%w: unknown operation: %d", ErrCorrupted, code)
+		}
+		kn, err := readLen()
+		if err != nil {
+			return nil, err
+		}
+		o := op{del: code == opDel, key: p[pos : pos+kn]}
+		pos += kn
+		if !o.del {
+			vn, err := readLen()
+			if err != nil {
+				return nil, err
+			}
+			o.voff, o.vlen = base+pos, uint32(vn)
+			pos += vn
+		}
+		dst = append(dst, o)
+	}
+	return dst, nil
+}
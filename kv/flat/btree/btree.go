@@ -24,14 +24,20 @@ import (
 )
 
 const (
+	// Name is a driver name for a volatile in-memory B-Tree.
 	Name = "btree"
+	// NameFile is a driver name for a B-Tree persisted to a directory. See OpenPath.
+	NameFile = "btree-file"
 )
 
+// caps are the capabilities of the driver.
+const caps = base.CapSeek | base.CapPrefixScan | base.CapBatchGet
+
 func init() {
 	flat.Register(flat.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "B-Tree",
-			Local: true, Volatile: true,
+			Local: true, Volatile: true, Capabilities: caps,
 		},
 		OpenPath: func(path string) (flat.KV, error) {
 			if path != "" {
@@ -40,6 +46,13 @@ func init() {
 			return New(), nil
 		},
 	})
+	flat.Register(flat.Registration{
+		Registration: base.Registration{
+			Name: NameFile, Title: "B-Tree (file)",
+			Local: true, Capabilities: caps,
+		},
+		OpenPath: OpenPath,
+	})
 }
 
 var _ flat.KV = (*DB)(nil)
@@ -52,14 +65,28 @@ func New() *DB {
 
 type DB struct {
 	t *Tree
+	p *persist // nil for in-memory tree
 }
 
 func (db *DB) Close() error {
-	return nil
+	if db.p == nil {
+		return nil
+	}
+	err := db.p.close(db.t)
+	db.p = nil
+	return err
+}
+
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
 }
 
 func (db *DB) Tx(ctx context.Context, rw bool) (flat.Tx, error) {
-	return &Tx{t: db.t, rw: rw}, nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &Tx{t: db.t, p: db.p, rw: rw}, nil
 }
 
 func (db *DB) View(ctx context.Context, fn func(tx flat.Tx) error) error {
@@ -72,10 +99,14 @@ func (db *DB) Update(ctx context.Context, fn func(tx flat.Tx) error) error {
 
 type Tx struct {
 	t  *Tree
+	p  *persist
 	rw bool
 }
 
 func (tx *Tx) Get(ctx context.Context, key flat.Key) (flat.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	v, ok := tx.t.Get(key)
 	if !ok {
 		return nil, flat.ErrNotFound
@@ -84,6 +115,9 @@ func (tx *Tx) Get(ctx context.Context, key flat.Key) (flat.Value, error) {
 }
 
 func (tx *Tx) GetBatch(ctx context.Context, keys []flat.Key) ([]flat.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	vals := make([]flat.Value, len(keys))
 	for i, k := range keys {
 		if v, ok := tx.t.Get(k); ok {
@@ -94,7 +128,13 @@ func (tx *Tx) GetBatch(ctx context.Context, keys []flat.Key) ([]flat.Value, erro
 }
 
 func (tx *Tx) Commit(ctx context.Context) error {
-	return nil
+	if err := ctx.Err(); err != nil {
+		// changes are applied immediately and cannot be rolled back
+		return err
+	} else if tx.p == nil || !tx.rw {
+		return nil
+	}
+	return tx.p.commit(tx.t)
 }
 
 func (tx *Tx) Close() error {
@@ -104,6 +144,13 @@ func (tx *Tx) Close() error {
 func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
 	if !tx.rw {
 		return flat.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tx.p != nil {
+		if err := tx.p.log(walPut, k, v); err != nil {
+			return err
+		}
 	}
 	tx.t.Set(k.Clone(), v.Clone())
 	return nil
@@ -112,6 +159,13 @@ func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
 func (tx *Tx) Del(ctx context.Context, k flat.Key) error {
 	if !tx.rw {
 		return flat.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tx.p != nil {
+		if err := tx.p.log(walDel, k, nil); err != nil {
+			return err
+		}
 	}
 	tx.t.Delete(k)
 	return nil
@@ -133,9 +187,11 @@ type Iterator struct {
 	pref []byte
 	e    *Enumerator
 	k, v []byte
+	err  error
 }
 
 func (it *Iterator) Reset() {
+	it.err = nil
 	if it.e != nil {
 		it.e.Close()
 		it.e = nil
@@ -161,17 +217,30 @@ func (it *Iterator) next() bool {
 	return true
 }
 
+// stop stops the iterator if the context is canceled.
+func (it *Iterator) stop(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		it.Reset()
+		it.err = err
+		return true
+	}
+	return false
+}
+
 func (it *Iterator) Seek(ctx context.Context, key flat.Key) bool {
 	if it.t == nil {
 		return false
 	}
 	it.Reset()
+	if it.stop(ctx) {
+		return false
+	}
 	it.e, _ = it.t.Seek(key)
 	return it.next()
 }
 
 func (it *Iterator) Next(ctx context.Context) bool {
-	if it.t == nil {
+	if it.t == nil || it.err != nil || it.stop(ctx) {
 		return false
 	}
 	if it.e == nil {
@@ -182,7 +251,7 @@ func (it *Iterator) Next(ctx context.Context) bool {
 
 func (it *Iterator) Key() flat.Key   { return it.k }
 func (it *Iterator) Val() flat.Value { return it.v }
-func (it *Iterator) Err() error      { return nil }
+func (it *Iterator) Err() error      { return it.err }
 
 func (it *Iterator) Close() error {
 	if it.e != nil {
@@ -1,8 +1,14 @@
 package btree
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/kv"
 	"github.com/hidal-go/hidalgo/kv/flat"
 	"github.com/hidal-go/hidalgo/kv/kvtest"
@@ -11,8 +17,132 @@ import (
 func TestBtree(t *testing.T) {
 	kvtest.RunTest(t, func(t testing.TB) kv.KV {
 		return flat.Upgrade(New())
-	}, &kvtest.Options{
-		NoLocks: true,
-		NoTx:    true,
+	}, nil)
+}
+
+func TestBtreePersistent(t *testing.T) {
+	kvtest.RunTestLocal(t, flat.UpgradeOpenPath(OpenPath), nil)
+}
+
+func BenchmarkBtree(b *testing.B) {
+	kvtest.RunBenchmarks(b, func(t testing.TB) kv.KV {
+		return flat.Upgrade(New())
+	}, nil)
+}
+
+func BenchmarkBtreePersistent(b *testing.B) {
+	kvtest.RunBenchmarksLocal(b, flat.UpgradeOpenPath(OpenPath), nil)
+}
+
+func put(t testing.TB, db flat.KV, kvs ...string) {
+	ctx := context.Background()
+	err := db.Update(ctx, func(tx flat.Tx) error {
+		for i := 0; i < len(kvs); i += 2 {
+			var err error
+			if kvs[i+1] == "" {
+				err = tx.Del(ctx, flat.Key(kvs[i]))
+			} else {
+				err = tx.Put(ctx, flat.Key(kvs[i]), flat.Value(kvs[i+1]))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
+	require.NoError(t, err)
+}
+
+func scan(t testing.TB, db flat.KV) []string {
+	var out []string
+	err := db.View(context.Background(), func(tx flat.Tx) error {
+		return flat.Each(context.Background(), tx, func(k flat.Key, v flat.Value) error {
+			out = append(out, string(k), string(v))
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	return out
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, nil)
+	require.NoError(t, err)
+	put(t, db, "b", "2", "a", "1", "c", "3")
+	put(t, db, "c", "")
+	require.NoError(t, db.Close())
+	require.NoError(t, db.Close())
+
+	_, err = os.Stat(filepath.Join(dir, walFile))
+	require.True(t, os.IsNotExist(err))
+
+	db, err = Open(dir, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Equal(t, []string{"a", "1", "b", "2"}, scan(t, db))
+}
+
+func TestWAL(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, &Options{WAL: true})
+	require.NoError(t, err)
+	put(t, db, "a", "1", "b", "2")
+	require.NoError(t, db.p.writeSnapshot(db.t))
+	put(t, db, "c", "3", "a", "")
+
+	// simulate a crash with a torn write at the end of the log
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{1, 2, 3, 4, 5, 0, 0, 0, walPut})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	db2, err := Open(dir, &Options{WAL: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "2", "c", "3"}, scan(t, db2))
+	put(t, db2, "d", "4")
+	require.NoError(t, db2.Close())
+
+	db2, err = Open(dir, nil)
+	require.NoError(t, err)
+	defer db2.Close()
+	require.Equal(t, []string{"b", "2", "c", "3", "d", "4"}, scan(t, db2))
+}
+
+func TestCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, nil)
+	require.NoError(t, err)
+	put(t, db, "a", "1")
+	require.NoError(t, db.Close())
+
+	path := filepath.Join(dir, snapshotFile)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(snapshotMagic)+1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	_, err = Open(dir, nil)
+	require.Equal(t, ErrCorrupted, err)
+}
+
+func TestRegistration(t *testing.T) {
+	r := flat.ByName(Name)
+	require.NotNil(t, r)
+	require.True(t, r.Volatile)
+	_, err := r.OpenPath(t.TempDir())
+	require.Equal(t, base.ErrVolatile, err)
+	db, err := r.OpenPath("")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	r = flat.ByName(NameFile)
+	require.NotNil(t, r)
+	require.False(t, r.Volatile)
+	_, err = r.OpenPath("")
+	require.Equal(t, ErrNoPath, err)
+	db, err = r.OpenPath(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
 }
@@ -0,0 +1,368 @@
+package btree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hidal-go/hidalgo/kv/flat"
+)
+
+const (
+	snapshotFile = "snapshot.db"
+	walFile      = "wal.log"
+	tmpSuffix    = ".tmp"
+
+	snapshotMagic = "HDGBTRE1"
+)
+
+const (
+	walPut = 1
+	walDel = 2
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrNoPath is returned when opening a persistent B-Tree without a path.
+var ErrNoPath = errors.New("btree: path is required")
+
+// ErrCorrupted is returned when the snapshot file cannot be decoded.
+var ErrCorrupted = errors.New("btree: snapshot is corrupted")
+
+// Options for a persistent B-Tree.
+type Options struct {
+	// SnapshotInterval is a minimal interval between snapshots written on commit.
+	// If not set, the snapshot is only written on Close.
+	SnapshotInterval time.Duration
+	// WAL enables a write-ahead log. Without it, changes made after the last snapshot are lost on crash.
+	WAL bool
+	// NoSync disables fsync of the write-ahead log on commit.
+	NoSync bool
+}
+
+// DefaultOptions are used by OpenPath.
+var DefaultOptions = Options{
+	SnapshotInterval: time.Minute,
+	WAL:              true,
+}
+
+// persist tracks the on-disk state of the tree.
+type persist struct {
+	dir   string
+	opts  Options
+	wal   *os.File
+	w     *bufio.Writer
+	dirty bool // tree was modified since the last snapshot
+	last  time.Time
+	buf   []byte
+}
+
+// Open opens or creates a persistent B-Tree in a given directory. Options can be nil.
+//
+// The tree is loaded into memory from the last snapshot and write-ahead log (if any),
+// and is written back to disk on Close and periodically on commit.
+// It's not safe for concurrent use.
+func Open(dir string, opts *Options) (*DB, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	db := New()
+	p := &persist{dir: dir, opts: *opts, last: time.Now()}
+	if err := p.loadSnapshot(db.t); err != nil {
+		return nil, err
+	}
+	if err := p.openWAL(db.t); err != nil {
+		return nil, err
+	}
+	db.p = p
+	return db, nil
+}
+
+// OpenPath opens a persistent B-Tree in a given directory with default options.
+// Use New for a volatile in-memory tree.
+func OpenPath(path string) (flat.KV, error) {
+	if path == "" {
+		return nil, ErrNoPath
+	}
+	db, err := Open(path, &DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (p *persist) loadSnapshot(t *Tree) error {
+	data, err := os.ReadFile(filepath.Join(p.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(data) < len(snapshotMagic)+4 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrCorrupted
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.Checksum(body, crcTable) != sum {
+		return ErrCorrupted
+	}
+	body = body[len(snapshotMagic):]
+	readBytes := func() ([]byte, bool) {
+		n, sz := binary.Uvarint(body)
+		if sz <= 0 || n > uint64(len(body)-sz) {
+			return nil, false
+		}
+		v := append([]byte{}, body[sz:sz+int(n)]...)
+		body = body[sz+int(n):]
+		return v, true
+	}
+	for len(body) > 0 {
+		k, ok := readBytes()
+		if !ok {
+			return ErrCorrupted
+		}
+		v, ok := readBytes()
+		if !ok {
+			return ErrCorrupted
+		}
+		t.Set(k, v)
+	}
+	return nil
+}
+
+// openWAL replays the write-ahead log and opens it for writing.
+// If WAL is disabled, the log is replayed and removed.
+func (p *persist) openWAL(t *Tree) error {
+	path := filepath.Join(p.dir, walFile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	size, err := replayWAL(f, t)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if size != 0 {
+		p.dirty = true
+	}
+	if !p.opts.WAL {
+		f.Close()
+		if size == 0 {
+			return os.Remove(path)
+		}
+		// make sure the changes from the log will not be lost
+		if err = p.writeSnapshot(t); err != nil {
+			return err
+		}
+		return os.Remove(path)
+	}
+	// discard the unfinished record, if any
+	if err = f.Truncate(size); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err = f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	p.wal = f
+	p.w = bufio.NewWriter(f)
+	return nil
+}
+
+// replayWAL applies all valid records from the log to the tree and returns the size of the valid part of the log.
+func replayWAL(f *os.File, t *Tree) (int64, error) {
+	r := bufio.NewReader(f)
+	var (
+		off int64
+		hdr [8]byte
+	)
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return off, nil
+		} else if err != nil {
+			return 0, err
+		}
+		sum, n := binary.LittleEndian.Uint32(hdr[0:]), binary.LittleEndian.Uint32(hdr[4:])
+		rec := make([]byte, n)
+		if _, err := io.ReadFull(r, rec); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return off, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if crc32.Checksum(rec, crcTable) != sum || len(rec) == 0 {
+			// torn write; everything after it is garbage
+			return off, nil
+		}
+		k, sz := binary.Uvarint(rec[1:])
+		if sz <= 0 || k > uint64(len(rec)-1-sz) {
+			return off, nil
+		}
+		key := rec[1+sz : 1+sz+int(k)]
+		switch rec[0] {
+		case walPut:
+			t.Set(key, rec[1+sz+int(k):])
+		case walDel:
+			t.Delete(key)
+		default:
+			return off, nil
+		}
+		off += int64(len(hdr)) + int64(n)
+	}
+}
+
+// log appends an operation to the write-ahead log. Log is flushed on commit.
+func (p *persist) log(op byte, k, v []byte) error {
+	p.dirty = true
+	if p.w == nil {
+		return nil
+	}
+	var tmp [binary.MaxVarintLen64]byte
+	buf := append(p.buf[:0], 0, 0, 0, 0, 0, 0, 0, 0, op)
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(k)))]...)
+	buf = append(buf, k...)
+	buf = append(buf, v...)
+	rec := buf[8:]
+	binary.LittleEndian.PutUint32(buf[0:], crc32.Checksum(rec, crcTable))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(rec)))
+	p.buf = buf
+	_, err := p.w.Write(buf)
+	return err
+}
+
+// commit flushes the write-ahead log and writes a snapshot if the interval has passed.
+func (p *persist) commit(t *Tree) error {
+	if p.w != nil {
+		if err := p.w.Flush(); err != nil {
+			return err
+		}
+		if !p.opts.NoSync {
+			if err := p.wal.Sync(); err != nil {
+				return err
+			}
+		}
+	}
+	if p.dirty && p.opts.SnapshotInterval > 0 && time.Since(p.last) >= p.opts.SnapshotInterval {
+		return p.writeSnapshot(t)
+	}
+	return nil
+}
+
+// writeSnapshot atomically replaces the snapshot file and truncates the write-ahead log.
+func (p *persist) writeSnapshot(t *Tree) error {
+	path := filepath.Join(p.dir, snapshotFile)
+	tmp := path + tmpSuffix
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	h := crc32.New(crcTable)
+	w := bufio.NewWriter(io.MultiWriter(f, h))
+	var tmpb [binary.MaxVarintLen64]byte
+	writeBytes := func(p []byte) error {
+		if _, err := w.Write(tmpb[:binary.PutUvarint(tmpb[:], uint64(len(p)))]); err != nil {
+			return err
+		}
+		_, err := w.Write(p)
+		return err
+	}
+	if _, err = w.WriteString(snapshotMagic); err != nil {
+		return fail(err)
+	}
+	if e, err := t.SeekFirst(); err == nil {
+		for {
+			k, v, err := e.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				e.Close()
+				return fail(err)
+			}
+			if err = writeBytes(k); err == nil {
+				err = writeBytes(v)
+			}
+			if err != nil {
+				e.Close()
+				return fail(err)
+			}
+		}
+		e.Close()
+	}
+	if err = w.Flush(); err != nil {
+		return fail(err)
+	}
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], h.Sum32())
+	if _, err = f.Write(sum[:]); err != nil {
+		return fail(err)
+	}
+	if err = f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err = syncDir(p.dir); err != nil {
+		return err
+	}
+	p.dirty = false
+	p.last = time.Now()
+	if p.wal == nil {
+		return nil
+	}
+	// all changes are in the snapshot now; replaying the log again is harmless, so truncating it is safe
+	if err = p.w.Flush(); err != nil {
+		return err
+	}
+	if err = p.wal.Truncate(0); err != nil {
+		return err
+	}
+	_, err = p.wal.Seek(0, io.SeekStart)
+	return err
+}
+
+// close writes the final snapshot and removes the write-ahead log.
+func (p *persist) close(t *Tree) error {
+	var err error
+	if p.dirty {
+		err = p.writeSnapshot(t)
+	}
+	if p.wal != nil {
+		if err2 := p.wal.Close(); err == nil {
+			err = err2
+		}
+		p.wal, p.w = nil, nil
+	}
+	return err
+}
+
+// syncDir persists file renames. It's a best effort on platforms that doesn't allow to sync directories.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err = d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) && !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package flat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hidal-go/hidalgo/kv"
+)
+
+// KeyFormat is a version of encoding used to store hierarchical keys in flat KV.
+type KeyFormat int
+
+const (
+	// KeyFormatV1 joins key parts with '/' separator and escapes it with '\'.
+	// It doesn't preserve the order of hierarchical keys.
+	KeyFormatV1 = KeyFormat(1)
+	// KeyFormatV2 terminates each key part with 0x00 0x01 and escapes 0x00 bytes as 0x00 0xff.
+	// Flat keys sort exactly the same way as hierarchical keys (see kv.Key.Compare).
+	KeyFormatV2 = KeyFormat(2)
+
+	// KeyFormatLatest is the format used for new databases.
+	KeyFormatLatest = KeyFormatV2
+)
+
+const (
+	sepV1 = '/'
+	escV1 = '\\'
+
+	zero    = 0x00
+	termV2  = 0x01 // follows a zero byte at the end of the key part
+	escV2   = 0xff // follows a zero byte that is a part of the key
+	metaTag = 0x02 // follows a zero byte in a reserved key; never produced by V2 encoding
+)
+
+// metaKey stores the key format version. It cannot be produced by V2 encoding, and is hidden from iteration.
+var metaKey = append(Key{zero, metaTag}, "hidalgo:keys"...)
+
+// KeyFormatter is implemented by iterators that convert hierarchical keys using a specific key format.
+type KeyFormatter interface {
+	// KeyFormat returns the key format, or zero if it's unknown.
+	KeyFormat() KeyFormat
+}
+
+// ErrUnknownKeyFormat is returned when a database uses key format that is not supported.
+var ErrUnknownKeyFormat = errors.New("flat: unknown key format")
+
+// isMetaKey checks if the key is reserved for the metadata.
+// Only the exact key is checked, since V1 keys may start with the same bytes.
+func isMetaKey(k Key) bool {
+	return bytes.Equal(k, metaKey)
+}
+
+// Escape converts kv.Key to a flat Key using the key format.
+func (f KeyFormat) Escape(k kv.Key) Key {
+	if f == KeyFormatV1 {
+		return KeyEscape(k)
+	}
+	return KeyEscapeV2(k)
+}
+
+// Unescape converts flat Key into kv.Key using the key format.
+func (f KeyFormat) Unescape(k Key) kv.Key {
+	if f == KeyFormatV1 {
+		return KeyUnescape(k)
+	}
+	return KeyUnescapeV2(k)
+}
+
+// KeyEscapeV2 converts kv.Key to a flat Key using the order-preserving format. See KeyFormatV2.
+func KeyEscapeV2(k kv.Key) Key {
+	var k2 Key
+	for i, s := range k {
+		if i != 0 {
+			k2 = append(k2, zero, termV2)
+		}
+		for _, p := range s {
+			k2 = append(k2, p)
+			if p == zero {
+				k2 = append(k2, escV2)
+			}
+		}
+	}
+	return k2
+}
+
+// KeyUnescapeV2 converts flat Key into kv.Key using the order-preserving format. See KeyFormatV2.
+//
+// Malformed escape sequences (as in binary prefixes of escaped keys) are decoded as is.
+func KeyUnescapeV2(k Key) kv.Key {
+	var (
+		k2  kv.Key
+		cur Key
+	)
+	for i := 0; i < len(k); i++ {
+		p := k[i]
+		if p == zero && i+1 < len(k) {
+			switch k[i+1] {
+			case escV2:
+				cur = append(cur, zero)
+				i++
+				continue
+			case termV2:
+				if cur == nil {
+					cur = Key{}
+				}
+				k2 = append(k2, cur)
+				cur = nil
+				i++
+				continue
+			}
+		}
+		cur = append(cur, p)
+	}
+	if cur != nil {
+		k2 = append(k2, cur)
+	} else if len(k) != 0 {
+		k2 = append(k2, Key{})
+	}
+	return k2
+}
+
+// KeyEscape converts kv.Key to a flat Key using the legacy format. See KeyFormatV1 and KeyEscapeV2.
+func KeyEscape(k kv.Key) Key {
+	var k2 Key
+	for i, s := range k {
+		if i != 0 {
+			k2 = append(k2, sepV1)
+		}
+		for _, p := range s {
+			if p == escV1 || p == sepV1 {
+				k2 = append(k2, escV1)
+			}
+			k2 = append(k2, p)
+		}
+	}
+	return k2
+}
+
+// KeyUnescape converts flat Key into kv.Key using the legacy format. See KeyFormatV1 and KeyUnescapeV2.
+func KeyUnescape(k Key) kv.Key {
+	var (
+		k2  kv.Key
+		cur Key
+	)
+	for i := 0; i < len(k); i++ {
+		p := k[i]
+		if p == escV1 && i+1 < len(k) {
+			cur = append(cur, k[i+1])
+			i++
+			continue
+		} else if p == sepV1 {
+			k2 = append(k2, cur)
+			cur = nil
+			continue
+		}
+		cur = append(cur, p)
+	}
+	if cur != nil {
+		k2 = append(k2, cur)
+	}
+	return k2
+}
+
+// detectKeyFormat returns the key format used in the database.
+// Second return value is false if the database is empty, and the format is not yet recorded.
+func detectKeyFormat(ctx context.Context, tx Tx) (KeyFormat, bool, error) {
+	v, err := tx.Get(ctx, metaKey)
+	if err == nil {
+		ver, err := strconv.Atoi(string(v))
+		if err != nil || (KeyFormat(ver) != KeyFormatV1 && KeyFormat(ver) != KeyFormatV2) {
+			return 0, false, fmt.Errorf("%w: %q", ErrUnknownKeyFormat, v)
+		}
+		return KeyFormat(ver), true, nil
+	} else if err != ErrNotFound {
+		return 0, false, err
+	}
+	// data written before the format was versioned doesn't have the marker
+	it := tx.Scan(ctx)
+	defer it.Close()
+	for it.Next(ctx) {
+		if !isMetaKey(it.Key()) {
+			return KeyFormatV1, true, nil
+		}
+	}
+	if err = it.Err(); err != nil {
+		return 0, false, err
+	}
+	return KeyFormatLatest, false, nil
+}
+
+func putKeyFormat(ctx context.Context, tx Tx, f KeyFormat) error {
+	return tx.Put(ctx, metaKey, Value(strconv.Itoa(int(f))))
+}
+
+// DetectKeyFormat returns the format of hierarchical keys used in the flat database.
+// Empty databases use KeyFormatLatest.
+func DetectKeyFormat(ctx context.Context, db KV) (KeyFormat, error) {
+	var f KeyFormat
+	err := View(ctx, db, func(tx Tx) error {
+		var err error
+		f, _, err = detectKeyFormat(ctx, tx)
+		return err
+	})
+	return f, err
+}
+
+// MigrateKeys converts all hierarchical keys in the flat database to the latest key format.
+//
+// Migration is done in a single transaction and requires all key-value pairs to fit into memory.
+// Database must not be used by Upgrade during the migration.
+func MigrateKeys(ctx context.Context, db KV) error {
+	return Update(ctx, db, func(tx Tx) error {
+		f, marked, err := detectKeyFormat(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if f == KeyFormatLatest {
+			if marked {
+				return nil
+			}
+			return putKeyFormat(ctx, tx, KeyFormatLatest)
+		}
+		var pairs []Pair
+		err = Each(ctx, tx, func(k Key, v Value) error {
+			if !isMetaKey(k) {
+				pairs = append(pairs, Pair{Key: k.Clone(), Val: v.Clone()})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// remove all keys first, since new keys may collide with old ones
+		for _, p := range pairs {
+			if err = tx.Del(ctx, p.Key); err != nil {
+				return err
+			}
+		}
+		for _, p := range pairs {
+			k := KeyFormatLatest.Escape(f.Unescape(p.Key))
+			if err = tx.Put(ctx, k, p.Val); err != nil {
+				return err
+			}
+		}
+		return putKeyFormat(ctx, tx, KeyFormatLatest)
+	})
+}
+
+var _ PrefixIterator = (*prefixIterator)(nil)
+
+// prefixIterator is a generic implementation of prefix iteration for flat stores that don't support it natively.
+type prefixIterator struct {
+	Iterator
+	pref Key
+	seek bool
+	done bool
+}
+
+func (it *prefixIterator) Reset() {
+	it.Iterator.Reset()
+	it.seek, it.done = false, false
+}
+
+func (it *prefixIterator) WithPrefix(pref Key) Iterator {
+	it.Reset()
+	it.pref = pref
+	return it
+}
+
+func (it *prefixIterator) Next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+	var ok bool
+	if !it.seek {
+		it.seek = true
+		ok = Seek(ctx, it.Iterator, it.pref)
+	} else {
+		ok = it.Iterator.Next(ctx)
+	}
+	if !ok || !bytes.HasPrefix(it.Iterator.Key(), it.pref) {
+		it.done = true
+		return false
+	}
+	return true
+}
+
+func withPrefix(it Iterator, pref Key) Iterator {
+	if it, ok := it.(PrefixIterator); ok {
+		return it.WithPrefix(pref)
+	}
+	return &prefixIterator{Iterator: it, pref: pref}
+}
@@ -0,0 +1,141 @@
+package flat_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/flat"
+	"github.com/hidal-go/hidalgo/kv/flat/btree"
+	"github.com/hidal-go/hidalgo/kv/kvtest"
+	"github.com/hidal-go/hidalgo/kv/options"
+)
+
+func TestKeyFormat(t *testing.T) {
+	ctx := context.Background()
+	keys := []kv.Key{
+		kv.SKey("a", "b"),
+		kv.SKey("a", "c/d"),
+		kv.SKey("a\x00"),
+		kv.SKey("b"),
+	}
+
+	// data written with the legacy key format
+	fdb := btree.New()
+	err := fdb.Update(ctx, func(tx flat.Tx) error {
+		for _, k := range keys {
+			if err := tx.Put(ctx, flat.KeyEscape(k), flat.Value(k[0])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	f, err := flat.DetectKeyFormat(ctx, fdb)
+	require.NoError(t, err)
+	require.Equal(t, flat.KeyFormatV1, f)
+
+	var exp []kv.Pair
+	for _, k := range keys {
+		exp = append(exp, kv.Pair{Key: k, Val: kv.Value(k[0])})
+	}
+	// legacy format is detected, but the order is not preserved
+	td := kvtest.NewTest(t, flat.Upgrade(fdb))
+	for _, p := range exp {
+		td.Expect(p.Key, p.Val)
+	}
+
+	require.NoError(t, flat.MigrateKeys(ctx, fdb))
+	f, err = flat.DetectKeyFormat(ctx, fdb)
+	require.NoError(t, err)
+	require.Equal(t, flat.KeyFormatV2, f)
+	// migration is idempotent
+	require.NoError(t, flat.MigrateKeys(ctx, fdb))
+
+	td = kvtest.NewTest(t, flat.Upgrade(fdb))
+	td.Scan(exp)
+	td.Put(kv.SKey("a", "a"), kv.Value("a"))
+	td.Scan(append([]kv.Pair{{Key: kv.SKey("a", "a"), Val: kv.Value("a")}}, exp...))
+
+	// new databases record the latest format on the first write
+	fdb = btree.New()
+	td = kvtest.NewTest(t, flat.Upgrade(fdb))
+	td.Put(kv.SKey("a"), kv.Value("1"))
+	td.Scan([]kv.Pair{{Key: kv.SKey("a"), Val: kv.Value("1")}})
+	f, err = flat.DetectKeyFormat(ctx, fdb)
+	require.NoError(t, err)
+	require.Equal(t, flat.KeyFormatV2, f)
+}
+
+func TestKeyFormatMetaPrefix(t *testing.T) {
+	ctx := context.Background()
+	// legacy key that starts with the same bytes as the key format marker
+	k := kv.SKey("\x00\x02a", "b")
+
+	fdb := btree.New()
+	err := fdb.Update(ctx, func(tx flat.Tx) error {
+		return tx.Put(ctx, flat.KeyEscape(k), flat.Value("v"))
+	})
+	require.NoError(t, err)
+
+	f, err := flat.DetectKeyFormat(ctx, fdb)
+	require.NoError(t, err)
+	require.Equal(t, flat.KeyFormatV1, f)
+
+	td := kvtest.NewTest(t, flat.Upgrade(fdb))
+	td.Scan([]kv.Pair{{Key: k, Val: kv.Value("v")}})
+	td.Expect(k, kv.Value("v"))
+}
+
+func TestKeyFormatPrefix(t *testing.T) {
+	ctx := context.Background()
+	keys := []kv.Key{
+		kv.SKey("a", "b"),
+		kv.SKey("a", "c"),
+		kv.SKey("b", "a"),
+	}
+	fdb := btree.New()
+	err := fdb.Update(ctx, func(tx flat.Tx) error {
+		for _, k := range keys {
+			if err := tx.Put(ctx, flat.KeyEscape(k), flat.Value(k[1])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// flat prefix applied to the hierarchical iterator is unescaped with the format of the store
+	td := kvtest.NewTest(t, flat.Upgrade(fdb))
+	td.Scan([]kv.Pair{
+		{Key: keys[0], Val: kv.Value("b")},
+		{Key: keys[1], Val: kv.Value("c")},
+	}, options.WithPrefixFlat(flat.KeyEscape(kv.SKey("a", ""))))
+
+	td.Scan([]kv.Pair{
+		{Key: keys[1], Val: kv.Value("c")},
+	}, options.WithPrefixKV(kv.SKey("a", "c")))
+
+	// hierarchical prefix applied to the flat iterator is escaped with the given format
+	scan := func(opt options.PrefixKV) []flat.Key {
+		var got []flat.Key
+		err := fdb.View(ctx, func(tx flat.Tx) error {
+			return flat.Each(ctx, tx, func(k flat.Key, _ flat.Value) error {
+				got = append(got, k.Clone())
+				return nil
+			}, opt)
+		})
+		require.NoError(t, err)
+		return got
+	}
+	f, err := flat.DetectKeyFormat(ctx, fdb)
+	require.NoError(t, err)
+	exp := []flat.Key{flat.KeyEscape(keys[1])}
+	require.Equal(t, exp, scan(options.PrefixKV{Pref: kv.SKey("a", "c"), Format: f}))
+	// plain flat iterators don't know the format, the legacy one is used for compatibility
+	require.Equal(t, exp, scan(options.PrefixKV{Pref: kv.SKey("a", "c")}))
+	require.Empty(t, scan(options.PrefixKV{Pref: kv.SKey("a", "c"), Format: flat.KeyFormatV2}))
+}
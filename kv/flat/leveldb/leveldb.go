@@ -30,11 +30,14 @@ const (
 	Name = "leveldb"
 )
 
+// caps are the capabilities of the driver.
+const caps = base.CapTx | base.CapConcurrentWrites | base.CapSeek | base.CapPrefixScan
+
 func init() {
 	flat.Register(flat.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "LevelDB",
-			Local: true,
+			Local: true, Capabilities: caps,
 		},
 		OpenPath: OpenPath,
 	})
@@ -80,7 +83,15 @@ func (db *DB) Close() error {
 	return db.db.Close()
 }
 
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
 func (db *DB) Tx(ctx context.Context, rw bool) (flat.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	tx := &Tx{db: db}
 	var err error
 	if rw {
@@ -113,6 +124,10 @@ func (tx *Tx) Commit(ctx context.Context) error {
 	if tx.err != nil {
 		return tx.err
 	}
+	if err := ctx.Err(); err != nil {
+		_ = tx.Close()
+		return err
+	}
 	if tx.tx != nil {
 		tx.err = tx.tx.Commit()
 		return tx.err
@@ -131,6 +146,9 @@ func (tx *Tx) Close() error {
 }
 
 func (tx *Tx) Get(ctx context.Context, key flat.Key) (flat.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	var (
 		val []byte
 		err error
@@ -155,6 +173,8 @@ func (tx *Tx) GetBatch(ctx context.Context, keys []flat.Key) ([]flat.Value, erro
 func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
 	if tx.tx == nil {
 		return flat.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		return err
 	}
 	return tx.tx.Put(k, v, tx.db.wo)
 }
@@ -162,6 +182,8 @@ func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
 func (tx *Tx) Del(ctx context.Context, k flat.Key) error {
 	if tx.tx == nil {
 		return flat.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		return err
 	}
 	return tx.tx.Delete(k, tx.db.wo)
 }
@@ -183,10 +205,12 @@ type Iterator struct {
 	tx    *Tx
 	it    iterator.Iterator
 	first bool
+	err   error
 }
 
 func (it *Iterator) Reset() {
 	it.first = true
+	it.err = nil
 }
 
 func (it *Iterator) WithPrefix(pref flat.Key) flat.Iterator {
@@ -203,13 +227,28 @@ func (it *Iterator) WithPrefix(pref flat.Key) flat.Iterator {
 	return it
 }
 
+// stop stops the iterator if the context is canceled.
+func (it *Iterator) stop(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return true
+	}
+	return false
+}
+
 func (it *Iterator) Seek(ctx context.Context, key flat.Key) bool {
 	it.Reset()
+	if it.stop(ctx) {
+		return false
+	}
 	it.first = false
 	return it.it.Seek(key)
 }
 
 func (it *Iterator) Next(ctx context.Context) bool {
+	if it.err != nil || it.stop(ctx) {
+		return false
+	}
 	if it.first {
 		it.first = false
 		return it.it.First()
@@ -219,7 +258,13 @@ func (it *Iterator) Next(ctx context.Context) bool {
 
 func (it *Iterator) Key() flat.Key   { return it.it.Key() }
 func (it *Iterator) Val() flat.Value { return it.it.Value() }
-func (it *Iterator) Err() error      { return it.it.Error() }
+
+func (it *Iterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Error()
+}
 
 func (it *Iterator) Close() error {
 	it.it.Release()
@@ -10,3 +10,7 @@ import (
 func TestLeveldb(t *testing.T) {
 	kvtest.RunTestLocal(t, flat.UpgradeOpenPath(OpenPath), nil)
 }
+
+func BenchmarkLeveldb(b *testing.B) {
+	kvtest.RunBenchmarksLocal(b, flat.UpgradeOpenPath(OpenPath), nil)
+}
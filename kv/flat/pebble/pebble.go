@@ -18,11 +18,14 @@ const (
 	Name = "pebble"
 )
 
+// caps are the capabilities of the driver.
+const caps = base.CapConcurrentWrites | base.CapSeek | base.CapPrefixScan
+
 func init() {
 	flat.Register(flat.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "Pebble",
-			Local: true,
+			Local: true, Capabilities: caps,
 		},
 		OpenPath: OpenPath,
 	})
@@ -64,7 +67,15 @@ func (db *DB) Close() error {
 	return db.db.Close()
 }
 
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
 func (db *DB) Tx(ctx context.Context, rw bool) (flat.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return &Tx{tx: db.db.NewIndexedBatch(), rw: rw}, nil
 }
 
@@ -84,6 +95,9 @@ type Tx struct {
 func (tx *Tx) Commit(ctx context.Context) error {
 	if !tx.rw {
 		return flat.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		// the batch is discarded on Close
+		return err
 	}
 	return tx.tx.Commit(pebble.Sync)
 }
@@ -93,7 +107,9 @@ func (tx *Tx) Close() error {
 }
 
 func (tx *Tx) Get(ctx context.Context, key flat.Key) (flat.Value, error) {
-	if len(key) == 0 {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if len(key) == 0 {
 		return nil, flat.ErrNotFound
 	}
 	val, closer, err := tx.tx.Get(key)
@@ -116,6 +132,8 @@ func (tx *Tx) GetBatch(ctx context.Context, keys []flat.Key) ([]flat.Value, erro
 func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
 	if !tx.rw {
 		return flat.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		return err
 	}
 	return tx.tx.Set(k, v, pebble.Sync)
 }
@@ -123,6 +141,8 @@ func (tx *Tx) Put(ctx context.Context, k flat.Key, v flat.Value) error {
 func (tx *Tx) Del(ctx context.Context, k flat.Key) error {
 	if !tx.rw {
 		return flat.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		return err
 	}
 	return tx.tx.Delete(k, pebble.Sync)
 }
@@ -157,14 +177,29 @@ func (it *Iterator) WithPrefix(pref flat.Key) flat.Iterator {
 	return it
 }
 
+// stop stops the iterator if the context is canceled.
+func (it *Iterator) stop(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return true
+	}
+	return false
+}
+
 func (it *Iterator) Seek(ctx context.Context, key flat.Key) bool {
 	it.Reset()
+	if it.stop(ctx) {
+		return false
+	}
 	it.first = false
 	it.it.SeekGE(key)
 	return it.isValid()
 }
 
 func (it *Iterator) Next(ctx context.Context) bool {
+	if it.err != nil || it.stop(ctx) {
+		return false
+	}
 	if it.first {
 		it.first = false
 		it.it.SeekGE(it.pref)
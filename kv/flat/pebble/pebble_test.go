@@ -10,7 +10,9 @@ import (
 )
 
 func TestPebble(t *testing.T) {
-	kvtest.RunTestLocal(t, flat.UpgradeOpenPath(OpenPath), &kvtest.Options{
-		NoTx: true,
-	})
+	kvtest.RunTestLocal(t, flat.UpgradeOpenPath(OpenPath), nil)
+}
+
+func BenchmarkPebble(b *testing.B) {
+	kvtest.RunBenchmarksLocal(b, flat.UpgradeOpenPath(OpenPath), nil)
 }
@@ -2,18 +2,18 @@ package flat
 
 import (
 	"context"
+	"sync"
 
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/kv"
 )
 
 var _ kv.KV = (*hieKV)(nil)
 
-const (
-	sep = '/'
-	esc = '\\'
-)
-
 // Upgrade upgrades flat KV to hierarchical KV.
+//
+// Hierarchical keys are stored using the latest key format (see KeyFormat). Databases with existing data
+// written in the legacy format are detected automatically and continue to use it. See MigrateKeys.
 func Upgrade(flat KV) kv.KV {
 	return &hieKV{flat: flat}
 }
@@ -31,60 +31,51 @@ func UpgradeOpenPath(open OpenPathFunc) kv.OpenPathFunc {
 
 type hieKV struct {
 	flat KV
+
+	mu     sync.Mutex
+	format KeyFormat // zero if not detected yet
+	marked bool      // format is recorded in the database
 }
 
-// KeyEscape converts kv.Key to a flat Key.
-func KeyEscape(k kv.Key) Key {
-	var k2 Key
-	for i, s := range k {
-		if i != 0 {
-			k2 = append(k2, sep)
-		}
-		for _, p := range s {
-			if p == esc || p == sep {
-				k2 = append(k2, esc)
-			}
-			k2 = append(k2, p)
-		}
-	}
-	return k2
-}
-
-// KeyUnescape converts flat Key into kv.Key.
-func KeyUnescape(k Key) kv.Key {
-	var (
-		k2  kv.Key
-		cur Key
-	)
-	for i := 0; i < len(k); i++ {
-		p := k[i]
-		if p == esc {
-			cur = append(cur, k[i+1])
-			i++
-			continue
-		} else if p == sep {
-			k2 = append(k2, cur)
-			cur = nil
-			continue
-		}
-		cur = append(cur, p)
+// keyFormat returns the key format of the database, detecting it if necessary.
+func (hkv *hieKV) keyFormat(ctx context.Context, tx Tx) (KeyFormat, bool, error) {
+	hkv.mu.Lock()
+	defer hkv.mu.Unlock()
+	if hkv.format != 0 {
+		return hkv.format, hkv.marked, nil
 	}
-	if cur != nil {
-		k2 = append(k2, cur)
+	f, marked, err := detectKeyFormat(ctx, tx)
+	if err != nil {
+		return 0, false, err
 	}
-	return k2
+	hkv.format, hkv.marked = f, marked
+	return f, marked, nil
 }
 
 func (hkv *hieKV) Close() error {
 	return hkv.flat.Close()
 }
 
+// Capabilities implements base.Capable. Capabilities of the flat store are preserved.
+func (hkv *hieKV) Capabilities() base.Capabilities {
+	c, _ := base.CapabilitiesOf(hkv.flat)
+	return c
+}
+
 func (hkv *hieKV) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
 	tx, err := hkv.flat.Tx(ctx, rw)
 	if err != nil {
 		return nil, err
 	}
-	return &flatTx{kv: hkv, tx: tx, rw: rw}, nil
+	f, marked, err := hkv.keyFormat(ctx, tx)
+	if err == nil && rw && !marked {
+		err = putKeyFormat(ctx, tx, f)
+	}
+	if err != nil {
+		tx.Close()
+		return nil, err
+	}
+	return &flatTx{kv: hkv, tx: tx, rw: rw, format: f, mark: rw && !marked}, nil
 }
 
 func (hkv *hieKV) View(ctx context.Context, fn func(tx kv.Tx) error) error {
@@ -96,16 +87,18 @@ func (hkv *hieKV) Update(ctx context.Context, fn func(tx kv.Tx) error) error {
 }
 
 type flatTx struct {
-	kv *hieKV
-	tx Tx
-	rw bool
+	kv     *hieKV
+	tx     Tx
+	rw     bool
+	format KeyFormat
+	mark   bool // key format is written in this transaction
 }
 
 func (tx *flatTx) key(key kv.Key) Key {
 	if len(key) == 0 {
 		return nil
 	}
-	return KeyEscape(key)
+	return tx.format.Escape(key)
 }
 
 func (tx *flatTx) Get(ctx context.Context, key kv.Key) (kv.Value, error) {
@@ -121,7 +114,15 @@ func (tx *flatTx) GetBatch(ctx context.Context, keys []kv.Key) ([]kv.Value, erro
 }
 
 func (tx *flatTx) Commit(ctx context.Context) error {
-	return tx.tx.Commit(ctx)
+	if err := tx.tx.Commit(ctx); err != nil {
+		return err
+	}
+	if tx.mark {
+		tx.kv.mu.Lock()
+		tx.kv.marked = true
+		tx.kv.mu.Unlock()
+	}
+	return nil
 }
 
 func (tx *flatTx) Close() error {
@@ -143,30 +144,55 @@ func (tx *flatTx) Del(ctx context.Context, k kv.Key) error {
 }
 
 func (tx *flatTx) Scan(ctx context.Context, opts ...kv.IteratorOption) kv.Iterator {
-	var (
-		native   []IteratorOption
-		fallback []kv.IteratorOption
-	)
-	for _, opt := range opts {
-		if v, ok := opt.(IteratorOption); ok {
-			native = append(native, v)
-		} else {
-			fallback = append(fallback, opt)
-		}
-	}
-	it := &prefIter{kv: tx.kv, Iterator: tx.tx.Scan(ctx, native...)}
-	return kv.ApplyIteratorOptions(it, fallback)
+	// options are applied to the upgraded iterator, which converts the keys according to the key format
+	var it kv.Iterator = &prefIter{format: tx.format, Iterator: tx.tx.Scan(ctx)}
+	return kv.ApplyIteratorOptions(it, opts)
 }
 
+var (
+	_ kv.Seeker         = (*prefIter)(nil)
+	_ kv.PrefixIterator = (*prefIter)(nil)
+	_ KeyFormatter      = (*prefIter)(nil)
+)
+
 type prefIter struct {
-	kv *hieKV
+	format KeyFormat
 	Iterator
 }
 
+// KeyFormat implements KeyFormatter.
+func (it *prefIter) KeyFormat() KeyFormat {
+	return it.format
+}
+
+func (it *prefIter) WithPrefix(pref kv.Key) kv.Iterator {
+	it.Iterator = withPrefix(it.Iterator, it.format.Escape(pref))
+	return it
+}
+
+func (it *prefIter) Next(ctx context.Context) bool {
+	for it.Iterator.Next(ctx) {
+		if !isMetaKey(it.Iterator.Key()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *prefIter) Seek(ctx context.Context, key kv.Key) bool {
+	if !Seek(ctx, it.Iterator, it.format.Escape(key)) {
+		return false
+	}
+	if isMetaKey(it.Iterator.Key()) {
+		return it.Next(ctx)
+	}
+	return true
+}
+
 func (it *prefIter) Val() kv.Value {
 	return it.Iterator.Val()
 }
 
 func (it *prefIter) Key() kv.Key {
-	return KeyUnescape(it.Iterator.Key())
+	return it.format.Unescape(it.Iterator.Key())
 }
@@ -1,6 +1,7 @@
 package flat
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -9,6 +10,15 @@ import (
 )
 
 func TestSepEscape(t *testing.T) {
+	k := kv.Key{
+		[]byte(`\/aa/b\b/c/d/\`),
+		[]byte(`/aa/b\b/c/d/`),
+	}
+	k2 := KeyUnescapeV2(KeyEscapeV2(k))
+	require.Equal(t, k, k2)
+}
+
+func TestSepEscapeV1(t *testing.T) {
 	k := kv.Key{
 		[]byte(`\/aa/b\b/c/d/\`),
 		[]byte(`/aa/b\b/c/d/`),
@@ -16,3 +26,34 @@ func TestSepEscape(t *testing.T) {
 	k2 := KeyUnescape(KeyEscape(k))
 	require.Equal(t, k, k2)
 }
+
+func TestKeyEscapeOrder(t *testing.T) {
+	// sorted in ascending order
+	keys := []kv.Key{
+		{[]byte("")},
+		{[]byte(""), []byte("a")},
+		{[]byte("\x00")},
+		{[]byte("a")},
+		{[]byte("a"), []byte("")},
+		{[]byte("a"), []byte("b")},
+		{[]byte("a"), []byte("b"), []byte("c")},
+		{[]byte("a"), []byte("b\x00")},
+		{[]byte("a\x00")},
+		{[]byte("a\x00"), []byte("\x00")},
+		{[]byte("a\x00\x00")},
+		{[]byte("a\x01")},
+		{[]byte("a\xff")},
+	}
+	for i, k := range keys {
+		ek := KeyEscapeV2(k)
+		require.False(t, isMetaKey(ek))
+		if i == 0 {
+			// single empty part cannot be distinguished from an empty key
+			require.Equal(t, kv.Key(nil), KeyUnescapeV2(ek))
+			continue
+		}
+		require.Equal(t, k, KeyUnescapeV2(ek))
+		prev := KeyEscapeV2(keys[i-1])
+		require.True(t, bytes.Compare(prev, ek) < 0, "%q >= %q", keys[i-1], k)
+	}
+}
@@ -0,0 +1,335 @@
+// Package keycodec implements an order-preserving encoding of value tuples into binary keys.
+//
+// The encoding is similar to the tuple layer of FoundationDB: each value is prefixed by a type code
+// and encoded in a self-delimiting way, thus a packed tuple is always a binary prefix of any longer
+// tuple that starts with the same values. Binary comparison of packed tuples matches the element-wise
+// comparison of values, making the encoding suitable for kv.Key parts and prefix scans.
+package keycodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+// Type codes used in the encoding. Values of different types are ordered by the code.
+const (
+	codeNil    = 0x00
+	codeBytes  = 0x01
+	codeString = 0x02
+	codeTuple  = 0x05
+	codeIntNeg = 0x0c // 0x0c - 0x13, negative ints by size
+	codeInt    = 0x14 // 0x14 - 0x1c, zero and positive ints by size
+	codeFloat  = 0x21
+	codeFalse  = 0x26
+	codeTrue   = 0x27
+	codeTime   = 0x34
+	codeUInt   = 0x40 // 0x40 - 0x48, uints by size
+)
+
+const (
+	escape = 0xff // used after a zero byte to distinguish it from terminator
+	intMax = 8    // max number of bytes in int
+)
+
+var sortableOrder = binary.BigEndian
+
+var (
+	// ErrUnsupported is returned when trying to encode a value of unsupported type.
+	ErrUnsupported = errors.New("keycodec: unsupported value type")
+	// ErrInvalid is returned when decoding a malformed tuple.
+	ErrInvalid = errors.New("keycodec: invalid encoding")
+)
+
+// Pack encodes values into an order-preserving binary key.
+// It panics if one of the values has an unsupported type. See Append for a version that returns an error.
+func Pack(vals ...values.Value) []byte {
+	p, err := Append(nil, vals...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Append encodes values and appends them to the buffer.
+func Append(dst []byte, vals ...values.Value) ([]byte, error) {
+	var err error
+	for _, v := range vals {
+		dst, err = appendValue(dst, v, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+func appendBytes(dst []byte, code byte, p []byte) []byte {
+	dst = append(dst, code)
+	for {
+		i := bytes.IndexByte(p, 0)
+		if i < 0 {
+			break
+		}
+		dst = append(dst, p[:i+1]...)
+		dst = append(dst, escape)
+		p = p[i+1:]
+	}
+	dst = append(dst, p...)
+	return append(dst, 0)
+}
+
+// bytesLen returns the minimal number of bytes needed to represent an integer.
+func bytesLen(v uint64) int {
+	n := 0
+	for ; v != 0; v >>= 8 {
+		n++
+	}
+	return n
+}
+
+func appendUint(dst []byte, v uint64, n int) []byte {
+	var buf [intMax]byte
+	sortableOrder.PutUint64(buf[:], v)
+	return append(dst, buf[intMax-n:]...)
+}
+
+func appendValue(dst []byte, v values.Value, nested bool) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		if nested {
+			return append(dst, codeNil, escape), nil
+		}
+		return append(dst, codeNil), nil
+	case values.Bytes:
+		return appendBytes(dst, codeBytes, v), nil
+	case values.String:
+		return appendBytes(dst, codeString, []byte(v)), nil
+	case values.Int:
+		if v >= 0 {
+			u := uint64(v)
+			n := bytesLen(u)
+			dst = append(dst, byte(codeInt+n))
+			return appendUint(dst, u, n), nil
+		}
+		// works for MinInt64 as well, since the conversion wraps it to 1<<63
+		u := uint64(-v)
+		n := bytesLen(u)
+		dst = append(dst, byte(codeInt-n))
+		return appendUint(dst, ^u, n), nil
+	case values.UInt:
+		u := uint64(v)
+		n := bytesLen(u)
+		dst = append(dst, byte(codeUInt+n))
+		return appendUint(dst, u, n), nil
+	case values.Float:
+		u := math.Float64bits(float64(v))
+		if u&(1<<63) != 0 {
+			u = ^u // negative - flip all bits
+		} else {
+			u |= 1 << 63 // positive - flip the sign bit
+		}
+		dst = append(dst, codeFloat)
+		return appendUint(dst, u, intMax), nil
+	case values.Bool:
+		if v {
+			return append(dst, codeTrue), nil
+		}
+		return append(dst, codeFalse), nil
+	case values.Time:
+		p, err := v.MarshalSortable()
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, codeTime)
+		return append(dst, p...), nil
+	case Tuple:
+		var err error
+		dst = append(dst, codeTuple)
+		for _, e := range v {
+			dst, err = appendValue(dst, e, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, 0), nil
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupported, v)
+}
+
+// Unpack decodes all values from a packed binary key.
+func Unpack(p []byte) (Tuple, error) {
+	var out Tuple
+	for len(p) > 0 {
+		v, n, err := decodeValue(p, false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		p = p[n:]
+	}
+	return out, nil
+}
+
+func decodeBytes(p []byte) ([]byte, int, error) {
+	out := []byte{}
+	for i := 0; i < len(p); i++ {
+		if c := p[i]; c != 0 {
+			out = append(out, c)
+			continue
+		}
+		if i+1 < len(p) && p[i+1] == escape {
+			out = append(out, 0)
+			i++
+			continue
+		}
+		return out, i + 1, nil
+	}
+	return nil, 0, fmt.Errorf("%w: unterminated string", ErrInvalid)
+}
+
+func decodeUint(p []byte, n int) (uint64, error) {
+	if n < 0 || n > intMax || len(p) < n {
+		return 0, fmt.Errorf("%w: short int", ErrInvalid)
+	}
+	var buf [intMax]byte
+	copy(buf[intMax-n:], p[:n])
+	return sortableOrder.Uint64(buf[:]), nil
+}
+
+// decodeValue decodes a single value and returns the number of bytes consumed.
+func decodeValue(p []byte, nested bool) (values.Value, int, error) {
+	code := p[0]
+	p = p[1:]
+	switch {
+	case code == codeNil:
+		if nested {
+			if len(p) == 0 || p[0] != escape {
+				return nil, 0, fmt.Errorf("%w: unescaped nil", ErrInvalid)
+			}
+			return nil, 2, nil
+		}
+		return nil, 1, nil
+	case code == codeBytes:
+		b, n, err := decodeBytes(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		return values.Bytes(b), 1 + n, nil
+	case code == codeString:
+		b, n, err := decodeBytes(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		return values.String(b), 1 + n, nil
+	case code >= codeIntNeg && code < codeInt:
+		n := int(codeInt - code)
+		u, err := decodeUint(p, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		u = ^u
+		if n < intMax {
+			u &= 1<<(8*uint(n)) - 1
+		}
+		return values.Int(-int64(u)), 1 + n, nil
+	case code >= codeInt && code <= codeInt+intMax:
+		n := int(code - codeInt)
+		u, err := decodeUint(p, n)
+		if err != nil {
+			return nil, 0, err
+		} else if u > math.MaxInt64 {
+			return nil, 0, fmt.Errorf("%w: int overflow", ErrInvalid)
+		}
+		return values.Int(u), 1 + n, nil
+	case code >= codeUInt && code <= codeUInt+intMax:
+		n := int(code - codeUInt)
+		u, err := decodeUint(p, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		return values.UInt(u), 1 + n, nil
+	case code == codeFloat:
+		u, err := decodeUint(p, intMax)
+		if err != nil {
+			return nil, 0, err
+		}
+		if u&(1<<63) != 0 {
+			u &^= 1 << 63
+		} else {
+			u = ^u
+		}
+		return values.Float(math.Float64frombits(u)), 1 + intMax, nil
+	case code == codeFalse:
+		return values.Bool(false), 1, nil
+	case code == codeTrue:
+		return values.Bool(true), 1, nil
+	case code == codeTime:
+		if len(p) < intMax {
+			return nil, 0, fmt.Errorf("%w: short time", ErrInvalid)
+		}
+		var t values.Time
+		if err := t.UnmarshalSortable(p[:intMax]); err != nil {
+			return nil, 0, err
+		}
+		return t, 1 + intMax, nil
+	case code == codeTuple:
+		out := Tuple{}
+		n := 1
+		for {
+			if len(p) == 0 {
+				return nil, 0, fmt.Errorf("%w: unterminated tuple", ErrInvalid)
+			}
+			if p[0] == 0 && (len(p) == 1 || p[1] != escape) {
+				return out, n + 1, nil
+			}
+			v, vn, err := decodeValue(p, true)
+			if err != nil {
+				return nil, 0, err
+			}
+			out = append(out, v)
+			p = p[vn:]
+			n += vn
+		}
+	}
+	return nil, 0, fmt.Errorf("%w: unknown type code: 0x%02x", ErrInvalid, code)
+}
+
+// Key packs each tuple into a separate part of a hierarchical key.
+func Key(parts ...Tuple) kv.Key {
+	k := make(kv.Key, 0, len(parts))
+	for _, t := range parts {
+		k = append(k, t.Pack())
+	}
+	return k
+}
+
+// UnpackKey decodes all parts of a hierarchical key created with Key.
+func UnpackKey(k kv.Key) ([]Tuple, error) {
+	out := make([]Tuple, 0, len(k))
+	for _, p := range k {
+		t, err := Unpack(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// PrefixEnd returns the first key that is greater than all keys that starts with a given packed prefix.
+// It returns nil if there is no such key.
+func PrefixEnd(p []byte) []byte {
+	end := append([]byte{}, p...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package keycodec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/bolt"
+	"github.com/hidal-go/hidalgo/kv/options"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+var casesRoundTrip = []Tuple{
+	nil,
+	{nil},
+	{values.Bytes{}},
+	{values.Bytes("\x00")},
+	{values.Bytes("a\x00\xffb\x00")},
+	{values.String("")},
+	{values.String("foo\x00bar")},
+	{values.Int(0)},
+	{values.Int(1)},
+	{values.Int(-1)},
+	{values.Int(255)},
+	{values.Int(-255)},
+	{values.Int(256)},
+	{values.Int(-256)},
+	{values.Int(math.MaxInt64)},
+	{values.Int(math.MinInt64)},
+	{values.Int(math.MinInt64 + 1)},
+	{values.UInt(0)},
+	{values.UInt(math.MaxUint64)},
+	{values.Float(0)},
+	{values.Float(-1.5)},
+	{values.Float(math.Inf(+1))},
+	{values.Float(math.Inf(-1))},
+	{values.Float(math.MaxFloat64)},
+	{values.Float(-math.SmallestNonzeroFloat64)},
+	{values.Bool(false)},
+	{values.Bool(true)},
+	{values.AsTime(time.Unix(123, 456))},
+	{Tuple{}},
+	{Tuple{nil, values.Bytes("\x00"), Tuple{nil}}},
+	{values.String("a"), values.Int(-3), nil, T(values.Bool(true), nil), values.Float(2)},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range casesRoundTrip {
+		t.Run(fmt.Sprintf("%v", c), func(t *testing.T) {
+			p := c.Pack()
+			got, err := Unpack(p)
+			require.NoError(t, err)
+			require.Equal(t, c, got)
+
+			var dst Tuple
+			err = dst.UnmarshalSortable(p)
+			require.NoError(t, err)
+			if c == nil {
+				c = Tuple{}
+			}
+			require.Equal(t, c, dst)
+		})
+	}
+}
+
+// casesOrder must be sorted in ascending order.
+var casesOrder = []Tuple{
+	{nil},
+	{values.Bytes{}},
+	{values.Bytes{}, values.Int(0)},
+	{values.Bytes("\x00")},
+	{values.Bytes("\x00\x00")},
+	{values.Bytes("\x00\x01")},
+	{values.Bytes("\x01")},
+	{values.String("a")},
+	{values.String("a"), values.String("b")},
+	{values.String("a\x00")},
+	{values.String("ab")},
+	{T(values.String("a"))},
+	{T(values.String("a"), nil)},
+	{T(values.String("a"), values.String("b"))},
+	{T(values.String("b"))},
+	{values.Int(math.MinInt64)},
+	{values.Int(math.MinInt64 + 1)},
+	{values.Int(-65536)},
+	{values.Int(-257)},
+	{values.Int(-256)},
+	{values.Int(-255)},
+	{values.Int(-2)},
+	{values.Int(-1)},
+	{values.Int(0)},
+	{values.Int(1)},
+	{values.Int(255)},
+	{values.Int(256)},
+	{values.Int(math.MaxInt64)},
+	{values.Float(math.Inf(-1))},
+	{values.Float(-math.MaxFloat64)},
+	{values.Float(-1)},
+	{values.Float(-math.SmallestNonzeroFloat64)},
+	{values.Float(0)},
+	{values.Float(math.SmallestNonzeroFloat64)},
+	{values.Float(1)},
+	{values.Float(math.Inf(+1))},
+	{values.Bool(false)},
+	{values.Bool(true)},
+	{values.AsTime(time.Unix(-1, 0))},
+	{values.AsTime(time.Unix(0, 0))},
+	{values.AsTime(time.Unix(0, 1))},
+	{values.UInt(0)},
+	{values.UInt(1)},
+	{values.UInt(256)},
+	{values.UInt(math.MaxUint64)},
+}
+
+func TestOrder(t *testing.T) {
+	for i := 1; i < len(casesOrder); i++ {
+		a, b := casesOrder[i-1], casesOrder[i]
+		pa, pb := a.Pack(), b.Pack()
+		require.True(t, bytes.Compare(pa, pb) < 0, "%v (%x) >= %v (%x)", a, pa, b, pb)
+		require.Equal(t, -1, a.Compare(b))
+		require.Equal(t, +1, b.Compare(a))
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	full := T(values.String("a"), values.Int(-5), values.Bytes("\x00"))
+	for i := range full {
+		pref := full[:i].Pack()
+		require.True(t, bytes.HasPrefix(full.Pack(), pref))
+		require.True(t, full.HasPrefix(full[:i]))
+	}
+	// a partial string is not a prefix of a tuple
+	require.False(t, bytes.HasPrefix(T(values.String("ab")).Pack(), T(values.String("a")).Pack()))
+	end := PrefixEnd(T(values.String("a")).Pack())
+	require.True(t, bytes.Compare(full.Pack(), end) < 0)
+	require.Nil(t, PrefixEnd([]byte{0xff, 0xff}))
+}
+
+func TestUnsupported(t *testing.T) {
+	_, err := Append(nil, values.String("a"), badValue{})
+	require.ErrorIs(t, err, ErrUnsupported)
+	require.Panics(t, func() {
+		Pack(badValue{})
+	})
+}
+
+func TestInvalid(t *testing.T) {
+	for _, p := range [][]byte{
+		{codeString, 'a'},
+		{codeInt + 2, 1},
+		{codeFloat, 1, 2},
+		{codeTuple, codeString, 'a'},
+		{codeTuple, codeInt},
+		{0x99},
+	} {
+		_, err := Unpack(p)
+		require.ErrorIs(t, err, ErrInvalid, "%x", p)
+	}
+}
+
+type badValue struct {
+	values.Int
+}
+
+func TestKV(t *testing.T) {
+	ctx := context.Background()
+	db, err := bolt.OpenPath(filepath.Join(t.TempDir(), "bolt.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	users := T(values.String("users"))
+	var keys []kv.Key
+	for _, id := range []int64{-300, -2, 0, 1, 10, 256} {
+		for _, ts := range []int64{-1, 5} {
+			keys = append(keys, Key(users, T(values.Int(id), values.AsTime(time.Unix(ts, 0)))))
+		}
+	}
+	keys = append(keys, Key(T(values.String("usersx"))))
+
+	err = kv.Update(ctx, db, func(tx kv.Tx) error {
+		// insert in reverse order
+		for i := len(keys) - 1; i >= 0; i-- {
+			if err := tx.Put(ctx, keys[i], kv.Value{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	scan := func(opts ...kv.IteratorOption) []kv.Key {
+		var out []kv.Key
+		err := kv.View(ctx, db, func(tx kv.Tx) error {
+			return kv.Each(ctx, tx, func(k kv.Key, _ kv.Value) error {
+				out = append(out, k.Clone())
+				return nil
+			}, opts...)
+		})
+		require.NoError(t, err)
+		return out
+	}
+	require.Equal(t, keys, scan())
+	require.Equal(t, keys[:len(keys)-1], scan(options.WithPrefixKV(Key(users))))
+	// partial tuple in the second key part
+	require.Equal(t, keys[2:4], scan(options.WithPrefixKV(Key(users, T(values.Int(-2))))))
+
+	parts, err := UnpackKey(keys[2])
+	require.NoError(t, err)
+	require.Equal(t, []Tuple{users, T(values.Int(-2), values.AsTime(time.Unix(-1, 0)))}, parts)
+}
@@ -0,0 +1,126 @@
+package keycodec
+
+import (
+	"bytes"
+
+	"github.com/hidal-go/hidalgo/values"
+)
+
+var (
+	_ values.Sortable     = Tuple(nil)
+	_ values.SortableDest = (*Tuple)(nil)
+	_ values.SortableType = TupleType{}
+)
+
+// TupleType is a type of Tuple values.
+type TupleType struct{}
+
+func (tp TupleType) New() values.ValueDest {
+	return tp.NewSortable()
+}
+
+func (TupleType) NewSortable() values.SortableDest {
+	return new(Tuple)
+}
+
+// Tuple is an ordered sequence of values. It can be nested into other tuples.
+//
+// Supported element types are Bytes, String, Int, UInt, Float, Bool, Time, Tuple and nil.
+type Tuple []values.Value
+
+// T is a shorthand for creating tuples.
+func T(vals ...values.Value) Tuple {
+	return Tuple(vals)
+}
+
+// Pack encodes the tuple into an order-preserving binary key. It panics on unsupported values.
+func (t Tuple) Pack() []byte {
+	return Pack(t...)
+}
+
+// Append appends additional values and returns a new tuple.
+func (t Tuple) Append(vals ...values.Value) Tuple {
+	t2 := make(Tuple, 0, len(t)+len(vals))
+	t2 = append(t2, t...)
+	return append(t2, vals...)
+}
+
+// HasPrefix checks if a tuple starts with all elements of a given prefix.
+func (t Tuple) HasPrefix(pref Tuple) bool {
+	if len(pref) > len(t) {
+		return false
+	}
+	return t[:len(pref)].Compare(pref) == 0
+}
+
+func (t Tuple) Native() interface{} {
+	out := make([]interface{}, 0, len(t))
+	for _, v := range t {
+		if v == nil {
+			out = append(out, nil)
+		} else {
+			out = append(out, v.Native())
+		}
+	}
+	return out
+}
+
+func (t *Tuple) NativePtr() interface{} {
+	return (*[]values.Value)(t)
+}
+
+func (Tuple) Type() values.Type {
+	return TupleType{}
+}
+
+func (Tuple) SortableType() values.SortableType {
+	return TupleType{}
+}
+
+func (t *Tuple) Value() values.Value {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func (t *Tuple) Sortable() values.Sortable {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func (t Tuple) Compare(b values.Sortable) int {
+	if b == nil {
+		return +1
+	}
+	ab, _ := t.MarshalSortable()
+	bb, _ := b.MarshalSortable()
+	return bytes.Compare(ab, bb)
+}
+
+// MarshalBinary encodes the tuple. It uses the same encoding as MarshalSortable.
+func (t Tuple) MarshalBinary() ([]byte, error) {
+	return t.MarshalSortable()
+}
+
+func (t *Tuple) UnmarshalBinary(p []byte) error {
+	return t.UnmarshalSortable(p)
+}
+
+func (t Tuple) MarshalSortable() ([]byte, error) {
+	return Append(nil, t...)
+}
+
+func (t *Tuple) UnmarshalSortable(p []byte) error {
+	t2, err := Unpack(p)
+	if err != nil {
+		return err
+	}
+	if t2 == nil {
+		t2 = Tuple{}
+	}
+	*t = t2
+	return nil
+}
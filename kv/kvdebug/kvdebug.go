@@ -7,6 +7,7 @@ import (
 	"log"
 	"sync/atomic"
 
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/kv"
 )
 
@@ -78,6 +79,12 @@ func (d *KV) Close() error {
 	return err
 }
 
+// Capabilities implements base.Capable.
+func (d *KV) Capabilities() base.Capabilities {
+	c, _ := base.CapabilitiesOf(d.KV)
+	return c
+}
+
 func (d *KV) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
 	tx, err := d.KV.Tx(ctx, rw)
 	if err != nil {
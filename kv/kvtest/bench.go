@@ -0,0 +1,288 @@
+package kvtest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/options"
+)
+
+const (
+	benchTx    = 100      // operations in a single transaction
+	benchGroup = 100      // keys with the same prefix
+	benchKeys  = 10000    // keys written before read benchmarks
+	benchData  = 32 << 20 // limits the number of keys written before read benchmarks
+)
+
+// benchSize describes the size of keys and values used in benchmarks.
+type benchSize struct {
+	key int // size of the last key part; at least 8 bytes
+	val int
+}
+
+var benchSizes = []benchSize{
+	{key: 16, val: 64},
+	{key: 16, val: 1024},
+	{key: 64, val: 64 << 10}, // large values
+}
+
+func (sz benchSize) String() string {
+	return fmt.Sprintf("k%d-v%d", sz.key, sz.val)
+}
+
+// bytes returns the size of a single key-value pair.
+func (sz benchSize) bytes() int {
+	return len(benchPrefix(0)) + sz.key + sz.val
+}
+
+// count returns the number of keys to write before read benchmarks.
+func (sz benchSize) count() int {
+	n := benchData / sz.bytes()
+	if n > benchKeys {
+		n = benchKeys
+	}
+	return n
+}
+
+func benchPrefix(i int) []byte {
+	return []byte(fmt.Sprintf("g%06d", i/benchGroup))
+}
+
+// keyAt returns i-th key in the ascending order. Each benchGroup keys share the same prefix.
+func (sz benchSize) keyAt(i int) kv.Key {
+	id := make([]byte, sz.key)
+	binary.BigEndian.PutUint64(id[len(id)-8:], uint64(i))
+	return kv.Key{benchPrefix(i), id}
+}
+
+// benchValues generates random values of a given size.
+type benchValues struct {
+	size int
+	buf  []byte
+}
+
+func newBenchValues(size int) *benchValues {
+	buf := make([]byte, 2*size)
+	rand.New(rand.NewSource(1)).Read(buf)
+	return &benchValues{size: size, buf: buf}
+}
+
+func (v *benchValues) get(i int) kv.Value {
+	off := i % v.size
+	return v.buf[off : off+v.size]
+}
+
+var benchList = []struct {
+	name  string
+	bench func(b *testing.B, db kv.KV, sz benchSize)
+	caps  base.Capabilities // required capabilities
+}{
+	{name: "put-seq", bench: benchPutSeq},
+	{name: "put-rand", bench: benchPutRand},
+	{name: "get", bench: benchGet},
+	{name: "get-batch", bench: benchGetBatch},
+	{name: "scan-prefix", bench: benchScanPrefix},
+	{name: "mixed", bench: benchMixed, caps: base.CapConcurrentWrites},
+}
+
+// RunBenchmarks runs all benchmarks for key-value implementations.
+//
+// Each benchmark runs for each combination of key and value size, and reports ops/s in addition to the throughput.
+// Flat implementations can be benchmarked with flat.Upgrade.
+func RunBenchmarks(b *testing.B, fnc Func, opts *Options) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	for _, c := range benchList {
+		b.Run(c.name, func(b *testing.B) {
+			for _, sz := range benchSizes {
+				b.Run(sz.String(), func(b *testing.B) {
+					db := fnc(b)
+					if caps := opts.Capabilities(db); !caps.Has(c.caps) {
+						b.Skipf("implementation doesn't support %v", c.caps&^caps)
+					}
+					c.bench(b, db, sz)
+				})
+			}
+		})
+	}
+}
+
+// RunBenchmarksLocal is a wrapper for RunBenchmarks that automatically creates a temporary directory and opens a database.
+func RunBenchmarksLocal(b *testing.B, open kv.OpenPathFunc, opts *Options) {
+	RunBenchmarks(b, localFunc(open), opts)
+}
+
+// benchStart resets the benchmark timer and returns the start time.
+func benchStart(b *testing.B, sz benchSize) time.Time {
+	b.SetBytes(int64(sz.bytes()))
+	b.ResetTimer()
+	return time.Now()
+}
+
+// benchReport reports the number of operations per second.
+func benchReport(b *testing.B, start time.Time) {
+	b.StopTimer()
+	if dt := time.Since(start); dt > 0 {
+		b.ReportMetric(float64(b.N)/dt.Seconds(), "ops/s")
+	}
+}
+
+// benchFill writes keys for read benchmarks and returns the number of keys.
+func benchFill(b *testing.B, db kv.KV, sz benchSize) int {
+	n := sz.count()
+	vals := newBenchValues(sz.val)
+	benchWrite(b, db, sz, vals, n, func(i int) int { return i })
+	return n
+}
+
+func benchWrite(b *testing.B, db kv.KV, sz benchSize, vals *benchValues, n int, order func(i int) int) {
+	ctx := context.Background()
+	for i := 0; i < n; i += benchTx {
+		err := kv.Update(ctx, db, func(tx kv.Tx) error {
+			for j := i; j < i+benchTx && j < n; j++ {
+				if err := tx.Put(ctx, sz.keyAt(order(j)), vals.get(j)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchPutSeq(b *testing.B, db kv.KV, sz benchSize) {
+	vals := newBenchValues(sz.val)
+	start := benchStart(b, sz)
+	benchWrite(b, db, sz, vals, b.N, func(i int) int { return i })
+	benchReport(b, start)
+}
+
+func benchPutRand(b *testing.B, db kv.KV, sz benchSize) {
+	vals := newBenchValues(sz.val)
+	perm := rand.New(rand.NewSource(1)).Perm(b.N)
+	start := benchStart(b, sz)
+	benchWrite(b, db, sz, vals, b.N, func(i int) int { return perm[i] })
+	benchReport(b, start)
+}
+
+func benchGet(b *testing.B, db kv.KV, sz benchSize) {
+	ctx := context.Background()
+	n := benchFill(b, db, sz)
+	rnd := rand.New(rand.NewSource(1))
+	start := benchStart(b, sz)
+	for i := 0; i < b.N; i += benchTx {
+		err := kv.View(ctx, db, func(tx kv.Tx) error {
+			for j := i; j < i+benchTx && j < b.N; j++ {
+				if _, err := tx.Get(ctx, sz.keyAt(rnd.Intn(n))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchReport(b, start)
+}
+
+func benchGetBatch(b *testing.B, db kv.KV, sz benchSize) {
+	ctx := context.Background()
+	n := benchFill(b, db, sz)
+	rnd := rand.New(rand.NewSource(1))
+	keys := make([]kv.Key, 0, benchTx)
+	start := benchStart(b, sz)
+	for i := 0; i < b.N; i += benchTx {
+		keys = keys[:0]
+		for j := i; j < i+benchTx && j < b.N; j++ {
+			keys = append(keys, sz.keyAt(rnd.Intn(n)))
+		}
+		err := kv.View(ctx, db, func(tx kv.Tx) error {
+			vals, err := tx.GetBatch(ctx, keys)
+			if err != nil {
+				return err
+			}
+			for _, v := range vals {
+				if v == nil {
+					return kv.ErrNotFound
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchReport(b, start)
+}
+
+// benchScanPrefix counts each key-value pair returned by prefix scans as a single operation.
+func benchScanPrefix(b *testing.B, db kv.KV, sz benchSize) {
+	ctx := context.Background()
+	n := benchFill(b, db, sz)
+	rnd := rand.New(rand.NewSource(1))
+	groups := (n + benchGroup - 1) / benchGroup
+	start := benchStart(b, sz)
+	for read := 0; read < b.N; {
+		pref := kv.Key{benchPrefix(rnd.Intn(groups) * benchGroup)}
+		err := kv.View(ctx, db, func(tx kv.Tx) error {
+			it := tx.Scan(ctx, options.WithPrefixKV(pref))
+			defer it.Close()
+			for read < b.N && it.Next(ctx) {
+				_ = it.Val()
+				read++
+			}
+			return it.Err()
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchReport(b, start)
+}
+
+// benchMixed runs concurrent point reads and writes in separate transactions, with one write per 10 operations.
+func benchMixed(b *testing.B, db kv.KV, sz benchSize) {
+	ctx := context.Background()
+	n := benchFill(b, db, sz)
+	vals := newBenchValues(sz.val)
+	var seed int64
+	start := benchStart(b, sz)
+	b.RunParallel(func(pb *testing.PB) {
+		rnd := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for pb.Next() {
+			i := rnd.Intn(n)
+			var err error
+			if rnd.Intn(10) == 0 {
+				for {
+					err = kv.Update(ctx, db, func(tx kv.Tx) error {
+						return tx.Put(ctx, sz.keyAt(i), vals.get(i))
+					})
+					if err != kv.ErrConflict {
+						break
+					}
+				}
+			} else {
+				err = kv.View(ctx, db, func(tx kv.Tx) error {
+					_, err := tx.Get(ctx, sz.keyAt(i))
+					return err
+				})
+			}
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	benchReport(b, start)
+}
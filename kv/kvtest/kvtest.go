@@ -7,9 +7,11 @@ import (
 	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/kv"
 	"github.com/hidal-go/hidalgo/kv/options"
 )
@@ -18,11 +20,28 @@ import (
 // It returns an empty database and a function to destroy it.
 type Func func(t testing.TB) kv.KV
 
+// Options overrides capabilities reported by the database.
 type Options struct {
 	NoLocks bool // not safe for concurrent writes
 	NoTx    bool // implementation doesn't support proper transactions
 }
 
+// Capabilities returns capabilities of the database that are used to select test cases.
+// Databases that don't implement base.Capable are assumed to support everything.
+func (o *Options) Capabilities(db interface{}) base.Capabilities {
+	caps, ok := base.CapabilitiesOf(db)
+	if !ok {
+		caps = base.AllCapabilities
+	}
+	if o.NoLocks {
+		caps &^= base.CapConcurrentWrites
+	}
+	if o.NoTx {
+		caps &^= base.CapTx
+	}
+	return caps
+}
+
 // RunTest runs all tests for key-value implementations.
 func RunTest(t *testing.T, fnc Func, opts *Options) {
 	if opts == nil {
@@ -30,13 +49,10 @@ func RunTest(t *testing.T, fnc Func, opts *Options) {
 	}
 	for _, c := range testList {
 		t.Run(c.name, func(t *testing.T) {
-			if c.concurrent && opts.NoLocks {
-				t.Skip("implementation doesn't support concurrent writes")
-			}
-			if c.txOnly && opts.NoTx {
-				t.Skip("implementation doesn't support transactions")
-			}
 			db := fnc(t)
+			if caps := opts.Capabilities(db); !caps.Has(c.caps) {
+				t.Skipf("implementation doesn't support %v", c.caps&^caps)
+			}
 			c.test(t, db)
 		})
 	}
@@ -44,10 +60,12 @@ func RunTest(t *testing.T, fnc Func, opts *Options) {
 
 // RunTestLocal is a wrapper for RunTest that automatically creates a temporary directory and opens a database.
 func RunTestLocal(t *testing.T, open kv.OpenPathFunc, opts *Options) {
-	if opts == nil {
-		opts = &Options{}
-	}
-	RunTest(t, func(t testing.TB) kv.KV {
+	RunTest(t, localFunc(open), opts)
+}
+
+// localFunc creates a database in a temporary directory.
+func localFunc(open kv.OpenPathFunc) Func {
+	return func(t testing.TB) kv.KV {
 		dir, err := ioutil.TempDir("", "dal-kv-")
 		require.NoError(t, err)
 		t.Cleanup(func() {
@@ -63,19 +81,23 @@ func RunTestLocal(t *testing.T, open kv.OpenPathFunc, opts *Options) {
 			db.Close() // test double close
 		})
 		return db
-	}, opts)
+	}
 }
 
 var testList = []struct {
-	name       string
-	test       func(t testing.TB, db kv.KV)
-	concurrent bool // requires concurrent safety
-	txOnly     bool // requires transactions
+	name string
+	test func(t testing.TB, db kv.KV)
+	caps base.Capabilities // required capabilities
 }{
 	{name: "basic", test: basic},
 	{name: "ro", test: readonly},
 	{name: "seek", test: seek},
-	{name: "increment", test: increment, txOnly: true, concurrent: true},
+	{name: "order", test: order},
+	{name: "increment", test: increment, caps: base.CapTx | base.CapConcurrentWrites},
+	{name: "random", test: random},
+	{name: "random tx", test: randomTx, caps: base.CapTx},
+	{name: "cancel", test: cancel},
+	{name: "cancel tx", test: cancelTx, caps: base.CapTx},
 }
 
 func basic(t testing.TB, db kv.KV) {
@@ -228,6 +250,168 @@ func seek(t testing.TB, db kv.KV) {
 	td.ExpectIt(it, all)
 }
 
+// order checks that keys are sorted part by part (see kv.Key.Compare), as in native hierarchical stores.
+// Key parts include bytes that are lower than common separators to make sure flat stores escape them correctly.
+func order(t testing.TB, db kv.KV) {
+	td := NewTest(t, db)
+
+	// sorted in ascending order
+	keys := []kv.Key{
+		{[]byte("a"), []byte("\x00")},
+		{[]byte("a"), []byte("b")},
+		{[]byte("a"), []byte("b\x00")},
+		{[]byte("a"), []byte("b\x00\x01")},
+		{[]byte("a"), []byte("b/c")},
+		{[]byte("a"), []byte("b\\")},
+		{[]byte("a\x00")},
+		{[]byte("a\x00\x01")},
+		{[]byte("a\x01")},
+		{[]byte("a/")},
+		{[]byte("a\\")},
+		{[]byte("a\xff")},
+		{[]byte("b"), []byte("\xff"), []byte("c")},
+		{[]byte("b"), []byte("\xff\x00")},
+	}
+
+	var all []kv.Pair
+	for i, k := range keys {
+		all = append(all, kv.Pair{Key: k, Val: kv.Value(strconv.Itoa(i))})
+	}
+	// insert in reverse order
+	for i := len(all) - 1; i >= 0; i-- {
+		td.Put(all[i].Key, all[i].Val)
+	}
+	td.Scan(all)
+
+	filter := func(pref kv.Key) []kv.Pair {
+		var out []kv.Pair
+		for _, p := range all {
+			if p.Key.HasPrefix(pref) {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	for _, pref := range []kv.Key{
+		{[]byte("a")},
+		{[]byte("a"), []byte("b")},
+		{[]byte("a"), []byte("b\x00")},
+		{[]byte("a\x00")},
+		{[]byte("b"), []byte("\xff")},
+	} {
+		td.Scan(filter(pref), options.WithPrefixKV(pref))
+	}
+
+	ctx := context.Background()
+	tx, err := db.Tx(ctx, false)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	it := tx.Scan(ctx)
+	defer it.Close()
+	for i, p := range all {
+		ok := kv.Seek(ctx, it, p.Key)
+		require.True(t, ok)
+		require.Equal(t, p.Key, it.Key())
+		td.ExpectIt(it, all[i+1:])
+	}
+}
+
+// canceled returns contexts that are already canceled and expired.
+func canceled() (context.Context, context.Context) {
+	cctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dctx, cancel := context.WithDeadline(context.Background(), time.Unix(1, 0))
+	cancel()
+	return cctx, dctx
+}
+
+// cancel checks that operations and iterators stop and return an error of the context, once it is canceled.
+func cancel(t testing.TB, db kv.KV) {
+	td := NewTest(t, db)
+
+	keys := []kv.Key{
+		{[]byte("a")},
+		{[]byte("b"), []byte("a")},
+		{[]byte("c")},
+	}
+	for i, k := range keys {
+		td.Put(k, kv.Value(strconv.Itoa(i)))
+	}
+
+	ctx := context.Background()
+	cctx, dctx := canceled()
+
+	_, err := db.Tx(cctx, false)
+	require.Equal(t, context.Canceled, err)
+
+	tx, err := db.Tx(ctx, false)
+	require.NoError(t, err)
+
+	_, err = tx.Get(cctx, keys[0])
+	require.Equal(t, context.Canceled, err)
+	_, err = tx.GetBatch(dctx, keys)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	it := tx.Scan(ctx)
+	require.True(t, it.Next(ctx))
+	require.False(t, it.Next(cctx))
+	require.Equal(t, context.Canceled, it.Err())
+	it.Close()
+
+	it = tx.Scan(ctx, options.WithPrefixKV(keys[1][:1]))
+	require.False(t, it.Next(dctx))
+	require.Equal(t, context.DeadlineExceeded, it.Err())
+	it.Close()
+
+	it = tx.Scan(ctx)
+	require.False(t, kv.Seek(cctx, it, keys[1]))
+	require.Equal(t, context.Canceled, it.Err())
+	it.Close()
+	require.NoError(t, tx.Close())
+
+	tx, err = db.Tx(ctx, true)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	err = tx.Put(cctx, keys[0], kv.Value("x"))
+	require.Equal(t, context.Canceled, err)
+	err = tx.Del(dctx, keys[0])
+	require.Equal(t, context.DeadlineExceeded, err)
+	err = tx.Close()
+	require.NoError(t, err)
+
+	for i, k := range keys {
+		td.Expect(k, kv.Value(strconv.Itoa(i)))
+	}
+}
+
+// cancelTx checks that a transaction is rolled back, if it's committed with a canceled context.
+func cancelTx(t testing.TB, db kv.KV) {
+	td := NewTest(t, db)
+
+	key := kv.Key{[]byte("a")}
+	td.Put(key, kv.Value("0"))
+
+	ctx := context.Background()
+	cctx, _ := canceled()
+
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	err = tx.Put(ctx, key, kv.Value("1"))
+	require.NoError(t, err)
+	err = tx.Put(ctx, kv.Key{[]byte("b")}, kv.Value("1"))
+	require.NoError(t, err)
+
+	err = tx.Commit(cctx)
+	require.Equal(t, context.Canceled, err)
+
+	td.Expect(key, kv.Value("0"))
+	td.NotExists(kv.Key{[]byte("b")})
+}
+
 func increment(t testing.TB, db kv.KV) {
 	td := NewTest(t, db)
 
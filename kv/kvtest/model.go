@@ -0,0 +1,628 @@
+package kvtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/options"
+)
+
+// Randomized tests execute random sequences of operations against the database and a simple in-memory model,
+// and compare the results. A failing sequence is minimized before it's reported.
+//
+// Sequences are generated from fixed seeds, so failures are reproducible.
+
+const (
+	modelRuns     = 16  // number of random sequences
+	modelSteps    = 150 // operations in each sequence
+	modelMinimize = 300 // limit on sequence runs during minimization
+)
+
+// modelKeyParts defines the key space for randomized tests.
+// The first part of the key defines the depth of the key, so hierarchical stores never see a key that is also a bucket.
+var modelKeyParts = []struct {
+	part  string
+	depth int
+}{
+	{"a", 1}, {"a\x00", 1}, {"ab", 1}, {"\x01", 1},
+	{"b", 2}, {"b\x00", 2}, {"bb", 2},
+	{"c", 3},
+}
+
+var (
+	modelMidParts  = []string{"x", "x\x00", "y"}
+	modelLeafParts = []string{"a", "a\x00", "a\x00b", "ab", "b", "\x00", "\x01", "\xff"}
+)
+
+type modelOpKind int
+
+const (
+	mBegin modelOpKind = iota
+	mCommit
+	mRollback
+	mPut
+	mDel
+	mGet
+	mGetBatch
+	mScan
+	mSeek
+)
+
+// modelOp is a single operation in a randomized test.
+type modelOp struct {
+	kind modelOpKind
+	tx   int // transaction slot
+	rw   bool
+	key  kv.Key
+	keys []kv.Key
+	val  kv.Value
+	pref kv.Key
+	n    int // maximal number of pairs to read; zero means all
+}
+
+func fmtKey(k kv.Key) string {
+	parts := make([]string, 0, len(k))
+	for _, p := range k {
+		parts = append(parts, strconv.Quote(string(p)))
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func (op modelOp) String() string {
+	tx := "tx" + strconv.Itoa(op.tx)
+	switch op.kind {
+	case mBegin:
+		return fmt.Sprintf("%s = begin(rw=%v)", tx, op.rw)
+	case mCommit:
+		return tx + ".commit()"
+	case mRollback:
+		return tx + ".rollback()"
+	case mPut:
+		return fmt.Sprintf("%s.put(%s, %q)", tx, fmtKey(op.key), op.val)
+	case mDel:
+		return fmt.Sprintf("%s.del(%s)", tx, fmtKey(op.key))
+	case mGet:
+		return fmt.Sprintf("%s.get(%s)", tx, fmtKey(op.key))
+	case mGetBatch:
+		keys := make([]string, 0, len(op.keys))
+		for _, k := range op.keys {
+			keys = append(keys, fmtKey(k))
+		}
+		return fmt.Sprintf("%s.getBatch(%s)", tx, strings.Join(keys, ", "))
+	case mScan:
+		return fmt.Sprintf("%s.scan(prefix=%s, n=%d)", tx, fmtKey(op.pref), op.n)
+	case mSeek:
+		return fmt.Sprintf("%s.seek(prefix=%s, key=%s, n=%d)", tx, fmtKey(op.pref), fmtKey(op.key), op.n)
+	}
+	return "unknown"
+}
+
+// modelState is a sorted list of key-value pairs.
+type modelState []kv.Pair
+
+func (s modelState) clone() modelState {
+	return append(modelState{}, s...)
+}
+
+func (s modelState) search(k kv.Key) int {
+	return sort.Search(len(s), func(i int) bool {
+		return s[i].Key.Compare(k) >= 0
+	})
+}
+
+func (s modelState) get(k kv.Key) (kv.Value, bool) {
+	i := s.search(k)
+	if i < len(s) && s[i].Key.Compare(k) == 0 {
+		return s[i].Val, true
+	}
+	return nil, false
+}
+
+func (s *modelState) put(k kv.Key, v kv.Value) {
+	i := s.search(k)
+	if i < len(*s) && (*s)[i].Key.Compare(k) == 0 {
+		(*s)[i].Val = v
+		return
+	}
+	*s = append(*s, kv.Pair{})
+	copy((*s)[i+1:], (*s)[i:])
+	(*s)[i] = kv.Pair{Key: k, Val: v}
+}
+
+func (s *modelState) del(k kv.Key) {
+	i := s.search(k)
+	if i < len(*s) && (*s)[i].Key.Compare(k) == 0 {
+		*s = append((*s)[:i], (*s)[i+1:]...)
+	}
+}
+
+// scan returns at most n pairs (or all, if n is zero) with a given prefix, starting from a given key.
+func (s modelState) scan(pref, from kv.Key, n int) []kv.Pair {
+	var out []kv.Pair
+	for _, p := range s[s.search(from):] {
+		if !p.Key.HasPrefix(pref) {
+			continue
+		}
+		out = append(out, p)
+		if n > 0 && len(out) >= n {
+			break
+		}
+	}
+	return out
+}
+
+// modelGen generates valid sequences of operations.
+type modelGen struct {
+	rnd   *rand.Rand
+	txs   bool // allow concurrent transactions and rollbacks
+	state modelState
+	views []modelState
+	open  []bool
+	val   int
+}
+
+func (g *modelGen) part(parts []string) []byte {
+	return []byte(parts[g.rnd.Intn(len(parts))])
+}
+
+func (g *modelGen) newKey() kv.Key {
+	first := modelKeyParts[g.rnd.Intn(len(modelKeyParts))]
+	k := kv.Key{[]byte(first.part)}
+	for i := 1; i < first.depth; i++ {
+		if i == first.depth-1 {
+			k = append(k, g.part(modelLeafParts))
+		} else {
+			k = append(k, g.part(modelMidParts))
+		}
+	}
+	return k
+}
+
+// key returns either an existing key or a random one.
+func (g *modelGen) key(view modelState) kv.Key {
+	if len(view) != 0 && g.rnd.Intn(2) == 0 {
+		return view[g.rnd.Intn(len(view))].Key
+	}
+	return g.newKey()
+}
+
+// prefix returns a random prefix of the key, which may end in the middle of the last part.
+func (g *modelGen) prefix(k kv.Key) kv.Key {
+	n := g.rnd.Intn(len(k) + 1)
+	if n == 0 {
+		return nil
+	}
+	pref := k[:n].Clone()
+	last := pref[n-1]
+	pref[n-1] = last[:g.rnd.Intn(len(last)+1)]
+	return pref
+}
+
+func (g *modelGen) readLimit() int {
+	if g.rnd.Intn(3) == 0 {
+		return 0
+	}
+	return 1 + g.rnd.Intn(5)
+}
+
+func (g *modelGen) next() modelOp {
+	slot := 0
+	if g.txs {
+		slot = g.rnd.Intn(len(g.open))
+	}
+	// slot zero is the only writer; other slots are readers
+	rw := slot == 0
+	if !g.open[slot] {
+		g.open[slot] = true
+		g.views[slot] = g.state.clone()
+		return modelOp{kind: mBegin, tx: slot, rw: rw}
+	}
+	view := g.views[slot]
+	op := modelOp{tx: slot}
+	switch r := g.rnd.Intn(100); {
+	case r < 30 && rw:
+		op.kind = mPut
+		op.key = g.key(view)
+		g.val++
+		op.val = kv.Value("v" + strconv.Itoa(g.val))
+		g.views[slot].put(op.key, op.val)
+	case r < 40 && rw:
+		op.kind = mDel
+		op.key = g.key(view)
+		g.views[slot].del(op.key)
+	case r < 55:
+		op.kind = mGet
+		op.key = g.key(view)
+	case r < 60:
+		op.kind = mGetBatch
+		for i := 1 + g.rnd.Intn(4); i > 0; i-- {
+			op.keys = append(op.keys, g.key(view))
+		}
+	case r < 72:
+		op.kind = mScan
+		op.pref = g.prefix(g.key(view))
+		op.n = g.readLimit()
+	case r < 84:
+		op.kind = mSeek
+		op.key = g.key(view)
+		op.pref = g.prefix(op.key)
+		op.n = g.readLimit()
+	case r < 92 && g.txs && rw:
+		op.kind = mRollback
+		g.open[slot] = false
+	default:
+		op.kind = mCommit
+		if !rw {
+			// commit of read-only transaction doesn't change anything
+			op.kind = mRollback
+		} else if op.tx = g.openReader(); op.tx != 0 {
+			// readers are closed before commit, see modelRunner.exec
+			op.kind = mRollback
+			slot = op.tx
+		} else {
+			g.state = view
+		}
+		g.open[slot] = false
+	}
+	return op
+}
+
+// openReader returns the slot of any open read-only transaction, or zero if there are none.
+func (g *modelGen) openReader() int {
+	for i := 1; i < len(g.open); i++ {
+		if g.open[i] {
+			return i
+		}
+	}
+	return 0
+}
+
+func genModelOps(seed int64, n int, txs bool) []modelOp {
+	g := &modelGen{rnd: rand.New(rand.NewSource(seed)), txs: txs}
+	slots := 1
+	if txs {
+		slots = 3
+	}
+	g.views = make([]modelState, slots)
+	g.open = make([]bool, slots)
+	ops := make([]modelOp, 0, n)
+	for len(ops) < n {
+		ops = append(ops, g.next())
+	}
+	return ops
+}
+
+// modelRunner executes operations against the database and the model.
+type modelRunner struct {
+	ctx   context.Context
+	db    kv.KV
+	state modelState
+	txs   []kv.Tx
+	rw    []bool
+	views []modelState
+}
+
+func (r *modelRunner) closeAll() {
+	for i, tx := range r.txs {
+		if tx != nil {
+			_ = tx.Close()
+			r.txs[i] = nil
+		}
+	}
+}
+
+func (r *modelRunner) read(it kv.Iterator, n int) ([]kv.Pair, error) {
+	var out []kv.Pair
+	for (n == 0 || len(out) < n) && it.Next(r.ctx) {
+		out = append(out, kv.Pair{Key: it.Key().Clone(), Val: it.Val().Clone()})
+	}
+	return out, it.Err()
+}
+
+func pairsEqual(a, b []kv.Pair) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key.Compare(b[i].Key) != 0 || !bytes.Equal(a[i].Val, b[i].Val) {
+			return false
+		}
+	}
+	return true
+}
+
+func fmtPairs(pairs []kv.Pair) string {
+	s := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		s = append(s, fmt.Sprintf("%s=%q", fmtKey(p.Key), p.Val))
+	}
+	return "{" + strings.Join(s, ", ") + "}"
+}
+
+// exec runs a single operation. Operations that are not valid in the current state (for example, after minimization)
+// are skipped.
+func (r *modelRunner) exec(op modelOp) error {
+	ctx := r.ctx
+	if op.tx >= len(r.txs) {
+		return nil
+	}
+	tx := r.txs[op.tx]
+	if (op.kind == mBegin) != (tx == nil) {
+		return nil
+	}
+	if (op.kind == mPut || op.kind == mDel) && !r.rw[op.tx] {
+		return nil
+	}
+	view := r.views[op.tx]
+	switch op.kind {
+	case mBegin:
+		tx, err := r.db.Tx(ctx, op.rw)
+		if err != nil {
+			return err
+		}
+		r.txs[op.tx], r.rw[op.tx] = tx, op.rw
+		r.views[op.tx] = r.state.clone()
+	case mCommit:
+		// some stores (like Bolt) may deadlock when committing while read-only transactions are open
+		// in the same goroutine, thus readers are always closed first
+		for i, rtx := range r.txs {
+			if rtx != nil && i != op.tx {
+				if err := rtx.Close(); err != nil {
+					return err
+				}
+				r.txs[i] = nil
+			}
+		}
+		r.txs[op.tx] = nil
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		if r.rw[op.tx] {
+			r.state = view
+		}
+	case mRollback:
+		r.txs[op.tx] = nil
+		return tx.Close()
+	case mPut:
+		r.views[op.tx].put(op.key, op.val)
+		return tx.Put(ctx, op.key, op.val)
+	case mDel:
+		r.views[op.tx].del(op.key)
+		return tx.Del(ctx, op.key)
+	case mGet:
+		got, err := tx.Get(ctx, op.key)
+		exp, ok := view.get(op.key)
+		if !ok {
+			if err != kv.ErrNotFound {
+				return fmt.Errorf("expected not found, got %q (err: %v)", got, err)
+			}
+		} else if err != nil {
+			return err
+		} else if !bytes.Equal(exp, got) {
+			return fmt.Errorf("expected %q, got %q", exp, got)
+		}
+	case mGetBatch:
+		got, err := tx.GetBatch(ctx, op.keys)
+		if err != nil {
+			return err
+		} else if len(got) != len(op.keys) {
+			return fmt.Errorf("expected %d values, got %d", len(op.keys), len(got))
+		}
+		for i, k := range op.keys {
+			exp, ok := view.get(k)
+			if ok != (got[i] != nil) || !bytes.Equal(exp, got[i]) {
+				return fmt.Errorf("key %s: expected %q, got %q", fmtKey(k), exp, got[i])
+			}
+		}
+	case mScan, mSeek:
+		var opts []kv.IteratorOption
+		if op.pref != nil {
+			opts = append(opts, options.WithPrefixKV(op.pref))
+		}
+		it := tx.Scan(ctx, opts...)
+		defer it.Close()
+		var got []kv.Pair
+		if op.kind == mSeek && kv.Seek(ctx, it, op.key) {
+			got = append(got, kv.Pair{Key: it.Key().Clone(), Val: it.Val().Clone()})
+			if op.n == 0 || op.n > 1 {
+				n := op.n
+				if n != 0 {
+					n--
+				}
+				rest, err := r.read(it, n)
+				if err != nil {
+					return err
+				}
+				got = append(got, rest...)
+			}
+		} else if op.kind == mScan {
+			var err error
+			if got, err = r.read(it, op.n); err != nil {
+				return err
+			}
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+		exp := view.scan(op.pref, op.key, op.n)
+		if !pairsEqual(exp, got) {
+			return fmt.Errorf("expected %s,\ngot %s", fmtPairs(exp), fmtPairs(got))
+		}
+	}
+	return nil
+}
+
+// verify checks that committed state of the database matches the model.
+func (r *modelRunner) verify() error {
+	var got []kv.Pair
+	err := kv.View(r.ctx, r.db, func(tx kv.Tx) error {
+		it := tx.Scan(r.ctx)
+		defer it.Close()
+		var err error
+		got, err = r.read(it, 0)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !pairsEqual(r.state, got) {
+		return fmt.Errorf("unexpected committed state: expected %s,\ngot %s", fmtPairs(r.state), fmtPairs(got))
+	}
+	return nil
+}
+
+// modelFailure describes the failing operation in a sequence.
+type modelFailure struct {
+	op  int // index of the failed operation; equal to the number of operations if the final check failed
+	err error
+}
+
+// runModel executes the sequence on an empty database. The database is cleared afterwards.
+// An error is returned if the database cannot be cleared.
+func runModel(ctx context.Context, db kv.KV, ops []modelOp, slots int) (*modelFailure, error) {
+	r := &modelRunner{
+		ctx: ctx, db: db,
+		txs:   make([]kv.Tx, slots),
+		rw:    make([]bool, slots),
+		views: make([]modelState, slots),
+	}
+	defer func() {
+		r.closeAll()
+	}()
+	if err := r.verify(); err != nil {
+		// database was not cleared properly; results of the run will be meaningless
+		return nil, err
+	}
+	for i, op := range ops {
+		if err := r.exec(op); err != nil {
+			r.closeAll()
+			return &modelFailure{op: i, err: err}, clearDB(ctx, db)
+		}
+	}
+	if slots == 1 && r.txs[0] != nil && r.rw[0] {
+		// stores without proper transactions may not support rollbacks
+		if err := r.exec(modelOp{kind: mCommit}); err != nil {
+			r.closeAll()
+			return &modelFailure{op: len(ops), err: err}, clearDB(ctx, db)
+		}
+	}
+	r.closeAll()
+	if err := r.verify(); err != nil {
+		return &modelFailure{op: len(ops), err: err}, clearDB(ctx, db)
+	}
+	return nil, clearDB(ctx, db)
+}
+
+// clearDB removes all keys from the database.
+func clearDB(ctx context.Context, db kv.KV) error {
+	return kv.Update(ctx, db, func(tx kv.Tx) error {
+		var keys []kv.Key
+		err := kv.Each(ctx, tx, func(k kv.Key, _ kv.Value) error {
+			keys = append(keys, k.Clone())
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err = tx.Del(ctx, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+var errMinimizeLimit = errors.New("minimization limit reached")
+
+// minimizeModel removes operations from the failing sequence while it still fails.
+func minimizeModel(ctx context.Context, db kv.KV, ops []modelOp, f *modelFailure, slots int) ([]modelOp, *modelFailure, error) {
+	// drop everything after the failed operation
+	if f.op < len(ops) {
+		ops = ops[:f.op+1]
+	}
+	runs := 0
+	for chunk := len(ops) / 2; chunk >= 1; {
+		removed := false
+		for i := 0; i+chunk <= len(ops); {
+			if runs >= modelMinimize {
+				return ops, f, errMinimizeLimit
+			}
+			runs++
+			cand := append(append([]modelOp{}, ops[:i]...), ops[i+chunk:]...)
+			f2, err := runModel(ctx, db, cand, slots)
+			if err != nil {
+				return ops, f, err
+			}
+			if f2 != nil {
+				ops, f, removed = cand, f2, true
+				if f.op < len(ops) {
+					ops = ops[:f.op+1]
+				}
+				continue
+			}
+			i += chunk
+		}
+		if !removed {
+			chunk /= 2
+		}
+	}
+	return ops, f, nil
+}
+
+func fmtModelOps(ops []modelOp, failed int) string {
+	var sb strings.Builder
+	for i, op := range ops {
+		mark := "  "
+		if i == failed {
+			mark = "> "
+		}
+		sb.WriteString(mark + op.String() + "\n")
+	}
+	return sb.String()
+}
+
+func randomModel(t testing.TB, db kv.KV, txs bool) {
+	ctx := context.Background()
+	slots := 1
+	if txs {
+		slots = 3
+	}
+	runs := modelRuns
+	if testing.Short() {
+		runs = 2
+	}
+	for seed := int64(1); seed <= int64(runs); seed++ {
+		ops := genModelOps(seed, modelSteps, txs)
+		f, err := runModel(ctx, db, ops, slots)
+		if err != nil {
+			t.Fatalf("seed %d: cannot prepare the database: %v", seed, err)
+		} else if f == nil {
+			continue
+		}
+		min, mf, err := minimizeModel(ctx, db, ops, f, slots)
+		note := ""
+		if err != nil {
+			note = fmt.Sprintf(" (not fully minimized: %v)", err)
+		}
+		t.Fatalf("seed %d: operation failed: %v\nsequence%s:\n%s", seed, mf.err, note, fmtModelOps(min, mf.op))
+	}
+}
+
+// random checks the database against the model using one transaction at a time.
+func random(t testing.TB, db kv.KV) {
+	randomModel(t, db, false)
+}
+
+// randomTx checks the database against the model with concurrent read-only transactions and rollbacks.
+func randomTx(t testing.TB, db kv.KV) {
+	randomModel(t, db, true)
+}
@@ -0,0 +1,570 @@
+// Package mem provides a native in-memory implementation of hierarchical key-value store.
+//
+// Buckets are stored as nested ordered maps and follow the same semantics as Bolt buckets:
+// a key part can either be a value or a nested bucket, but not both at the same time.
+// Read-only transactions operate on a snapshot of the database, while read-write
+// transactions are serialized, similar to Bolt.
+package mem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+)
+
+const (
+	Name = "mem"
+)
+
+// caps are the capabilities of the driver.
+const caps = base.CapTx | base.CapConcurrentWrites | base.CapSeek | base.CapPrefixScan | base.CapBatchGet
+
+func init() {
+	kv.Register(kv.Registration{
+		Registration: base.Registration{
+			Name: Name, Title: "In-memory",
+			Local: true, Volatile: true, Capabilities: caps,
+		},
+		OpenPath: func(path string) (kv.KV, error) {
+			if path != "" {
+				return nil, base.ErrVolatile
+			}
+			return New(), nil
+		},
+	})
+}
+
+var (
+	// ErrIncompatibleValue is returned when trying to write a value to a bucket key or vice versa.
+	ErrIncompatibleValue = errors.New("mem: incompatible value")
+	// ErrKeyRequired is returned when trying to write a value with an empty key.
+	ErrKeyRequired = errors.New("mem: key required")
+	// ErrBucketNameRequired is returned when one of the bucket names in the key is empty.
+	ErrBucketNameRequired = errors.New("mem: bucket name required")
+	// ErrTxClosed is returned when committing a transaction that was already closed.
+	ErrTxClosed = errors.New("mem: tx closed")
+	// ErrClosed is returned when opening a transaction on a closed database.
+	ErrClosed = errors.New("mem: database closed")
+)
+
+var _ kv.KV = (*DB)(nil)
+
+// New creates a new in-memory hierarchical key-value store.
+func New() *DB {
+	return &DB{root: &bucket{}}
+}
+
+type DB struct {
+	wmu sync.Mutex // single writer lock
+
+	mu     sync.RWMutex
+	root   *bucket
+	gen    uint64
+	closed bool
+}
+
+func (db *DB) Close() error {
+	db.mu.Lock()
+	db.closed = true
+	db.mu.Unlock()
+	return nil
+}
+
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
+func (db *DB) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if rw {
+		db.wmu.Lock()
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		if rw {
+			db.wmu.Unlock()
+		}
+		return nil, ErrClosed
+	}
+	tx := &Tx{db: db, root: db.root, rw: rw}
+	if rw {
+		db.gen++
+		tx.gen = db.gen
+	}
+	return tx, nil
+}
+
+func (db *DB) View(ctx context.Context, fn func(tx kv.Tx) error) error {
+	return kv.View(ctx, db, fn)
+}
+
+func (db *DB) Update(ctx context.Context, fn func(tx kv.Tx) error) error {
+	return kv.Update(ctx, db, fn)
+}
+
+type entry struct {
+	key []byte
+	val kv.Value
+	sub *bucket // set if the entry is a bucket
+}
+
+// bucket is a sorted list of entries. Committed buckets are never modified, instead
+// write transactions make a copy of all buckets on the path to the modified key.
+type bucket struct {
+	gen  uint64 // generation of the transaction that owns this bucket
+	mod  int    // modification counter, used to revalidate iterators
+	ents []entry
+}
+
+// search returns an index of the first entry with a key greater or equal to a given one.
+func (b *bucket) search(k []byte) (int, bool) {
+	i := sort.Search(len(b.ents), func(i int) bool {
+		return bytes.Compare(b.ents[i].key, k) >= 0
+	})
+	return i, i < len(b.ents) && bytes.Equal(b.ents[i].key, k)
+}
+
+func (b *bucket) get(k []byte) *entry {
+	if b == nil {
+		return nil
+	}
+	i, ok := b.search(k)
+	if !ok {
+		return nil
+	}
+	return &b.ents[i]
+}
+
+func (b *bucket) clone(gen uint64) *bucket {
+	return &bucket{gen: gen, ents: append([]entry{}, b.ents...)}
+}
+
+func (b *bucket) insert(i int, e entry) {
+	b.ents = append(b.ents, entry{})
+	copy(b.ents[i+1:], b.ents[i:])
+	b.ents[i] = e
+	b.mod++
+}
+
+func (b *bucket) remove(i int) {
+	b.ents = append(b.ents[:i], b.ents[i+1:]...)
+	b.mod++
+}
+
+type Tx struct {
+	db   *DB
+	root *bucket
+	gen  uint64
+	rw   bool
+	done bool
+}
+
+func (tx *Tx) release() {
+	if tx.done {
+		return
+	}
+	tx.done = true
+	if tx.rw {
+		tx.db.wmu.Unlock()
+	}
+}
+
+func (tx *Tx) Commit(ctx context.Context) error {
+	if tx.done {
+		return ErrTxClosed
+	} else if !tx.rw {
+		return kv.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		tx.release()
+		return err
+	}
+	tx.db.mu.Lock()
+	tx.db.root = tx.root
+	tx.db.mu.Unlock()
+	tx.release()
+	return nil
+}
+
+func (tx *Tx) Close() error {
+	tx.release()
+	return nil
+}
+
+// bucket finds a bucket that holds the last part of the key.
+// It returns nil if one of the buckets does not exist.
+func (tx *Tx) bucket(key kv.Key) *bucket {
+	b := tx.root
+	for len(key) > 1 {
+		e := b.get(key[0])
+		if e == nil || e.sub == nil {
+			return nil
+		}
+		b = e.sub
+		key = key[1:]
+	}
+	return b
+}
+
+func (tx *Tx) entry(key kv.Key) *entry {
+	if len(key) == 0 {
+		return nil
+	}
+	return tx.bucket(key).get(key[len(key)-1])
+}
+
+// writable returns a copy of the bucket owned by this transaction.
+func (tx *Tx) writable(b *bucket) *bucket {
+	if b.gen == tx.gen {
+		return b
+	}
+	return b.clone(tx.gen)
+}
+
+// bucketRW is similar to bucket, but returns a writable bucket.
+// If create is set, missing buckets will be created, or nil is returned otherwise.
+func (tx *Tx) bucketRW(path kv.Key, create bool) (*bucket, error) {
+	tx.root = tx.writable(tx.root)
+	b := tx.root
+	for _, name := range path {
+		if len(name) == 0 {
+			return nil, ErrBucketNameRequired
+		}
+		i, ok := b.search(name)
+		if !ok {
+			if !create {
+				return nil, nil
+			}
+			sub := &bucket{gen: tx.gen}
+			b.insert(i, entry{key: append([]byte{}, name...), sub: sub})
+			b = sub
+			continue
+		}
+		e := &b.ents[i]
+		if e.sub == nil {
+			if !create {
+				return nil, nil
+			}
+			return nil, ErrIncompatibleValue
+		}
+		e.sub = tx.writable(e.sub)
+		b = e.sub
+	}
+	return b, nil
+}
+
+func (tx *Tx) Get(ctx context.Context, key kv.Key) (kv.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e := tx.entry(key)
+	if e == nil || e.sub != nil {
+		return nil, kv.ErrNotFound
+	}
+	return e.val.Clone(), nil
+}
+
+func (tx *Tx) GetBatch(ctx context.Context, keys []kv.Key) ([]kv.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vals := make([]kv.Value, len(keys))
+	for i, k := range keys {
+		if e := tx.entry(k); e != nil && e.sub == nil {
+			vals[i] = e.val.Clone()
+		}
+	}
+	return vals, nil
+}
+
+func (tx *Tx) Put(ctx context.Context, k kv.Key, v kv.Value) error {
+	if tx.done {
+		return ErrTxClosed
+	} else if !tx.rw {
+		return kv.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		return err
+	} else if len(k) == 0 {
+		return ErrKeyRequired
+	}
+	b, err := tx.bucketRW(k[:len(k)-1], true)
+	if err != nil {
+		return err
+	}
+	name := k[len(k)-1]
+	if len(name) == 0 {
+		if len(v) == 0 {
+			return nil // bucket creation, no need to put value
+		}
+		return ErrKeyRequired
+	}
+	// never store nil values, since GetBatch uses nil to indicate missing keys
+	v = append(kv.Value{}, v...)
+	i, ok := b.search(name)
+	if !ok {
+		b.insert(i, entry{key: append([]byte{}, name...), val: v})
+		return nil
+	}
+	e := &b.ents[i]
+	if e.sub != nil {
+		return ErrIncompatibleValue
+	}
+	e.val = v
+	b.mod++
+	return nil
+}
+
+func (tx *Tx) Del(ctx context.Context, k kv.Key) error {
+	if tx.done {
+		return ErrTxClosed
+	} else if !tx.rw {
+		return kv.ErrReadOnly
+	} else if err := ctx.Err(); err != nil {
+		return err
+	} else if len(k) == 0 {
+		return nil
+	}
+	if e := tx.entry(k); e == nil {
+		return nil
+	} else if e.sub != nil {
+		return ErrIncompatibleValue
+	}
+	b, err := tx.bucketRW(k[:len(k)-1], false)
+	if err != nil || b == nil {
+		return err
+	}
+	if i, ok := b.search(k[len(k)-1]); ok {
+		b.remove(i)
+	}
+	return nil
+}
+
+func (tx *Tx) Scan(ctx context.Context, opts ...kv.IteratorOption) kv.Iterator {
+	var it kv.Iterator = &Iterator{tx: tx, rootb: tx.root}
+	return kv.ApplyIteratorOptions(it, opts)
+}
+
+var (
+	_ kv.Seeker         = &Iterator{}
+	_ kv.PrefixIterator = &Iterator{}
+)
+
+// frame is a position of the iterator in a single bucket.
+type frame struct {
+	b   *bucket
+	i   int
+	key []byte // current key; used to find the position if bucket is modified
+	mod int
+}
+
+func (f *frame) valid() bool {
+	return f.i < len(f.b.ents)
+}
+
+func (f *frame) cur() *entry {
+	return &f.b.ents[f.i]
+}
+
+// sync records the current position of the frame.
+func (f *frame) sync() {
+	f.mod = f.b.mod
+	if f.valid() {
+		f.key = f.cur().key
+	}
+}
+
+// next advances the frame to the next key in the bucket.
+func (f *frame) next() {
+	if f.mod != f.b.mod {
+		// bucket was modified, find the next key after the current one
+		i, ok := f.b.search(f.key)
+		if ok {
+			i++
+		}
+		f.i = i
+	} else {
+		f.i++
+	}
+	f.sync()
+}
+
+type Iterator struct {
+	tx    *Tx
+	rootb *bucket // bucket to start iteration from
+	rootk kv.Key  // path to the root bucket
+	pref  []byte  // binary prefix for keys in the root bucket
+	stack []frame
+	start bool
+	done  bool
+	err   error
+}
+
+func (it *Iterator) Reset() {
+	it.stack = it.stack[:0]
+	it.start = false
+	it.done = false
+	it.err = nil
+}
+
+func (it *Iterator) WithPrefix(pref kv.Key) kv.Iterator {
+	it.Reset()
+	it.rootk, it.pref = nil, nil
+	it.rootb = it.tx.root
+	if len(pref) == 0 {
+		return it
+	}
+	b := it.tx.bucket(pref)
+	if b == nil {
+		// a bucket mentioned in the prefix does not exists,
+		// we can safely return an empty iterator
+		it.rootb = nil
+		return it
+	}
+	it.rootb = b
+	it.rootk = pref[:len(pref)-1].Clone()
+	it.pref = append([]byte{}, pref[len(pref)-1]...)
+	return it
+}
+
+func (it *Iterator) push(b *bucket, i int) {
+	f := frame{b: b, i: i}
+	f.sync()
+	it.stack = append(it.stack, f)
+}
+
+// settle moves the iterator forward until it points to a value.
+func (it *Iterator) settle() bool {
+	for len(it.stack) > 0 {
+		f := &it.stack[len(it.stack)-1]
+		if !f.valid() {
+			it.stack = it.stack[:len(it.stack)-1]
+			if len(it.stack) != 0 {
+				it.stack[len(it.stack)-1].next()
+			}
+			continue
+		}
+		e := f.cur()
+		if len(it.stack) == 1 && !bytes.HasPrefix(e.key, it.pref) {
+			// keys are sorted, and we reached the end of the prefix
+			break
+		}
+		if e.sub != nil {
+			it.push(e.sub, 0)
+			continue
+		}
+		return true
+	}
+	it.stack = it.stack[:0]
+	it.done = true
+	return false
+}
+
+// stop stops the iterator if the context is canceled.
+func (it *Iterator) stop(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		it.stack = it.stack[:0]
+		it.done = true
+		it.err = err
+		return true
+	}
+	return false
+}
+
+func (it *Iterator) Next(ctx context.Context) bool {
+	if it.done || it.rootb == nil || it.stop(ctx) {
+		return false
+	}
+	if !it.start {
+		it.start = true
+		i, _ := it.rootb.search(it.pref)
+		it.push(it.rootb, i)
+	} else if len(it.stack) != 0 {
+		it.stack[len(it.stack)-1].next()
+	}
+	return it.settle()
+}
+
+func (it *Iterator) Seek(ctx context.Context, key kv.Key) bool {
+	it.Reset()
+	if it.rootb == nil || it.stop(ctx) {
+		return false
+	}
+	it.start = true
+	n := len(it.rootk)
+	if len(key) < n || key[:n].Compare(it.rootk) != 0 {
+		if key.Compare(it.rootk) < 0 {
+			// seek to the beginning
+			it.start = false
+			return it.Next(ctx)
+		}
+		it.done = true
+		return false
+	}
+	key = key[n:]
+	b := it.rootb
+	i, _ := b.search(it.pref)
+	for {
+		if len(key) == 0 {
+			it.push(b, i)
+			break
+		}
+		j, ok := b.search(key[0])
+		if j < i {
+			// seek key is before the prefix
+			it.push(b, i)
+			break
+		}
+		it.push(b, j)
+		if !ok {
+			break
+		}
+		e := &b.ents[j]
+		if e.sub == nil {
+			if len(key) > 1 {
+				// the key is shorter than the seek key, thus it's less than it
+				it.stack[len(it.stack)-1].next()
+			}
+			break
+		}
+		b, i = e.sub, 0
+		key = key[1:]
+	}
+	return it.settle()
+}
+
+func (it *Iterator) Key() kv.Key {
+	if len(it.stack) == 0 {
+		return nil
+	}
+	k := make(kv.Key, 0, len(it.rootk)+len(it.stack))
+	k = append(k, it.rootk...)
+	for _, f := range it.stack {
+		k = append(k, f.cur().key)
+	}
+	return k
+}
+
+func (it *Iterator) Val() kv.Value {
+	if len(it.stack) == 0 {
+		return nil
+	}
+	return it.stack[len(it.stack)-1].cur().val
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Close() error {
+	it.stack = nil
+	it.done = true
+	return nil
+}
@@ -0,0 +1,178 @@
+package mem
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/bolt"
+	"github.com/hidal-go/hidalgo/kv/kvtest"
+	"github.com/hidal-go/hidalgo/kv/options"
+)
+
+func TestMem(t *testing.T) {
+	kvtest.RunTest(t, func(t testing.TB) kv.KV {
+		return New()
+	}, nil)
+}
+
+func BenchmarkMem(b *testing.B) {
+	kvtest.RunBenchmarks(b, func(t testing.TB) kv.KV {
+		return New()
+	}, nil)
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	td := kvtest.NewTest(t, db)
+
+	k1, k2 := kv.SKey("a", "b"), kv.SKey("a", "c")
+	td.Put(k1, kv.Value("1"))
+
+	ro, err := db.Tx(ctx, false)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	td.Put(k1, kv.Value("2"))
+	td.Put(k2, kv.Value("3"))
+
+	// read-only transaction must not see later writes
+	v, err := ro.Get(ctx, k1)
+	require.NoError(t, err)
+	require.Equal(t, kv.Value("1"), v)
+	_, err = ro.Get(ctx, k2)
+	require.Equal(t, kv.ErrNotFound, err)
+	td.ExpectIt(ro.Scan(ctx), []kv.Pair{
+		{Key: k1, Val: kv.Value("1")},
+	})
+
+	// rolled back writes are not visible
+	rw, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	require.NoError(t, rw.Put(ctx, k1, kv.Value("4")))
+	require.NoError(t, rw.Close())
+	td.Expect(k1, kv.Value("2"))
+}
+
+func TestDeleteWhileIterating(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	td := kvtest.NewTest(t, db)
+	for _, k := range []kv.Key{
+		kv.SKey("a"), kv.SKey("b", "a"), kv.SKey("b", "b"), kv.SKey("b", "c"), kv.SKey("c"),
+	} {
+		td.Put(k, kv.Value("v"))
+	}
+	err := kv.Update(ctx, db, func(tx kv.Tx) error {
+		return kv.Each(ctx, tx, func(k kv.Key, _ kv.Value) error {
+			return tx.Del(ctx, k)
+		})
+	})
+	require.NoError(t, err)
+	td.Scan(nil)
+}
+
+// TestBoltCompat runs the same operations on Bolt and in-memory store and compares the results.
+func TestBoltCompat(t *testing.T) {
+	ctx := context.Background()
+	bdb, err := bolt.OpenPath(filepath.Join(t.TempDir(), "bolt.db"))
+	require.NoError(t, err)
+	defer bdb.Close()
+	mdb := New()
+
+	type op struct {
+		del bool
+		key kv.Key
+		val string
+	}
+	ops := []op{
+		{key: kv.SKey("a"), val: "1"},
+		{key: kv.SKey("a", "b"), val: "2"}, // incompatible value
+		{key: kv.SKey("c", "d"), val: "3"},
+		{key: kv.SKey("c", "d", "e"), val: "3"}, // incompatible value
+		{key: kv.SKey("c"), val: "4"},           // incompatible value
+		{del: true, key: kv.SKey("c")},          // incompatible value
+		{key: kv.SKey("c", "a"), val: "5"},
+		{key: kv.Key{[]byte("c"), nil}},           // create existing bucket
+		{key: kv.Key{[]byte("e"), nil}},           // create empty bucket
+		{key: kv.Key{[]byte("f"), nil}, val: "6"}, // key required
+		{key: kv.Key{nil, []byte("x")}, val: "7"}, // bucket name required
+		{key: kv.SKey("g", "h", "i"), val: "8"},
+		{key: kv.SKey("g", "h\x00"), val: "9"},
+		{key: kv.SKey("g", "h", "i"), val: "10"},
+		{del: true, key: kv.SKey("x", "y")},
+		{del: true, key: kv.SKey("c", "a")},
+	}
+	for _, o := range ops {
+		var errs [2]error
+		for i, db := range []kv.KV{bdb, mdb} {
+			errs[i] = kv.Update(ctx, db, func(tx kv.Tx) error {
+				if o.del {
+					return tx.Del(ctx, o.key)
+				}
+				var v kv.Value
+				if o.val != "" {
+					v = kv.Value(o.val)
+				}
+				return tx.Put(ctx, o.key, v)
+			})
+		}
+		require.Equal(t, errs[0] != nil, errs[1] != nil, "%q: %v vs %v", o.key, errs[0], errs[1])
+	}
+
+	scan := func(db kv.KV, opts ...kv.IteratorOption) []kv.Pair {
+		var out []kv.Pair
+		err := kv.View(ctx, db, func(tx kv.Tx) error {
+			return kv.Each(ctx, tx, func(k kv.Key, v kv.Value) error {
+				out = append(out, kv.Pair{Key: k.Clone(), Val: v.Clone()})
+				return nil
+			}, opts...)
+		})
+		require.NoError(t, err)
+		return out
+	}
+	prefixes := []kv.Key{
+		nil,
+		kv.SKey("a"),
+		kv.SKey("c"),
+		kv.SKey("e"),
+		kv.SKey("g"),
+		kv.SKey("g", "h"),
+		kv.SKey("g", "h", ""),
+		kv.SKey("x", "y"),
+	}
+	for _, pref := range prefixes {
+		var opts []kv.IteratorOption
+		if pref != nil {
+			opts = append(opts, options.WithPrefixKV(pref))
+		}
+		require.Equal(t, scan(bdb, opts...), scan(mdb, opts...), "prefix: %q", pref)
+	}
+
+	seek := func(db kv.KV, key kv.Key) kv.Key {
+		var out kv.Key
+		err := kv.View(ctx, db, func(tx kv.Tx) error {
+			it := tx.Scan(ctx, options.WithPrefixKV(kv.SKey("g")))
+			defer it.Close()
+			if kv.Seek(ctx, it, key) {
+				out = it.Key().Clone()
+			}
+			return it.Err()
+		})
+		require.NoError(t, err)
+		return out
+	}
+	for _, key := range []kv.Key{
+		kv.SKey("g"),
+		kv.SKey("g", "h"),
+		kv.SKey("g", "h", "j"),
+		kv.SKey("g", "h\x00"),
+		kv.SKey("g", "i"),
+	} {
+		require.Equal(t, seek(bdb, key), seek(mdb, key), "seek: %q", key)
+	}
+}
@@ -20,3 +20,24 @@ type IteratorOption interface {
 	// ApplyFlat option to the flat KV iterator. Implementation may wrap or replace the iterator.
 	ApplyFlat(it flat.Iterator) flat.Iterator
 }
+
+// keyFormat returns the key format to convert the prefix for the iterator.
+// Explicitly set format is preferred, then the format of the iterator. Iterators that don't report
+// the format use the legacy one, the same as before key formats were versioned (see flat.DetectKeyFormat).
+func keyFormat(f flat.KeyFormat, it interface{}) flat.KeyFormat {
+	if f != 0 {
+		return f
+	}
+	if f = keyFormatOf(it); f != 0 {
+		return f
+	}
+	return flat.KeyFormatV1
+}
+
+// keyFormatOf returns the key format of the iterator, or zero if it's unknown.
+func keyFormatOf(it interface{}) flat.KeyFormat {
+	if it, ok := it.(flat.KeyFormatter); ok {
+		return it.KeyFormat()
+	}
+	return 0
+}
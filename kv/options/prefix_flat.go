@@ -17,6 +17,9 @@ func WithPrefixFlat(pref flat.Key) IteratorOption {
 // PrefixFlat implements IteratorOption. See WithPrefixFlat.
 type PrefixFlat struct {
 	Pref flat.Key
+	// Format is used to unescape the prefix when the option is applied to the KV iterator.
+	// If not set, the format of the iterator is used (see flat.KeyFormatter), or flat.KeyFormatV1.
+	Format flat.KeyFormat
 }
 
 func (opt PrefixFlat) ApplyFlat(it flat.Iterator) flat.Iterator {
@@ -26,15 +29,19 @@ func (opt PrefixFlat) ApplyFlat(it flat.Iterator) flat.Iterator {
 	return &prefixIteratorFlat{base: it, pref: opt.Pref}
 }
 
+// ApplyKV applies the option to the KV iterator. The prefix is unescaped using the key format of the iterator.
 func (opt PrefixFlat) ApplyKV(it kv.Iterator) kv.Iterator {
-	pref := flat.KeyUnescape(opt.Pref)
+	pref := keyFormat(opt.Format, it).Unescape(opt.Pref)
 	if it, ok := it.(kv.PrefixIterator); ok {
 		return it.WithPrefix(pref)
 	}
 	return &prefixIteratorKV{base: it, pref: pref}
 }
 
-var _ flat.PrefixIterator = &prefixIteratorFlat{}
+var (
+	_ flat.PrefixIterator = &prefixIteratorFlat{}
+	_ flat.KeyFormatter   = &prefixIteratorFlat{}
+)
 
 type prefixIteratorFlat struct {
 	base flat.Iterator
@@ -88,6 +95,10 @@ func (it *prefixIteratorFlat) Next(ctx context.Context) bool {
 	return false
 }
 
+func (it *prefixIteratorFlat) KeyFormat() flat.KeyFormat {
+	return keyFormatOf(it.base)
+}
+
 func (it *prefixIteratorFlat) Err() error {
 	return it.base.Err()
 }
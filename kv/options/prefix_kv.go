@@ -16,6 +16,9 @@ func WithPrefixKV(pref kv.Key) IteratorOption {
 // PrefixKV implements IteratorOption. See WithPrefixKV.
 type PrefixKV struct {
 	Pref kv.Key
+	// Format is used to escape the prefix when the option is applied to the flat KV iterator.
+	// If not set, the format of the iterator is used (see flat.KeyFormatter), or flat.KeyFormatV1.
+	Format flat.KeyFormat
 }
 
 func (opt PrefixKV) ApplyKV(it kv.Iterator) kv.Iterator {
@@ -25,15 +28,19 @@ func (opt PrefixKV) ApplyKV(it kv.Iterator) kv.Iterator {
 	return &prefixIteratorKV{base: it, pref: opt.Pref}
 }
 
+// ApplyFlat applies the option to the flat KV iterator. The prefix is escaped using the key format of the iterator.
 func (opt PrefixKV) ApplyFlat(it flat.Iterator) flat.Iterator {
-	pref := flat.KeyEscape(opt.Pref)
+	pref := keyFormat(opt.Format, it).Escape(opt.Pref)
 	if it, ok := it.(flat.PrefixIterator); ok {
 		return it.WithPrefix(pref)
 	}
 	return &prefixIteratorFlat{base: it, pref: pref}
 }
 
-var _ kv.PrefixIterator = &prefixIteratorKV{}
+var (
+	_ kv.PrefixIterator = &prefixIteratorKV{}
+	_ flat.KeyFormatter = &prefixIteratorKV{}
+)
 
 type prefixIteratorKV struct {
 	base kv.Iterator
@@ -87,6 +94,10 @@ func (it *prefixIteratorKV) Next(ctx context.Context) bool {
 	return false
 }
 
+func (it *prefixIteratorKV) KeyFormat() flat.KeyFormat {
+	return keyFormatOf(it.base)
+}
+
 func (it *prefixIteratorKV) Err() error {
 	return it.base.Err()
 }
@@ -0,0 +1,464 @@
+package remote
+
+import (
+	"context"
+	"encoding/gob"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+)
+
+const (
+	Name = "remote"
+)
+
+// caps are the capabilities implemented by the client itself.
+// Other capabilities depend on the database served by the remote server.
+const caps = base.CapSeek | base.CapPrefixScan | base.CapBatchGet
+
+// serverCaps are the capabilities reported by the server that are preserved by the client.
+const serverCaps = base.CapTx | base.CapConcurrentWrites | base.CapConflicts
+
+func init() {
+	kv.Register(kv.Registration{
+		Registration: base.Registration{
+			Name: Name, Title: "Remote",
+			Local: false, Capabilities: caps,
+		},
+		OpenPath: OpenPath,
+	})
+}
+
+const defaultMaxIdle = 4
+
+// Options configures the client.
+type Options struct {
+	// Token is an authentication token sent to the server.
+	Token string
+	// Dialer is used to establish connections. Default is a TCP dialer.
+	Dialer func(ctx context.Context, addr string) (net.Conn, error)
+	// MaxIdle is the number of idle connections kept in the pool. Default is 4.
+	MaxIdle int
+	// Batch is the number of key-value pairs fetched in one request during iteration. Default is 128.
+	Batch int
+}
+
+var _ kv.KV = (*DB)(nil)
+
+// Dial connects to a remote database server. Options can be nil.
+func Dial(ctx context.Context, addr string, opts *Options) (*DB, error) {
+	db := &DB{addr: addr}
+	if opts != nil {
+		db.opts = *opts
+	}
+	if db.opts.Dialer == nil {
+		var d net.Dialer
+		db.opts.Dialer = func(ctx context.Context, addr string) (net.Conn, error) {
+			return d.DialContext(ctx, "tcp", addr)
+		}
+	}
+	if db.opts.MaxIdle <= 0 {
+		db.opts.MaxIdle = defaultMaxIdle
+	}
+	if db.opts.Batch <= 0 {
+		db.opts.Batch = defaultBatch
+	}
+	// make sure the server is reachable and accepts the token
+	c, err := db.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db.caps = caps | c.caps&serverCaps
+	db.put(c)
+	return db, nil
+}
+
+// OpenPath connects to a remote database server. Path has the following format: [token@]host:port.
+func OpenPath(path string) (kv.KV, error) {
+	var opts Options
+	if i := strings.LastIndexByte(path, '@'); i >= 0 {
+		opts.Token, path = path[:i], path[i+1:]
+	}
+	db, err := Dial(context.Background(), path, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// DB is a client for a remote key-value database.
+type DB struct {
+	addr string
+	opts Options
+	caps base.Capabilities
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// conn is a single client session.
+type conn struct {
+	c      net.Conn
+	enc    *gob.Encoder
+	dec    *gob.Decoder
+	broken bool
+	caps   base.Capabilities // reported by the server
+}
+
+func (db *DB) dial(ctx context.Context) (*conn, error) {
+	nc, err := db.opts.Dialer(ctx, db.addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{c: nc, enc: gob.NewEncoder(nc), dec: gob.NewDecoder(nc)}
+	var resp response
+	err = c.roundTrip(ctx, &hello{Version: protocolVersion, Token: db.opts.Token}, &resp)
+	if err == nil {
+		err = resp.error()
+	}
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	c.caps = resp.Caps
+	return c, nil
+}
+
+// get returns an idle connection from the pool or dials a new one.
+func (db *DB) get(ctx context.Context) (*conn, error) {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if n := len(db.idle); n > 0 {
+		c := db.idle[n-1]
+		db.idle = db.idle[:n-1]
+		db.mu.Unlock()
+		return c, nil
+	}
+	db.mu.Unlock()
+	return db.dial(ctx)
+}
+
+// put returns the connection to the pool.
+func (db *DB) put(c *conn) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if c.broken || db.closed || len(db.idle) >= db.opts.MaxIdle {
+		c.c.Close()
+		return
+	}
+	db.idle = append(db.idle, c)
+}
+
+// roundTrip sends a message and waits for the response. The deadline of the context is applied to the connection.
+// If the request fails or is interrupted, the connection is marked as broken, since its state is unknown.
+func (c *conn) roundTrip(ctx context.Context, req interface{}, resp *response) error {
+	if c.broken {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	dl, _ := ctx.Deadline()
+	if err := c.c.SetDeadline(dl); err != nil {
+		c.broken = true
+		return err
+	}
+	// interrupt blocked I/O when the context is canceled
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = c.c.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	err := c.enc.Encode(req)
+	if err == nil {
+		err = c.dec.Decode(resp)
+	}
+	close(done)
+	<-stopped
+	if err != nil {
+		c.broken = true
+		if ne, ok := err.(net.Error); ok && ne.Timeout() && !dl.IsZero() {
+			// connection deadline may expire slightly before the context
+			<-ctx.Done()
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+	return nil
+}
+
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+	for _, c := range db.idle {
+		c.c.Close()
+	}
+	db.idle = nil
+	return nil
+}
+
+// Capabilities implements base.Capable. Capabilities of the served database are reported by the server on connect.
+func (db *DB) Capabilities() base.Capabilities {
+	return db.caps
+}
+
+func (db *DB) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
+	c, err := db.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Tx{db: db, c: c}
+	if _, err = tx.do(ctx, &request{Op: opBegin, RW: rw}); err != nil {
+		tx.release()
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (db *DB) View(ctx context.Context, fn func(tx kv.Tx) error) error {
+	return kv.View(ctx, db, fn)
+}
+
+func (db *DB) Update(ctx context.Context, fn func(tx kv.Tx) error) error {
+	return kv.Update(ctx, db, fn)
+}
+
+// Tx is a transaction bound to a single connection.
+type Tx struct {
+	db *DB
+	mu sync.Mutex
+	c  *conn // nil when the transaction is closed
+}
+
+func (tx *Tx) do(ctx context.Context, req *request) (*response, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.c == nil {
+		return nil, ErrTxClosed
+	}
+	req.Deadline, _ = ctx.Deadline()
+	var resp response
+	if err := tx.c.roundTrip(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	if err := resp.error(); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// release returns the connection to the pool.
+func (tx *Tx) release() {
+	tx.mu.Lock()
+	c := tx.c
+	tx.c = nil
+	tx.mu.Unlock()
+	if c != nil {
+		tx.db.put(c)
+	}
+}
+
+func (tx *Tx) Get(ctx context.Context, key kv.Key) (kv.Value, error) {
+	resp, err := tx.do(ctx, &request{Op: opGet, Key: key})
+	if err != nil {
+		return nil, err
+	}
+	return value(resp.Val), nil
+}
+
+// value converts empty values back to non-nil ones, since gob doesn't distinguish them.
+func value(v kv.Value) kv.Value {
+	if v == nil {
+		return kv.Value{}
+	}
+	return v
+}
+
+func (tx *Tx) GetBatch(ctx context.Context, keys []kv.Key) ([]kv.Value, error) {
+	resp, err := tx.do(ctx, &request{Op: opGetBatch, Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]kv.Value, len(keys))
+	for i := range vals {
+		if i < len(resp.Found) && resp.Found[i] {
+			vals[i] = value(resp.Vals[i])
+		}
+	}
+	return vals, nil
+}
+
+func (tx *Tx) Put(ctx context.Context, k kv.Key, v kv.Value) error {
+	_, err := tx.do(ctx, &request{Op: opPut, Key: k, Val: v})
+	return err
+}
+
+func (tx *Tx) Del(ctx context.Context, k kv.Key) error {
+	_, err := tx.do(ctx, &request{Op: opDel, Key: k})
+	return err
+}
+
+func (tx *Tx) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		// the transaction is still open on the server
+		_ = tx.Close()
+		return err
+	}
+	_, err := tx.do(ctx, &request{Op: opCommit})
+	tx.release()
+	return err
+}
+
+func (tx *Tx) Close() error {
+	tx.mu.Lock()
+	closed := tx.c == nil
+	tx.mu.Unlock()
+	if closed {
+		return nil
+	}
+	_, err := tx.do(context.Background(), &request{Op: opRollback})
+	tx.release()
+	return err
+}
+
+func (tx *Tx) Scan(ctx context.Context, opts ...kv.IteratorOption) kv.Iterator {
+	var it kv.Iterator = &Iterator{tx: tx}
+	it = kv.ApplyIteratorOptions(it, opts)
+	return it
+}
+
+var (
+	_ kv.Seeker         = &Iterator{}
+	_ kv.PrefixIterator = &Iterator{}
+)
+
+// Iterator fetches key-value pairs from the server in batches.
+//
+// Since pairs are prefetched, writes made in the same transaction during iteration
+// may not be visible to the iterator until the next batch is fetched.
+type Iterator struct {
+	tx    *Tx
+	pref  kv.Key
+	id    uint64 // zero if not started
+	seek  kv.Key
+	reset bool // must restart the iteration on the server
+	buf   []kv.Pair
+	i     int
+	done  bool
+	close bool
+	err   error
+}
+
+func (it *Iterator) Reset() {
+	it.reset = true
+	it.seek = nil
+	it.buf, it.i = nil, 0
+	it.done = false
+	it.err = nil
+}
+
+func (it *Iterator) WithPrefix(pref kv.Key) kv.Iterator {
+	it.Reset()
+	it.pref = pref
+	return it
+}
+
+func (it *Iterator) fetch(ctx context.Context) bool {
+	req := &request{Op: opNext, Iter: it.id, Batch: it.tx.db.opts.Batch}
+	if it.id == 0 || it.reset {
+		req.Op, req.Prefix, req.Seek = opScan, it.pref, it.seek
+	}
+	resp, err := it.tx.do(ctx, req)
+	if err != nil {
+		it.err = err
+		it.done = true
+		return false
+	}
+	it.id, it.reset, it.seek = resp.Iter, false, nil
+	it.buf, it.i = resp.Pairs, 0
+	it.done = resp.Done
+	for j := range it.buf {
+		it.buf[j].Val = value(it.buf[j].Val)
+	}
+	return len(it.buf) != 0
+}
+
+func (it *Iterator) Next(ctx context.Context) bool {
+	if it.err != nil || it.close {
+		return false
+	} else if err := ctx.Err(); err != nil {
+		// prefetched pairs are not returned either
+		it.err = err
+		it.buf, it.i = nil, 0
+		it.done = true
+		return false
+	}
+	if it.id != 0 && !it.reset && it.i+1 < len(it.buf) {
+		it.i++
+		return true
+	}
+	if it.id != 0 && !it.reset && it.done {
+		it.buf, it.i = nil, 0
+		return false
+	}
+	return it.fetch(ctx)
+}
+
+func (it *Iterator) Seek(ctx context.Context, key kv.Key) bool {
+	if it.close {
+		return false
+	}
+	it.Reset()
+	it.seek = key.Clone()
+	return it.fetch(ctx)
+}
+
+func (it *Iterator) Key() kv.Key {
+	if it.i < len(it.buf) {
+		return it.buf[it.i].Key
+	}
+	return nil
+}
+
+func (it *Iterator) Val() kv.Value {
+	if it.i < len(it.buf) {
+		return it.buf[it.i].Val
+	}
+	return nil
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Close() error {
+	if it.id != 0 {
+		_, err := it.tx.do(context.Background(), &request{Op: opIterClose, Iter: it.id})
+		if err != nil && err != ErrTxClosed && it.err == nil {
+			it.err = err
+		}
+		it.id = 0
+	}
+	it.buf, it.i = nil, 0
+	it.done, it.close = true, true
+	return it.err
+}
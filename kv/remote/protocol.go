@@ -0,0 +1,150 @@
+// Package remote exposes hierarchical key-value stores over the network.
+//
+// Server serves any kv.KV implementation, and the client implements kv.KV on top of a network connection.
+//
+// Protocol is a sequence of gob-encoded request and response messages over a stream connection.
+// Each connection is a session that can hold at most one transaction at a time. Client keeps a pool
+// of idle connections and binds a connection to each transaction for its lifetime.
+package remote
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+)
+
+const protocolVersion = 1
+
+var (
+	// ErrUnauthorized is returned when the server rejects an authentication token.
+	ErrUnauthorized = errors.New("remote: unauthorized")
+	// ErrTxClosed is returned when using a transaction after Commit or Close.
+	ErrTxClosed = errors.New("remote: transaction is closed")
+	// ErrNoTx is returned by the server when a request requires an open transaction.
+	ErrNoTx = errors.New("remote: no active transaction")
+	// ErrTxActive is returned by the server when opening a second transaction in the same session.
+	ErrTxActive = errors.New("remote: transaction is already active")
+	// ErrClosed is returned when using a closed client or server.
+	ErrClosed = errors.New("remote: connection closed")
+)
+
+type opCode int
+
+const (
+	opBegin opCode = iota + 1
+	opCommit
+	opRollback
+	opGet
+	opGetBatch
+	opPut
+	opDel
+	opScan
+	opNext
+	opIterClose
+)
+
+// hello is the first message sent by the client.
+type hello struct {
+	Version int
+	Token   string
+}
+
+type request struct {
+	Op       opCode
+	Deadline time.Time // zero means no deadline
+	RW       bool
+	Key      kv.Key
+	Keys     []kv.Key
+	Val      kv.Value
+	Iter     uint64
+	Prefix   kv.Key
+	Seek     kv.Key // only for scan; nil means no seek
+	Batch    int
+}
+
+type response struct {
+	Code  errCode
+	Err   string
+	Val   kv.Value
+	Found []bool // for Get and GetBatch
+	Vals  []kv.Value
+	Iter  uint64
+	Pairs []kv.Pair
+	Done  bool // iterator has no more pairs
+	// Caps are the capabilities of the served database, sent in response to hello.
+	// Zero means that the server doesn't report capabilities.
+	Caps base.Capabilities
+}
+
+type errCode int
+
+const (
+	codeOK errCode = iota
+	codeOther
+	codeNotFound
+	codeReadOnly
+	codeConflict
+	codeDeadline
+	codeCanceled
+	codeUnauthorized
+	codeTxClosed
+	codeNoTx
+	codeTxActive
+)
+
+var errCodes = []struct {
+	code errCode
+	err  error
+}{
+	{codeNotFound, kv.ErrNotFound},
+	{codeReadOnly, kv.ErrReadOnly},
+	{codeConflict, kv.ErrConflict},
+	{codeDeadline, context.DeadlineExceeded},
+	{codeCanceled, context.Canceled},
+	{codeUnauthorized, ErrUnauthorized},
+	{codeTxClosed, ErrTxClosed},
+	{codeNoTx, ErrNoTx},
+	{codeTxActive, ErrTxActive},
+}
+
+// encodeError sets the error code and message in the response.
+func (r *response) setError(err error) {
+	if err == nil {
+		return
+	}
+	r.Code, r.Err = codeOther, err.Error()
+	for _, c := range errCodes {
+		if errors.Is(err, c.err) {
+			r.Code = c.code
+			return
+		}
+	}
+}
+
+// error maps the error code back to a known error value.
+func (r *response) error() error {
+	switch r.Code {
+	case codeOK:
+		return nil
+	case codeOther:
+		return &Error{Msg: r.Err}
+	}
+	for _, c := range errCodes {
+		if r.Code == c.code {
+			return c.err
+		}
+	}
+	return &Error{Msg: r.Err}
+}
+
+// Error is an error returned by the remote database that has no local equivalent.
+type Error struct {
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return "remote: " + e.Msg
+}
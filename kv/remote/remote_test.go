@@ -0,0 +1,241 @@
+package remote
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/flat"
+	"github.com/hidal-go/hidalgo/kv/flat/btree"
+	"github.com/hidal-go/hidalgo/kv/kvtest"
+	"github.com/hidal-go/hidalgo/kv/mem"
+)
+
+// serve starts a server for the database on a loopback interface and returns its address.
+func serve(t testing.TB, db kv.KV, opts *ServerOptions) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := NewServer(db, opts)
+	go srv.Serve(l)
+	t.Cleanup(func() {
+		srv.Close()
+		db.Close()
+	})
+	return l.Addr().String()
+}
+
+func dial(t testing.TB, addr string, opts *Options) *DB {
+	db, err := Dial(context.Background(), addr, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+	return db
+}
+
+func TestRemote(t *testing.T) {
+	kvtest.RunTest(t, func(t testing.TB) kv.KV {
+		addr := serve(t, mem.New(), nil)
+		// small batches to test iterator pagination
+		return dial(t, addr, &Options{Batch: 2})
+	}, nil)
+}
+
+func BenchmarkRemote(b *testing.B) {
+	kvtest.RunBenchmarks(b, func(t testing.TB) kv.KV {
+		addr := serve(t, mem.New(), nil)
+		return dial(t, addr, nil)
+	}, nil)
+}
+
+func TestCapabilities(t *testing.T) {
+	db := dial(t, serve(t, mem.New(), nil), nil)
+	require.Equal(t, base.CapTx|base.CapConcurrentWrites|base.CapSeek|base.CapPrefixScan|base.CapBatchGet, db.Capabilities())
+
+	// transactions are not available if the served database doesn't support them
+	db = dial(t, serve(t, flat.Upgrade(btree.New()), nil), nil)
+	require.Equal(t, base.CapSeek|base.CapPrefixScan|base.CapBatchGet, db.Capabilities())
+}
+
+func TestAuth(t *testing.T) {
+	addr := serve(t, mem.New(), &ServerOptions{Tokens: []string{"secret", "other"}})
+
+	_, err := Dial(context.Background(), addr, nil)
+	require.Equal(t, ErrUnauthorized, err)
+	_, err = Dial(context.Background(), addr, &Options{Token: "wrong"})
+	require.Equal(t, ErrUnauthorized, err)
+
+	db, err := OpenPath("secret@" + addr)
+	require.NoError(t, err)
+	defer db.Close()
+	td := kvtest.NewTest(t, db)
+	td.Put(kv.SKey("a"), kv.Value("1"))
+	td.Expect(kv.SKey("a"), kv.Value("1"))
+}
+
+func TestDeadline(t *testing.T) {
+	ctx := context.Background()
+	addr := serve(t, mem.New(), nil)
+	db := dial(t, addr, nil)
+
+	// hold the write lock on the server
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = db.Tx(tctx, true)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	tctx, cancel = context.WithCancel(ctx)
+	cancel()
+	_, err = tx.Get(tctx, kv.SKey("a"))
+	require.Equal(t, context.Canceled, err)
+
+	_, err = tx.Get(ctx, kv.SKey("a"))
+	require.Equal(t, kv.ErrNotFound, err)
+	require.NoError(t, tx.Close())
+
+	// session with a blocked transaction was dropped, but the database is still usable
+	err = db.Update(ctx, func(tx kv.Tx) error {
+		return tx.Put(ctx, kv.SKey("a"), kv.Value("1"))
+	})
+	require.NoError(t, err)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	ctx := context.Background()
+	addr := serve(t, mem.New(), &ServerOptions{IdleTimeout: 50 * time.Millisecond})
+	db := dial(t, addr, nil)
+
+	// abandoned transaction holds the write lock on the server
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	time.Sleep(200 * time.Millisecond)
+	_, err = tx.Get(ctx, kv.SKey("a"))
+	require.Error(t, err)
+	_ = tx.Close()
+
+	tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = db.Update(tctx, func(tx kv.Tx) error {
+		return tx.Put(tctx, kv.SKey("a"), kv.Value("1"))
+	})
+	require.NoError(t, err)
+}
+
+// scanCtxKV returns iterators that stop when the context passed to Scan is done.
+type scanCtxKV struct {
+	kv.KV
+}
+
+func (db *scanCtxKV) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
+	tx, err := db.KV.Tx(ctx, rw)
+	if err != nil {
+		return nil, err
+	}
+	return &scanCtxTx{Tx: tx}, nil
+}
+
+type scanCtxTx struct {
+	kv.Tx
+}
+
+func (tx *scanCtxTx) Scan(ctx context.Context, opts ...kv.IteratorOption) kv.Iterator {
+	return &scanCtxIterator{Iterator: tx.Tx.Scan(ctx, opts...), ctx: ctx}
+}
+
+type scanCtxIterator struct {
+	kv.Iterator
+	ctx context.Context
+}
+
+func (it *scanCtxIterator) Next(ctx context.Context) bool {
+	if it.ctx.Err() != nil {
+		return false
+	}
+	return it.Iterator.Next(ctx)
+}
+
+func (it *scanCtxIterator) Err() error {
+	if err := it.ctx.Err(); err != nil {
+		return err
+	}
+	return it.Iterator.Err()
+}
+
+func TestIteratorOutlivesRequest(t *testing.T) {
+	ctx := context.Background()
+	addr := serve(t, &scanCtxKV{KV: mem.New()}, nil)
+	// fetch one pair per request, so the iterator is reused by later requests
+	db := dial(t, addr, &Options{Batch: 1})
+	td := kvtest.NewTest(t, db)
+	td.Put(kv.SKey("a"), kv.Value("1"))
+	td.Put(kv.SKey("b"), kv.Value("2"))
+	td.Put(kv.SKey("c"), kv.Value("3"))
+
+	// requests carry the deadline of the context
+	tctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	var keys []string
+	err := kv.View(tctx, db, func(tx kv.Tx) error {
+		return kv.Each(tctx, tx, func(k kv.Key, _ kv.Value) error {
+			keys = append(keys, string(k[0]))
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, keys)
+}
+
+// conflictKV returns a conflict on the first commit.
+type conflictKV struct {
+	kv.KV
+	n int32
+}
+
+func (db *conflictKV) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
+	tx, err := db.KV.Tx(ctx, rw)
+	if err != nil {
+		return nil, err
+	}
+	return &conflictTx{Tx: tx, db: db}, nil
+}
+
+type conflictTx struct {
+	kv.Tx
+	db *conflictKV
+}
+
+func (tx *conflictTx) Commit(ctx context.Context) error {
+	if atomic.AddInt32(&tx.db.n, 1) == 1 {
+		return kv.ErrConflict
+	}
+	return tx.Tx.Commit(ctx)
+}
+
+func TestConflict(t *testing.T) {
+	ctx := context.Background()
+	addr := serve(t, &conflictKV{KV: mem.New()}, nil)
+	db := dial(t, addr, nil)
+
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	require.NoError(t, tx.Put(ctx, kv.SKey("a"), kv.Value("1")))
+	require.Equal(t, kv.ErrConflict, tx.Commit(ctx))
+
+	calls := 0
+	err = db.Update(ctx, func(tx kv.Tx) error {
+		calls++
+		return tx.Put(ctx, kv.SKey("a"), kv.Value("2"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	kvtest.NewTest(t, db).Expect(kv.SKey("a"), kv.Value("2"))
+}
@@ -0,0 +1,336 @@
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/gob"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/options"
+)
+
+const (
+	defaultBatch = 128
+	maxBatch     = 4096
+)
+
+// ServerOptions configures the server.
+type ServerOptions struct {
+	// Tokens is a list of accepted authentication tokens. If empty, authentication is disabled.
+	Tokens []string
+	// HandshakeTimeout limits the time for the client to authenticate. Default is 10 seconds.
+	HandshakeTimeout time.Duration
+	// IdleTimeout limits the time between requests while a transaction is open. When it expires,
+	// the connection is closed and the transaction is rolled back, so a dead client cannot keep
+	// the transaction and its iterators open forever. Default is 5 minutes.
+	IdleTimeout time.Duration
+}
+
+// Server exposes a key-value database over the network.
+type Server struct {
+	db   kv.KV
+	opts ServerOptions
+
+	ctx    context.Context
+	cancel func()
+
+	mu     sync.Mutex
+	lis    map[net.Listener]struct{}
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewServer creates a new server for the database. Options can be nil.
+// Server does not take ownership of the database; it must be closed separately after closing the server.
+func NewServer(db kv.KV, opts *ServerOptions) *Server {
+	s := &Server{
+		db:    db,
+		lis:   make(map[net.Listener]struct{}),
+		conns: make(map[net.Conn]struct{}),
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.HandshakeTimeout <= 0 {
+		s.opts.HandshakeTimeout = 10 * time.Second
+	}
+	if s.opts.IdleTimeout <= 0 {
+		s.opts.IdleTimeout = 5 * time.Minute
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Serve accepts connections on the listener. It blocks until the listener fails or the server is closed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.lis[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.lis, l)
+		s.mu.Unlock()
+	}()
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return ErrClosed
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops all listeners, closes all connections and rolls back active transactions.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.cancel()
+	for l := range s.lis {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) auth(token string) bool {
+	if len(s.opts.Tokens) == 0 {
+		return true
+	}
+	ok := false
+	for _, t := range s.opts.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// session is a state of a single client connection.
+type session struct {
+	s     *Server
+	tx    kv.Tx
+	iters map[uint64]kv.Iterator
+	last  uint64
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	defer c.Close()
+	enc, dec := gob.NewEncoder(c), gob.NewDecoder(c)
+
+	_ = c.SetDeadline(time.Now().Add(s.opts.HandshakeTimeout))
+	var h hello
+	if err := dec.Decode(&h); err != nil {
+		return
+	}
+	var resp response
+	if h.Version != protocolVersion {
+		resp.setError(errors.New("unsupported protocol version"))
+	} else if !s.auth(h.Token) {
+		resp.setError(ErrUnauthorized)
+	} else {
+		resp.Caps, _ = base.CapabilitiesOf(s.db)
+	}
+	if err := enc.Encode(&resp); err != nil || resp.Code != codeOK {
+		return
+	}
+	_ = c.SetDeadline(time.Time{})
+
+	ss := &session{s: s, iters: make(map[uint64]kv.Iterator)}
+	defer ss.rollback()
+	for {
+		// idle connections without a transaction hold no resources and can stay open
+		var dl time.Time
+		if ss.tx != nil {
+			dl = time.Now().Add(s.opts.IdleTimeout)
+		}
+		_ = c.SetReadDeadline(dl)
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		resp := ss.handle(&req)
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (ss *session) closeIters() {
+	for id, it := range ss.iters {
+		it.Close()
+		delete(ss.iters, id)
+	}
+}
+
+func (ss *session) rollback() error {
+	ss.closeIters()
+	if ss.tx == nil {
+		return nil
+	}
+	err := ss.tx.Close()
+	ss.tx = nil
+	return err
+}
+
+func (ss *session) handle(req *request) *response {
+	ctx := ss.s.ctx
+	if !req.Deadline.IsZero() {
+		var cancel func()
+		ctx, cancel = context.WithDeadline(ctx, req.Deadline)
+		defer cancel()
+	}
+	resp := &response{}
+	if err := ctx.Err(); err != nil {
+		resp.setError(err)
+		return resp
+	}
+	if req.Op != opBegin && ss.tx == nil {
+		resp.setError(ErrNoTx)
+		return resp
+	}
+	resp.setError(ss.exec(ctx, req, resp))
+	return resp
+}
+
+func (ss *session) exec(ctx context.Context, req *request, resp *response) error {
+	switch req.Op {
+	case opBegin:
+		if ss.tx != nil {
+			return ErrTxActive
+		}
+		// transaction outlives the request, thus it must not inherit the request deadline
+		tx, err := ss.s.db.Tx(ss.s.ctx, req.RW)
+		if err != nil {
+			return err
+		}
+		ss.tx = tx
+		return nil
+	case opCommit:
+		ss.closeIters()
+		err := ss.tx.Commit(ctx)
+		if err2 := ss.rollback(); err == nil {
+			err = err2
+		}
+		return err
+	case opRollback:
+		return ss.rollback()
+	case opGet:
+		v, err := ss.tx.Get(ctx, req.Key)
+		if err != nil {
+			return err
+		}
+		resp.Val, resp.Found = v, []bool{true}
+		return nil
+	case opGetBatch:
+		vals, err := ss.tx.GetBatch(ctx, req.Keys)
+		if err != nil {
+			return err
+		}
+		resp.Vals, resp.Found = vals, make([]bool, len(vals))
+		for i, v := range vals {
+			resp.Found[i] = v != nil
+		}
+		return nil
+	case opPut:
+		return ss.tx.Put(ctx, req.Key, req.Val)
+	case opDel:
+		return ss.tx.Del(ctx, req.Key)
+	case opScan:
+		if it, ok := ss.iters[req.Iter]; ok {
+			it.Close()
+			delete(ss.iters, req.Iter)
+		}
+		var opts []kv.IteratorOption
+		if len(req.Prefix) != 0 {
+			opts = append(opts, options.WithPrefixKV(req.Prefix))
+		}
+		// iterator outlives the request, the same as the transaction; the request context is only used to fetch pairs
+		it := ss.tx.Scan(ss.s.ctx, opts...)
+		id := req.Iter
+		if id == 0 {
+			ss.last++
+			id = ss.last
+		}
+		ss.iters[id] = it
+		resp.Iter = id
+		if req.Seek != nil {
+			if !kv.Seek(ctx, it, req.Seek) {
+				resp.Done = true
+				return it.Err()
+			}
+			resp.Pairs = append(resp.Pairs, kv.Pair{Key: it.Key().Clone(), Val: it.Val().Clone()})
+		}
+		return ss.fill(ctx, it, req.Batch, resp)
+	case opNext:
+		it, ok := ss.iters[req.Iter]
+		resp.Iter = req.Iter
+		if !ok {
+			resp.Done = true
+			return nil
+		}
+		return ss.fill(ctx, it, req.Batch, resp)
+	case opIterClose:
+		if it, ok := ss.iters[req.Iter]; ok {
+			delete(ss.iters, req.Iter)
+			return it.Close()
+		}
+		return nil
+	}
+	return errors.New("unknown operation")
+}
+
+// fill reads the next batch of pairs from the iterator.
+func (ss *session) fill(ctx context.Context, it kv.Iterator, n int, resp *response) error {
+	if n <= 0 {
+		n = defaultBatch
+	} else if n > maxBatch {
+		n = maxBatch
+	}
+	for len(resp.Pairs) < n {
+		if !it.Next(ctx) {
+			resp.Done = true
+			return it.Err()
+		}
+		resp.Pairs = append(resp.Pairs, kv.Pair{Key: it.Key().Clone(), Val: it.Val().Clone()})
+	}
+	return nil
+}
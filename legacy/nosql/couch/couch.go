@@ -17,11 +17,14 @@ const (
 	DriverCouch = "couch"
 )
 
+// caps are the capabilities of the driver.
+const caps = base.CapConcurrentWrites
+
 func init() {
 	nosql.Register(nosql.Registration{
 		Registration: base.Registration{
 			Name: NameCouch, Title: "CouchDB",
-			Local: false, Volatile: false,
+			Local: false, Volatile: false, Capabilities: caps,
 		},
 		Traits: Traits(),
 		New:    CreateCouch, Open: OpenCouch,
@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-kivik/kivik"
 
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/legacy/nosql"
 )
 
@@ -75,6 +76,11 @@ func (db *DB) Close() error {
 	return nil
 }
 
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
 const (
 	collectionIndex   = "collection-index"
 	secondaryIndexFmt = "%s-secondary-%d"
@@ -412,6 +418,9 @@ func (it *Iterator) next(ctx context.Context) bool {
 func (it *Iterator) Next(ctx context.Context) bool {
 	if it.err != nil || it.closed {
 		return false
+	} else if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
 	}
 	if it.rows == nil && !it.open(ctx) {
 		return false
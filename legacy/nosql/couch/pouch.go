@@ -16,11 +16,14 @@ const (
 	DriverPouch = "couch"
 )
 
+// caps are the capabilities of the driver.
+const caps = base.CapConcurrentWrites
+
 func init() {
 	nosql.Register(nosql.Registration{
 		Registration: base.Registration{
 			Name: NamePouch, Title: DriverPouch,
-			Local: true, Volatile: false,
+			Local: true, Volatile: false, Capabilities: caps,
 		},
 		Traits: Traits(),
 		New:    CreatePouch, Open: OpenPouch,
@@ -26,11 +26,14 @@ func Traits() nosql.Traits {
 	}
 }
 
+// caps are the capabilities of the driver.
+const caps = base.CapConcurrentWrites
+
 func init() {
 	nosql.Register(nosql.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "ElasticSearch",
-			Local: false, Volatile: false,
+			Local: false, Volatile: false, Capabilities: caps,
 		},
 		Traits: Traits(),
 		Open: func(ctx context.Context, addr, ns string, opt nosql.Options) (nosql.Database, error) {
@@ -114,6 +117,11 @@ func (db *DB) Close() error {
 	return nil
 }
 
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
 type indType string
 
 const (
@@ -581,6 +589,10 @@ type Iterator struct {
 func (it *Iterator) Next(ctx context.Context) bool {
 	if it.done {
 		return false
+	} else if err := ctx.Err(); err != nil {
+		it.err = err
+		it.done = true
+		return false
 	}
 	if it.buf == nil {
 		it.buf, it.err = it.qu.Do(ctx)
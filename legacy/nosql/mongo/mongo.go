@@ -28,11 +28,14 @@ func Traits() nosql.Traits {
 	}
 }
 
+// caps are the capabilities of the driver.
+const caps = base.CapConcurrentWrites
+
 func init() {
 	nosql.Register(nosql.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "MongoDB",
-			Local: false, Volatile: false,
+			Local: false, Volatile: false, Capabilities: caps,
 		},
 		Traits: Traits(),
 		Open: func(ctx context.Context, addr, ns string, opt nosql.Options) (nosql.Database, error) {
@@ -113,6 +116,11 @@ func (db *DB) Close() error {
 	return nil
 }
 
+// Capabilities implements base.Capable.
+func (db *DB) Capabilities() base.Capabilities {
+	return caps
+}
+
 func (db *DB) EnsureIndex(ctx context.Context, col string, primary nosql.Index, secondary []nosql.Index) error {
 	if primary.Type != nosql.StringExact {
 		return fmt.Errorf("unsupported type of primary index: %v", primary.Type)
@@ -527,7 +535,10 @@ type Iterator struct {
 func (it *Iterator) Next(ctx context.Context) bool {
 	elem := make(primitive.M)
 
-	if it.it == nil {
+	if it.it == nil || it.err != nil {
+		return false
+	} else if err := ctx.Err(); err != nil {
+		it.err = err
 		return false
 	}
 	if !it.it.Next(ctx) {
@@ -124,6 +124,7 @@ var testsNoSQLKey = []struct {
 	{name: "delete by key", t: testDeleteByKey},
 	{name: "update", t: testUpdate},
 	{name: "delete query", t: testDeleteQuery},
+	{name: "cancel", t: testCancel},
 }
 
 type tableConf struct {
@@ -564,3 +565,21 @@ func testDeleteQuery(t *testing.T, c tableConf) {
 
 	c.expectAll(t, nil)
 }
+
+func testCancel(t *testing.T, c tableConf) {
+	c.ensurePK(t)
+
+	c.insertDocs(t, 3, func(i int) nosql.Document {
+		return nosql.Document{
+			"data": nosql.Int(i),
+		}
+	})
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	it := c.Query().Iterate(ctx)
+	defer it.Close()
+	require.True(t, it.Next(ctx))
+	cancel()
+	require.False(t, it.Next(ctx))
+	require.Equal(t, context.Canceled, it.Err())
+}
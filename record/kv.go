@@ -0,0 +1,171 @@
+package record
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/options"
+)
+
+var _ kv.KV = (*KV)(nil)
+
+// NewKV wraps a hierarchical key-value store and records all operations to the log.
+func NewKV(db kv.KV, log *Log) *KV {
+	return &KV{KV: db, log: log}
+}
+
+// KV is a hierarchical key-value store that records all operations.
+type KV struct {
+	log *Log
+	KV  kv.KV
+}
+
+// Close closes the store and flushes the log. The log is not closed.
+func (d *KV) Close() error {
+	err := d.KV.Close()
+	if err2 := d.log.Flush(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Capabilities implements base.Capable.
+func (d *KV) Capabilities() base.Capabilities {
+	c, _ := base.CapabilitiesOf(d.KV)
+	return c
+}
+
+func (d *KV) Tx(ctx context.Context, rw bool) (kv.Tx, error) {
+	id := d.log.nextID()
+	tx, err := d.KV.Tx(ctx, rw)
+	d.log.write(&Entry{Op: OpTx, Tx: id, In: Args{RW: rw}, Out: errResult(err)})
+	if err != nil {
+		return nil, err
+	}
+	return &kvTx{log: d.log, id: id, tx: tx}, nil
+}
+
+func (d *KV) View(ctx context.Context, fn func(tx kv.Tx) error) error {
+	return kv.View(ctx, d, fn)
+}
+
+func (d *KV) Update(ctx context.Context, fn func(tx kv.Tx) error) error {
+	return kv.Update(ctx, d, fn)
+}
+
+type kvTx struct {
+	log *Log
+	id  uint64
+	tx  kv.Tx
+}
+
+func (tx *kvTx) Commit(ctx context.Context) error {
+	err := tx.tx.Commit(ctx)
+	tx.log.write(&Entry{Op: OpCommit, Tx: tx.id, Out: errResult(err)})
+	return err
+}
+
+func (tx *kvTx) Close() error {
+	err := tx.tx.Close()
+	tx.log.write(&Entry{Op: OpRollback, Tx: tx.id, Out: errResult(err)})
+	return err
+}
+
+func (tx *kvTx) Get(ctx context.Context, k kv.Key) (kv.Value, error) {
+	v, err := tx.tx.Get(ctx, k)
+	out := errResult(err)
+	out.Val = v
+	tx.log.write(&Entry{Op: OpGet, Tx: tx.id, In: Args{Key: k}, Out: out})
+	return v, err
+}
+
+func (tx *kvTx) GetBatch(ctx context.Context, keys []kv.Key) ([]kv.Value, error) {
+	vals, err := tx.tx.GetBatch(ctx, keys)
+	out := errResult(err)
+	out.Vals = vals
+	if err == nil {
+		out.Found = make([]bool, len(vals))
+		for i, v := range vals {
+			out.Found[i] = v != nil
+		}
+	}
+	tx.log.write(&Entry{Op: OpGetBatch, Tx: tx.id, In: Args{Keys: keys}, Out: out})
+	return vals, err
+}
+
+func (tx *kvTx) Put(ctx context.Context, k kv.Key, v kv.Value) error {
+	err := tx.tx.Put(ctx, k, v)
+	tx.log.write(&Entry{Op: OpPut, Tx: tx.id, In: Args{Key: k, Val: v}, Out: errResult(err)})
+	return err
+}
+
+func (tx *kvTx) Del(ctx context.Context, k kv.Key) error {
+	err := tx.tx.Del(ctx, k)
+	tx.log.write(&Entry{Op: OpDel, Tx: tx.id, In: Args{Key: k}, Out: errResult(err)})
+	return err
+}
+
+func (tx *kvTx) Scan(ctx context.Context, opts ...kv.IteratorOption) kv.Iterator {
+	var in Args
+	for _, opt := range opts {
+		if p, ok := opt.(options.PrefixKV); ok && in.Prefix == nil {
+			in.Prefix = p.Pref
+		} else {
+			in.Unsupported = fmt.Sprintf("unsupported iterator option: %T", opt)
+		}
+	}
+	it := &kvIterator{log: tx.log, tx: tx.id, id: tx.log.nextID(), it: tx.tx.Scan(ctx, opts...)}
+	tx.log.write(&Entry{Op: OpScan, Tx: tx.id, Iter: it.id, In: in})
+	return it
+}
+
+type kvIterator struct {
+	log *Log
+	tx  uint64
+	id  uint64
+	it  kv.Iterator
+}
+
+func (it *kvIterator) result(ok bool) Result {
+	if !ok {
+		return errResult(it.it.Err())
+	}
+	return Result{OK: true, Key: it.it.Key(), Val: it.it.Val()}
+}
+
+func (it *kvIterator) Reset() {
+	it.it.Reset()
+	it.log.write(&Entry{Op: OpReset, Tx: it.tx, Iter: it.id})
+}
+
+func (it *kvIterator) Next(ctx context.Context) bool {
+	ok := it.it.Next(ctx)
+	it.log.write(&Entry{Op: OpNext, Tx: it.tx, Iter: it.id, Out: it.result(ok)})
+	return ok
+}
+
+func (it *kvIterator) Seek(ctx context.Context, key kv.Key) bool {
+	ok := kv.Seek(ctx, it.it, key)
+	it.log.write(&Entry{Op: OpSeek, Tx: it.tx, Iter: it.id, In: Args{Key: key}, Out: it.result(ok)})
+	return ok
+}
+
+func (it *kvIterator) Key() kv.Key {
+	return it.it.Key()
+}
+
+func (it *kvIterator) Val() kv.Value {
+	return it.it.Val()
+}
+
+func (it *kvIterator) Err() error {
+	return it.it.Err()
+}
+
+func (it *kvIterator) Close() error {
+	err := it.it.Close()
+	it.log.write(&Entry{Op: OpIterClose, Tx: it.tx, Iter: it.id, Out: errResult(err)})
+	return err
+}
@@ -0,0 +1,327 @@
+// Package record provides wrappers that record all operations performed on key-value and tuple stores,
+// and a replayer that re-executes recorded operations against a different store and reports differences in results.
+//
+// The log is a compact binary stream of entries. Each entry describes one operation, its arguments and its result.
+// Operations are logged in the order they complete.
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/tuple"
+)
+
+const (
+	logMagic   = "hidalgo-record"
+	logVersion = 2
+)
+
+// Op is a type of recorded operation.
+type Op uint8
+
+const (
+	OpTx          = Op(iota + 1) // opening a transaction
+	OpCommit                     // committing a transaction
+	OpRollback                   // closing a transaction
+	OpGet                        // Get and GetTuple
+	OpGetBatch                   // GetBatch and GetTupleBatch
+	OpPut                        // Put
+	OpDel                        // Del and DeleteTuples
+	OpScan                       // starting an iterator
+	OpNext                       // advancing an iterator
+	OpSeek                       // seeking an iterator
+	OpReset                      // resetting an iterator
+	OpIterClose                  // closing an iterator
+	OpTable                      // opening a table
+	OpListTables                 // listing tables
+	OpCreateTable                // creating a table
+	OpDropTable                  // dropping a table
+	OpClearTable                 // removing all tuples from a table
+	OpInsert                     // InsertTuple
+	OpUpdate                     // UpdateTuple
+	OpAlterTable                 // changing a table schema
+)
+
+var opNames = map[Op]string{
+	OpTx:          "tx",
+	OpCommit:      "commit",
+	OpRollback:    "rollback",
+	OpGet:         "get",
+	OpGetBatch:    "get-batch",
+	OpPut:         "put",
+	OpDel:         "del",
+	OpScan:        "scan",
+	OpNext:        "next",
+	OpSeek:        "seek",
+	OpReset:       "reset",
+	OpIterClose:   "iter-close",
+	OpTable:       "table",
+	OpListTables:  "list-tables",
+	OpCreateTable: "create-table",
+	OpDropTable:   "drop-table",
+	OpClearTable:  "clear-table",
+	OpInsert:      "insert",
+	OpUpdate:      "update",
+	OpAlterTable:  "alter-table",
+}
+
+func (op Op) String() string {
+	if s, ok := opNames[op]; ok {
+		return s
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// Entry is a single recorded operation.
+type Entry struct {
+	Op   Op
+	Tx   uint64 // transaction; zero for operations on the store
+	Iter uint64 // iterator; zero for operations not related to iterators
+	In   Args
+	Out  Result
+}
+
+// Args are the arguments of the recorded operation.
+type Args struct {
+	RW     bool
+	Key    kv.Key
+	Keys   []kv.Key
+	Val    kv.Value
+	Prefix kv.Key
+
+	Table  string
+	Header []byte // table header, encoded with tuplepb
+	Tuple  Tuple
+	TKeys  [][]Value
+	Upsert bool
+	Filter *Filter
+	Scan   *ScanOptions
+	Alter  []Alteration
+
+	// Unsupported is set if arguments can not be recorded. Such operations can not be replayed.
+	Unsupported string
+}
+
+// ErrKind is a stable kind of the error returned by the operation.
+// Error messages depend on the store, thus only kinds are compared during the replay.
+type ErrKind string
+
+const (
+	ErrKindNone          = ErrKind("")
+	ErrKindNotFound      = ErrKind("not-found")       // kv.ErrNotFound and tuple.ErrNotFound
+	ErrKindConflict      = ErrKind("conflict")        // kv.ErrConflict
+	ErrKindReadOnly      = ErrKind("read-only")       // kv.ErrReadOnly and tuple.ErrReadOnly
+	ErrKindExists        = ErrKind("exists")          // tuple.ErrExists
+	ErrKindUnique        = ErrKind("unique")          // tuple.ErrUnique
+	ErrKindTableNotFound = ErrKind("table-not-found") // tuple.ErrTableNotFound
+	ErrKindTableExists   = ErrKind("table-exists")    // tuple.ErrTableExists
+	ErrKindIndexNotFound = ErrKind("index-not-found") // tuple.ErrIndexNotFound
+	ErrKindCanceled      = ErrKind("canceled")        // context.Canceled
+	ErrKindDeadline      = ErrKind("deadline")        // context.DeadlineExceeded
+	ErrKindOther         = ErrKind("error")           // any other error
+)
+
+var errKinds = []struct {
+	err  error
+	kind ErrKind
+}{
+	{kv.ErrNotFound, ErrKindNotFound},
+	{tuple.ErrNotFound, ErrKindNotFound},
+	{kv.ErrConflict, ErrKindConflict},
+	{kv.ErrReadOnly, ErrKindReadOnly},
+	{tuple.ErrReadOnly, ErrKindReadOnly},
+	{tuple.ErrExists, ErrKindExists},
+	{tuple.ErrUnique, ErrKindUnique},
+	{tuple.ErrTableNotFound, ErrKindTableNotFound},
+	{tuple.ErrTableExists, ErrKindTableExists},
+	{tuple.ErrIndexNotFound, ErrKindIndexNotFound},
+	{context.Canceled, ErrKindCanceled},
+	{context.DeadlineExceeded, ErrKindDeadline},
+}
+
+// errKind returns a stable kind of the error.
+func errKind(err error) ErrKind {
+	if err == nil {
+		return ErrKindNone
+	}
+	for _, k := range errKinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	return ErrKindOther
+}
+
+// Result is the result of the recorded operation.
+type Result struct {
+	Err    ErrKind
+	Msg    string // error message; it's not compared during the replay
+	OK     bool   // Next and Seek
+	Key    kv.Key
+	Val    kv.Value
+	Vals   []kv.Value
+	Tuple  Tuple
+	Tuples []Tuple
+	Found  []bool // GetBatch and GetTupleBatch
+	Tables []string
+}
+
+// errResult returns a result with an error kind and message set.
+func errResult(err error) Result {
+	if err == nil {
+		return Result{}
+	}
+	return Result{Err: errKind(err), Msg: err.Error()}
+}
+
+// Equal checks if two results are the same. Error messages are ignored.
+func (r Result) Equal(r2 Result) bool {
+	if r.Err != r2.Err || r.OK != r2.OK || r.Key.Compare(r2.Key) != 0 || !bytes.Equal(r.Val, r2.Val) {
+		return false
+	}
+	if len(r.Vals) != len(r2.Vals) || len(r.Tuples) != len(r2.Tuples) ||
+		len(r.Found) != len(r2.Found) || len(r.Tables) != len(r2.Tables) {
+		return false
+	}
+	for i := range r.Vals {
+		if !bytes.Equal(r.Vals[i], r2.Vals[i]) {
+			return false
+		}
+	}
+	if !r.Tuple.equal(r2.Tuple) {
+		return false
+	}
+	for i := range r.Tuples {
+		if !r.Tuples[i].equal(r2.Tuples[i]) {
+			return false
+		}
+	}
+	for i := range r.Found {
+		if r.Found[i] != r2.Found[i] {
+			return false
+		}
+	}
+	for i := range r.Tables {
+		if r.Tables[i] != r2.Tables[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type logHeader struct {
+	Magic   string
+	Version int
+}
+
+// Log writes recorded operations to a stream. It's safe for concurrent use.
+// Multiple wrappers can share the same log.
+type Log struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	enc    *gob.Encoder
+	closer io.Closer
+	err    error
+	id     uint64
+}
+
+// NewLog creates a new log that writes to w. If w implements io.Closer, it will be closed by Close.
+func NewLog(w io.Writer) *Log {
+	bw := bufio.NewWriter(w)
+	l := &Log{w: bw, enc: gob.NewEncoder(bw)}
+	if c, ok := w.(io.Closer); ok {
+		l.closer = c
+	}
+	l.err = l.enc.Encode(logHeader{Magic: logMagic, Version: logVersion})
+	return l
+}
+
+// nextID allocates a new transaction or iterator id.
+func (l *Log) nextID() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.id++
+	return l.id
+}
+
+func (l *Log) write(e *Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	l.err = l.enc.Encode(e)
+}
+
+// Err returns the first error encountered while writing the log.
+func (l *Log) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Flush writes buffered entries to the underlying writer.
+func (l *Log) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return l.err
+	}
+	l.err = l.w.Flush()
+	return l.err
+}
+
+// Close flushes the log and closes the underlying writer.
+func (l *Log) Close() error {
+	err := l.Flush()
+	if l.closer != nil {
+		if err2 := l.closer.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// Reader reads recorded operations from a stream.
+type Reader struct {
+	dec *gob.Decoder
+	err error
+}
+
+// NewReader creates a log reader.
+func NewReader(r io.Reader) *Reader {
+	lr := &Reader{dec: gob.NewDecoder(bufio.NewReader(r))}
+	var h logHeader
+	if err := lr.dec.Decode(&h); err != nil {
+		lr.err = fmt.Errorf("cannot read log header: %w", err)
+	} else if h.Magic != logMagic {
+		lr.err = errors.New("not a record log")
+	} else if h.Version != logVersion {
+		lr.err = fmt.Errorf("unsupported log version: %d", h.Version)
+	}
+	return lr
+}
+
+// Next reads the next entry. It returns io.EOF at the end of the log.
+func (r *Reader) Next() (*Entry, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	e := new(Entry)
+	if err := r.dec.Decode(e); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("truncated log: %w", err)
+		}
+		r.err = err
+		return nil, err
+	}
+	return e, nil
+}
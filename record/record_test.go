@@ -0,0 +1,239 @@
+package record_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/filter"
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/flat"
+	"github.com/hidal-go/hidalgo/kv/flat/btree"
+	"github.com/hidal-go/hidalgo/kv/kvtest"
+	"github.com/hidal-go/hidalgo/kv/mem"
+	"github.com/hidal-go/hidalgo/kv/options"
+	"github.com/hidal-go/hidalgo/record"
+	"github.com/hidal-go/hidalgo/tuple"
+	tuplekv "github.com/hidal-go/hidalgo/tuple/kv"
+	"github.com/hidal-go/hidalgo/tuple/tupletest"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+func TestKV(t *testing.T) {
+	kvtest.RunTest(t, func(t testing.TB) kv.KV {
+		return record.NewKV(mem.New(), record.NewLog(io.Discard))
+	}, nil)
+}
+
+func TestTupleStore(t *testing.T) {
+	tupletest.RunTest(t, func(t testing.TB) tuple.Store {
+		return record.NewTupleStore(tuplekv.New(mem.New()), record.NewLog(io.Discard))
+	}, nil)
+}
+
+func kvWorkload(t *testing.T, db kv.KV) {
+	ctx := context.Background()
+	err := db.Update(ctx, func(tx kv.Tx) error {
+		for _, k := range []string{"a", "b", "c"} {
+			if err := tx.Put(ctx, kv.SKey("p", k), kv.Value(k)); err != nil {
+				return err
+			}
+		}
+		return tx.Put(ctx, kv.SKey("q"), kv.Value("q"))
+	})
+	require.NoError(t, err)
+
+	err = db.Update(ctx, func(tx kv.Tx) error {
+		return tx.Del(ctx, kv.SKey("p", "b"))
+	})
+	require.NoError(t, err)
+
+	err = db.View(ctx, func(tx kv.Tx) error {
+		if _, err := tx.Get(ctx, kv.SKey("p", "b")); err != kv.ErrNotFound {
+			return err
+		}
+		if _, err := tx.GetBatch(ctx, []kv.Key{kv.SKey("p", "a"), kv.SKey("x")}); err != nil {
+			return err
+		}
+		it := tx.Scan(ctx, options.WithPrefixKV(kv.SKey("p")))
+		defer it.Close()
+		for it.Next(ctx) {
+		}
+		kv.Seek(ctx, it, kv.SKey("p", "c"))
+		return it.Err()
+	})
+	require.NoError(t, err)
+}
+
+func TestReplayKV(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	log := record.NewLog(&buf)
+	db := record.NewKV(mem.New(), log)
+	kvWorkload(t, db)
+	require.NoError(t, db.Close())
+
+	data := buf.Bytes()
+
+	// the same operations on a different backend must return the same results
+	rep, err := record.ReplayKV(ctx, flat.Upgrade(btree.New()), bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, rep.Entries > 10)
+	require.Zero(t, rep.Skipped)
+	require.Empty(t, rep.Diffs)
+
+	// replaying on a store with different data must report differences
+	db2 := mem.New()
+	err = db2.Update(ctx, func(tx kv.Tx) error {
+		return tx.Put(ctx, kv.SKey("x"), kv.Value("x"))
+	})
+	require.NoError(t, err)
+	rep, err = record.ReplayKV(ctx, db2, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, rep.Diffs, 1)
+	require.Equal(t, record.OpGetBatch, rep.Diffs[0].Entry.Op)
+}
+
+func TestReadLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := record.NewLog(&buf)
+	db := record.NewKV(mem.New(), log)
+	kvWorkload(t, db)
+	require.NoError(t, db.Close())
+
+	r := record.NewReader(&buf)
+	var ops []record.Op
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ops = append(ops, e.Op)
+		if e.Op == record.OpGet {
+			require.Equal(t, record.ErrKindNotFound, e.Out.Err)
+			require.Equal(t, kv.ErrNotFound.Error(), e.Out.Msg)
+		}
+	}
+	require.Equal(t, []record.Op{
+		record.OpTx, record.OpPut, record.OpPut, record.OpPut, record.OpPut, record.OpCommit, record.OpRollback,
+	}, ops[:7])
+
+	_, err := record.NewReader(bytes.NewReader([]byte("garbage"))).Next()
+	require.Error(t, err)
+}
+
+func TestResultEqual(t *testing.T) {
+	// only error kinds are compared
+	r1 := record.Result{Err: record.ErrKindNotFound, Msg: "kv: not found"}
+	r2 := record.Result{Err: record.ErrKindNotFound, Msg: "bolt: key not found"}
+	require.True(t, r1.Equal(r2))
+	r2.Err = record.ErrKindOther
+	require.False(t, r1.Equal(r2))
+
+	// empty and nil slices are the same after decoding
+	r1 = record.Result{Vals: []kv.Value{{}}, Tuples: []record.Tuple{}}
+	r2 = record.Result{Vals: []kv.Value{nil}}
+	require.True(t, r1.Equal(r2))
+
+	r1 = record.Result{Found: []bool{true, false}}
+	r2 = record.Result{Found: []bool{true, true}}
+	require.False(t, r1.Equal(r2))
+	r1 = record.Result{Tuple: record.Tuple{Key: []record.Value{{Type: 1, Data: []byte("a")}}}}
+	r2 = record.Result{Tuple: record.Tuple{Key: []record.Value{{Type: 1, Data: []byte("b")}}}}
+	require.False(t, r1.Equal(r2))
+}
+
+func TestReplayTuple(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	db := record.NewTupleStore(tuplekv.New(mem.New()), record.NewLog(&buf))
+
+	now := time.Unix(1600000000, 0).UTC()
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "test",
+			Key: []tuple.KeyField{
+				{Name: "k1", Type: values.StringType{}},
+				{Name: "k2", Type: values.IntType{}},
+			},
+			Data: []tuple.Field{
+				{Name: "f1", Type: values.TimeType{}},
+				{Name: "f2", Type: values.FloatType{}},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 5; i++ {
+			_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+				Key:  tuple.Key{values.String("a"), values.Int(i)},
+				Data: tuple.Data{values.AsTime(now.Add(time.Duration(i) * time.Second)), values.Float(float64(i) / 2)},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return tbl.UpdateTuple(ctx, tuple.Tuple{
+			Key:  tuple.Key{values.String("b"), values.Int(0)},
+			Data: tuple.Data{values.AsTime(now), values.Float(0)},
+		}, &tuple.UpdateOpt{Upsert: true})
+	})
+	require.NoError(t, err)
+
+	info, err := db.Table(ctx, "test")
+	require.NoError(t, err)
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := info.Open(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return tbl.DeleteTuples(ctx, &tuple.Filter{
+			KeyFilter: tuple.KeyFilters{filter.EQ(values.String("a")), filter.GTE(values.Int(3))},
+		})
+	})
+	require.NoError(t, err)
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.AlterTable(ctx, "test",
+			tuple.AddField{Field: tuple.Field{Name: "f3", Type: values.IntType{}, Default: values.Int(7)}},
+			tuple.RenameField{Name: "f1", NewName: "ts"},
+			tuple.AddField{Field: tuple.Field{Name: "f4", Type: values.StringType{}, Nullable: true}},
+			tuple.SetDefault{Name: "f3", Default: values.Int(8)},
+		)
+		return err
+	})
+	require.NoError(t, err)
+
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, "test")
+		if err != nil {
+			return err
+		}
+		if _, err = tbl.GetTupleBatch(ctx, []tuple.Key{{values.String("a"), values.Int(1)}, {values.String("a"), values.Int(4)}}); err != nil {
+			return err
+		}
+		it := tbl.Scan(ctx, &tuple.ScanOptions{
+			Filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.Prefix(values.String("a"))}},
+			Fields: []string{"f3", "ts"},
+		})
+		defer it.Close()
+		for it.Next(ctx) {
+		}
+		return it.Err()
+	})
+	require.NoError(t, err)
+	_, err = db.ListTables(ctx)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	rep, err := record.ReplayTuple(ctx, tuplekv.New(flat.Upgrade(btree.New())), bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.True(t, rep.Entries > 20)
+	require.Zero(t, rep.Skipped)
+	require.Empty(t, rep.Diffs)
+}
@@ -0,0 +1,359 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/options"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/tuple/tuplepb"
+)
+
+// Diff describes an operation that returned a different result during the replay.
+type Diff struct {
+	Seq   int    // index of the entry in the log
+	Entry *Entry // recorded operation and result
+	Got   Result // result returned during the replay
+}
+
+func (d Diff) String() string {
+	return fmt.Sprintf("#%d %v (tx %d): expected %+v, got %+v", d.Seq, d.Entry.Op, d.Entry.Tx, d.Entry.Out, d.Got)
+}
+
+// Report is a summary of the replay.
+type Report struct {
+	Entries int    // number of entries in the log
+	Skipped int    // entries that can not be replayed
+	Diffs   []Diff // operations that returned different results
+}
+
+// replayer executes a single entry. It returns false if the entry must be skipped.
+type replayer interface {
+	exec(ctx context.Context, e *Entry) (Result, bool)
+	close()
+}
+
+func replay(ctx context.Context, rp replayer, r io.Reader) (*Report, error) {
+	defer rp.close()
+	lr := NewReader(r)
+	rep := &Report{}
+	for {
+		e, err := lr.Next()
+		if err == io.EOF {
+			return rep, nil
+		} else if err != nil {
+			return rep, err
+		}
+		seq := rep.Entries
+		rep.Entries++
+		if e.In.Unsupported != "" {
+			rep.Skipped++
+			continue
+		}
+		got, ok := rp.exec(ctx, e)
+		if !ok {
+			rep.Skipped++
+		} else if !got.Equal(e.Out) {
+			rep.Diffs = append(rep.Diffs, Diff{Seq: seq, Entry: e, Got: got})
+		}
+	}
+}
+
+// ReplayKV re-executes all operations recorded in the log against a key-value store,
+// and reports operations that returned different results.
+//
+// Operations are executed sequentially, in the order they were logged. Operations that depend on a transaction
+// or an iterator that failed to open are skipped.
+func ReplayKV(ctx context.Context, db kv.KV, r io.Reader) (*Report, error) {
+	return replay(ctx, &kvReplayer{
+		db:  db,
+		txs: make(map[uint64]kv.Tx),
+		its: make(map[uint64]*kvIterator),
+	}, r)
+}
+
+type kvReplayer struct {
+	db  kv.KV
+	txs map[uint64]kv.Tx
+	its map[uint64]*kvIterator
+}
+
+func (r *kvReplayer) close() {
+	for _, it := range r.its {
+		_ = it.it.Close()
+	}
+	for _, tx := range r.txs {
+		_ = tx.Close()
+	}
+}
+
+func (r *kvReplayer) exec(ctx context.Context, e *Entry) (Result, bool) {
+	switch e.Op {
+	case OpTx:
+		tx, err := r.db.Tx(ctx, e.In.RW)
+		if err == nil {
+			r.txs[e.Tx] = tx
+		}
+		return errResult(err), true
+	case OpNext, OpSeek, OpReset, OpIterClose:
+		it, ok := r.its[e.Iter]
+		if !ok {
+			return Result{}, false
+		}
+		switch e.Op {
+		case OpNext:
+			return it.result(it.it.Next(ctx)), true
+		case OpSeek:
+			return it.result(kv.Seek(ctx, it.it, e.In.Key)), true
+		case OpReset:
+			it.it.Reset()
+			return Result{}, true
+		default:
+			delete(r.its, e.Iter)
+			return errResult(it.it.Close()), true
+		}
+	}
+	tx, ok := r.txs[e.Tx]
+	if !ok {
+		return Result{}, false
+	}
+	switch e.Op {
+	case OpCommit:
+		return errResult(tx.Commit(ctx)), true
+	case OpRollback:
+		// transactions can be closed multiple times, thus they are kept until the end
+		return errResult(tx.Close()), true
+	case OpGet:
+		v, err := tx.Get(ctx, e.In.Key)
+		out := errResult(err)
+		out.Val = v
+		return out, true
+	case OpGetBatch:
+		vals, err := tx.GetBatch(ctx, e.In.Keys)
+		out := errResult(err)
+		out.Vals = vals
+		if err == nil {
+			out.Found = make([]bool, len(vals))
+			for i, v := range vals {
+				out.Found[i] = v != nil
+			}
+		}
+		return out, true
+	case OpPut:
+		return errResult(tx.Put(ctx, e.In.Key, e.In.Val)), true
+	case OpDel:
+		return errResult(tx.Del(ctx, e.In.Key)), true
+	case OpScan:
+		var opts []kv.IteratorOption
+		if e.In.Prefix != nil {
+			opts = append(opts, options.WithPrefixKV(e.In.Prefix))
+		}
+		r.its[e.Iter] = &kvIterator{it: tx.Scan(ctx, opts...)}
+		return Result{}, true
+	}
+	return Result{}, false
+}
+
+// ReplayTuple re-executes all operations recorded in the log against a tuple store,
+// and reports operations that returned different results.
+//
+// See ReplayKV for details.
+func ReplayTuple(ctx context.Context, db tuple.Store, r io.Reader) (*Report, error) {
+	return replay(ctx, &tupleReplayer{
+		db:     db,
+		txs:    make(map[uint64]tuple.Tx),
+		tables: make(map[tableKey]tuple.Table),
+		its:    make(map[uint64]*tupleIterator),
+	}, r)
+}
+
+type tableKey struct {
+	tx   uint64
+	name string
+}
+
+type tupleReplayer struct {
+	db     tuple.Store
+	txs    map[uint64]tuple.Tx
+	tables map[tableKey]tuple.Table
+	its    map[uint64]*tupleIterator
+}
+
+func (r *tupleReplayer) close() {
+	for _, it := range r.its {
+		_ = it.it.Close()
+	}
+	for _, tx := range r.txs {
+		_ = tx.Close()
+	}
+}
+
+func tableNames(list []tuple.Table) []string {
+	var names []string
+	for _, t := range list {
+		names = append(names, t.Header().Name)
+	}
+	return names
+}
+
+func (r *tupleReplayer) exec(ctx context.Context, e *Entry) (Result, bool) {
+	switch e.Op {
+	case OpTx:
+		tx, err := r.db.Tx(ctx, e.In.RW)
+		if err == nil {
+			r.txs[e.Tx] = tx
+		}
+		return errResult(err), true
+	case OpNext, OpReset, OpIterClose:
+		it, ok := r.its[e.Iter]
+		if !ok {
+			return Result{}, false
+		}
+		switch e.Op {
+		case OpNext:
+			return it.result(it.it.Next(ctx)), true
+		case OpReset:
+			it.it.Reset()
+			return Result{}, true
+		default:
+			delete(r.its, e.Iter)
+			return errResult(it.it.Close()), true
+		}
+	}
+	if e.Tx == 0 {
+		// operations on the store
+		switch e.Op {
+		case OpTable:
+			_, err := r.db.Table(ctx, e.In.Table)
+			return errResult(err), true
+		case OpListTables:
+			list, err := r.db.ListTables(ctx)
+			out := errResult(err)
+			for _, info := range list {
+				out.Tables = append(out.Tables, info.Header().Name)
+			}
+			return out, true
+		}
+		return Result{}, false
+	}
+	tx, ok := r.txs[e.Tx]
+	if !ok {
+		return Result{}, false
+	}
+	tk := tableKey{tx: e.Tx, name: e.In.Table}
+	switch e.Op {
+	case OpCommit:
+		return errResult(tx.Commit(ctx)), true
+	case OpRollback:
+		return errResult(tx.Close()), true
+	case OpTable:
+		t, err := tx.Table(ctx, e.In.Table)
+		if err == nil {
+			r.tables[tk] = t
+		}
+		return errResult(err), true
+	case OpListTables:
+		list, err := tx.ListTables(ctx)
+		for _, t := range list {
+			r.tables[tableKey{tx: e.Tx, name: t.Header().Name}] = t
+		}
+		out := errResult(err)
+		out.Tables = tableNames(list)
+		return out, true
+	case OpCreateTable:
+		h, err := tuplepb.UnmarshalTable(e.In.Header)
+		if err != nil {
+			return Result{}, false
+		}
+		t, err := tx.CreateTable(ctx, *h)
+		if err == nil {
+			r.tables[tk] = t
+		}
+		return errResult(err), true
+	case OpAlterTable:
+		changes, err := decodeAlterations(e.In.Alter)
+		if err != nil {
+			return Result{}, false
+		}
+		t, err := tx.AlterTable(ctx, e.In.Table, changes...)
+		if err == nil {
+			r.tables[tk] = t
+		}
+		return errResult(err), true
+	}
+	t, ok := r.tables[tk]
+	if !ok {
+		return Result{}, false
+	}
+	switch e.Op {
+	case OpDropTable:
+		return errResult(t.Drop(ctx)), true
+	case OpClearTable:
+		return errResult(t.Clear(ctx)), true
+	case OpGet:
+		key, err := decodeKey(e.In.Tuple.Key)
+		if err != nil {
+			return Result{}, false
+		}
+		data, err := t.GetTuple(ctx, key)
+		out := errResult(err)
+		if err == nil {
+			out.Tuple.Data, _ = encodeData(data)
+		}
+		return out, true
+	case OpGetBatch:
+		keys := make([]tuple.Key, 0, len(e.In.TKeys))
+		for _, ek := range e.In.TKeys {
+			k, err := decodeKey(ek)
+			if err != nil {
+				return Result{}, false
+			}
+			keys = append(keys, k)
+		}
+		data, err := t.GetTupleBatch(ctx, keys)
+		out := errResult(err)
+		if err == nil {
+			out.Found = make([]bool, len(data))
+			out.Tuples = make([]Tuple, len(data))
+			for i, d := range data {
+				out.Found[i] = d != nil
+				out.Tuples[i].Data, _ = encodeData(d)
+			}
+		}
+		return out, true
+	case OpInsert, OpUpdate:
+		v, err := e.In.Tuple.decode()
+		if err != nil {
+			return Result{}, false
+		}
+		if e.Op == OpUpdate {
+			err = t.UpdateTuple(ctx, v, &tuple.UpdateOpt{Upsert: e.In.Upsert})
+			return errResult(err), true
+		}
+		key, err := t.InsertTuple(ctx, v)
+		out := errResult(err)
+		if err == nil {
+			out.Tuple.Key, _ = encodeKey(key)
+		}
+		return out, true
+	case OpDel:
+		f, err := e.In.Filter.decode()
+		if err != nil {
+			return Result{}, false
+		}
+		return errResult(t.DeleteTuples(ctx, f)), true
+	case OpScan:
+		opt, err := e.In.Scan.decode()
+		if err != nil {
+			return Result{}, false
+		}
+		r.its[e.Iter] = &tupleIterator{
+			keysOnly: opt != nil && opt.KeysOnly,
+			it:       t.Scan(ctx, opt),
+		}
+		return Result{}, true
+	}
+	return Result{}, false
+}
@@ -0,0 +1,362 @@
+package record
+
+import (
+	"context"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/tuple/tuplepb"
+)
+
+var _ tuple.Store = (*TupleStore)(nil)
+
+// NewTupleStore wraps a tuple store and records all operations to the log.
+func NewTupleStore(db tuple.Store, log *Log) *TupleStore {
+	return &TupleStore{Store: db, log: log}
+}
+
+// TupleStore is a tuple store that records all operations.
+type TupleStore struct {
+	log   *Log
+	Store tuple.Store
+}
+
+// Close closes the store and flushes the log. The log is not closed.
+func (d *TupleStore) Close() error {
+	err := d.Store.Close()
+	if err2 := d.log.Flush(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Capabilities implements base.Capable.
+func (d *TupleStore) Capabilities() base.Capabilities {
+	c, _ := base.CapabilitiesOf(d.Store)
+	return c
+}
+
+func (d *TupleStore) Tx(ctx context.Context, rw bool) (tuple.Tx, error) {
+	id := d.log.nextID()
+	tx, err := d.Store.Tx(ctx, rw)
+	d.log.write(&Entry{Op: OpTx, Tx: id, In: Args{RW: rw}, Out: errResult(err)})
+	if err != nil {
+		return nil, err
+	}
+	return &tupleTx{log: d.log, id: id, tx: tx}, nil
+}
+
+func (d *TupleStore) View(ctx context.Context, view func(tx tuple.Tx) error) error {
+	return tuple.View(ctx, d, view)
+}
+
+func (d *TupleStore) Update(ctx context.Context, update func(tx tuple.Tx) error) error {
+	return tuple.Update(ctx, d, update)
+}
+
+func (d *TupleStore) Table(ctx context.Context, name string) (tuple.TableInfo, error) {
+	info, err := d.Store.Table(ctx, name)
+	d.log.write(&Entry{Op: OpTable, In: Args{Table: name}, Out: errResult(err)})
+	if err != nil {
+		return nil, err
+	}
+	return &tableInfo{log: d.log, info: info}, nil
+}
+
+func (d *TupleStore) ListTables(ctx context.Context) ([]tuple.TableInfo, error) {
+	list, err := d.Store.ListTables(ctx)
+	out := errResult(err)
+	for _, info := range list {
+		out.Tables = append(out.Tables, info.Header().Name)
+	}
+	d.log.write(&Entry{Op: OpListTables, Out: out})
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]tuple.TableInfo, 0, len(list))
+	for _, info := range list {
+		infos = append(infos, &tableInfo{log: d.log, info: info})
+	}
+	return infos, nil
+}
+
+type tupleTx struct {
+	log *Log
+	id  uint64
+	tx  tuple.Tx
+}
+
+func (tx *tupleTx) Commit(ctx context.Context) error {
+	err := tx.tx.Commit(ctx)
+	tx.log.write(&Entry{Op: OpCommit, Tx: tx.id, Out: errResult(err)})
+	return err
+}
+
+func (tx *tupleTx) Close() error {
+	err := tx.tx.Close()
+	tx.log.write(&Entry{Op: OpRollback, Tx: tx.id, Out: errResult(err)})
+	return err
+}
+
+func (tx *tupleTx) table(t tuple.Table) tuple.Table {
+	return &table{log: tx.log, tx: tx.id, name: t.Header().Name, tbl: t}
+}
+
+func (tx *tupleTx) Table(ctx context.Context, name string) (tuple.Table, error) {
+	t, err := tx.tx.Table(ctx, name)
+	tx.log.write(&Entry{Op: OpTable, Tx: tx.id, In: Args{Table: name}, Out: errResult(err)})
+	if err != nil {
+		return nil, err
+	}
+	return tx.table(t), nil
+}
+
+func (tx *tupleTx) ListTables(ctx context.Context) ([]tuple.Table, error) {
+	list, err := tx.tx.ListTables(ctx)
+	out := errResult(err)
+	for _, t := range list {
+		out.Tables = append(out.Tables, t.Header().Name)
+	}
+	tx.log.write(&Entry{Op: OpListTables, Tx: tx.id, Out: out})
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]tuple.Table, 0, len(list))
+	for _, t := range list {
+		tables = append(tables, tx.table(t))
+	}
+	return tables, nil
+}
+
+func (tx *tupleTx) CreateTable(ctx context.Context, h tuple.Header) (tuple.Table, error) {
+	in := Args{Table: h.Name}
+	if p, err := tuplepb.MarshalTable(&h); err != nil {
+		in.Unsupported = err.Error()
+	} else {
+		in.Header = p
+	}
+	t, err := tx.tx.CreateTable(ctx, h)
+	tx.log.write(&Entry{Op: OpCreateTable, Tx: tx.id, In: in, Out: errResult(err)})
+	if err != nil {
+		return nil, err
+	}
+	return tx.table(t), nil
+}
+
+func (tx *tupleTx) AlterTable(ctx context.Context, name string, changes ...tuple.Alteration) (tuple.Table, error) {
+	in := Args{Table: name}
+	if arr, err := encodeAlterations(changes); err != nil {
+		in.Unsupported = err.Error()
+	} else {
+		in.Alter = arr
+	}
+	t, err := tx.tx.AlterTable(ctx, name, changes...)
+	tx.log.write(&Entry{Op: OpAlterTable, Tx: tx.id, In: in, Out: errResult(err)})
+	if err != nil {
+		return nil, err
+	}
+	return tx.table(t), nil
+}
+
+// open binds the table info to a transaction, unwrapping the transaction if it was created by the wrapper.
+func open(ctx context.Context, log *Log, info tuple.TableInfo, tx tuple.Tx) (tuple.Table, error) {
+	wtx, ok := tx.(*tupleTx)
+	if !ok {
+		return info.Open(ctx, tx)
+	}
+	name := info.Header().Name
+	t, err := info.Open(ctx, wtx.tx)
+	log.write(&Entry{Op: OpTable, Tx: wtx.id, In: Args{Table: name}, Out: errResult(err)})
+	if err != nil {
+		return nil, err
+	}
+	return wtx.table(t), nil
+}
+
+type tableInfo struct {
+	log  *Log
+	info tuple.TableInfo
+}
+
+func (t *tableInfo) Header() tuple.Header {
+	return t.info.Header()
+}
+
+func (t *tableInfo) Open(ctx context.Context, tx tuple.Tx) (tuple.Table, error) {
+	return open(ctx, t.log, t.info, tx)
+}
+
+type table struct {
+	log  *Log
+	tx   uint64
+	name string
+	tbl  tuple.Table
+}
+
+func (t *table) write(e *Entry) {
+	e.Tx = t.tx
+	e.In.Table = t.name
+	t.log.write(e)
+}
+
+func (t *table) Header() tuple.Header {
+	return t.tbl.Header()
+}
+
+func (t *table) Open(ctx context.Context, tx tuple.Tx) (tuple.Table, error) {
+	return open(ctx, t.log, t.tbl, tx)
+}
+
+func (t *table) Drop(ctx context.Context) error {
+	err := t.tbl.Drop(ctx)
+	t.write(&Entry{Op: OpDropTable, Out: errResult(err)})
+	return err
+}
+
+func (t *table) Clear(ctx context.Context) error {
+	err := t.tbl.Clear(ctx)
+	t.write(&Entry{Op: OpClearTable, Out: errResult(err)})
+	return err
+}
+
+func (t *table) GetTuple(ctx context.Context, key tuple.Key) (tuple.Data, error) {
+	var in Args
+	var err error
+	in.Tuple.Key, err = encodeKey(key)
+	if err != nil {
+		in.Unsupported = err.Error()
+	}
+	data, err := t.tbl.GetTuple(ctx, key)
+	out := errResult(err)
+	if err == nil {
+		out.Tuple.Data, _ = encodeData(data)
+	}
+	t.write(&Entry{Op: OpGet, In: in, Out: out})
+	return data, err
+}
+
+func (t *table) GetTupleBatch(ctx context.Context, keys []tuple.Key) ([]tuple.Data, error) {
+	var in Args
+	for _, k := range keys {
+		ek, err := encodeKey(k)
+		if err != nil {
+			in.Unsupported = err.Error()
+			break
+		}
+		in.TKeys = append(in.TKeys, ek)
+	}
+	data, err := t.tbl.GetTupleBatch(ctx, keys)
+	out := errResult(err)
+	if err == nil {
+		out.Found = make([]bool, len(data))
+		out.Tuples = make([]Tuple, len(data))
+		for i, d := range data {
+			out.Found[i] = d != nil
+			out.Tuples[i].Data, _ = encodeData(d)
+		}
+	}
+	t.write(&Entry{Op: OpGetBatch, In: in, Out: out})
+	return data, err
+}
+
+func (t *table) InsertTuple(ctx context.Context, v tuple.Tuple) (tuple.Key, error) {
+	var in Args
+	var err error
+	if in.Tuple, err = encodeTuple(v); err != nil {
+		in.Unsupported = err.Error()
+	}
+	key, err := t.tbl.InsertTuple(ctx, v)
+	out := errResult(err)
+	if err == nil {
+		out.Tuple.Key, _ = encodeKey(key)
+	}
+	t.write(&Entry{Op: OpInsert, In: in, Out: out})
+	return key, err
+}
+
+func (t *table) UpdateTuple(ctx context.Context, v tuple.Tuple, opt *tuple.UpdateOpt) error {
+	var in Args
+	var err error
+	if in.Tuple, err = encodeTuple(v); err != nil {
+		in.Unsupported = err.Error()
+	}
+	in.Upsert = opt != nil && opt.Upsert
+	err = t.tbl.UpdateTuple(ctx, v, opt)
+	t.write(&Entry{Op: OpUpdate, In: in, Out: errResult(err)})
+	return err
+}
+
+func (t *table) DeleteTuples(ctx context.Context, f *tuple.Filter) error {
+	var in Args
+	var err error
+	if in.Filter, err = encodeFilter(f); err != nil {
+		in.Unsupported = err.Error()
+	}
+	err = t.tbl.DeleteTuples(ctx, f)
+	t.write(&Entry{Op: OpDel, In: in, Out: errResult(err)})
+	return err
+}
+
+func (t *table) Scan(ctx context.Context, opt *tuple.ScanOptions) tuple.Iterator {
+	var in Args
+	var err error
+	if in.Scan, err = encodeScanOptions(opt); err != nil {
+		in.Unsupported = err.Error()
+	}
+	it := &tupleIterator{
+		log: t.log, tx: t.tx, id: t.log.nextID(),
+		keysOnly: opt != nil && opt.KeysOnly,
+		it:       t.tbl.Scan(ctx, opt),
+	}
+	t.write(&Entry{Op: OpScan, Iter: it.id, In: in})
+	return it
+}
+
+type tupleIterator struct {
+	log      *Log
+	tx       uint64
+	id       uint64
+	keysOnly bool // do not record the payload
+	it       tuple.Iterator
+}
+
+func (it *tupleIterator) Reset() {
+	it.it.Reset()
+	it.log.write(&Entry{Op: OpReset, Tx: it.tx, Iter: it.id})
+}
+
+func (it *tupleIterator) result(ok bool) Result {
+	if !ok {
+		return errResult(it.it.Err())
+	}
+	out := Result{OK: true}
+	out.Tuple.Key, _ = encodeKey(it.it.Key())
+	if !it.keysOnly {
+		out.Tuple.Data, _ = encodeData(it.it.Data())
+	}
+	return out
+}
+
+func (it *tupleIterator) Next(ctx context.Context) bool {
+	ok := it.it.Next(ctx)
+	it.log.write(&Entry{Op: OpNext, Tx: it.tx, Iter: it.id, Out: it.result(ok)})
+	return ok
+}
+
+func (it *tupleIterator) Key() tuple.Key {
+	return it.it.Key()
+}
+
+func (it *tupleIterator) Data() tuple.Data {
+	return it.it.Data()
+}
+
+func (it *tupleIterator) Err() error {
+	return it.it.Err()
+}
+
+func (it *tupleIterator) Close() error {
+	err := it.it.Close()
+	it.log.write(&Entry{Op: OpIterClose, Tx: it.tx, Iter: it.id, Out: errResult(err)})
+	return err
+}
@@ -0,0 +1,621 @@
+package record
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hidal-go/hidalgo/filter"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/tuple/tuplepb"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+// Value is a serialized tuple value. Type is zero for nil values.
+type Value struct {
+	Type tuplepb.ValueType
+	Data []byte
+}
+
+func (v Value) equal(v2 Value) bool {
+	return v.Type == v2.Type && bytes.Equal(v.Data, v2.Data)
+}
+
+func valuesEqual(a, b []Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func valueType(v values.Value) (tuplepb.ValueType, bool) {
+	switch v.(type) {
+	case values.Bytes:
+		return tuplepb.ValueType_TYPE_BYTES, true
+	case values.String:
+		return tuplepb.ValueType_TYPE_STRING, true
+	case values.UInt:
+		return tuplepb.ValueType_TYPE_UINT, true
+	case values.Int:
+		return tuplepb.ValueType_TYPE_INT, true
+	case values.Bool:
+		return tuplepb.ValueType_TYPE_BOOL, true
+	case values.Time:
+		return tuplepb.ValueType_TYPE_TIME, true
+	case values.Float:
+		return tuplepb.ValueType_TYPE_FLOAT, true
+	}
+	return 0, false
+}
+
+func encodeValue(v values.Value) (Value, error) {
+	if v == nil {
+		return Value{}, nil
+	}
+	tp, ok := valueType(v)
+	if !ok {
+		return Value{}, fmt.Errorf("unsupported value type: %T", v)
+	}
+	p, err := v.MarshalBinary()
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Type: tp, Data: p}, nil
+}
+
+func (v Value) decode() (values.Value, error) {
+	var dst values.ValueDest
+	switch v.Type {
+	case 0:
+		return nil, nil
+	case tuplepb.ValueType_TYPE_BYTES:
+		dst = new(values.Bytes)
+	case tuplepb.ValueType_TYPE_STRING:
+		dst = new(values.String)
+	case tuplepb.ValueType_TYPE_UINT:
+		dst = new(values.UInt)
+	case tuplepb.ValueType_TYPE_INT:
+		dst = new(values.Int)
+	case tuplepb.ValueType_TYPE_BOOL:
+		dst = new(values.Bool)
+	case tuplepb.ValueType_TYPE_TIME:
+		dst = new(values.Time)
+	case tuplepb.ValueType_TYPE_FLOAT:
+		dst = new(values.Float)
+	default:
+		return nil, fmt.Errorf("unsupported value type: %v", v.Type)
+	}
+	if err := dst.UnmarshalBinary(v.Data); err != nil {
+		return nil, err
+	}
+	return dst.Value(), nil
+}
+
+func (v Value) decodeSortable() (values.Sortable, error) {
+	dv, err := v.decode()
+	if err != nil || dv == nil {
+		return nil, err
+	}
+	s, ok := dv.(values.Sortable)
+	if !ok {
+		return nil, fmt.Errorf("value is not sortable: %T", dv)
+	}
+	return s, nil
+}
+
+func encodeKey(k tuple.Key) ([]Value, error) {
+	if k == nil {
+		return nil, nil
+	}
+	out := make([]Value, 0, len(k))
+	for _, v := range k {
+		ev, err := encodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+func decodeKey(k []Value) (tuple.Key, error) {
+	if k == nil {
+		return nil, nil
+	}
+	out := make(tuple.Key, 0, len(k))
+	for _, v := range k {
+		s, err := v.decodeSortable()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func encodeData(d tuple.Data) ([]Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	out := make([]Value, 0, len(d))
+	for _, v := range d {
+		ev, err := encodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+func decodeData(d []Value) (tuple.Data, error) {
+	if d == nil {
+		return nil, nil
+	}
+	out := make(tuple.Data, 0, len(d))
+	for _, v := range d {
+		dv, err := v.decode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, dv)
+	}
+	return out, nil
+}
+
+// Tuple is a serialized tuple.
+type Tuple struct {
+	Key  []Value
+	Data []Value
+}
+
+func encodeTuple(t tuple.Tuple) (Tuple, error) {
+	k, err := encodeKey(t.Key)
+	if err != nil {
+		return Tuple{}, err
+	}
+	d, err := encodeData(t.Data)
+	if err != nil {
+		return Tuple{}, err
+	}
+	return Tuple{Key: k, Data: d}, nil
+}
+
+func (t Tuple) equal(t2 Tuple) bool {
+	return valuesEqual(t.Key, t2.Key) && valuesEqual(t.Data, t2.Data)
+}
+
+func (t Tuple) decode() (tuple.Tuple, error) {
+	k, err := decodeKey(t.Key)
+	if err != nil {
+		return tuple.Tuple{}, err
+	}
+	d, err := decodeData(t.Data)
+	if err != nil {
+		return tuple.Tuple{}, err
+	}
+	return tuple.Tuple{Key: k, Data: d}, nil
+}
+
+type filterKind uint8
+
+const (
+	filterNone = filterKind(iota)
+	filterAny
+	filterEqual
+	filterLess
+	filterGreater
+	filterRange
+	filterAnd
+	filterOr
+	filterNot
+)
+
+// ValueFilter is a serialized filter.ValueFilter.
+type ValueFilter struct {
+	Kind  filterKind
+	Value Value
+	Equal bool
+	Sub   []ValueFilter
+}
+
+func encodeValueFilter(f filter.ValueFilter) (ValueFilter, error) {
+	switch f := f.(type) {
+	case nil:
+		return ValueFilter{}, nil
+	case filter.Any:
+		return ValueFilter{Kind: filterAny}, nil
+	case filter.Equal:
+		v, err := encodeValue(f.Value)
+		return ValueFilter{Kind: filterEqual, Value: v}, err
+	case filter.Less:
+		v, err := encodeValue(f.Value)
+		return ValueFilter{Kind: filterLess, Value: v, Equal: f.Equal}, err
+	case *filter.Less:
+		if f == nil {
+			return ValueFilter{}, nil
+		}
+		return encodeValueFilter(*f)
+	case filter.Greater:
+		v, err := encodeValue(f.Value)
+		return ValueFilter{Kind: filterGreater, Value: v, Equal: f.Equal}, err
+	case *filter.Greater:
+		if f == nil {
+			return ValueFilter{}, nil
+		}
+		return encodeValueFilter(*f)
+	case filter.Range:
+		return encodeFilters(filterRange, []filter.ValueFilter{f.Start, f.End})
+	case *filter.Range:
+		return encodeValueFilter(*f)
+	case filter.And:
+		return encodeFilters(filterAnd, f)
+	case filter.Or:
+		return encodeFilters(filterOr, f)
+	case filter.Not:
+		return encodeFilters(filterNot, []filter.ValueFilter{f.Filter})
+	}
+	return ValueFilter{}, fmt.Errorf("unsupported filter: %T", f)
+}
+
+func encodeFilters(kind filterKind, arr []filter.ValueFilter) (ValueFilter, error) {
+	out := ValueFilter{Kind: kind, Sub: make([]ValueFilter, 0, len(arr))}
+	for _, f := range arr {
+		ef, err := encodeValueFilter(f)
+		if err != nil {
+			return ValueFilter{}, err
+		}
+		out.Sub = append(out.Sub, ef)
+	}
+	return out, nil
+}
+
+func (f ValueFilter) decode() (filter.ValueFilter, error) {
+	switch f.Kind {
+	case filterNone:
+		return nil, nil
+	case filterAny:
+		return filter.Any{}, nil
+	case filterEqual:
+		v, err := f.Value.decode()
+		if err != nil {
+			return nil, err
+		}
+		return filter.Equal{Value: v}, nil
+	case filterLess:
+		v, err := f.Value.decodeSortable()
+		if err != nil {
+			return nil, err
+		}
+		return filter.Less{Value: v, Equal: f.Equal}, nil
+	case filterGreater:
+		v, err := f.Value.decodeSortable()
+		if err != nil {
+			return nil, err
+		}
+		return filter.Greater{Value: v, Equal: f.Equal}, nil
+	case filterRange:
+		if len(f.Sub) != 2 {
+			return nil, fmt.Errorf("invalid range filter")
+		}
+		var r filter.Range
+		if s := f.Sub[0]; s.Kind != filterNone {
+			v, err := s.Value.decodeSortable()
+			if err != nil {
+				return nil, err
+			}
+			r.Start = &filter.Greater{Value: v, Equal: s.Equal}
+		}
+		if e := f.Sub[1]; e.Kind != filterNone {
+			v, err := e.Value.decodeSortable()
+			if err != nil {
+				return nil, err
+			}
+			r.End = &filter.Less{Value: v, Equal: e.Equal}
+		}
+		return r, nil
+	case filterAnd, filterOr:
+		arr, err := decodeFilters(f.Sub)
+		if err != nil {
+			return nil, err
+		}
+		if f.Kind == filterAnd {
+			return filter.And(arr), nil
+		}
+		return filter.Or(arr), nil
+	case filterNot:
+		if len(f.Sub) != 1 {
+			return nil, fmt.Errorf("invalid not filter")
+		}
+		sub, err := f.Sub[0].decode()
+		if err != nil {
+			return nil, err
+		}
+		return filter.Not{Filter: sub}, nil
+	}
+	return nil, fmt.Errorf("unsupported filter kind: %d", f.Kind)
+}
+
+func decodeFilters(arr []ValueFilter) ([]filter.ValueFilter, error) {
+	if arr == nil {
+		return nil, nil
+	}
+	out := make([]filter.ValueFilter, 0, len(arr))
+	for _, f := range arr {
+		df, err := f.decode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, df)
+	}
+	return out, nil
+}
+
+// Filter is a serialized tuple.Filter.
+type Filter struct {
+	KeyFilters []ValueFilter
+	Keys       [][]Value
+	HasKeys    bool // KeyFilter is tuple.Keys
+	DataFilter []ValueFilter
+	HasData    bool // DataFilter is set
+}
+
+func encodeFilter(f *tuple.Filter) (*Filter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	out := &Filter{}
+	switch kf := f.KeyFilter.(type) {
+	case nil:
+	case tuple.KeyFilters:
+		ef, err := encodeFilters(filterAnd, kf)
+		if err != nil {
+			return nil, err
+		}
+		out.KeyFilters = ef.Sub
+	case tuple.Keys:
+		out.HasKeys = true
+		for _, k := range kf {
+			ek, err := encodeKey(k)
+			if err != nil {
+				return nil, err
+			}
+			out.Keys = append(out.Keys, ek)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key filter: %T", f.KeyFilter)
+	}
+	switch df := f.DataFilter.(type) {
+	case nil:
+	case tuple.DataFilters:
+		ef, err := encodeFilters(filterAnd, df)
+		if err != nil {
+			return nil, err
+		}
+		out.HasData = true
+		out.DataFilter = ef.Sub
+	default:
+		return nil, fmt.Errorf("unsupported data filter: %T", f.DataFilter)
+	}
+	return out, nil
+}
+
+func (f *Filter) decode() (*tuple.Filter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	out := &tuple.Filter{}
+	if f.HasKeys {
+		keys := make(tuple.Keys, 0, len(f.Keys))
+		for _, ek := range f.Keys {
+			k, err := decodeKey(ek)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		}
+		out.KeyFilter = keys
+	} else if len(f.KeyFilters) != 0 {
+		arr, err := decodeFilters(f.KeyFilters)
+		if err != nil {
+			return nil, err
+		}
+		out.KeyFilter = tuple.KeyFilters(arr)
+	}
+	if f.HasData {
+		arr, err := decodeFilters(f.DataFilter)
+		if err != nil {
+			return nil, err
+		}
+		out.DataFilter = tuple.DataFilters(arr)
+	}
+	return out, nil
+}
+
+// ScanOptions is a serialized tuple.ScanOptions.
+type ScanOptions struct {
+	KeysOnly bool
+	Sort     tuple.Sorting
+	Filter   *Filter
+	Limit    int
+	Fields   []string
+	After    []Value
+	Before   []Value
+	Offset   int
+}
+
+func encodeScanOptions(opt *tuple.ScanOptions) (*ScanOptions, error) {
+	if opt == nil {
+		return nil, nil
+	}
+	f, err := encodeFilter(opt.Filter)
+	if err != nil {
+		return nil, err
+	}
+	after, err := encodeKey(opt.After)
+	if err != nil {
+		return nil, err
+	}
+	before, err := encodeKey(opt.Before)
+	if err != nil {
+		return nil, err
+	}
+	return &ScanOptions{
+		KeysOnly: opt.KeysOnly,
+		Sort:     opt.Sort,
+		Filter:   f,
+		Limit:    opt.Limit,
+		Fields:   opt.Fields,
+		After:    after,
+		Before:   before,
+		Offset:   opt.Offset,
+	}, nil
+}
+
+func (opt *ScanOptions) decode() (*tuple.ScanOptions, error) {
+	if opt == nil {
+		return nil, nil
+	}
+	f, err := opt.Filter.decode()
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeKey(opt.After)
+	if err != nil {
+		return nil, err
+	}
+	before, err := decodeKey(opt.Before)
+	if err != nil {
+		return nil, err
+	}
+	return &tuple.ScanOptions{
+		KeysOnly: opt.KeysOnly,
+		Sort:     opt.Sort,
+		Filter:   f,
+		Limit:    opt.Limit,
+		Fields:   opt.Fields,
+		After:    after,
+		Before:   before,
+		Offset:   opt.Offset,
+	}, nil
+}
+
+func encodeType(t values.Type) (tuplepb.ValueType, error) {
+	if t == nil {
+		return 0, fmt.Errorf("unsupported value type: %T", t)
+	}
+	tp, ok := valueType(t.New().Value())
+	if !ok {
+		return 0, fmt.Errorf("unsupported value type: %T", t)
+	}
+	return tp, nil
+}
+
+func decodeType(tp tuplepb.ValueType) (values.Type, error) {
+	switch tp {
+	case tuplepb.ValueType_TYPE_BYTES:
+		return values.BytesType{}, nil
+	case tuplepb.ValueType_TYPE_STRING:
+		return values.StringType{}, nil
+	case tuplepb.ValueType_TYPE_UINT:
+		return values.UIntType{}, nil
+	case tuplepb.ValueType_TYPE_INT:
+		return values.IntType{}, nil
+	case tuplepb.ValueType_TYPE_BOOL:
+		return values.BoolType{}, nil
+	case tuplepb.ValueType_TYPE_TIME:
+		return values.TimeType{}, nil
+	case tuplepb.ValueType_TYPE_FLOAT:
+		return values.FloatType{}, nil
+	}
+	return nil, fmt.Errorf("unsupported value type: %v", tp)
+}
+
+type alterKind uint8
+
+const (
+	alterAddField = alterKind(iota + 1)
+	alterDropField
+	alterRenameField
+	alterSetDefault
+)
+
+// Alteration is a serialized tuple.Alteration.
+type Alteration struct {
+	Kind     alterKind
+	Name     string
+	NewName  string
+	Type     tuplepb.ValueType
+	Nullable bool
+	Default  Value
+}
+
+func encodeAlterations(arr []tuple.Alteration) ([]Alteration, error) {
+	out := make([]Alteration, 0, len(arr))
+	for _, a := range arr {
+		switch a := a.(type) {
+		case tuple.AddField:
+			tp, err := encodeType(a.Field.Type)
+			if err != nil {
+				return nil, err
+			}
+			def, err := encodeValue(a.Field.Default)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Alteration{
+				Kind: alterAddField, Name: a.Field.Name, Type: tp, Nullable: a.Field.Nullable, Default: def,
+			})
+		case tuple.DropField:
+			out = append(out, Alteration{Kind: alterDropField, Name: a.Name})
+		case tuple.RenameField:
+			out = append(out, Alteration{Kind: alterRenameField, Name: a.Name, NewName: a.NewName})
+		case tuple.SetDefault:
+			def, err := encodeValue(a.Default)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Alteration{Kind: alterSetDefault, Name: a.Name, Default: def})
+		default:
+			return nil, fmt.Errorf("unsupported alteration: %T", a)
+		}
+	}
+	return out, nil
+}
+
+func decodeAlterations(arr []Alteration) ([]tuple.Alteration, error) {
+	out := make([]tuple.Alteration, 0, len(arr))
+	for _, a := range arr {
+		switch a.Kind {
+		case alterAddField:
+			tp, err := decodeType(a.Type)
+			if err != nil {
+				return nil, err
+			}
+			def, err := a.Default.decode()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, tuple.AddField{Field: tuple.Field{
+				Name: a.Name, Type: tp, Nullable: a.Nullable, Default: def,
+			}})
+		case alterDropField:
+			out = append(out, tuple.DropField{Name: a.Name})
+		case alterRenameField:
+			out = append(out, tuple.RenameField{Name: a.Name, NewName: a.NewName})
+		case alterSetDefault:
+			def, err := a.Default.decode()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, tuple.SetDefault{Name: a.Name, Default: def})
+		default:
+			return nil, fmt.Errorf("unsupported alteration kind: %d", a.Kind)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,347 @@
+package tuple
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/hidal-go/hidalgo/values"
+)
+
+// AggFunc is an aggregation function.
+type AggFunc int
+
+const (
+	// Count returns a number of tuples in a group, or a number of non-null values, if the field is set.
+	Count = AggFunc(iota + 1)
+	// Sum returns a sum of non-null values. It supports int, uint and float fields.
+	Sum
+	// Min returns a minimal non-null value. It supports int, uint, float, string and time fields.
+	Min
+	// Max returns a maximal non-null value. It supports int, uint, float, string and time fields.
+	Max
+	// Avg returns an average of non-null values as a float. It supports int, uint and float fields.
+	Avg
+)
+
+func (f AggFunc) String() string {
+	switch f {
+	case Count:
+		return "count"
+	case Sum:
+		return "sum"
+	case Min:
+		return "min"
+	case Max:
+		return "max"
+	case Avg:
+		return "avg"
+	}
+	return fmt.Sprintf("AggFunc(%d)", int(f))
+}
+
+// Aggregate is a single aggregation of a payload field.
+type Aggregate struct {
+	Func  AggFunc // aggregation function
+	Field string  // name of the payload field; optional for Count
+}
+
+// ResultType returns a type of the aggregation result.
+func (a Aggregate) ResultType(h Header) Type {
+	switch a.Func {
+	case Count:
+		return values.IntType{}
+	case Avg:
+		return values.FloatType{}
+	}
+	f, _ := h.DataByName(a.Field)
+	if f == nil {
+		return nil
+	}
+	return f.Type
+}
+
+// GroupBy selects fields used to group tuples for aggregation.
+type GroupBy struct {
+	// KeyPrefix is a number of primary key fields to group by.
+	KeyPrefix int
+	// Fields is a list of payload fields to group by.
+	Fields []string
+}
+
+// IsAny checks if tuples are not grouped.
+func (g GroupBy) IsAny() bool {
+	return g.KeyPrefix == 0 && len(g.Fields) == 0
+}
+
+// AggregateOptions describes aggregations computed by Aggregate.
+type AggregateOptions struct {
+	// Filter is an optional filter for tuples.
+	Filter *Filter
+	// GroupBy is an optional grouping of tuples. All tuples are aggregated into one group by default.
+	GroupBy GroupBy
+	// Aggregates is a list of aggregations to compute for each group.
+	Aggregates []Aggregate
+}
+
+// Validate checks if aggregations can be computed for the table.
+func (opt *AggregateOptions) Validate(h Header) error {
+	if len(opt.Aggregates) == 0 {
+		return fmt.Errorf("no aggregations specified")
+	} else if opt.GroupBy.KeyPrefix < 0 || opt.GroupBy.KeyPrefix > len(h.Key) {
+		return fmt.Errorf("invalid key prefix: %d", opt.GroupBy.KeyPrefix)
+	}
+	if _, err := h.Projection(opt.GroupBy.Fields); err != nil {
+		return err
+	}
+	for _, a := range opt.Aggregates {
+		if a.Func == Count && a.Field == "" {
+			continue
+		}
+		f, _ := h.DataByName(a.Field)
+		if f == nil {
+			return fmt.Errorf("%v: no such payload field: %q", a.Func, a.Field)
+		}
+		var ok bool
+		switch f.Type.(type) {
+		case values.IntType, values.UIntType, values.FloatType:
+			ok = a.Func != 0 && a.Func <= Avg
+		case values.StringType, values.TimeType:
+			ok = a.Func == Count || a.Func == Min || a.Func == Max
+		default:
+			ok = a.Func == Count
+		}
+		if !ok {
+			return fmt.Errorf("%v is not supported for %q field", a.Func, a.Field)
+		}
+	}
+	return nil
+}
+
+// Group is a result of the aggregation for one group of tuples.
+type Group struct {
+	Key    Key  // values of the key prefix used for grouping
+	Fields Data // values of payload fields used for grouping
+	Values Data // aggregation results, in the same order as aggregations; nil if there are no values
+}
+
+// Aggregator is an optional interface for tables that can compute aggregations natively.
+type Aggregator interface {
+	// Aggregate computes aggregations for groups of tuples matching the filter.
+	// Groups are returned in an unspecified order. Groups with no tuples are not returned,
+	// except when tuples are not grouped, in which case a single group is always returned.
+	Aggregate(ctx context.Context, opt *AggregateOptions) ([]Group, error)
+}
+
+// AggregateTable computes aggregations for the table. See Aggregator.
+// If the table does not implement Aggregator, it scans all matching tuples with AggregateScan.
+func AggregateTable(ctx context.Context, t Table, opt *AggregateOptions) ([]Group, error) {
+	if a, ok := t.(Aggregator); ok {
+		return a.Aggregate(ctx, opt)
+	}
+	return AggregateScan(ctx, t.Header(), t, opt)
+}
+
+// AggregateScan computes aggregations by scanning all tuples matching the filter.
+// Only fields required by the aggregation are fetched. It can be used by backends
+// that cannot compute specific aggregations natively.
+func AggregateScan(ctx context.Context, h Header, s Scanner, opt *AggregateOptions) ([]Group, error) {
+	if err := opt.Validate(h); err != nil {
+		return nil, err
+	}
+	// fetch only fields that are used for grouping and aggregation
+	var (
+		fields []string
+		pos    = make(map[string]int)
+	)
+	use := func(name string) int {
+		if i, ok := pos[name]; ok {
+			return i
+		}
+		pos[name] = len(fields)
+		fields = append(fields, name)
+		return len(fields) - 1
+	}
+	groups := make([]int, 0, len(opt.GroupBy.Fields))
+	for _, name := range opt.GroupBy.Fields {
+		groups = append(groups, use(name))
+	}
+	aggs := make([]int, 0, len(opt.Aggregates))
+	for _, a := range opt.Aggregates {
+		if a.Field == "" {
+			aggs = append(aggs, -1)
+			continue
+		}
+		aggs = append(aggs, use(a.Field))
+	}
+	sopt := &ScanOptions{Filter: opt.Filter, Fields: fields}
+	if len(fields) == 0 && opt.Filter.IsAnyData() {
+		sopt.KeysOnly = true
+	}
+
+	type group struct {
+		Group
+		acc []aggState
+	}
+	var (
+		out  []*group
+		byID = make(map[string]*group)
+		buf  []byte
+	)
+	it := s.Scan(ctx, sopt)
+	defer it.Close()
+	for it.Next(ctx) {
+		key := it.Key()[:opt.GroupBy.KeyPrefix]
+		var data Data
+		if !sopt.KeysOnly {
+			data = it.Data()
+		}
+		gdata := make(Data, len(groups))
+		for i, j := range groups {
+			gdata[i] = data[j]
+		}
+		var err error
+		buf, err = appendGroupID(buf[:0], key, gdata)
+		if err != nil {
+			return nil, err
+		}
+		g := byID[string(buf)]
+		if g == nil {
+			g = &group{Group: Group{Key: key, Fields: gdata}, acc: make([]aggState, len(aggs))}
+			byID[string(buf)] = g
+			out = append(out, g)
+		}
+		for i, j := range aggs {
+			if j < 0 {
+				g.acc[i].add(nil, true)
+			} else {
+				g.acc[i].add(data[j], false)
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 && opt.GroupBy.IsAny() {
+		out = append(out, &group{acc: make([]aggState, len(aggs))})
+	}
+	res := make([]Group, 0, len(out))
+	for _, g := range out {
+		g.Values = make(Data, len(aggs))
+		for i, a := range opt.Aggregates {
+			g.Values[i] = g.acc[i].result(a.Func, a.ResultType(h))
+		}
+		res = append(res, g.Group)
+	}
+	return res, nil
+}
+
+// appendGroupID appends a unique binary representation of the group values to the buffer.
+func appendGroupID(buf []byte, key Key, data Data) ([]byte, error) {
+	var tmp [binary.MaxVarintLen64]byte
+	add := func(v Value) error {
+		if v == nil {
+			buf = append(buf, 0)
+			return nil
+		}
+		p, err := v.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		n := binary.PutUvarint(tmp[:], uint64(len(p)))
+		buf = append(buf, 1)
+		buf = append(buf, tmp[:n]...)
+		buf = append(buf, p...)
+		return nil
+	}
+	for _, v := range key {
+		if err := add(v); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range data {
+		if err := add(v); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// aggState is a state of a single aggregation within a group.
+type aggState struct {
+	n    int64 // number of non-null values
+	isum int64
+	usum uint64
+	fsum float64
+	min  Value
+	max  Value
+}
+
+// add accumulates the value. If all flag is set, the value is counted even if it is null.
+func (s *aggState) add(v Value, all bool) {
+	if v == nil {
+		if all {
+			s.n++
+		}
+		return
+	}
+	s.n++
+	switch v := v.(type) {
+	case values.Int:
+		s.isum += int64(v)
+		s.fsum += float64(v)
+	case values.UInt:
+		s.usum += uint64(v)
+		s.fsum += float64(v)
+	case values.Float:
+		s.fsum += float64(v)
+	}
+	if s.min == nil || compareAgg(v, s.min) < 0 {
+		s.min = v
+	}
+	if s.max == nil || compareAgg(v, s.max) > 0 {
+		s.max = v
+	}
+}
+
+func (s *aggState) result(f AggFunc, typ Type) Value {
+	if f == Count {
+		return values.Int(s.n)
+	} else if s.n == 0 {
+		return nil
+	}
+	switch f {
+	case Sum:
+		switch typ.(type) {
+		case values.IntType:
+			return values.Int(s.isum)
+		case values.UIntType:
+			return values.UInt(s.usum)
+		}
+		return values.Float(s.fsum)
+	case Avg:
+		return values.Float(s.fsum / float64(s.n))
+	case Min:
+		return s.min
+	case Max:
+		return s.max
+	}
+	return nil
+}
+
+// compareAgg compares values used in Min and Max aggregations.
+func compareAgg(a, b Value) int {
+	if fa, ok := a.(values.Float); ok {
+		fb, _ := b.(values.Float)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return +1
+		}
+		return 0
+	}
+	sa, _ := a.(Sortable)
+	sb, _ := b.(Sortable)
+	return values.Compare(sa, sb)
+}
@@ -0,0 +1,144 @@
+package tuple
+
+import "fmt"
+
+// Alteration is a single change of the table schema. See Tx.AlterTable.
+type Alteration interface {
+	// alter applies the change to the header and updates the migration accordingly.
+	alter(h *Header, m *Migration) error
+}
+
+// AddField appends a new payload field to the table. Existing tuples will get a default value of the field,
+// or null, if the field has no default value. Thus, the field must either be nullable or have a default value.
+type AddField struct {
+	Field Field // new payload field
+}
+
+func (a AddField) alter(h *Header, m *Migration) error {
+	if a.Field.Default == nil && !a.Field.Nullable {
+		return fmt.Errorf("add field %q: field must be nullable or have a default value", a.Field.Name)
+	}
+	h.Data = append(h.Data, a.Field)
+	m.Fields = append(m.Fields, -1)
+	m.Defaults = append(m.Defaults, a.Field.Default)
+	return nil
+}
+
+// DropField removes a payload field from the table. Fields used by indexes cannot be removed.
+type DropField struct {
+	Name string // name of the payload field
+}
+
+func (a DropField) alter(h *Header, m *Migration) error {
+	f, i := h.DataByName(a.Name)
+	if f == nil {
+		return fmt.Errorf("drop field %q: no such payload field", a.Name)
+	}
+	for _, ind := range h.Indexes {
+		for _, name := range ind.Fields {
+			if name == a.Name {
+				return fmt.Errorf("drop field %q: field is used by index %q", a.Name, ind.Name)
+			}
+		}
+	}
+	h.Data = append(h.Data[:i:i], h.Data[i+1:]...)
+	m.Fields = append(m.Fields[:i:i], m.Fields[i+1:]...)
+	m.Defaults = append(m.Defaults[:i:i], m.Defaults[i+1:]...)
+	return nil
+}
+
+// RenameField changes a name of the payload field. Indexes are updated accordingly.
+type RenameField struct {
+	Name    string // current name of the payload field
+	NewName string // new name of the payload field
+}
+
+func (a RenameField) alter(h *Header, m *Migration) error {
+	f, i := h.DataByName(a.Name)
+	if f == nil {
+		return fmt.Errorf("rename field %q: no such payload field", a.Name)
+	}
+	h.Data[i].Name = a.NewName
+	for _, ind := range h.Indexes {
+		for j, name := range ind.Fields {
+			if name == a.Name {
+				ind.Fields[j] = a.NewName
+			}
+		}
+	}
+	return nil
+}
+
+// SetDefault changes a default value of the payload field. Nil value removes the default.
+// Existing tuples are not affected.
+type SetDefault struct {
+	Name    string // name of the payload field
+	Default Value  // new default value
+}
+
+func (a SetDefault) alter(h *Header, m *Migration) error {
+	f, i := h.DataByName(a.Name)
+	if f == nil {
+		return fmt.Errorf("set default %q: no such payload field", a.Name)
+	}
+	h.Data[i].Default = a.Default
+	return nil
+}
+
+// Migration describes how payloads of the old schema are converted to the new one.
+type Migration struct {
+	// Fields contains an index of the field in the old payload for each field of the new payload.
+	// Negative index indicates a new field, in which case the value is taken from Defaults.
+	Fields []int
+	// Defaults contains values for the new fields, which may be null. Values for other fields are nil.
+	Defaults Data
+}
+
+// IsIdentity checks if payloads are not changed by the migration.
+func (m *Migration) IsIdentity(old int) bool {
+	if len(m.Fields) != old {
+		return false
+	}
+	for i, j := range m.Fields {
+		if i != j {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply converts the payload of the old schema to the new one.
+func (m *Migration) Apply(d Data) Data {
+	out := make(Data, len(m.Fields))
+	for i, j := range m.Fields {
+		if j < 0 {
+			out[i] = m.Defaults[i]
+		} else {
+			out[i] = d[j]
+		}
+	}
+	return out
+}
+
+// Alter applies schema changes to the header and returns the new header,
+// as well as a migration that converts payloads of the old schema.
+// Primary key of the table cannot be changed.
+func (t Header) Alter(changes ...Alteration) (Header, *Migration, error) {
+	h := t.Clone()
+	m := &Migration{
+		Fields:   make([]int, len(h.Data)),
+		Defaults: make(Data, len(h.Data)),
+	}
+	for i := range m.Fields {
+		m.Fields[i] = i
+	}
+	for _, c := range changes {
+		if err := c.alter(&h, m); err != nil {
+			return Header{}, nil, err
+		}
+	}
+	if err := h.Validate(); err != nil {
+		return Header{}, nil, err
+	}
+	return h, m, nil
+}
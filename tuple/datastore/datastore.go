@@ -9,6 +9,8 @@ import (
 	"cloud.google.com/go/datastore"
 	"google.golang.org/api/iterator"
 
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/filter"
 	"github.com/hidal-go/hidalgo/tuple"
 	"github.com/hidal-go/hidalgo/tuple/tuplepb"
 	"github.com/hidal-go/hidalgo/values"
@@ -34,6 +36,12 @@ func (s *TupleStore) Close() error {
 	return s.c.Close()
 }
 
+// Capabilities implements base.Capable.
+func (s *TupleStore) Capabilities() base.Capabilities {
+	// TODO: support transactions
+	return base.CapConcurrentWrites
+}
+
 func (s *TupleStore) metaRoot() *datastore.Key {
 	return datastore.NameKey(kindHidalgo, idHidalgo, nil)
 }
@@ -93,6 +101,9 @@ func (s *TupleStore) ListTables(ctx context.Context) ([]tuple.TableInfo, error)
 }
 
 func (s *TupleStore) Tx(ctx context.Context, rw bool) (tuple.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return &Tx{s: s, rw: rw}, nil
 }
 
@@ -117,7 +128,7 @@ type Tx struct {
 
 func (tx *Tx) Commit(ctx context.Context) error {
 	// TODO: support transactions properly
-	return nil
+	return ctx.Err()
 }
 
 func (tx *Tx) Close() error {
@@ -155,6 +166,13 @@ type tableObject struct {
 func (tx *Tx) CreateTable(ctx context.Context, table tuple.Header) (tuple.Table, error) {
 	if !tx.rw {
 		return nil, tuple.ErrReadOnly
+	} else if err := table.Validate(); err != nil {
+		return nil, err
+	}
+	for _, ind := range table.Indexes {
+		if ind.Unique {
+			return nil, fmt.Errorf("datastore: unique indexes are not supported")
+		}
 	}
 	data, err := tuplepb.MarshalTable(&table)
 	if err != nil {
@@ -179,6 +197,81 @@ func (tx *Tx) CreateTable(ctx context.Context, table tuple.Header) (tuple.Table,
 	return &Table{tx: tx, h: table}, nil
 }
 
+// AlterTable updates the table header. Entities are rewritten only if fields are added or renamed,
+// since dropped properties are ignored when loading tuples.
+func (tx *Tx) AlterTable(ctx context.Context, name string, changes ...tuple.Alteration) (tuple.Table, error) {
+	if !tx.rw {
+		return nil, tuple.ErrReadOnly
+	}
+	info, err := tx.s.Table(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	tbl := &Table{tx: tx, h: info.Header()}
+	h, m, err := tbl.h.Alter(changes...)
+	if err != nil {
+		return nil, err
+	}
+	data, err := tuplepb.MarshalTable(&h)
+	if err != nil {
+		return nil, err
+	}
+	rewrite := false
+	for _, c := range changes {
+		switch c.(type) {
+		case tuple.AddField, tuple.RenameField:
+			rewrite = true
+		}
+	}
+	if rewrite {
+		if err = tbl.migrate(ctx, &h, m); err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.s.c.Put(ctx, tx.s.tableKey(name), &tableObject{Data: data})
+	if err != nil {
+		return nil, err
+	}
+	tbl.h = h
+	return tbl, nil
+}
+
+// migrate rewrites all entities of the table using a new header.
+func (tbl *Table) migrate(ctx context.Context, h *tuple.Header, m *tuple.Migration) error {
+	var (
+		keys []*datastore.Key
+		rows []payload
+	)
+	it := tbl.cli().Run(ctx, datastore.NewQuery(tbl.h.Name))
+	for {
+		p := payload{h: &tbl.h}
+		k, err := it.Next(&p)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return err
+		}
+		keys = append(keys, k)
+		rows = append(rows, payload{h: h, t: tuple.Tuple{Key: p.t.Key, Data: m.Apply(p.t.Data)}})
+	}
+	const batch = 100
+	for len(keys) != 0 {
+		n := batch
+		if n > len(keys) {
+			n = len(keys)
+		}
+		if _, err := tbl.cli().PutMulti(ctx, keys[:n], rows[:n]); err != nil {
+			return err
+		}
+		keys, rows = keys[n:], rows[n:]
+	}
+	return nil
+}
+
+// Table is a tuple table backed by entities of one kind.
+//
+// Table does not implement tuple.Aggregator, since the client library has no aggregation queries.
+// Aggregations are computed by tuple.AggregateScan, which uses keys-only and projection queries when possible.
 type Table struct {
 	tx *Tx
 	h  tuple.Header
@@ -396,41 +489,63 @@ func (p *payload) Save() ([]datastore.Property, error) {
 		})
 	}
 	for i, c := range p.h.Data {
-		v := p.t.Data[i]
-		var val interface{}
-		if v != nil {
-			switch c.Type.(type) {
-			case values.BytesType:
-				val = []byte(v.(values.Bytes))
-			case values.StringType:
-				val = string(v.(values.String))
-			case values.IntType:
-				val = int64(v.(values.Int))
-			case values.UIntType:
-				val = int64(v.(values.UInt))
-			case values.FloatType:
-				val = float64(v.(values.Float))
-			case values.BoolType:
-				val = bool(v.(values.Bool))
-			case values.TimeType:
-				val = time.Time(v.(values.Time))
-			default:
-				data, err := v.MarshalBinary()
-				if err != nil {
-					return nil, err
-				}
-				val = data
-			}
+		val, err := propValue(p.t.Data[i])
+		if err != nil {
+			return nil, err
 		}
 		out = append(out, datastore.Property{
 			Name:    c.Name,
-			NoIndex: true,
+			NoIndex: !indexed(p.h, c.Name),
 			Value:   val,
 		})
 	}
 	return out, nil
 }
 
+// propValue converts a payload value to a property value.
+func propValue(v values.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case values.Bytes:
+		return []byte(v), nil
+	case values.String:
+		return string(v), nil
+	case values.Int:
+		return int64(v), nil
+	case values.UInt:
+		return int64(v), nil
+	case values.Float:
+		return float64(v), nil
+	case values.Bool:
+		return bool(v), nil
+	case values.Time:
+		return time.Time(v), nil
+	}
+	return v.MarshalBinary()
+}
+
+func dataFilters(f *tuple.Filter) (tuple.DataFilters, bool) {
+	if f.IsAnyData() {
+		return nil, false
+	}
+	df, ok := f.DataFilter.(tuple.DataFilters)
+	return df, ok
+}
+
+// indexed checks if a payload field is covered by a secondary index.
+// Such fields are stored as indexed properties.
+func indexed(h *tuple.Header, name string) bool {
+	for _, ind := range h.Indexes {
+		for _, f := range ind.Fields {
+			if f == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (tbl *Table) GetTuple(ctx context.Context, key tuple.Key) (tuple.Data, error) {
 	if err := tbl.h.ValidateKey(key, false); err != nil {
 		return nil, err
@@ -477,6 +592,7 @@ func (tbl *Table) InsertTuple(ctx context.Context, t tuple.Tuple) (tuple.Key, er
 	} else if err := tbl.h.ValidateData(t.Data); err != nil {
 		return nil, err
 	}
+	t.Data = tbl.h.WithDefaults(t.Data)
 	tx, err := tbl.cli().NewTransaction(ctx)
 	if err != nil {
 		return nil, err
@@ -510,6 +626,7 @@ func (tbl *Table) UpdateTuple(ctx context.Context, t tuple.Tuple, opt *tuple.Upd
 	} else if err := tbl.h.ValidateData(t.Data); err != nil {
 		return err
 	}
+	t.Data = tbl.h.WithDefaults(t.Data)
 	if opt == nil {
 		opt = &tuple.UpdateOpt{}
 	}
@@ -533,6 +650,140 @@ func (tbl *Table) UpdateTuple(ctx context.Context, t tuple.Tuple, opt *tuple.Upd
 	return err
 }
 
+var _ tuple.BatchWriter = (*Table)(nil)
+
+// maxBatch is the maximal number of entities written in a single commit.
+const maxBatch = 500
+
+// validateBatch checks keys and payloads of all tuples in the batch and replaces nulls with defaults.
+func (tbl *Table) validateBatch(tuples []tuple.Tuple, insert bool) ([]tuple.Tuple, error) {
+	if !tbl.tx.rw {
+		return nil, tuple.ErrReadOnly
+	}
+	out := make([]tuple.Tuple, 0, len(tuples))
+	for _, t := range tuples {
+		if err := tbl.h.ValidateKey(t.Key, insert); err != nil {
+			return nil, err
+		} else if err := tbl.h.ValidateData(t.Data); err != nil {
+			return nil, err
+		}
+		t.Data = tbl.h.WithDefaults(t.Data)
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// InsertTupleBatch implements tuple.BatchWriter. Tuples are written with PutMulti in transactions
+// of up to maxBatch entities. Auto-increment keys are allocated by the datastore.
+func (tbl *Table) InsertTupleBatch(ctx context.Context, tuples []tuple.Tuple) ([]tuple.Key, error) {
+	tuples, err := tbl.validateBatch(tuples, true)
+	if err != nil {
+		return nil, err
+	}
+	auto := len(tbl.h.Key) != 0 && tbl.h.Key[0].Auto
+	if !auto {
+		uniq, err := tuple.UniqueTuples(tuples)
+		if err != nil {
+			return nil, err
+		} else if len(uniq) != len(tuples) {
+			return nil, tuple.ErrExists
+		}
+	}
+	keys := make([]tuple.Key, 0, len(tuples))
+	for len(tuples) != 0 {
+		batch := tuples
+		if len(batch) > maxBatch {
+			batch = batch[:maxBatch]
+		}
+		tuples = tuples[len(batch):]
+
+		dkeys := make([]*datastore.Key, 0, len(batch))
+		data := make([]*payload, 0, len(batch))
+		for _, t := range batch {
+			dkeys = append(dkeys, tbl.key(t.Key, true))
+			data = append(data, &payload{h: &tbl.h, t: t})
+		}
+		tx, err := tbl.cli().NewTransaction(ctx)
+		if err != nil {
+			return keys, err
+		}
+		if !auto {
+			old := make([]payload, len(batch))
+			for i := range old {
+				old[i].h = &tbl.h
+			}
+			err = tx.GetMulti(dkeys, old)
+			if err == nil {
+				tx.Rollback()
+				return keys, tuple.ErrExists
+			}
+			merr, ok := err.(datastore.MultiError)
+			if !ok {
+				tx.Rollback()
+				return keys, err
+			}
+			for _, e := range merr {
+				if e != datastore.ErrNoSuchEntity {
+					tx.Rollback()
+					if e == nil {
+						e = tuple.ErrExists
+					}
+					return keys, e
+				}
+			}
+		}
+		pkeys, err := tx.PutMulti(dkeys, data)
+		if err != nil {
+			tx.Rollback()
+			return keys, err
+		}
+		c, err := tx.Commit()
+		if err != nil {
+			return keys, err
+		}
+		for i, t := range batch {
+			if !auto {
+				keys = append(keys, t.Key)
+				continue
+			}
+			id := c.Key(pkeys[i])
+			keys = append(keys, tuple.Key{values.UInt(id.ID)})
+		}
+	}
+	return keys, nil
+}
+
+// UpsertTupleBatch implements tuple.BatchWriter. Tuples are written with PutMulti in batches of up to maxBatch entities.
+func (tbl *Table) UpsertTupleBatch(ctx context.Context, tuples []tuple.Tuple) error {
+	tuples, err := tbl.validateBatch(tuples, false)
+	if err != nil {
+		return err
+	}
+	// a single commit cannot write the same entity twice
+	tuples, err = tuple.UniqueTuples(tuples)
+	if err != nil {
+		return err
+	}
+	for len(tuples) != 0 {
+		batch := tuples
+		if len(batch) > maxBatch {
+			batch = batch[:maxBatch]
+		}
+		tuples = tuples[len(batch):]
+
+		dkeys := make([]*datastore.Key, 0, len(batch))
+		data := make([]*payload, 0, len(batch))
+		for _, t := range batch {
+			dkeys = append(dkeys, tbl.key(t.Key, false))
+			data = append(data, &payload{h: &tbl.h, t: t})
+		}
+		if _, err = tbl.cli().PutMulti(ctx, dkeys, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (tbl *Table) DeleteTuples(ctx context.Context, f *tuple.Filter) error {
 	if !tbl.tx.rw {
 		return tuple.ErrReadOnly
@@ -558,24 +809,81 @@ func (tbl *Table) Scan(ctx context.Context, opt *tuple.ScanOptions) tuple.Iterat
 	if opt == nil {
 		opt = &tuple.ScanOptions{}
 	}
+	proj, err := tbl.h.Projection(opt.Fields)
+	if err != nil {
+		return &Iterator{err: err}
+	} else if err = opt.Validate(tbl.h); err != nil {
+		return &Iterator{err: err}
+	}
 	q := datastore.NewQuery(tbl.h.Name)
-	if opt.KeysOnly {
+	// payload filters need the payload, even if only keys are requested
+	keysOnly := opt.KeysOnly && opt.Filter.IsAnyData()
+	if keysOnly {
 		q = q.KeysOnly()
+	} else if proj != nil && opt.Filter.IsAnyData() && tbl.projectable(proj) {
+		// payload filters are applied to the full payload, so projection is only used without them
+		q = q.Project(opt.Fields...)
+	}
+	if opt.Filter.IsAny() {
+		// filters are applied to fetched entities, thus the offset and the limit are applied by the iterator otherwise
+		if opt.Limit > 0 {
+			q = q.Limit(opt.Limit)
+		}
+		if opt.Offset > 0 {
+			q = q.Offset(opt.Offset)
+		}
+	}
+	// key bounds are compared with the entity key, which has the same order as the tuple key
+	if opt.After != nil {
+		q = q.Filter("__key__ >", tbl.key(opt.After, false))
 	}
-	if opt.Limit > 0 {
-		q = q.Limit(opt.Limit)
+	if opt.Before != nil {
+		q = q.Filter("__key__ <", tbl.key(opt.Before, false))
 	}
-	switch opt.Sort {
-	case tuple.SortAsc:
+	bounds := opt.After != nil || opt.Before != nil
+	if df, ok := dataFilters(opt.Filter); ok && opt.Sort == tuple.SortAny && len(df) == len(tbl.h.Data) {
+		// equality filters on indexed fields can be executed by the query
+		for i, f := range tbl.h.Data {
+			eq, ok := df[i].(filter.Equal)
+			if !ok || eq.Value == nil || !indexed(&tbl.h, f.Name) {
+				continue
+			}
+			if v, err := propValue(eq.Value); err == nil {
+				q = q.Filter(f.Name+" =", v)
+			}
+		}
+	}
+	switch {
+	case bounds && opt.Sort == tuple.SortAsc:
+		// the first sort order must be on the property with an inequality filter
+		q = q.Order("__key__")
+	case bounds && opt.Sort == tuple.SortDesc:
+		q = q.Order("-__key__")
+	case opt.Sort == tuple.SortAsc:
 		for _, f := range tbl.h.Key {
 			q = q.Order(f.Name)
 		}
-	case tuple.SortDesc:
+	case opt.Sort == tuple.SortDesc:
 		for _, f := range tbl.h.Key {
 			q = q.Order("-" + f.Name)
 		}
 	}
-	return &Iterator{tbl: tbl, q: q, keysOnly: opt.KeysOnly, f: opt.Filter}
+	it := &Iterator{tbl: tbl, q: q, keysOnly: keysOnly, f: opt.Filter, proj: proj}
+	if opt.Filter.IsAny() {
+		return it
+	}
+	return tuple.LimitIterator(tuple.OffsetIterator(it, opt.Offset), opt.Limit)
+}
+
+// projectable checks if projection query can be used for selected fields.
+// Only indexed properties can be projected.
+func (tbl *Table) projectable(proj []int) bool {
+	for _, i := range proj {
+		if !indexed(&tbl.h, tbl.h.Data[i].Name) {
+			return false
+		}
+	}
+	return true
 }
 
 type Iterator struct {
@@ -583,6 +891,7 @@ type Iterator struct {
 	q        *datastore.Query
 	keysOnly bool
 	f        *tuple.Filter
+	proj     []int // payload fields to return; all fields if nil
 
 	it  *datastore.Iterator
 	t   tuple.Tuple
@@ -600,11 +909,14 @@ func (it *Iterator) Reset() {
 func (it *Iterator) Next(ctx context.Context) bool {
 	if it.err != nil {
 		return false
+	} else if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
 	}
 	if it.it == nil {
 		it.it = it.tbl.cli().Run(ctx, it.q)
 	}
-	return tuple.FilterIterator(it, it.f, func() bool {
+	return tuple.FilterIterator(fullTuple{it}, it.f, func() bool {
 		it.t = tuple.Tuple{}
 		var (
 			p   *payload
@@ -649,5 +961,18 @@ func (it *Iterator) Key() tuple.Key {
 }
 
 func (it *Iterator) Data() tuple.Data {
-	return it.t.Data
+	return it.t.Data.Project(it.proj)
+}
+
+// fullTuple exposes the full payload of the current tuple, regardless of the projection.
+type fullTuple struct {
+	it *Iterator
+}
+
+func (t fullTuple) Key() tuple.Key {
+	return t.it.t.Key
+}
+
+func (t fullTuple) Data() tuple.Data {
+	return t.it.t.Data
 }
@@ -55,6 +55,186 @@ func FilterIterator(it LazyTuple, f *Filter, next func() bool) bool {
 	return false
 }
 
+// LimitIterator returns at most n tuples from the iterator. It can be used by backends
+// that cannot apply the limit natively, for example, when some filters are applied to fetched tuples.
+func LimitIterator(it Iterator, n int) Iterator {
+	if n <= 0 {
+		return it
+	}
+	return &limitIterator{Iterator: it, n: n, left: n}
+}
+
+type limitIterator struct {
+	Iterator
+	n    int
+	left int
+}
+
+func (it *limitIterator) Reset() {
+	it.Iterator.Reset()
+	it.left = it.n
+}
+
+func (it *limitIterator) Next(ctx context.Context) bool {
+	if it.left <= 0 || !it.Iterator.Next(ctx) {
+		return false
+	}
+	it.left--
+	return true
+}
+
+// OffsetIterator skips first n tuples of the iterator. It can be used by backends
+// that cannot apply the offset natively.
+func OffsetIterator(it Iterator, n int) Iterator {
+	if n <= 0 {
+		return it
+	}
+	return &offsetIterator{Iterator: it, n: n}
+}
+
+type offsetIterator struct {
+	Iterator
+	n       int
+	skipped bool
+}
+
+func (it *offsetIterator) Reset() {
+	it.Iterator.Reset()
+	it.skipped = false
+}
+
+func (it *offsetIterator) Next(ctx context.Context) bool {
+	if !it.skipped {
+		it.skipped = true
+		for i := 0; i < it.n; i++ {
+			if !it.Iterator.Next(ctx) {
+				return false
+			}
+		}
+	}
+	return it.Iterator.Next(ctx)
+}
+
+// Projector is an optional interface for tables that can fetch selected payload fields only.
+type Projector interface {
+	// GetTupleFields is like GetTuple, but only returns selected payload fields, in the specified order.
+	GetTupleFields(ctx context.Context, key Key, fields []string) (Data, error)
+	// GetTupleBatchFields is like GetTupleBatch, but only returns selected payload fields, in the specified order.
+	GetTupleBatchFields(ctx context.Context, keys []Key, fields []string) ([]Data, error)
+}
+
+// GetTupleFields fetches selected payload fields of one tuple. See Projector.
+// If the table does not implement Projector, the full payload is fetched.
+func GetTupleFields(ctx context.Context, tbl Table, key Key, fields []string) (Data, error) {
+	if p, ok := tbl.(Projector); ok {
+		return p.GetTupleFields(ctx, key, fields)
+	}
+	h := tbl.Header()
+	proj, err := h.Projection(fields)
+	if err != nil {
+		return nil, err
+	}
+	data, err := tbl.GetTuple(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return data.Project(proj), nil
+}
+
+// GetTupleBatchFields fetches selected payload fields of multiple tuples. See Projector.
+// If the table does not implement Projector, full payloads are fetched.
+func GetTupleBatchFields(ctx context.Context, tbl Table, keys []Key, fields []string) ([]Data, error) {
+	if p, ok := tbl.(Projector); ok {
+		return p.GetTupleBatchFields(ctx, keys, fields)
+	}
+	h := tbl.Header()
+	proj, err := h.Projection(fields)
+	if err != nil {
+		return nil, err
+	}
+	data, err := tbl.GetTupleBatch(ctx, keys)
+	for i, d := range data {
+		data[i] = d.Project(proj)
+	}
+	return data, err
+}
+
+// BatchWriter is an optional interface for tables that can write multiple tuples at once.
+// Tuples might be partially written if an error is returned.
+type BatchWriter interface {
+	// InsertTupleBatch creates new tuples, the same way as InsertTuple does, and returns their keys
+	// in the same order. Keys are allocated for all auto-increment tuples at once.
+	// If one of the keys already exists or is repeated in the batch, it returns ErrExists.
+	InsertTupleBatch(ctx context.Context, tuples []Tuple) ([]Key, error)
+	// UpsertTupleBatch creates or rewrites tuples, the same way as UpdateTuple with Upsert flag does.
+	// If the key is repeated in the batch, the last tuple is written.
+	UpsertTupleBatch(ctx context.Context, tuples []Tuple) error
+}
+
+// InsertTupleBatch creates multiple tuples. See BatchWriter.
+// If the table does not implement BatchWriter, tuples are inserted one by one.
+func InsertTupleBatch(ctx context.Context, tbl Table, tuples []Tuple) ([]Key, error) {
+	if w, ok := tbl.(BatchWriter); ok {
+		return w.InsertTupleBatch(ctx, tuples)
+	}
+	keys := make([]Key, 0, len(tuples))
+	for _, t := range tuples {
+		k, err := tbl.InsertTuple(ctx, t)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// UpsertTupleBatch creates or rewrites multiple tuples. See BatchWriter.
+// If the table does not implement BatchWriter, tuples are written one by one.
+func UpsertTupleBatch(ctx context.Context, tbl Table, tuples []Tuple) error {
+	if w, ok := tbl.(BatchWriter); ok {
+		return w.UpsertTupleBatch(ctx, tuples)
+	}
+	for _, t := range tuples {
+		if err := tbl.UpdateTuple(ctx, t, &UpdateOpt{Upsert: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UniqueTuples removes tuples with repeated keys from the batch, keeping the last tuple for each key.
+// It returns the same slice if all keys are unique. Backends may use it to implement BatchWriter.
+func UniqueTuples(tuples []Tuple) ([]Tuple, error) {
+	var (
+		buf  []byte
+		err  error
+		out  []Tuple
+		seen = make(map[string]int, len(tuples))
+	)
+	for i, t := range tuples {
+		buf, err = appendGroupID(buf[:0], t.Key, nil)
+		if err != nil {
+			return nil, err
+		}
+		j, ok := seen[string(buf)]
+		if !ok {
+			seen[string(buf)] = len(seen)
+			if out != nil {
+				out = append(out, t)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]Tuple, 0, len(tuples)), tuples[:i]...)
+		}
+		out[j] = t
+	}
+	if out == nil {
+		return tuples, nil
+	}
+	return out, nil
+}
+
 type Deleter interface {
 	// DeleteTuplesByKey removes tuples by key.
 	DeleteTuplesByKey(ctx context.Context, keys []Key) error
@@ -0,0 +1,260 @@
+package tuplekv
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/options"
+	"github.com/hidal-go/hidalgo/tuple"
+)
+
+// tableVersion returns a key for the schema versions of the table.
+//
+// Tables without this key store tuple payloads as a list of length-prefixed fields.
+// Tables with this key prefix each payload with a schema version it was written with,
+// thus schema changes do not require rewriting all the tuples.
+func (db *tupleStore) tableVersion(name string) kv.Key {
+	k := kv.SKey("system", "version")
+	if name != "" {
+		k = k.AppendBytes([]byte(name))
+	}
+	return k
+}
+
+func (db *tupleStore) versionsWith(ctx context.Context, tx kv.Tx, name string) (*schemaVersions, error) {
+	data, err := tx.Get(ctx, db.tableVersion(name))
+	if err == kv.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, tupleErr(err)
+	}
+	return decodeVersions(data)
+}
+
+// schemaVersions is a list of schema changes applied to the table.
+// Payloads of the version i are converted to the version i+1 with steps[i-1].
+// The first version is the schema the table was created with.
+type schemaVersions struct {
+	steps []migration
+}
+
+// current returns the current version of the table schema.
+func (v *schemaVersions) current() uint64 {
+	return uint64(len(v.steps)) + 1
+}
+
+// migration is a tuple.Migration applied to the encoded payload fields. Null fields are nil.
+type migration struct {
+	fields   []int
+	defaults [][]byte
+}
+
+func newMigration(m *tuple.Migration) (migration, error) {
+	mg := migration{
+		fields:   append([]int{}, m.Fields...),
+		defaults: make([][]byte, len(m.Fields)),
+	}
+	for i, j := range m.Fields {
+		if j >= 0 {
+			continue
+		}
+		if m.Defaults[i] == nil {
+			continue
+		}
+		b, err := m.Defaults[i].MarshalBinary()
+		if err != nil {
+			return migration{}, err
+		} else if b == nil {
+			b = []byte{}
+		}
+		mg.defaults[i] = b
+	}
+	return mg, nil
+}
+
+func (m migration) apply(fields [][]byte) ([][]byte, error) {
+	out := make([][]byte, len(m.fields))
+	for i, j := range m.fields {
+		if j < 0 {
+			out[i] = m.defaults[i]
+		} else if j >= len(fields) {
+			return nil, fmt.Errorf("invalid tuple field count: %d vs %d", len(fields), j+1)
+		} else {
+			out[i] = fields[j]
+		}
+	}
+	return out, nil
+}
+
+// encodeVersions encodes the schema versions in the following format:
+//
+//	<steps count> (<fields count> (<old field index + 1> | 0 (<default value size + 1> <default value> | 0))...)...
+func encodeVersions(v *schemaVersions) kv.Value {
+	var buf []byte
+	tmp := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(x uint64) {
+		n := binary.PutUvarint(tmp, x)
+		buf = append(buf, tmp[:n]...)
+	}
+	putUvarint(uint64(len(v.steps)))
+	for _, s := range v.steps {
+		putUvarint(uint64(len(s.fields)))
+		for i, j := range s.fields {
+			if j >= 0 {
+				putUvarint(uint64(j) + 1)
+				continue
+			}
+			putUvarint(0)
+			if s.defaults[i] == nil {
+				putUvarint(0)
+				continue
+			}
+			putUvarint(uint64(len(s.defaults[i])) + 1)
+			buf = append(buf, s.defaults[i]...)
+		}
+	}
+	return buf
+}
+
+func decodeVersions(data []byte) (*schemaVersions, error) {
+	readUvarint := func() (uint64, error) {
+		x, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, fmt.Errorf("cannot decode schema versions: %v", io.ErrUnexpectedEOF)
+		}
+		data = data[n:]
+		return x, nil
+	}
+	cnt, err := readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	v := &schemaVersions{steps: make([]migration, 0, cnt)}
+	for ; cnt > 0; cnt-- {
+		n, err := readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		s := migration{
+			fields:   make([]int, n),
+			defaults: make([][]byte, n),
+		}
+		for i := range s.fields {
+			j, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			s.fields[i] = int(j) - 1
+			if j != 0 {
+				continue
+			}
+			sz, err := readUvarint()
+			if err != nil {
+				return nil, err
+			} else if sz == 0 {
+				continue // null
+			}
+			sz--
+			if sz > uint64(len(data)) {
+				return nil, fmt.Errorf("invalid default value size: %d vs %d", sz, len(data))
+			}
+			s.defaults[i] = data[:sz:sz]
+			data = data[sz:]
+		}
+		v.steps = append(v.steps, s)
+	}
+	return v, nil
+}
+
+func (tbl *tupleTable) version() kv.Key {
+	return tbl.tx.db.tableVersion(tbl.h.Name)
+}
+
+func (tbl *tupleTable) saveVersions(ctx context.Context) error {
+	return tupleErr(tbl.tx.tx.Put(ctx, tbl.version(), encodeVersions(tbl.v)))
+}
+
+// upgradeFields converts encoded payload fields of a specific schema version to the current version.
+func (tbl *tupleTable) upgradeFields(ver uint64, fields [][]byte) ([][]byte, error) {
+	if ver == 0 || ver > tbl.v.current() {
+		return nil, fmt.Errorf("unknown tuple schema version: %d", ver)
+	}
+	for _, s := range tbl.v.steps[ver-1:] {
+		var err error
+		fields, err = s.apply(fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// versionRows rewrites all tuples of a table without schema versions to the versioned format.
+func (tbl *tupleTable) versionRows(ctx context.Context) error {
+	type row struct {
+		key kv.Key
+		val kv.Value
+	}
+	// rows are written to the same prefix, so collect them first
+	var rows []row
+	it := tbl.tx.tx.Scan(ctx, options.WithPrefixKV(tbl.row(nil)))
+	defer it.Close()
+	for it.Next(ctx) {
+		rows = append(rows, row{key: it.Key().Clone(), val: it.Val().Clone()})
+	}
+	if err := it.Err(); err != nil {
+		return tupleErr(err)
+	}
+	vtbl := &tupleTable{tx: tbl.tx, h: tbl.h, v: &schemaVersions{}}
+	for _, r := range rows {
+		fields, err := splitFields(r.val, len(tbl.h.Data), false)
+		if err != nil {
+			return err
+		}
+		if err = tbl.tx.tx.Put(ctx, r.key, vtbl.encodeFields(fields)); err != nil {
+			return tupleErr(err)
+		}
+	}
+	return nil
+}
+
+func (tx *tupleTx) AlterTable(ctx context.Context, name string, changes ...tuple.Alteration) (tuple.Table, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	info, err := tx.db.tableWith(ctx, tx.tx, name)
+	if err != nil {
+		return nil, err
+	}
+	tbl := &tupleTable{tx: tx, h: info.h, v: info.v}
+	h, m, err := tbl.h.Alter(changes...)
+	if err != nil {
+		return nil, err
+	}
+	if tbl.v == nil {
+		// table was created before schema versions were introduced
+		if err = tbl.versionRows(ctx); err != nil {
+			return nil, err
+		}
+		tbl.v = &schemaVersions{}
+	}
+	if !m.IsIdentity(len(tbl.h.Data)) {
+		step, err := newMigration(m)
+		if err != nil {
+			return nil, err
+		}
+		steps := append(tbl.v.steps[:len(tbl.v.steps):len(tbl.v.steps)], step)
+		tbl.v = &schemaVersions{steps: steps}
+	}
+	if err = tbl.saveVersions(ctx); err != nil {
+		return nil, err
+	}
+	tbl.h = h
+	if err = tbl.saveSchema(ctx); err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
@@ -1,9 +1,11 @@
 package tuplekv
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/filter"
 	"github.com/hidal-go/hidalgo/kv/flat"
 	"github.com/hidal-go/hidalgo/tuple"
@@ -46,6 +48,13 @@ func (kv *flatKV) Close() error {
 	return kv.db.Close()
 }
 
+// Capabilities implements base.Capable.
+func (kv *flatKV) Capabilities() base.Capabilities {
+	c, _ := base.CapabilitiesOf(kv.db)
+	// seeks and prefix scans are implemented with key filters
+	return c&(base.CapTx|base.CapConcurrentWrites|base.CapConflicts|base.CapBatchGet) | base.CapSeek | base.CapPrefixScan
+}
+
 func (kv *flatKV) Tx(ctx context.Context, rw bool) (flat.Tx, error) {
 	tx, err := kv.db.Tx(ctx, rw)
 	if err != nil {
@@ -164,9 +173,15 @@ type flatIterator struct {
 	pref flat.Key
 	it   tuple.Iterator
 	err  error
+	seen bool // iterator was positioned with Seek
 }
 
 func (it *flatIterator) Reset() {
+	if it.seen {
+		// underlying iterator is bound to the seek key
+		it.seek(it.ctx, nil)
+		return
+	}
 	it.err = nil
 	if it.it != nil {
 		it.it.Reset()
@@ -190,24 +205,31 @@ func (it *flatIterator) Err() error {
 	return it.err
 }
 
-func (it *flatIterator) filters() tuple.KeyFilters {
-	if len(it.pref) == 0 {
+// filters returns key filters for the prefix, starting from a given key.
+func (it *flatIterator) filters(from flat.Key) tuple.KeyFilters {
+	if bytes.Compare(from, it.pref) < 0 {
+		// all keys with the prefix are greater than the prefix itself
+		from = it.pref
+	}
+	if len(from) == 0 {
 		return nil
 	}
-	return tuple.KeyFilters{
-		filter.Prefix(flatKeyPart(it.pref)),
+	rng := filter.Range{Start: filter.GTE(flatKeyPart(from))}
+	if len(it.pref) != 0 {
+		if end := flatKeyPart(it.pref).PrefixEnd(); end != nil {
+			rng.End = filter.LT(end)
+		}
 	}
+	return tuple.KeyFilters{rng}
 }
 
 func (it *flatIterator) seek(ctx context.Context, key flat.Key) {
-	it.Reset()
+	it.err = nil
+	it.seen = len(key) != 0
 	if it.it != nil {
 		_ = it.it.Close()
 	}
-	filters := it.filters()
-	if len(key) != 0 {
-		filters = append(filters, filter.GTE(flatKeyPart(key)))
-	}
+	filters := it.filters(key)
 	var f *tuple.Filter
 	if len(filters) != 0 {
 		f = &tuple.Filter{KeyFilter: filters}
@@ -0,0 +1,53 @@
+package tuplekv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/kv/flat"
+	"github.com/hidal-go/hidalgo/kv/mem"
+	"github.com/hidal-go/hidalgo/kv/options"
+	tuplekv "github.com/hidal-go/hidalgo/tuple/kv"
+)
+
+// TestFlatSeekPrefix checks that Seek on a prefixed iterator starts from the seek key.
+// Previously the seek key was applied as a filter for a second key field, which does not exist.
+func TestFlatSeekPrefix(t *testing.T) {
+	ctx := context.Background()
+	db, err := tuplekv.NewKV(ctx, tuplekv.New(mem.New()), "kv")
+	require.NoError(t, err)
+	defer db.Close()
+	err = flat.Update(ctx, db, func(tx flat.Tx) error {
+		for _, k := range []string{"a1", "b1", "b2", "b3", "c1"} {
+			if err := tx.Put(ctx, flat.Key(k), flat.Value("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, c := range []struct {
+		seek, exp string
+	}{
+		{seek: "a", exp: "b1"},
+		{seek: "b", exp: "b1"},
+		{seek: "b2", exp: "b2"},
+		{seek: "b25", exp: "b3"},
+		{seek: "c"},
+	} {
+		err = flat.View(ctx, db, func(tx flat.Tx) error {
+			it := tx.Scan(ctx, options.WithPrefixFlat(flat.Key("b")))
+			defer it.Close()
+			if !flat.Seek(ctx, it, flat.Key(c.seek)) {
+				require.Empty(t, c.exp, "seek %q", c.seek)
+				return it.Err()
+			}
+			require.Equal(t, c.exp, string(it.Key()), "seek %q", c.seek)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,354 @@
+package tuplekv
+
+import (
+	"context"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/filter"
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/options"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+var _ tuple.Indexer = (*tupleTable)(nil)
+
+// index returns a key prefix for all entries of the index. Empty name returns a prefix for all indexes of the table.
+//
+// Each index entry has the following structure:
+//
+//	data/index/<table>/<index>/<field values...>/<primary key...>
+//
+// Values of the entries are empty.
+func (tbl *tupleTable) index(name string) kv.Key {
+	k := kv.SKey("data", "index", tbl.h.Name)
+	if name != "" {
+		k = k.AppendBytes([]byte(name))
+	}
+	return k
+}
+
+// indexValue encodes a payload field as a part of the index key. Null values are sorted first.
+func indexValue(v tuple.Value) []byte {
+	s, ok := v.(values.Sortable)
+	if !ok || s == nil {
+		return []byte{0}
+	}
+	data, _ := s.MarshalSortable()
+	return append([]byte{1}, data...)
+}
+
+// indexValues returns payload values of the indexed fields.
+// It returns false if at least one value is null.
+func (tbl *tupleTable) indexValues(ind *tuple.Index, data tuple.Data) (kv.Key, bool) {
+	key := make(kv.Key, 0, len(ind.Fields))
+	notNull := true
+	for _, name := range ind.Fields {
+		_, i := tbl.h.DataByName(name)
+		if data[i] == nil {
+			notNull = false
+		}
+		key = append(key, indexValue(data[i]))
+	}
+	return key, notNull
+}
+
+// indexEntry returns an index key for the tuple.
+func (tbl *tupleTable) indexEntry(ind *tuple.Index, t tuple.Tuple) kv.Key {
+	vals, _ := tbl.indexValues(ind, t.Data)
+	return tbl.index(ind.Name).Append(vals).Append(toKvKey(t.Key))
+}
+
+// checkUnique verifies that no other tuple has the same values of the fields covered by a unique index.
+// Null values are never considered equal.
+func (tbl *tupleTable) checkUnique(ctx context.Context, ind *tuple.Index, t tuple.Tuple) error {
+	vals, ok := tbl.indexValues(ind, t.Data)
+	if !ind.Unique || !ok {
+		return nil
+	}
+	pref := tbl.index(ind.Name).Append(vals)
+	it := tbl.tx.tx.Scan(ctx, options.WithPrefixKV(pref.Append(kv.Key{nil})))
+	defer it.Close()
+	for it.Next(ctx) {
+		key, err := tbl.decodeKeyParts(it.Key()[len(pref):])
+		if err != nil {
+			return err
+		}
+		if key.Compare(t.Key) != 0 {
+			return tuple.ErrUnique
+		}
+	}
+	return tupleErr(it.Err())
+}
+
+// updateIndexes replaces index entries of the old tuple payload with the entries for a new one.
+// Old payload can be nil if the tuple was just created.
+func (tbl *tupleTable) updateIndexes(ctx context.Context, t tuple.Tuple, old tuple.Data) error {
+	if len(tbl.h.Indexes) == 0 {
+		return nil
+	}
+	for i := range tbl.h.Indexes {
+		if err := tbl.checkUnique(ctx, &tbl.h.Indexes[i], t); err != nil {
+			return err
+		}
+	}
+	for i := range tbl.h.Indexes {
+		ind := &tbl.h.Indexes[i]
+		key := tbl.indexEntry(ind, t)
+		if old != nil {
+			prev := tbl.indexEntry(ind, tuple.Tuple{Key: t.Key, Data: old})
+			if prev.Compare(key) == 0 {
+				continue
+			}
+			if err := tbl.tx.tx.Del(ctx, prev); err != nil {
+				return tupleErr(err)
+			}
+		}
+		if err := tbl.tx.tx.Put(ctx, key, kv.Value{}); err != nil {
+			return tupleErr(err)
+		}
+	}
+	return nil
+}
+
+// deleteTuple removes the tuple and all its index entries.
+func (tbl *tupleTable) deleteTuple(ctx context.Context, t tuple.Tuple) error {
+	if err := tbl.tx.tx.Del(ctx, tbl.row(t.Key)); err != nil {
+		return tupleErr(err)
+	}
+	for i := range tbl.h.Indexes {
+		if err := tbl.tx.tx.Del(ctx, tbl.indexEntry(&tbl.h.Indexes[i], t)); err != nil {
+			return tupleErr(err)
+		}
+	}
+	return nil
+}
+
+// indexFor selects an index that can be used to narrow a scan with the filter.
+// It returns an index and a key prefix for the index scan, or nil, if no index can be used.
+// Only equality filters on the leading index fields are considered.
+// Boolean flag indicates that all indexed fields are fixed, thus entries will be sorted by the primary key.
+func (tbl *tupleTable) indexFor(f *tuple.Filter) (*tuple.Index, kv.Key, bool) {
+	if f.IsAnyData() || len(tbl.h.Indexes) == 0 {
+		return nil, nil, false
+	}
+	df, ok := f.DataFilter.(tuple.DataFilters)
+	if !ok || len(df) != len(tbl.h.Data) {
+		return nil, nil, false
+	}
+	var (
+		best *tuple.Index
+		vals kv.Key
+	)
+	for i := range tbl.h.Indexes {
+		ind := &tbl.h.Indexes[i]
+		var cur kv.Key
+		for _, name := range ind.Fields {
+			_, j := tbl.h.DataByName(name)
+			eq, ok := df[j].(filter.Equal)
+			if !ok {
+				break
+			}
+			s, ok := eq.Value.(values.Sortable)
+			if !ok || s == nil {
+				break
+			}
+			cur = append(cur, indexValue(s))
+		}
+		if len(cur) > len(vals) {
+			best, vals = ind, cur
+		}
+	}
+	if best == nil {
+		return nil, nil, false
+	}
+	pref := tbl.index(best.Name).Append(vals).Append(kv.Key{nil})
+	return best, pref, len(vals) == len(best.Fields)
+}
+
+// scanIndex returns an iterator that uses secondary index for the scan, or nil, if no index can be used.
+func (tbl *tupleTable) scanIndex(ctx context.Context, f *tuple.Filter, sort tuple.Sorting) tuple.Iterator {
+	ind, pref, exact := tbl.indexFor(f)
+	if ind == nil || (sort != tuple.SortAny && !exact) {
+		return nil
+	}
+	return &indexIterator{
+		tbl: tbl, f: f,
+		it:    tbl.tx.tx.Scan(ctx, options.WithPrefixKV(pref)),
+		batch: tbl.tx.caps.Has(base.CapBatchGet),
+	}
+}
+
+// CreateIndex implements tuple.Indexer.
+func (tbl *tupleTable) CreateIndex(ctx context.Context, ind tuple.Index) error {
+	h := tbl.h.Clone()
+	h.Indexes = append(h.Indexes, ind)
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	// index entries are written to the same store, so collect tuples first
+	var rows []tuple.Tuple
+	it := tbl.scanKeys(ctx, nil)
+	defer it.Close()
+	for it.Next(ctx) {
+		rows = append(rows, tuple.Tuple{Key: it.Key(), Data: it.Data()})
+	}
+	if err := it.Err(); err != nil {
+		return tupleErr(err)
+	}
+	ntbl := &tupleTable{tx: tbl.tx, h: h, v: tbl.v}
+	pind := &ntbl.h.Indexes[len(ntbl.h.Indexes)-1]
+	for _, t := range rows {
+		if err := ntbl.checkUnique(ctx, pind, t); err != nil {
+			return err
+		}
+		if err := tbl.tx.tx.Put(ctx, ntbl.indexEntry(pind, t), kv.Value{}); err != nil {
+			return tupleErr(err)
+		}
+	}
+	if err := ntbl.saveSchema(ctx); err != nil {
+		return err
+	}
+	tbl.h = h
+	return nil
+}
+
+// DropIndex implements tuple.Indexer.
+func (tbl *tupleTable) DropIndex(ctx context.Context, name string) error {
+	if tbl.h.IndexByName(name) == nil {
+		return tuple.ErrIndexNotFound
+	}
+	h := tbl.h.Clone()
+	h.Indexes = h.Indexes[:0]
+	for _, ind := range tbl.h.Indexes {
+		if ind.Name != name {
+			h.Indexes = append(h.Indexes, ind)
+		}
+	}
+	if len(h.Indexes) == 0 {
+		h.Indexes = nil
+	}
+	if err := tbl.delPrefix(ctx, tbl.index(name).Append(kv.Key{nil})); err != nil {
+		return err
+	}
+	ntbl := &tupleTable{tx: tbl.tx, h: h, v: tbl.v}
+	if err := ntbl.saveSchema(ctx); err != nil {
+		return err
+	}
+	tbl.h = h
+	return nil
+}
+
+// indexBatch is the number of index entries resolved at once, if the store fetches keys in batches.
+const indexBatch = 64
+
+// indexIterator iterates over index entries and fetches tuples they point to.
+type indexIterator struct {
+	tbl      *tupleTable
+	f        *tuple.Filter
+	it       kv.Iterator
+	proj     []int // payload fields to return; all fields if nil
+	keysOnly bool  // payloads are not returned
+	batch    bool  // tuples are fetched in batches, see indexBatch
+
+	keys []tuple.Key  // keys of index entries that are read, but not yet returned
+	rows []tuple.Data // tuples for keys
+
+	key  tuple.Key
+	data tuple.Data
+	err  error
+}
+
+func (it *indexIterator) Reset() {
+	it.key, it.data, it.err = nil, nil, nil
+	it.keys, it.rows = nil, nil
+	it.it.Reset()
+}
+
+func (it *indexIterator) Close() error {
+	return it.it.Close()
+}
+
+func (it *indexIterator) Err() error {
+	if err := it.it.Err(); err != nil {
+		return err
+	}
+	return it.err
+}
+
+// fetch reads the next index entries and fetches tuples they point to.
+func (it *indexIterator) fetch(ctx context.Context) bool {
+	n := 1
+	if it.batch {
+		n = indexBatch
+	}
+	keys := make([]tuple.Key, 0, n)
+	nk := len(it.tbl.h.Key)
+	for len(keys) < n && it.it.Next(ctx) {
+		k := it.it.Key()
+		key, err := it.tbl.decodeKeyParts(k[len(k)-nk:])
+		if err != nil {
+			it.err = err
+			return false
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return false
+	}
+	var rows []tuple.Data
+	if it.batch {
+		var err error
+		rows, err = it.tbl.GetTupleBatch(ctx, keys)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		for _, data := range rows {
+			if data == nil {
+				// index entry points to a missing tuple, same as GetTuple
+				it.err = tuple.ErrNotFound
+				return false
+			}
+		}
+	} else {
+		data, err := it.tbl.GetTuple(ctx, keys[0])
+		if err != nil {
+			it.err = err
+			return false
+		}
+		rows = []tuple.Data{data}
+	}
+	it.keys, it.rows = keys, rows
+	return true
+}
+
+func (it *indexIterator) Next(ctx context.Context) bool {
+	it.key, it.data = nil, nil
+	if it.err != nil {
+		return false
+	}
+	for {
+		if len(it.keys) == 0 && !it.fetch(ctx) {
+			return false
+		}
+		key, data := it.keys[0], it.rows[0]
+		it.keys, it.rows = it.keys[1:], it.rows[1:]
+		if !it.f.FilterTuple(tuple.Tuple{Key: key, Data: data}) {
+			continue
+		}
+		it.key = key
+		if !it.keysOnly {
+			it.data = data.Project(it.proj)
+		}
+		return true
+	}
+}
+
+func (it *indexIterator) Key() tuple.Key {
+	return it.key
+}
+
+func (it *indexIterator) Data() tuple.Data {
+	return it.data
+}
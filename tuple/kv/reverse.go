@@ -0,0 +1,324 @@
+package tuplekv
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/tuple"
+)
+
+// reverseIterator returns tuples of the underlying iterator in the reverse order.
+// Tuples are loaded into memory on the first call to Next, since key-value stores only iterate forward.
+// If the limit is set, only the last tuples are kept in a ring buffer.
+type reverseIterator struct {
+	it    tuple.Iterator
+	limit int
+
+	rows   []tuple.Tuple
+	i      int // index of the current tuple + 1
+	loaded bool
+	err    error
+}
+
+func (it *reverseIterator) load(ctx context.Context) {
+	it.loaded = true
+	head := 0 // index of the oldest tuple, if the buffer is full
+	for it.it.Next(ctx) {
+		t := tuple.Tuple{Key: it.it.Key(), Data: it.it.Data()}
+		if it.limit > 0 && len(it.rows) == it.limit {
+			it.rows[head] = t
+			head = (head + 1) % it.limit
+			continue
+		}
+		it.rows = append(it.rows, t)
+	}
+	if head != 0 {
+		it.rows = append(it.rows[head:], it.rows[:head]...)
+	}
+	it.err = it.it.Err()
+	it.i = len(it.rows) + 1
+}
+
+func (it *reverseIterator) Reset() {
+	it.it.Reset()
+	it.rows, it.i, it.loaded, it.err = nil, 0, false, nil
+}
+
+func (it *reverseIterator) Next(ctx context.Context) bool {
+	if !it.loaded {
+		it.load(ctx)
+	}
+	if it.err != nil || it.i <= 1 {
+		it.i = 0
+		return false
+	}
+	it.i--
+	return true
+}
+
+func (it *reverseIterator) cur() *tuple.Tuple {
+	if it.i <= 0 || it.i > len(it.rows) {
+		return nil
+	}
+	return &it.rows[it.i-1]
+}
+
+func (it *reverseIterator) Key() tuple.Key {
+	if t := it.cur(); t != nil {
+		return t.Key
+	}
+	return nil
+}
+
+func (it *reverseIterator) Data() tuple.Data {
+	if t := it.cur(); t != nil {
+		return t.Data
+	}
+	return nil
+}
+
+func (it *reverseIterator) Err() error {
+	return it.err
+}
+
+func (it *reverseIterator) Close() error {
+	return it.it.Close()
+}
+
+// descIterator returns tuples of the range in the descending order without reading the range from the lower bound.
+// Key-value stores only iterate forward, so the previous tuple is found by seeking: the iterator bisects the key space
+// between the last known key and the upper bound until no keys are left in between.
+// Each tuple costs a number of seeks logarithmic in the size of the key space, regardless of the size of the table.
+// It must only be used if the store seeks natively.
+type descIterator struct {
+	it *tupleIterator
+	hi kv.Key // exclusive upper bound for the next tuple
+
+	known []kv.Key // keys found while searching for the current tuple, in ascending order; lower bounds for the next search
+	cur   bool     // the underlying iterator is positioned at the current tuple
+	done  bool
+}
+
+// newDescIterator returns a descending iterator over the range of the tuple iterator.
+// It returns nil if the range has no upper bound.
+func newDescIterator(it *tupleIterator) *descIterator {
+	hi := it.stop
+	if hi == nil {
+		hi = prefixEnd(it.pref)
+	}
+	if hi == nil {
+		return nil
+	}
+	return &descIterator{it: it, hi: hi}
+}
+
+func (it *descIterator) Reset() {
+	it.it.Reset()
+	it.hi = it.it.stop
+	if it.hi == nil {
+		it.hi = prefixEnd(it.it.pref)
+	}
+	it.known, it.cur, it.done = nil, false, false
+}
+
+// seek moves the underlying iterator to the first key after or equal to k and checks that it's below the upper bound.
+func (it *descIterator) seek(ctx context.Context, k kv.Key) (kv.Key, bool) {
+	if !kv.Seek(ctx, it.it.it, k) {
+		return nil, false
+	}
+	key := it.it.it.Key()
+	if key.Compare(it.hi) >= 0 {
+		return nil, false
+	}
+	return key.Clone(), true
+}
+
+// first returns the first key of the range.
+func (it *descIterator) first(ctx context.Context) (kv.Key, bool) {
+	if it.it.start != nil {
+		return it.seek(ctx, it.it.start)
+	}
+	it.it.it.Reset()
+	if !it.it.it.Next(ctx) {
+		return nil, false
+	}
+	key := it.it.it.Key()
+	if key.Compare(it.hi) >= 0 {
+		return nil, false
+	}
+	return key.Clone(), true
+}
+
+// prev finds the last key below the upper bound and positions the underlying iterator at it.
+func (it *descIterator) prev(ctx context.Context) bool {
+	if it.done || it.it.it == nil {
+		return false
+	}
+	var (
+		k  kv.Key
+		ok bool
+	)
+	if n := len(it.known); n != 0 {
+		k, ok = it.known[n-1], true
+	} else if k, ok = it.first(ctx); ok {
+		it.known = append(it.known, k)
+	}
+	if !ok {
+		it.done = true
+		return false
+	}
+	hi := it.hi
+	for {
+		// check that there are keys after the last known one
+		next, ok := it.seek(ctx, keyAfter(k))
+		if !ok || next.Compare(hi) >= 0 {
+			break
+		}
+		k = next
+		it.known = append(it.known, k)
+		c := midKey(k, hi)
+		if c == nil {
+			continue
+		}
+		if next, ok = it.seek(ctx, c); ok && next.Compare(hi) < 0 {
+			k = next
+			it.known = append(it.known, k)
+		} else {
+			// no keys in the upper half
+			hi = c
+		}
+	}
+	// the last known key is the current one
+	it.known = it.known[:len(it.known)-1]
+	it.hi = k
+	if !kv.Seek(ctx, it.it.it, k) {
+		it.done = true
+		return false
+	}
+	return true
+}
+
+func (it *descIterator) Next(ctx context.Context) bool {
+	if it.it.err != nil {
+		return false
+	}
+	it.cur = tuple.FilterIterator(fullTuple{it.it}, it.it.f, func() bool {
+		return it.prev(ctx)
+	})
+	return it.cur
+}
+
+func (it *descIterator) Key() tuple.Key {
+	if !it.cur {
+		return nil
+	}
+	return it.it.Key()
+}
+
+func (it *descIterator) Data() tuple.Data {
+	if !it.cur {
+		return nil
+	}
+	return it.it.Data()
+}
+
+func (it *descIterator) Err() error {
+	return it.it.Err()
+}
+
+func (it *descIterator) Close() error {
+	return it.it.Close()
+}
+
+// keyAfter returns the smallest key after a given one with the same number of parts.
+func keyAfter(k kv.Key) kv.Key {
+	k = append(kv.Key{}, k...)
+	last := k[len(k)-1]
+	k[len(k)-1] = append(last[:len(last):len(last)], 0)
+	return k
+}
+
+// prefixEnd returns the smallest key after all keys with a given prefix, or nil if there is no such key.
+// The last part of the prefix is a binary prefix, other parts must match exactly.
+func prefixEnd(pref kv.Key) kv.Key {
+	n := len(pref)
+	if n == 0 {
+		return nil
+	}
+	if p := bytes.TrimRight(pref[n-1], "\xff"); len(p) != 0 {
+		p = append([]byte{}, p...)
+		p[len(p)-1]++
+		return append(append(kv.Key{}, pref[:n-1]...), p)
+	}
+	if n == 1 {
+		return nil
+	}
+	// the last part matches any value, so the previous part must be followed by anything
+	return keyAfter(pref[:n-1])
+}
+
+// midKey returns a key between a and b, or nil if it cannot be found by splitting one of the parts.
+func midKey(a, b kv.Key) kv.Key {
+	i := 0
+	for i < len(a) && i < len(b) && bytes.Equal(a[i], b[i]) {
+		i++
+	}
+	if i == len(b) {
+		return nil
+	}
+	part := func(k kv.Key, i int) []byte {
+		if i < len(k) {
+			return k[i]
+		}
+		return nil
+	}
+	if m := midBytes(part(a, i), b[i]); m != nil {
+		return append(append(kv.Key{}, a[:i]...), m)
+	}
+	if i == len(a) {
+		return nil
+	}
+	// parts are too close, but all keys that start with the same part are before b
+	if m := midBytes(part(a, i+1), nil); m != nil {
+		return append(append(kv.Key{}, a[:i+1]...), m)
+	}
+	return nil
+}
+
+// midBytes returns a byte string between x and y, or nil if there is none. Strings are treated as base-256 fractions.
+// If y is nil, the upper bound is one, thus the result is after all strings that start with x.
+func midBytes(x, y []byte) []byte {
+	n := len(x)
+	if len(y) > n {
+		n = len(y)
+	}
+	n++
+	at := func(p []byte, i int) int {
+		if i < len(p) {
+			return int(p[i])
+		}
+		return 0
+	}
+	// sum of fractions; the carry is the integer part
+	sum := make([]int, n)
+	carry := 0
+	for i := n - 1; i >= 0; i-- {
+		v := at(x, i) + at(y, i) + carry
+		sum[i], carry = v&0xff, v>>8
+	}
+	if y == nil {
+		carry++
+	}
+	m := make([]byte, n)
+	rem := carry
+	for i, v := range sum {
+		v += rem << 8
+		m[i], rem = byte(v>>1), v&1
+	}
+	m = bytes.TrimRight(m, "\x00")
+	if bytes.Compare(x, m) >= 0 || (y != nil && bytes.Compare(m, y) >= 0) {
+		return nil
+	}
+	return m
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/filter"
 	"github.com/hidal-go/hidalgo/kv"
 	"github.com/hidal-go/hidalgo/kv/options"
@@ -36,12 +37,19 @@ func (db *tupleStore) Close() error {
 	return db.db.Close()
 }
 
+// Capabilities implements base.Capable. Capabilities of the key-value store are preserved.
+func (db *tupleStore) Capabilities() base.Capabilities {
+	c, _ := base.CapabilitiesOf(db.db)
+	return c
+}
+
 func (db *tupleStore) Tx(ctx context.Context, rw bool) (tuple.Tx, error) {
 	tx, err := db.db.Tx(ctx, rw)
 	if err != nil {
 		return nil, err
 	}
-	return &tupleTx{tx: tx}, nil
+	caps, _ := base.CapabilitiesOf(db.db)
+	return &tupleTx{db: db, tx: tx, caps: caps}, nil
 }
 
 func (db *tupleStore) View(ctx context.Context, fn func(tx tuple.Tx) error) error {
@@ -80,7 +88,11 @@ func (db *tupleStore) tableWith(ctx context.Context, tx kv.Tx, name string) (*tu
 	if err != nil {
 		return nil, err
 	}
-	return &tupleTableInfo{h: *h}, nil
+	v, err := db.versionsWith(ctx, tx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &tupleTableInfo{h: *h, v: v}, nil
 }
 
 func (db *tupleStore) Table(ctx context.Context, name string) (tuple.TableInfo, error) {
@@ -111,6 +123,16 @@ func (db *tupleStore) listTablesWith(ctx context.Context, tx kv.Tx) ([]*tupleTab
 		}
 		tables = append(tables, &tupleTableInfo{h: *h})
 	}
+	if err := it.Err(); err != nil {
+		return nil, tupleErr(err)
+	}
+	for _, t := range tables {
+		v, err := db.versionsWith(ctx, tx, t.h.Name)
+		if err != nil {
+			return nil, err
+		}
+		t.v = v
+	}
 	return tables, nil
 }
 
@@ -136,6 +158,7 @@ var _ tuple.TableInfo = (*tupleTableInfo)(nil)
 
 type tupleTableInfo struct {
 	h tuple.Header
+	v *schemaVersions // nil for tables without schema versions
 }
 
 func (t *tupleTableInfo) Header() tuple.Header {
@@ -147,12 +170,13 @@ func (t *tupleTableInfo) Open(ctx context.Context, tx tuple.Tx) (tuple.Table, er
 	if !ok {
 		return nil, fmt.Errorf("tuplekv: unexpected tx type: %T", tx)
 	}
-	return &tupleTable{tx: ktx, h: t.h}, nil
+	return &tupleTable{tx: ktx, h: t.h, v: t.v}, nil
 }
 
 type tupleTx struct {
-	db *tupleStore
-	tx kv.Tx
+	db   *tupleStore
+	tx   kv.Tx
+	caps base.Capabilities // capabilities of the key-value store; used to choose native access paths
 }
 
 func (tx *tupleTx) Commit(ctx context.Context) error {
@@ -206,13 +230,18 @@ func (tx *tupleTx) CreateTable(ctx context.Context, table tuple.Header) (tuple.T
 	if err != nil {
 		return nil, tupleErr(err)
 	}
+	tbl := &tupleTable{tx: tx, h: table.Clone(), v: &schemaVersions{}}
+	if err = tbl.saveVersions(ctx); err != nil {
+		return nil, err
+	}
 	// TODO: populate table info cache on commit
-	return &tupleTable{tx: tx, h: table.Clone()}, nil
+	return tbl, nil
 }
 
 type tupleTable struct {
 	tx *tupleTx
 	h  tuple.Header
+	v  *schemaVersions // nil for tables without schema versions
 }
 
 func (tbl *tupleTable) Header() tuple.Header {
@@ -220,7 +249,7 @@ func (tbl *tupleTable) Header() tuple.Header {
 }
 
 func (tbl *tupleTable) Open(ctx context.Context, tx tuple.Tx) (tuple.Table, error) {
-	return (&tupleTableInfo{h: tbl.h}).Open(ctx, tx)
+	return (&tupleTableInfo{h: tbl.h, v: tbl.v}).Open(ctx, tx)
 }
 
 func (tbl *tupleTable) schema() kv.Key {
@@ -231,6 +260,14 @@ func (tbl *tupleTable) auto() kv.Key {
 	return tbl.tx.db.tableAuto(tbl.h.Name)
 }
 
+func (tbl *tupleTable) saveSchema(ctx context.Context) error {
+	data, err := tuplepb.MarshalTable(&tbl.h)
+	if err != nil {
+		return err
+	}
+	return tupleErr(tbl.tx.tx.Put(ctx, tbl.schema(), data))
+}
+
 func toKvKey(k tuple.Key) kv.Key {
 	if k == nil {
 		return nil
@@ -261,12 +298,24 @@ func (tbl *tupleTable) Drop(ctx context.Context) error {
 	if err := tbl.Clear(ctx); err != nil {
 		return err
 	}
+	if tbl.v != nil {
+		if err := tbl.tx.tx.Del(ctx, tbl.version()); err != nil {
+			return tupleErr(err)
+		}
+	}
 	return tbl.tx.tx.Del(ctx, tbl.schema())
 }
 
 func (tbl *tupleTable) Clear(ctx context.Context) error {
+	if err := tbl.delPrefix(ctx, tbl.row(nil)); err != nil {
+		return err
+	}
+	return tbl.delPrefix(ctx, tbl.index("").Append(kv.Key{nil}))
+}
+
+func (tbl *tupleTable) delPrefix(ctx context.Context, pref kv.Key) error {
 	// TODO: support prefix delete on kv
-	it := tbl.tx.tx.Scan(ctx, options.WithPrefixKV(tbl.row(nil)))
+	it := tbl.tx.tx.Scan(ctx, options.WithPrefixKV(pref))
 	defer it.Close()
 	for it.Next(ctx) {
 		if err := tbl.tx.tx.Del(ctx, it.Key()); err != nil {
@@ -286,6 +335,14 @@ func (tbl *tupleTable) decodeKey(key kv.Key) (tuple.Key, error) {
 	if len(key) != len(tbl.h.Key) {
 		return nil, fmt.Errorf("decodeKey: wrong key size: %d vs %d (%v)", len(key), len(tbl.h.Key), k0)
 	}
+	return tbl.decodeKeyParts(key)
+}
+
+// decodeKeyParts decodes a tuple key from the key parts without a table prefix.
+func (tbl *tupleTable) decodeKeyParts(key kv.Key) (tuple.Key, error) {
+	if len(key) != len(tbl.h.Key) {
+		return nil, fmt.Errorf("decodeKey: wrong key size: %d vs %d", len(key), len(tbl.h.Key))
+	}
 	row := make(tuple.Key, len(tbl.h.Key))
 	for i, f := range tbl.h.Key {
 		v := f.Type.NewSortable()
@@ -300,42 +357,134 @@ func (tbl *tupleTable) decodeKey(key kv.Key) (tuple.Key, error) {
 
 func (tbl *tupleTable) encodeTuple(data tuple.Data) (kv.Value, error) {
 	fields := make([][]byte, len(data))
-	sz := 0
 	for i, v := range data {
+		if v == nil {
+			if tbl.v == nil {
+				return nil, fmt.Errorf("tuplekv: table %q cannot store null values", tbl.h.Name)
+			}
+			continue
+		}
 		b, err := v.MarshalBinary()
 		if err != nil {
 			return nil, err
+		} else if b == nil {
+			b = []byte{}
 		}
 		fields[i] = b
+	}
+	return tbl.encodeFields(fields), nil
+}
+
+// encodeFields joins encoded payload fields. Nil fields are stored as nulls.
+//
+// Tables with schema versions store the version first, followed by fields with a size prefix set to size+1,
+// or zero for null fields. Other tables store fields with a size prefix and cannot store nulls.
+func (tbl *tupleTable) encodeFields(fields [][]byte) kv.Value {
+	sz := 0
+	if tbl.v != nil {
+		sz += binary.MaxVarintLen64
+	}
+	for _, f := range fields {
 		// TODO: calculate size more precisely
-		sz += len(b) + binary.MaxVarintLen32
+		sz += len(f) + binary.MaxVarintLen32
 	}
 	buf := make(kv.Value, sz)
 	i := 0
+	if tbl.v != nil {
+		i += binary.PutUvarint(buf[i:], tbl.v.current())
+	}
 	for _, f := range fields {
-		i += binary.PutUvarint(buf[i:], uint64(len(f)))
+		switch {
+		case tbl.v == nil:
+			i += binary.PutUvarint(buf[i:], uint64(len(f)))
+		case f == nil:
+			i += binary.PutUvarint(buf[i:], 0)
+		default:
+			i += binary.PutUvarint(buf[i:], uint64(len(f))+1)
+		}
 		i += copy(buf[i:], f)
 	}
-	buf = buf[:i]
-	return buf, nil
+	return buf[:i]
 }
 
 func (tbl *tupleTable) decodeTuple(data kv.Value) (tuple.Data, error) {
-	row := make(tuple.Data, len(tbl.h.Data))
-	for i, f := range tbl.h.Data {
+	return tbl.decodeTupleFields(data, nil)
+}
+
+// decodeTupleFields decodes only selected payload fields. See tuple.Header.Projection.
+func (tbl *tupleTable) decodeTupleFields(data kv.Value, proj []int) (tuple.Data, error) {
+	if tbl.v == nil {
+		fields, err := splitFields(data, len(tbl.h.Data), false)
+		if err != nil {
+			return nil, err
+		}
+		return tbl.decodeFields(fields, proj)
+	}
+	ver, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("cannot decode tuple version: %v", io.ErrUnexpectedEOF)
+	}
+	data = data[n:]
+	fields, err := splitFields(data, -1, true)
+	if err != nil {
+		return nil, err
+	}
+	if ver != tbl.v.current() {
+		fields, err = tbl.upgradeFields(ver, fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) != len(tbl.h.Data) {
+		return nil, fmt.Errorf("invalid tuple field count: %d vs %d", len(fields), len(tbl.h.Data))
+	}
+	return tbl.decodeFields(fields, proj)
+}
+
+// splitFields splits the encoded payload into fields. Negative count reads all fields from the payload.
+// If nulls flag is set, size prefixes are expected to be shifted by one and null fields are returned as nil.
+func splitFields(data []byte, cnt int, nulls bool) ([][]byte, error) {
+	var fields [][]byte
+	if cnt >= 0 {
+		fields = make([][]byte, 0, cnt)
+	}
+	for cnt < 0 && len(data) != 0 || len(fields) < cnt {
 		sz, n := binary.Uvarint(data)
-		data = data[n:]
-		if n == 0 {
+		if n <= 0 {
 			return nil, fmt.Errorf("cannot decode tuple data: %v", io.ErrUnexpectedEOF)
-		} else if sz > uint64(len(data)) {
+		}
+		data = data[n:]
+		if nulls {
+			if sz == 0 {
+				fields = append(fields, nil)
+				continue
+			}
+			sz--
+		}
+		if sz > uint64(len(data)) {
 			return nil, fmt.Errorf("invalid tuple field size: %d vs %d", sz, len(data))
 		}
-
-		head := data[:sz]
+		fields = append(fields, data[:sz:sz])
 		data = data[sz:] // keep the rest for the next loop
+	}
+	return fields, nil
+}
 
-		v := f.Type.New()
-		err := v.UnmarshalBinary(head)
+// decodeFields decodes encoded payload fields. Only fields from the projection are decoded, if it is set.
+func (tbl *tupleTable) decodeFields(fields [][]byte, proj []int) (tuple.Data, error) {
+	if proj == nil {
+		proj = make([]int, len(tbl.h.Data))
+		for i := range proj {
+			proj[i] = i
+		}
+	}
+	row := make(tuple.Data, len(proj))
+	for i, j := range proj {
+		if fields[j] == nil {
+			continue
+		}
+		v := tbl.h.Data[j].Type.New()
+		err := v.UnmarshalBinary(fields[j])
 		if err != nil {
 			return nil, fmt.Errorf("cannot decode tuple field: %v", err)
 		}
@@ -345,6 +494,19 @@ func (tbl *tupleTable) decodeTuple(data kv.Value) (tuple.Data, error) {
 }
 
 func (tbl *tupleTable) GetTuple(ctx context.Context, key tuple.Key) (tuple.Data, error) {
+	return tbl.getTuple(ctx, key, nil)
+}
+
+// GetTupleFields implements tuple.Projector.
+func (tbl *tupleTable) GetTupleFields(ctx context.Context, key tuple.Key, fields []string) (tuple.Data, error) {
+	proj, err := tbl.h.Projection(fields)
+	if err != nil {
+		return nil, err
+	}
+	return tbl.getTuple(ctx, key, proj)
+}
+
+func (tbl *tupleTable) getTuple(ctx context.Context, key tuple.Key, proj []int) (tuple.Data, error) {
 	if err := tbl.h.ValidateKey(key, false); err != nil {
 		return nil, err
 	}
@@ -354,10 +516,23 @@ func (tbl *tupleTable) GetTuple(ctx context.Context, key tuple.Key) (tuple.Data,
 	} else if err != nil {
 		return nil, tupleErr(err)
 	}
-	return tbl.decodeTuple(data)
+	return tbl.decodeTupleFields(data, proj)
 }
 
 func (tbl *tupleTable) GetTupleBatch(ctx context.Context, key []tuple.Key) ([]tuple.Data, error) {
+	return tbl.getTupleBatch(ctx, key, nil)
+}
+
+// GetTupleBatchFields implements tuple.Projector.
+func (tbl *tupleTable) GetTupleBatchFields(ctx context.Context, key []tuple.Key, fields []string) ([]tuple.Data, error) {
+	proj, err := tbl.h.Projection(fields)
+	if err != nil {
+		return nil, err
+	}
+	return tbl.getTupleBatch(ctx, key, proj)
+}
+
+func (tbl *tupleTable) getTupleBatch(ctx context.Context, key []tuple.Key, proj []int) ([]tuple.Data, error) {
 	keys := make([]kv.Key, 0, len(key))
 	for _, k := range key {
 		if err := tbl.h.ValidateKey(k, false); err != nil {
@@ -374,7 +549,7 @@ func (tbl *tupleTable) GetTupleBatch(ctx context.Context, key []tuple.Key) ([]tu
 		if p == nil {
 			continue
 		}
-		row, err := tbl.decodeTuple(p)
+		row, err := tbl.decodeTupleFields(p, proj)
 		if err != nil {
 			return nil, err
 		}
@@ -384,6 +559,15 @@ func (tbl *tupleTable) GetTupleBatch(ctx context.Context, key []tuple.Key) ([]tu
 }
 
 func (tbl *tupleTable) nextAuto(ctx context.Context) (tuple.Key, error) {
+	keys, err := tbl.nextAutos(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+// nextAutos allocates n consecutive auto-increment keys with a single counter update.
+func (tbl *tupleTable) nextAutos(ctx context.Context, n int) ([]tuple.Key, error) {
 	key := tbl.auto()
 	v, err := tbl.tx.tx.Get(ctx, key)
 	if err == kv.ErrNotFound {
@@ -396,7 +580,11 @@ func (tbl *tupleTable) nextAuto(ctx context.Context) (tuple.Key, error) {
 	if err = last.UnmarshalBinary(v); err != nil {
 		return nil, err
 	}
-	last++
+	keys := make([]tuple.Key, 0, n)
+	for i := 0; i < n; i++ {
+		last++
+		keys = append(keys, tuple.Key{last})
+	}
 	data, err := last.MarshalBinary()
 	if err != nil {
 		return nil, err
@@ -404,7 +592,7 @@ func (tbl *tupleTable) nextAuto(ctx context.Context) (tuple.Key, error) {
 	if err = tbl.tx.tx.Put(ctx, key, kv.Value(data)); err != nil {
 		return nil, err
 	}
-	return tuple.Key{last}, nil
+	return keys, nil
 }
 
 func (tbl *tupleTable) InsertTuple(ctx context.Context, t tuple.Tuple) (tuple.Key, error) {
@@ -413,6 +601,7 @@ func (tbl *tupleTable) InsertTuple(ctx context.Context, t tuple.Tuple) (tuple.Ke
 	} else if err = tbl.h.ValidateData(t.Data); err != nil {
 		return nil, err
 	}
+	t.Data = tbl.h.WithDefaults(t.Data)
 	if tbl.h.Key[0].Auto {
 		key, err := tbl.nextAuto(ctx)
 		if err != nil {
@@ -431,6 +620,9 @@ func (tbl *tupleTable) InsertTuple(ctx context.Context, t tuple.Tuple) (tuple.Ke
 	if err != nil {
 		return nil, err
 	}
+	if err = tbl.updateIndexes(ctx, t, nil); err != nil {
+		return nil, err
+	}
 	err = tbl.tx.tx.Put(ctx, key, val)
 	if err != nil {
 		return nil, tupleErr(err)
@@ -438,28 +630,159 @@ func (tbl *tupleTable) InsertTuple(ctx context.Context, t tuple.Tuple) (tuple.Ke
 	return t.Key, nil
 }
 
+var _ tuple.BatchWriter = (*tupleTable)(nil)
+
+// InsertTupleBatch implements tuple.BatchWriter. Existing keys are checked with a single batch read.
+func (tbl *tupleTable) InsertTupleBatch(ctx context.Context, tuples []tuple.Tuple) ([]tuple.Key, error) {
+	for _, t := range tuples {
+		if err := tbl.h.ValidateKey(t.Key, true); err != nil {
+			return nil, err
+		} else if err = tbl.h.ValidateData(t.Data); err != nil {
+			return nil, err
+		}
+	}
+	if len(tuples) == 0 {
+		return nil, nil
+	}
+	keys := make([]tuple.Key, 0, len(tuples))
+	if tbl.h.Key[0].Auto {
+		auto, err := tbl.nextAutos(ctx, len(tuples))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, auto...)
+	} else {
+		uniq, err := tuple.UniqueTuples(tuples)
+		if err != nil {
+			return nil, err
+		} else if len(uniq) != len(tuples) {
+			return nil, tuple.ErrExists
+		}
+		for _, t := range tuples {
+			keys = append(keys, t.Key)
+		}
+	}
+	rows := make([]kv.Key, 0, len(keys))
+	for _, k := range keys {
+		rows = append(rows, tbl.row(k))
+	}
+	old, err := tbl.tx.tx.GetBatch(ctx, rows)
+	if err != nil {
+		return nil, tupleErr(err)
+	}
+	for _, v := range old {
+		if v != nil {
+			return nil, tuple.ErrExists
+		}
+	}
+	for i, t := range tuples {
+		t.Key = keys[i]
+		t.Data = tbl.h.WithDefaults(t.Data)
+		val, err := tbl.encodeTuple(t.Data)
+		if err != nil {
+			return nil, err
+		}
+		if err = tbl.updateIndexes(ctx, t, nil); err != nil {
+			return nil, err
+		}
+		if err = tbl.tx.tx.Put(ctx, rows[i], val); err != nil {
+			return nil, tupleErr(err)
+		}
+	}
+	return keys, nil
+}
+
+// UpsertTupleBatch implements tuple.BatchWriter. Old payloads are fetched with a single batch read,
+// if they are required to update indexes.
+func (tbl *tupleTable) UpsertTupleBatch(ctx context.Context, tuples []tuple.Tuple) error {
+	for _, t := range tuples {
+		if err := tbl.h.ValidateKey(t.Key, false); err != nil {
+			return err
+		} else if err = tbl.h.ValidateData(t.Data); err != nil {
+			return err
+		}
+	}
+	if len(tbl.h.Indexes) != 0 {
+		// index entries of the old tuple are replaced, so each key must be written once
+		var err error
+		tuples, err = tuple.UniqueTuples(tuples)
+		if err != nil {
+			return err
+		}
+	}
+	rows := make([]kv.Key, 0, len(tuples))
+	for _, t := range tuples {
+		rows = append(rows, tbl.row(t.Key))
+	}
+	var old []kv.Value
+	if len(tbl.h.Indexes) != 0 {
+		var err error
+		old, err = tbl.tx.tx.GetBatch(ctx, rows)
+		if err != nil {
+			return tupleErr(err)
+		}
+	}
+	for i, t := range tuples {
+		t.Data = tbl.h.WithDefaults(t.Data)
+		if old != nil {
+			var prev tuple.Data
+			if old[i] != nil {
+				var err error
+				prev, err = tbl.decodeTuple(old[i])
+				if err != nil {
+					return err
+				}
+			}
+			if err := tbl.updateIndexes(ctx, t, prev); err != nil {
+				return err
+			}
+		}
+		val, err := tbl.encodeTuple(t.Data)
+		if err != nil {
+			return err
+		}
+		if err = tbl.tx.tx.Put(ctx, rows[i], val); err != nil {
+			return tupleErr(err)
+		}
+	}
+	return nil
+}
+
 func (tbl *tupleTable) UpdateTuple(ctx context.Context, t tuple.Tuple, opt *tuple.UpdateOpt) error {
 	if err := tbl.h.ValidateKey(t.Key, false); err != nil {
 		return err
 	} else if err = tbl.h.ValidateData(t.Data); err != nil {
 		return err
 	}
+	t.Data = tbl.h.WithDefaults(t.Data)
 	key := tbl.row(t.Key)
 	if opt == nil {
 		opt = &tuple.UpdateOpt{}
 	}
-	if !opt.Upsert {
-		_, err := tbl.tx.tx.Get(ctx, key)
+	var old tuple.Data
+	if !opt.Upsert || len(tbl.h.Indexes) != 0 {
+		// old payload is required to update index entries
+		data, err := tbl.tx.tx.Get(ctx, key)
 		if err == kv.ErrNotFound {
-			return tuple.ErrNotFound
+			if !opt.Upsert {
+				return tuple.ErrNotFound
+			}
 		} else if err != nil {
 			return tupleErr(err)
+		} else if len(tbl.h.Indexes) != 0 {
+			old, err = tbl.decodeTuple(data)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	val, err := tbl.encodeTuple(t.Data)
 	if err != nil {
 		return err
 	}
+	if err = tbl.updateIndexes(ctx, t, old); err != nil {
+		return err
+	}
 	err = tbl.tx.tx.Put(ctx, key, val)
 	if err != nil {
 		return tupleErr(err)
@@ -477,7 +800,7 @@ func (tbl *tupleTable) DeleteTuples(ctx context.Context, f *tuple.Filter) error
 					return err
 				}
 			}
-			if f.IsAnyData() {
+			if f.IsAnyData() && len(tbl.h.Indexes) == 0 {
 				// if data won't be filtered - delete tuples directly
 				for _, key := range arr {
 					if err := tbl.tx.tx.Del(ctx, tbl.row(key)); err != nil {
@@ -485,54 +808,165 @@ func (tbl *tupleTable) DeleteTuples(ctx context.Context, f *tuple.Filter) error
 					}
 				}
 				return nil
+			} else if f.IsAnyData() {
+				// payload is required to delete index entries
+				rows, err := tbl.GetTupleBatch(ctx, arr)
+				if err != nil {
+					return err
+				}
+				for i, data := range rows {
+					if data == nil {
+						continue
+					}
+					if err = tbl.deleteTuple(ctx, tuple.Tuple{Key: arr[i], Data: data}); err != nil {
+						return err
+					}
+				}
+				return nil
 			}
 		}
 	}
-	// fallback to iterate + delete
-	it := tbl.scan(ctx, f)
+	if len(tbl.h.Indexes) == 0 {
+		// fallback to iterate + delete
+		it := tbl.scanKeys(ctx, f)
+		defer it.Close()
+		for it.Next(ctx) {
+			if err := tbl.tx.tx.Del(ctx, it.key()); err != nil {
+				return tupleErr(err)
+			}
+		}
+		return it.Err()
+	}
+	// index entries are deleted as well, so collect tuples first
+	var rows []tuple.Tuple
+	it := tbl.scan(ctx, f, tuple.SortAny)
 	defer it.Close()
 	for it.Next(ctx) {
-		if err := tbl.tx.tx.Del(ctx, it.key()); err != nil {
-			return tupleErr(err)
+		rows = append(rows, tuple.Tuple{Key: it.Key(), Data: it.Data()})
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	for _, t := range rows {
+		if err := tbl.deleteTuple(ctx, t); err != nil {
+			return err
 		}
 	}
-	return it.Err()
+	return nil
 }
 
-func (tbl *tupleTable) scan(ctx context.Context, f *tuple.Filter) *tupleIterator {
-	pref := tbl.row(nil)
-	removeWildcard := func() {
-		if n := len(pref); n != 0 && len(pref[n-1]) == 0 {
-			pref = pref[:n-1]
+func (tbl *tupleTable) scan(ctx context.Context, f *tuple.Filter, sort tuple.Sorting) tuple.Iterator {
+	if !tbl.keyRange(f).narrow {
+		// cannot narrow the scan by the primary key
+		if it := tbl.scanIndex(ctx, f, sort); it != nil {
+			return it
 		}
 	}
-	if !f.IsAny() {
-		if kf, ok := f.KeyFilter.(tuple.KeyFilters); ok {
-			// find common prefix, if any
-		loop:
-			for _, vf := range kf {
-				switch vf := vf.(type) {
-				case filter.Equal:
-					s, ok := vf.Value.(values.Sortable)
-					if !ok {
-						break loop
-					}
-					removeWildcard()
-					pref = pref.Append(toKvKey(tuple.Key{s}))
-				case filter.Range:
-					p, ok := vf.Prefix()
-					if ok && p != nil {
-						removeWildcard()
-						pref = pref.Append(toKvKey(tuple.Key{p}))
-					}
-					break loop
-				}
+	return tbl.scanKeys(ctx, f)
+}
+
+// rowRange is a range of rows matching the filter.
+type rowRange struct {
+	pref   kv.Key // common prefix of rows
+	start  kv.Key // optional key to seek to
+	stop   kv.Key // optional key to stop at, exclusive
+	narrow bool   // the range is narrower than the table
+}
+
+// keyRange returns a range of rows matching the filter. Equality filters on leading key fields
+// are converted to a prefix, and the range of the next key field is converted to seek bounds.
+func (tbl *tupleTable) keyRange(f *tuple.Filter) rowRange {
+	r := rowRange{pref: tbl.row(nil)}
+	if f.IsAny() {
+		return r
+	}
+	kf, ok := f.KeyFilter.(tuple.KeyFilters)
+	if !ok {
+		return r
+	}
+	// find common prefix, if any
+	for i, vf := range kf {
+		if eq, ok := vf.(filter.Equal); ok {
+			s, ok := eq.Value.(values.Sortable)
+			if !ok {
+				break
+			}
+			r.pref = trimWildcard(r.pref).Append(toKvKey(tuple.Key{s}))
+			if i < len(tbl.h.Key)-1 {
+				// the value must match the key part exactly, not as a binary prefix
+				r.pref = r.pref.Append(kv.Key{nil})
+			}
+			r.narrow = true
+			continue
+		}
+		sf, ok := vf.(filter.SortableFilter)
+		if !ok || i >= len(tbl.h.Key) {
+			break
+		}
+		vr := sf.ValuesRange()
+		if vr == nil {
+			break
+		}
+		if p, ok := vr.Prefix(); ok && p != nil {
+			r.pref = trimWildcard(r.pref).Append(toKvKey(tuple.Key{p}))
+			r.narrow = true
+			break
+		}
+		// key fields are compared by their sortable encoding, so values must have the same type
+		typ := tbl.h.Key[i].Type
+		base := trimWildcard(r.pref)
+		if v := vr.Start; v != nil && v.Value != nil && v.Value.Type() == typ {
+			p, _ := v.Value.MarshalSortable()
+			if !v.Equal {
+				// the smallest value after the bound
+				p = append(p[:len(p):len(p)], 0)
+			}
+			r.start = base.AppendBytes(p)
+			r.narrow = true
+		}
+		if v := vr.End; v != nil && v.Value != nil && v.Value.Type() == typ {
+			p, _ := v.Value.MarshalSortable()
+			if v.Equal {
+				// rows with the same value have more key parts, thus they are after the bound
+				p = append(p[:len(p):len(p)], 0)
 			}
+			r.stop = base.AppendBytes(p)
+			r.narrow = true
+		}
+		break
+	}
+	return r
+}
+
+// trimWildcard removes an empty trailing part of the key prefix.
+func trimWildcard(pref kv.Key) kv.Key {
+	if n := len(pref); n != 0 && len(pref[n-1]) == 0 {
+		return pref[:n-1]
+	}
+	return pref
+}
+
+func (tbl *tupleTable) scanKeys(ctx context.Context, f *tuple.Filter) *tupleIterator {
+	return tbl.scanRange(ctx, f, nil, nil)
+}
+
+// scanRange is like scanKeys, but only returns rows with keys within optional exclusive bounds.
+func (tbl *tupleTable) scanRange(ctx context.Context, f *tuple.Filter, after, before tuple.Key) *tupleIterator {
+	r := tbl.keyRange(f)
+	if after != nil {
+		if k := keyAfter(tbl.row(after)); r.start == nil || k.Compare(r.start) > 0 {
+			r.start = k
+		}
+	}
+	if before != nil {
+		if k := tbl.row(before); r.stop == nil || k.Compare(r.stop) < 0 {
+			r.stop = k
 		}
 	}
 	return &tupleIterator{
 		tbl: tbl, f: f,
-		it: tbl.tx.tx.Scan(ctx, options.WithPrefixKV(pref)),
+		it:   tbl.tx.tx.Scan(ctx, options.WithPrefixKV(r.pref)),
+		pref: r.pref, start: r.start, stop: r.stop,
 	}
 }
 
@@ -540,23 +974,70 @@ func (tbl *tupleTable) Scan(ctx context.Context, opt *tuple.ScanOptions) tuple.I
 	if opt == nil {
 		opt = &tuple.ScanOptions{}
 	}
+	proj, err := tbl.h.Projection(opt.Fields)
+	if err != nil {
+		return &tupleIterator{err: err}
+	} else if err = opt.Validate(tbl.h); err != nil {
+		return &tupleIterator{err: err}
+	}
+	sort := opt.Sort
+	if sort == tuple.SortDesc {
+		// rows are stored in ascending order and are reversed by the iterator
+		sort = tuple.SortAsc
+	}
+	var it tuple.Iterator
+	if opt.After != nil || opt.Before != nil {
+		// bounds are applied to the primary key, so indexes are not used
+		it = tbl.scanRange(ctx, opt.Filter, opt.After, opt.Before)
+	} else {
+		it = tbl.scan(ctx, opt.Filter, sort)
+	}
+	switch it := it.(type) {
+	case *tupleIterator:
+		it.proj, it.keysOnly = proj, opt.KeysOnly
+	case *indexIterator:
+		it.proj, it.keysOnly = proj, opt.KeysOnly
+	}
 	if opt.Sort == tuple.SortDesc {
-		// FIXME: support descending order
-		return &tupleIterator{err: fmt.Errorf("descending order is not supported")}
+		it = tbl.reverse(it, opt)
+	}
+	return tuple.LimitIterator(tuple.OffsetIterator(it, opt.Offset), opt.Limit)
+}
+
+// reverse returns tuples of the iterator in the descending order.
+func (tbl *tupleTable) reverse(it tuple.Iterator, opt *tuple.ScanOptions) tuple.Iterator {
+	if ti, ok := it.(*tupleIterator); ok && ti.it != nil && tbl.tx.caps.Has(base.CapSeek) {
+		// read only the tuples that are returned by seeking backwards from the upper bound
+		if d := newDescIterator(ti); d != nil {
+			return d
+		}
 	}
-	// FIXME: support limit
-	return tbl.scan(ctx, opt.Filter)
+	// index scans and stores without native seek read all tuples from the lower bound;
+	// only the last tuples are kept, if the limit is set
+	limit := 0
+	if opt.Limit > 0 {
+		limit = opt.Offset + opt.Limit
+	}
+	return &reverseIterator{it: it, limit: limit}
 }
 
 type tupleIterator struct {
-	tbl *tupleTable
-	f   *tuple.Filter
-	it  kv.Iterator
-	err error
+	tbl      *tupleTable
+	f        *tuple.Filter
+	it       kv.Iterator
+	proj     []int  // payload fields to return; all fields if nil
+	keysOnly bool   // payloads are only decoded for filters
+	pref     kv.Key // prefix of rows in the range
+	start    kv.Key // optional key to seek to on the first call to Next
+	stop     kv.Key // optional key to stop at, exclusive
+	seek     bool   // seek was already done
+	done     bool   // stop key was reached
+	err      error
 }
 
 func (it *tupleIterator) Reset() {
 	it.err = nil
+	it.seek, it.done = false, false
 	it.it.Reset()
 }
 
@@ -581,11 +1062,31 @@ func (it *tupleIterator) Next(ctx context.Context) bool {
 	if it.err != nil {
 		return false
 	}
-	return tuple.FilterIterator(it, it.f, func() bool {
-		return it.it.Next(ctx)
+	// filters are applied to the full payload
+	return tuple.FilterIterator(fullTuple{it}, it.f, func() bool {
+		return it.next(ctx)
 	})
 }
 
+// next advances the underlying iterator within the range.
+func (it *tupleIterator) next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+	var ok bool
+	if it.start != nil && !it.seek {
+		it.seek = true
+		ok = kv.Seek(ctx, it.it, it.start)
+	} else {
+		ok = it.it.Next(ctx)
+	}
+	if ok && it.stop != nil && it.it.Key().Compare(it.stop) >= 0 {
+		it.done = true
+		return false
+	}
+	return ok
+}
+
 func (it *tupleIterator) key() kv.Key {
 	if it.it == nil {
 		return nil
@@ -609,12 +1110,32 @@ func (it *tupleIterator) Key() tuple.Key {
 }
 
 func (it *tupleIterator) Data() tuple.Data {
+	if it.keysOnly {
+		return nil
+	}
+	return it.data(it.proj)
+}
+
+func (it *tupleIterator) data(proj []int) tuple.Data {
 	if it.it == nil {
 		return nil
 	}
-	data, err := it.tbl.decodeTuple(it.it.Val())
+	data, err := it.tbl.decodeTupleFields(it.it.Val(), proj)
 	if err != nil {
 		it.err = err
 	}
 	return data
 }
+
+// fullTuple exposes the full payload of the current tuple, regardless of the projection.
+type fullTuple struct {
+	it *tupleIterator
+}
+
+func (t fullTuple) Key() tuple.Key {
+	return t.it.Key()
+}
+
+func (t fullTuple) Data() tuple.Data {
+	return t.it.data(nil)
+}
@@ -1,13 +1,25 @@
 package tuplekv_test
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/filter"
+	"github.com/hidal-go/hidalgo/kv"
 	"github.com/hidal-go/hidalgo/kv/flat"
 	"github.com/hidal-go/hidalgo/kv/flat/btree"
+	"github.com/hidal-go/hidalgo/kv/mem"
+	"github.com/hidal-go/hidalgo/kv/options"
+	"github.com/hidal-go/hidalgo/record"
 	"github.com/hidal-go/hidalgo/tuple"
 	tuplekv "github.com/hidal-go/hidalgo/tuple/kv"
 	"github.com/hidal-go/hidalgo/tuple/tupletest"
+	"github.com/hidal-go/hidalgo/values"
 )
 
 func TestKV2Tuple(t *testing.T) {
@@ -15,7 +27,554 @@ func TestKV2Tuple(t *testing.T) {
 		kdb := btree.New()
 		db := tuplekv.New(flat.Upgrade(kdb))
 		return db
-	}, &tupletest.Options{
-		NoLocks: true,
+	}, nil)
+}
+
+func TestKV2TupleTx(t *testing.T) {
+	tupletest.RunTest(t, func(t testing.TB) tuple.Store {
+		return tuplekv.New(mem.New())
+	}, nil)
+}
+
+func TestIndexEntries(t *testing.T) {
+	ctx := context.Background()
+	kdb := mem.New()
+	db := tuplekv.New(kdb)
+
+	entries := func() int {
+		n := 0
+		err := kdb.View(ctx, func(tx kv.Tx) error {
+			it := tx.Scan(ctx, options.WithPrefixKV(kv.SKey("data", "index", "test")))
+			defer it.Close()
+			for it.Next(ctx) {
+				n++
+			}
+			return it.Err()
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "test",
+			Key:  []tuple.KeyField{{Name: "k", Type: values.StringType{}}},
+			Data: []tuple.Field{{Name: "v", Type: values.IntType{}}},
+			Indexes: []tuple.Index{
+				{Name: "v", Fields: []string{"v"}},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		for i, k := range []string{"a", "b", "c"} {
+			_, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: tuple.SKey(k), Data: tuple.Data{values.Int(i % 2)}})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, entries())
+
+	scan := func() int {
+		n := 0
+		err := db.View(ctx, func(tx tuple.Tx) error {
+			tbl, err := tx.Table(ctx, "test")
+			if err != nil {
+				return err
+			}
+			it := tbl.Scan(ctx, &tuple.ScanOptions{Filter: &tuple.Filter{
+				DataFilter: tuple.DataFilters{filter.EQ(values.Int(0))},
+			}})
+			defer it.Close()
+			for it.Next(ctx) {
+				n++
+			}
+			return it.Err()
+		})
+		require.NoError(t, err)
+		return n
+	}
+	require.Equal(t, 2, scan())
+
+	// remove index entries to make sure that scan uses them
+	err = kdb.Update(ctx, func(tx kv.Tx) error {
+		var keys []kv.Key
+		it := tx.Scan(ctx, options.WithPrefixKV(kv.SKey("data", "index", "test")))
+		defer it.Close()
+		for it.Next(ctx) {
+			keys = append(keys, it.Key().Clone())
+		}
+		for _, k := range keys {
+			if err := tx.Del(ctx, k); err != nil {
+				return err
+			}
+		}
+		return it.Err()
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, scan())
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, "test")
+		if err != nil {
+			return err
+		}
+		_, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: tuple.SKey("d"), Data: tuple.Data{values.Int(0)}})
+		if err != nil {
+			return err
+		}
+		return tbl.Drop(ctx)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, entries())
+}
+
+func TestAlterLegacyTable(t *testing.T) {
+	ctx := context.Background()
+	kdb := mem.New()
+	db := tuplekv.New(kdb)
+
+	h := tuple.Header{
+		Name: "test",
+		Key:  []tuple.KeyField{{Name: "k", Type: values.StringType{}}},
+		Data: []tuple.Field{{Name: "v", Type: values.IntType{}}},
+	}
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.CreateTable(ctx, h)
+		return err
+	})
+	require.NoError(t, err)
+
+	// tables created by older versions have no schema versions
+	err = kdb.Update(ctx, func(tx kv.Tx) error {
+		return tx.Del(ctx, kv.SKey("system", "version", "test"))
+	})
+	require.NoError(t, err)
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, "test")
+		if err != nil {
+			return err
+		}
+		_, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: tuple.SKey("a"), Data: tuple.Data{values.Int(1)}})
+		return err
+	})
+	require.NoError(t, err)
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.AlterTable(ctx, "test", tuple.AddField{
+			Field: tuple.Field{Name: "s", Type: values.StringType{}, Default: values.String("x")},
+		})
+		return err
+	})
+	require.NoError(t, err)
+
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, "test")
+		if err != nil {
+			return err
+		}
+		data, err := tbl.GetTuple(ctx, tuple.SKey("a"))
+		require.NoError(t, err)
+		require.Equal(t, tuple.Data{values.Int(1), values.String("x")}, data)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func BenchmarkKV2Tuple(b *testing.B) {
+	tupletest.RunBenchmarks(b, func(t testing.TB) tuple.Store {
+		kdb := btree.New()
+		db := tuplekv.New(flat.Upgrade(kdb))
+		return db
+	}, nil)
+}
+
+func TestKeyRangeScan(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	log := record.NewLog(&buf)
+	db := tuplekv.New(record.NewKV(mem.New(), log))
+
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "test",
+			Key: []tuple.KeyField{
+				{Name: "k1", Type: values.StringType{}},
+				{Name: "k2", Type: values.IntType{}},
+			},
+			Data: []tuple.Field{{Name: "v", Type: values.IntType{}}},
+		})
+		if err != nil {
+			return err
+		}
+		for _, k1 := range []string{"a", "b", "c"} {
+			for k2 := -5; k2 < 5; k2++ {
+				_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+					Key:  tuple.Key{values.String(k1), values.Int(k2)},
+					Data: tuple.Data{values.Int(k2)},
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// steps returns the number of recorded kv iterator steps
+	steps := func() int {
+		require.NoError(t, log.Flush())
+		n := 0
+		r := record.NewReader(bytes.NewReader(buf.Bytes()))
+		for {
+			e, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			if e.Op == record.OpNext || e.Op == record.OpSeek {
+				n++
+			}
+		}
+		return n
+	}
+
+	// scan returns keys matching the filter and the number of kv iterator steps
+	scan := func(kf tuple.KeyFilters) ([]string, int) {
+		before := steps()
+		var keys []string
+		err := db.View(ctx, func(tx tuple.Tx) error {
+			tbl, err := tx.Table(ctx, "test")
+			if err != nil {
+				return err
+			}
+			it := tbl.Scan(ctx, &tuple.ScanOptions{Filter: &tuple.Filter{KeyFilter: kf}})
+			defer it.Close()
+			for it.Next(ctx) {
+				k := it.Key()
+				keys = append(keys, fmt.Sprintf("%v%v", k[0], k[1]))
+			}
+			return it.Err()
+		})
+		require.NoError(t, err)
+		return keys, steps() - before
+	}
+
+	keys, n := scan(tuple.KeyFilters{filter.EQ(values.String("b")), filter.Range{Start: filter.GTE(values.Int(-1)), End: filter.LT(values.Int(2))}})
+	require.Equal(t, []string{"b-1", "b0", "b1"}, keys)
+	require.True(t, n <= 5, "steps: %d", n)
+
+	keys, n = scan(tuple.KeyFilters{filter.EQ(values.String("c")), filter.GT(values.Int(3))})
+	require.Equal(t, []string{"c4"}, keys)
+	require.True(t, n <= 3, "steps: %d", n)
+
+	keys, n = scan(tuple.KeyFilters{filter.LTE(values.String("a")), filter.LTE(values.Int(-4))})
+	require.Equal(t, []string{"a-5", "a-4"}, keys)
+	require.True(t, n <= 12, "steps: %d", n)
+
+	keys, n = scan(tuple.KeyFilters{filter.GT(values.String("b")), filter.Any{}})
+	require.Len(t, keys, 10)
+	require.True(t, n <= 12, "steps: %d", n)
+
+	// equality on the first field must not match other values with the same binary prefix
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, "test")
+		if err != nil {
+			return err
+		}
+		for k2 := 0; k2 < 10; k2++ {
+			_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+				Key:  tuple.Key{values.String("bb"), values.Int(k2)},
+				Data: tuple.Data{values.Int(k2)},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
+	require.NoError(t, err)
+
+	keys, n = scan(tuple.KeyFilters{filter.EQ(values.String("b"))})
+	require.Len(t, keys, 10)
+	require.True(t, n <= 12, "steps: %d", n)
+}
+
+// noCaps hides capabilities of the key-value store.
+type noCaps struct {
+	kv.KV
+}
+
+func TestIndexBatchGet(t *testing.T) {
+	ctx := context.Background()
+	for _, c := range []struct {
+		name  string
+		batch bool
+	}{
+		{name: "batch", batch: true},
+		{name: "single", batch: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := record.NewLog(&buf)
+			var kdb kv.KV = record.NewKV(mem.New(), log)
+			if !c.batch {
+				kdb = noCaps{kdb}
+			}
+			db := tuplekv.New(kdb)
+
+			const n = 100
+			err := db.Update(ctx, func(tx tuple.Tx) error {
+				tbl, err := tx.CreateTable(ctx, tuple.Header{
+					Name: "test",
+					Key:  []tuple.KeyField{{Name: "k", Type: values.IntType{}}},
+					Data: []tuple.Field{{Name: "v", Type: values.IntType{}}},
+					Indexes: []tuple.Index{
+						{Name: "v", Fields: []string{"v"}},
+					},
+				})
+				if err != nil {
+					return err
+				}
+				for i := 0; i < n; i++ {
+					_, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: tuple.Key{values.Int(i)}, Data: tuple.Data{values.Int(i % 2)}})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			require.NoError(t, err)
+
+			// reads returns the number of recorded point and batch reads
+			reads := func() (gets, batches int) {
+				require.NoError(t, log.Flush())
+				r := record.NewReader(bytes.NewReader(buf.Bytes()))
+				for {
+					e, err := r.Next()
+					if err == io.EOF {
+						return gets, batches
+					}
+					require.NoError(t, err)
+					switch e.Op {
+					case record.OpGet:
+						gets++
+					case record.OpGetBatch:
+						batches++
+					}
+				}
+			}
+			gets0, batches0 := reads()
+
+			var got []int64
+			err = db.View(ctx, func(tx tuple.Tx) error {
+				tbl, err := tx.Table(ctx, "test")
+				if err != nil {
+					return err
+				}
+				it := tbl.Scan(ctx, &tuple.ScanOptions{Filter: &tuple.Filter{
+					DataFilter: tuple.DataFilters{filter.EQ(values.Int(1))},
+				}})
+				defer it.Close()
+				for it.Next(ctx) {
+					got = append(got, int64(it.Key()[0].(values.Int)))
+					require.Equal(t, tuple.Data{values.Int(1)}, it.Data())
+				}
+				return it.Err()
+			})
+			require.NoError(t, err)
+			require.Len(t, got, n/2)
+			for i, k := range got {
+				require.Equal(t, int64(2*i+1), k)
+			}
+
+			gets, batches := reads()
+			gets, batches = gets-gets0, batches-batches0
+			if c.batch {
+				require.True(t, batches > 0 && batches < n/2, "batches: %d", batches)
+			} else {
+				require.True(t, gets >= n/2, "gets: %d", gets)
+				require.Equal(t, 0, batches)
+			}
+		})
+	}
+}
+
+func TestDescPages(t *testing.T) {
+	ctx := context.Background()
+	k1s := []string{"\x00", "a", "a\x00", "a\x00\x00", "a\x01", "ab", "a\xff", "a\xff\xff", "b", "\xff", "\xff\xff"}
+	for _, c := range []struct {
+		name string
+		seek bool
+	}{
+		{name: "seek", seek: true},
+		{name: "no seek", seek: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var kdb kv.KV = mem.New()
+			if !c.seek {
+				kdb = noCaps{kdb}
+			}
+			db := tuplekv.New(kdb)
+
+			var exp []string
+			err := db.Update(ctx, func(tx tuple.Tx) error {
+				tbl, err := tx.CreateTable(ctx, tuple.Header{
+					Name: "test",
+					Key: []tuple.KeyField{
+						{Name: "k1", Type: values.StringType{}},
+						{Name: "k2", Type: values.IntType{}},
+					},
+					Data: []tuple.Field{{Name: "v", Type: values.IntType{}}},
+				})
+				if err != nil {
+					return err
+				}
+				for _, k1 := range k1s {
+					for k2 := -3; k2 <= 3; k2++ {
+						_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+							Key:  tuple.Key{values.String(k1), values.Int(k2)},
+							Data: tuple.Data{values.Int(k2)},
+						})
+						if err != nil {
+							return err
+						}
+						exp = append(exp, fmt.Sprintf("%q%d", k1, k2))
+					}
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			// reverse the order
+			for i, j := 0, len(exp)-1; i < j; i, j = i+1, j-1 {
+				exp[i], exp[j] = exp[j], exp[i]
+			}
+
+			// page returns keys of the page before the bound
+			page := func(f *tuple.Filter, before tuple.Key, limit int) ([]string, tuple.Key) {
+				var (
+					keys []string
+					last tuple.Key
+				)
+				err := db.View(ctx, func(tx tuple.Tx) error {
+					tbl, err := tx.Table(ctx, "test")
+					if err != nil {
+						return err
+					}
+					it := tbl.Scan(ctx, &tuple.ScanOptions{Filter: f, Sort: tuple.SortDesc, Before: before, Limit: limit})
+					defer it.Close()
+					for it.Next(ctx) {
+						k := it.Key()
+						require.Equal(t, tuple.Data{k[1]}, it.Data())
+						keys = append(keys, fmt.Sprintf("%q%d", string(k[0].(values.String)), k[1]))
+						last = k
+					}
+					return it.Err()
+				})
+				require.NoError(t, err)
+				return keys, last
+			}
+
+			var (
+				got    []string
+				before tuple.Key
+			)
+			for {
+				keys, last := page(nil, before, 5)
+				got = append(got, keys...)
+				if len(keys) < 5 {
+					break
+				}
+				before = last
+			}
+			require.Equal(t, exp, got)
+
+			got, _ = page(&tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("a\x00")), filter.GT(values.Int(0))}}, nil, 0)
+			require.Equal(t, []string{`"a\x00"3`, `"a\x00"2`, `"a\x00"1`}, got)
+
+			got, _ = page(&tuple.Filter{KeyFilter: tuple.KeyFilters{filter.Prefix(values.String("a\xff"))}}, nil, 3)
+			require.Equal(t, []string{`"a\xff\xff"3`, `"a\xff\xff"2`, `"a\xff\xff"1`}, got)
+
+			got, _ = page(&tuple.Filter{DataFilter: tuple.DataFilters{filter.EQ(values.Int(-3))}}, tuple.Key{values.String("b"), values.Int(0)}, 2)
+			require.Equal(t, []string{`"b"-3`, `"a\xff\xff"-3`}, got)
+		})
+	}
+}
+
+func TestDescPageReads(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	log := record.NewLog(&buf)
+	db := tuplekv.New(record.NewKV(mem.New(), log))
+
+	const n = 5000
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "test",
+			Key:  []tuple.KeyField{{Name: "k", Type: values.IntType{}}},
+			Data: []tuple.Field{{Name: "v", Type: values.IntType{}}},
+		})
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			_, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: tuple.Key{values.Int(i)}, Data: tuple.Data{values.Int(i)}})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// steps returns the number of recorded kv iterator steps
+	steps := func() int {
+		require.NoError(t, log.Flush())
+		cnt := 0
+		r := record.NewReader(bytes.NewReader(buf.Bytes()))
+		for {
+			e, err := r.Next()
+			if err == io.EOF {
+				return cnt
+			}
+			require.NoError(t, err)
+			if e.Op == record.OpNext || e.Op == record.OpSeek {
+				cnt++
+			}
+		}
+	}
+
+	const limit = 10
+	var before tuple.Key
+	for p := 0; p < 5; p++ {
+		s0 := steps()
+		var got []int64
+		err = db.View(ctx, func(tx tuple.Tx) error {
+			tbl, err := tx.Table(ctx, "test")
+			if err != nil {
+				return err
+			}
+			it := tbl.Scan(ctx, &tuple.ScanOptions{Sort: tuple.SortDesc, Before: before, Limit: limit})
+			defer it.Close()
+			for it.Next(ctx) {
+				got = append(got, int64(it.Key()[0].(values.Int)))
+			}
+			return it.Err()
+		})
+		require.NoError(t, err)
+		require.Len(t, got, limit)
+		for i, k := range got {
+			require.Equal(t, int64(n-1-p*limit-i), k)
+		}
+		before = tuple.Key{values.Int(got[len(got)-1])}
+
+		// each page is found by seeking, not by reading the table from the beginning
+		cnt := steps() - s0
+		require.True(t, cnt <= 20*limit, "steps: %d", cnt)
+	}
 }
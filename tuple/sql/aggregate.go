@@ -0,0 +1,135 @@
+package sqltuple
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+var _ tuple.Aggregator = (*sqlTable)(nil)
+
+// Aggregate implements tuple.Aggregator. Aggregations are computed with SELECT ... GROUP BY,
+// unless some filters cannot be converted to SQL; in that case tuples are aggregated while scanning.
+func (tbl *sqlTable) Aggregate(ctx context.Context, opt *tuple.AggregateOptions) ([]tuple.Group, error) {
+	if err := opt.Validate(tbl.h); err != nil {
+		return nil, err
+	}
+	f, where := tbl.asWhere(opt.Filter)
+	if !f.IsAny() {
+		// some filters still remain
+		return tuple.AggregateScan(ctx, tbl.h, tbl, opt)
+	}
+	dia := tbl.tx.dia
+	// strings are grouped and compared with the same collation as in filters
+	var groups []tuple.Field
+	for _, f := range tbl.h.Key[:opt.GroupBy.KeyPrefix] {
+		groups = append(groups, tuple.Field{Name: f.Name, Type: f.Type})
+	}
+	for _, name := range opt.GroupBy.Fields {
+		f, _ := tbl.h.DataByName(name)
+		groups = append(groups, *f)
+	}
+
+	b := tbl.sql()
+	b.Write(`SELECT `)
+	for i, c := range groups {
+		if i != 0 {
+			b.Write(", ")
+		}
+		dia.column(b, c.Name, c.Type)
+	}
+	for i, a := range opt.Aggregates {
+		if i != 0 || len(groups) != 0 {
+			b.Write(", ")
+		}
+		b.Write(strings.ToUpper(a.Func.String()))
+		b.Write("(")
+		if a.Field == "" {
+			b.Write("*")
+		} else {
+			f, _ := tbl.h.DataByName(a.Field)
+			dia.column(b, f.Name, f.Type)
+		}
+		b.Write(")")
+	}
+	b.Write(` FROM `)
+	b.Idents(tbl.h.Name)
+	if where != nil {
+		b.Write(" WHERE ")
+		where(b)
+	}
+	if len(groups) != 0 {
+		b.Write(" GROUP BY ")
+		for i, c := range groups {
+			if i != 0 {
+				b.Write(", ")
+			}
+			dia.column(b, c.Name, c.Type)
+		}
+	}
+	rows, err := tbl.tx.db.queryb(ctx, tbl.tx.tx, b)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []tuple.Group
+	for rows.Next() {
+		g, err := tbl.scanGroup(rows, opt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// scanGroup scans grouping fields and aggregation results.
+func (tbl *sqlTable) scanGroup(row scanner, opt *tuple.AggregateOptions) (tuple.Group, error) {
+	key := make([]values.SortableDest, 0, opt.GroupBy.KeyPrefix)
+	fields := make([]*nullableDest, 0, len(opt.GroupBy.Fields))
+	aggs := make([]*nullableDest, 0, len(opt.Aggregates))
+	in := make([]interface{}, 0, cap(key)+cap(fields)+cap(aggs))
+
+	for _, f := range tbl.h.Key[:opt.GroupBy.KeyPrefix] {
+		v := f.Type.NewSortable()
+		key = append(key, v)
+		in = append(in, v.NativePtr())
+	}
+	for _, name := range opt.GroupBy.Fields {
+		f, _ := tbl.h.DataByName(name)
+		v := newNullableDest(f.Type)
+		fields = append(fields, v)
+		in = append(in, v.NativePtr())
+	}
+	for _, a := range opt.Aggregates {
+		v := newNullableDest(a.ResultType(tbl.h))
+		aggs = append(aggs, v)
+		in = append(in, v.NativePtr())
+	}
+
+	if err := row.Scan(in...); err != nil {
+		return tuple.Group{}, err
+	}
+
+	g := tuple.Group{
+		Key:    make(tuple.Key, 0, len(key)),
+		Fields: make(tuple.Data, 0, len(fields)),
+		Values: make(tuple.Data, 0, len(aggs)),
+	}
+	for _, k := range key {
+		g.Key = append(g.Key, k.Sortable())
+	}
+	for _, d := range fields {
+		g.Fields = append(g.Fields, d.Value())
+	}
+	for _, d := range aggs {
+		g.Values = append(g.Values, d.Value())
+	}
+	return g, nil
+}
@@ -0,0 +1,178 @@
+package sqltuple
+
+import (
+	"context"
+
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+var _ tuple.BatchWriter = (*sqlTable)(nil)
+
+// maxBatchArgs limits the number of arguments in a single multi-row statement.
+// PostgreSQL allows at most 65535 arguments, so keep some margin.
+const maxBatchArgs = 32000
+
+// batches splits tuples into batches that fit into a single statement with a given number of columns.
+func batches(tuples []tuple.Tuple, cols int) [][]tuple.Tuple {
+	n := maxBatchArgs
+	if cols > 0 {
+		n /= cols
+	}
+	if n < 1 {
+		n = 1
+	}
+	out := make([][]tuple.Tuple, 0, (len(tuples)+n-1)/n)
+	for len(tuples) > n {
+		out = append(out, tuples[:n])
+		tuples = tuples[n:]
+	}
+	if len(tuples) != 0 {
+		out = append(out, tuples)
+	}
+	return out
+}
+
+// validateBatch checks keys and payloads of all tuples in the batch.
+func (tbl *sqlTable) validateBatch(tuples []tuple.Tuple, insert bool) error {
+	for _, t := range tuples {
+		if err := tbl.h.ValidateKey(t.Key, insert); err != nil {
+			return err
+		} else if err = tbl.h.ValidateData(t.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertValues writes a multi-row INSERT statement for tuples, without a terminating clause.
+func (tbl *sqlTable) insertValues(b *Builder, stmt string, tuples []tuple.Tuple, auto bool) {
+	b.Write(stmt + " INTO ")
+	b.Idents(tbl.h.Name)
+	b.Write("(")
+	b.Idents(tbl.insertNames(auto)...)
+	b.Write(") VALUES ")
+	var args []interface{}
+	for i, t := range tuples {
+		if i != 0 {
+			b.Write(", ")
+		}
+		b.Write("(")
+		args = tbl.appendInsert(args[:0], t, auto)
+		b.Place(args...)
+		b.Write(")")
+	}
+}
+
+// InsertTupleBatch implements tuple.BatchWriter. Tuples are inserted with multi-row INSERT statements.
+func (tbl *sqlTable) InsertTupleBatch(ctx context.Context, tuples []tuple.Tuple) ([]tuple.Key, error) {
+	if err := tbl.validateBatch(tuples, true); err != nil {
+		return nil, err
+	}
+	auto := tbl.h.Key[0].Auto
+	if !auto {
+		uniq, err := tuple.UniqueTuples(tuples)
+		if err != nil {
+			return nil, err
+		} else if len(uniq) != len(tuples) {
+			return nil, tuple.ErrExists
+		}
+	}
+	if auto && !tbl.tx.dia.Returning {
+		// ids of a multi-row insert cannot be read back reliably without RETURNING,
+		// for example, MySQL only reports the first one and the rest depend on the lock mode
+		keys := make([]tuple.Key, 0, len(tuples))
+		for _, t := range tuples {
+			key, err := tbl.InsertTuple(ctx, t)
+			if err != nil {
+				return keys, err
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}
+	keys := make([]tuple.Key, 0, len(tuples))
+	for _, batch := range batches(tuples, len(tbl.insertNames(auto))) {
+		b := tbl.sql()
+		tbl.insertValues(b, "INSERT", batch, auto)
+		if !auto {
+			if _, err := tbl.tx.db.execb(ctx, tbl.tx.tx, b); err != nil {
+				return keys, err
+			}
+			for _, t := range batch {
+				keys = append(keys, t.Key)
+			}
+			continue
+		}
+		b.Write(" RETURNING ")
+		b.Idents(tbl.h.Key[0].Name)
+		rows, err := tbl.tx.db.queryb(ctx, tbl.tx.tx, b)
+		if err != nil {
+			return keys, err
+		}
+		for rows.Next() {
+			var id uint64
+			if err = rows.Scan(&id); err != nil {
+				rows.Close()
+				return keys, err
+			}
+			keys = append(keys, tuple.Key{values.UInt(id)})
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return keys, tbl.tx.db.convError(err)
+		}
+	}
+	return keys, nil
+}
+
+// UpsertTupleBatch implements tuple.BatchWriter. Tuples are written with multi-row INSERT ... ON CONFLICT
+// or REPLACE statements, depending on the dialect.
+func (tbl *sqlTable) UpsertTupleBatch(ctx context.Context, tuples []tuple.Tuple) error {
+	if err := tbl.validateBatch(tuples, false); err != nil {
+		return err
+	}
+	dia := tbl.tx.dia
+	if !dia.OnConflict && (!dia.ReplaceStmt || tbl.hasUnique()) {
+		// REPLACE removes rows that conflict with unique indexes, see UpdateTuple
+		for _, t := range tuples {
+			if err := tbl.UpdateTuple(ctx, t, &tuple.UpdateOpt{Upsert: true}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// a single statement cannot update the same row twice
+	tuples, err := tuple.UniqueTuples(tuples)
+	if err != nil {
+		return err
+	}
+	for _, batch := range batches(tuples, len(tbl.h.Key)+len(tbl.h.Data)) {
+		b := tbl.sql()
+		if !dia.OnConflict {
+			tbl.insertValues(b, "REPLACE", batch, false)
+		} else {
+			tbl.insertValues(b, "INSERT", batch, false)
+			b.Write(` ON CONFLICT ON CONSTRAINT `)
+			b.Idents(tbl.h.Name + "_pkey") // TODO: should be in the dialect
+			if len(tbl.h.Data) == 0 {
+				b.Write(` DO NOTHING`)
+			} else {
+				b.Write(` DO UPDATE SET `)
+			}
+			for i, name := range tbl.payloadNames() {
+				if i != 0 {
+					b.Write(", ")
+				}
+				b.Idents(name)
+				b.Write(" = EXCLUDED.")
+				b.Idents(name)
+			}
+		}
+		if _, err = tbl.tx.db.execb(ctx, tbl.tx.tx, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -26,7 +26,13 @@ func (b *Builder) Write(s string) {
 	b.buf.WriteString(s)
 }
 
+// defaultValue can be passed as an argument to use a column default instead of a placeholder.
+type defaultValue struct{}
+
 func (b *Builder) place(v interface{}) string {
+	if _, ok := v.(defaultValue); ok {
+		return "DEFAULT"
+	}
 	p := b.pi
 	b.pi++
 	b.args = append(b.args, v)
@@ -1,8 +1,11 @@
 package sqltuple
 
 import (
+	"encoding/hex"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hidal-go/hidalgo/values"
 )
@@ -25,7 +28,31 @@ type Dialect struct {
 	DefaultSchema string
 	// ListColumns is a query that will be executed to get columns info.
 	// Two parameters will be passed to the query: current schema and the table name.
+	// It should return a name, a type, a nullable flag (YES/NO), a key type, a comment
+	// and a default value for each column.
 	ListColumns string
+	// ColumnDefault converts a column default returned by ListColumns to a plain value text.
+	// It should return false if the default is an expression. If not set, defaults are used as-is.
+	ColumnDefault func(s string) (string, bool)
+	// StringCollation is used to compare strings in filters. It must compare strings byte-wise,
+	// the same way as values.Compare does. If not set, the column collation is used.
+	// If it differs from StringTypeCollation, indexes on string columns cannot be used for filters and sorting.
+	StringCollation string
+	// BytesLiteral writes a binary string literal. If not set, X'...' literal is used.
+	BytesLiteral func(p []byte) string
+	// ListIndexes is a query that will be executed to get secondary indexes info.
+	// Two parameters will be passed to the query: current schema and the table name.
+	// It should return an index name, a column name and a unique flag for each indexed column,
+	// ordered by the index name and the position of the column in the index.
+	ListIndexes string
+	// DropIndexOnTable indicates that DROP INDEX statement requires a table name.
+	DropIndexOnTable bool
+	// ModifyColumnType changes the type of existing column. It is used to convert
+	// payload columns to types that can be indexed.
+	ModifyColumnType func(b *Builder, tbl, col, typ string)
+	// RenameColumn changes the name of existing column. Column definition is passed for databases
+	// that require it. If not set, RENAME COLUMN statement is used.
+	RenameColumn func(b *Builder, tbl, col, name, typ string)
 	// Unsigned indicates that a database supports UNSIGNED modifier for integer types.
 	Unsigned bool
 	// NoIteratorsWhenMutating mark indicates that backend cannot run iterators and
@@ -73,16 +100,18 @@ func (d *Dialect) QuoteIdentifier(s string) string {
 }
 
 func (d *Dialect) QuoteString(s string) string {
-	// only used when setting comments, so it's pretty naive
+	// only used for comments and default values, so it's pretty naive
 	return "'" + strings.Replace(s, "'", "''", -1) + "'"
 }
 
-func (d *Dialect) sqlType(t values.Type, key bool) string {
+// sqlType returns an SQL type for a column. Key columns should use key types and cannot be nullable.
+// Indexed payload columns and columns with default values use the same types as keys.
+func (d *Dialect) sqlType(t values.Type, keyType, null bool) string {
 	var tp string
 	switch t.(type) {
 	case values.StringType:
 		tp = d.StringType
-		if key {
+		if keyType {
 			tp = d.StringKeyType
 		}
 		if d.StringTypeCollation != "" {
@@ -91,7 +120,7 @@ func (d *Dialect) sqlType(t values.Type, key bool) string {
 		}
 	case values.BytesType:
 		tp = d.BytesType
-		if key {
+		if keyType {
 			tp = d.BytesKeyType
 		}
 	case values.IntType:
@@ -110,14 +139,87 @@ func (d *Dialect) sqlType(t values.Type, key bool) string {
 	default:
 		panic(fmt.Errorf("unsupported type: %T", t))
 	}
-	if key {
-		tp += " NOT NULL"
-	} else {
+	if null {
 		tp += " NULL"
+	} else {
+		tp += " NOT NULL"
 	}
 	return tp
 }
 
+// timeLayout is used for time literals.
+const timeLayout = "2006-01-02 15:04:05.999999"
+
+// sqlLiteral returns an SQL literal for a value. It is used for column defaults.
+func (d *Dialect) sqlLiteral(v values.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case values.String:
+		return d.QuoteString(string(v))
+	case values.Bytes:
+		if d.BytesLiteral != nil {
+			return d.BytesLiteral(v)
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	case values.Int:
+		return strconv.FormatInt(int64(v), 10)
+	case values.UInt:
+		return strconv.FormatUint(uint64(v), 10)
+	case values.Float:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case values.Bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case values.Time:
+		return d.QuoteString(time.Time(v).UTC().Format(timeLayout))
+	}
+	panic(fmt.Errorf("unsupported type: %T", v))
+}
+
+// parseDefault converts a column default returned by ListColumns to a value of a given type.
+// It returns nil if the default is not a literal.
+func (d *Dialect) parseDefault(t values.Type, s string) values.Value {
+	if d.ColumnDefault != nil {
+		var ok bool
+		if s, ok = d.ColumnDefault(s); !ok {
+			return nil
+		}
+	}
+	switch t.(type) {
+	case values.StringType:
+		return values.String(s)
+	case values.BytesType:
+		return values.Bytes(s)
+	case values.IntType:
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return values.Int(v)
+		}
+	case values.UIntType:
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return values.UInt(v)
+		}
+	case values.FloatType:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return values.Float(v)
+		}
+	case values.BoolType:
+		switch strings.ToLower(s) {
+		case "1", "t", "true":
+			return values.Bool(true)
+		case "0", "f", "false":
+			return values.Bool(false)
+		}
+	case values.TimeType:
+		if v, err := time.Parse(timeLayout, s); err == nil {
+			return values.AsTime(v)
+		}
+	}
+	return nil
+}
+
 func (d *Dialect) sqlColumnComment(t values.Type) string {
 	var c string
 	switch t.(type) {
@@ -12,11 +12,14 @@ import (
 
 const Name = "mysql"
 
+// caps are the capabilities of the driver.
+const caps = base.CapTx | base.CapConcurrentWrites
+
 func init() {
 	sqltuple.Register(sqltuple.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "MySQL",
-			Local: false, Volatile: false,
+			Local: false, Volatile: false, Capabilities: caps,
 		},
 		Driver: "mysql",
 		DSN: func(addr, ns string) (string, error) {
@@ -31,11 +34,33 @@ func init() {
 			TimeType:      "DATETIME(6)",
 			// TODO: set it on the table/database
 			StringTypeCollation:     " CHARACTER SET utf8 COLLATE utf8_unicode_ci",
+			StringCollation:         "utf8_bin",
 			Unsigned:                true,
 			ReplaceStmt:             true,
 			NoIteratorsWhenMutating: true,
-			ListColumns: `SELECT column_name, column_type, is_nullable, column_key, column_comment
+			ListColumns: `SELECT column_name, column_type, is_nullable, column_key, column_comment, column_default
 FROM information_schema.columns WHERE table_schema = ? AND table_name = ?`,
+			ListIndexes: `SELECT index_name, column_name, non_unique = 0
+FROM information_schema.statistics WHERE table_schema = ? AND table_name = ? AND index_name <> 'PRIMARY'
+ORDER BY index_name, seq_in_index`,
+			DropIndexOnTable: true,
+			ModifyColumnType: func(b *sqltuple.Builder, tbl, col, typ string) {
+				b.Write(`ALTER TABLE `)
+				b.Idents(tbl)
+				b.Write(` MODIFY COLUMN `)
+				b.Idents(col)
+				b.Write(" " + typ)
+			},
+			RenameColumn: func(b *sqltuple.Builder, tbl, col, name, typ string) {
+				// RENAME COLUMN is not supported before MySQL 8.0
+				b.Write(`ALTER TABLE `)
+				b.Idents(tbl)
+				b.Write(` CHANGE COLUMN `)
+				b.Idents(col)
+				b.Write(" ")
+				b.Idents(name)
+				b.Write(" " + typ)
+			},
 			QuoteIdentifierFunc: func(s string) string {
 				return "`" + strings.Replace(s, "`", "", -1) + "`"
 			},
@@ -47,6 +72,10 @@ FROM information_schema.columns WHERE table_schema = ? AND table_name = ?`,
 					switch e.Number {
 					case 1146:
 						return sqltuple.ErrTableNotFound
+					case 1062: // duplicate entry
+						if !strings.HasSuffix(e.Message, "PRIMARY'") {
+							return sqltuple.ErrUnique
+						}
 					}
 				}
 				return err
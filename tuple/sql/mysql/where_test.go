@@ -0,0 +1,83 @@
+package mysql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/filter"
+	"github.com/hidal-go/hidalgo/tuple"
+	sqltuple "github.com/hidal-go/hidalgo/tuple/sql"
+	"github.com/hidal-go/hidalgo/tuple/sql/mysql"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+var whereTable = tuple.Header{
+	Name: "test",
+	Key: []tuple.KeyField{
+		{Name: "k1", Type: values.StringType{}},
+		{Name: "k2", Type: values.UIntType{}},
+	},
+	Data: []tuple.Field{
+		{Name: "name", Type: values.StringType{}, Nullable: true},
+		{Name: "data", Type: values.BytesType{}},
+	},
+}
+
+var whereCases = []struct {
+	name   string
+	filter *tuple.Filter
+	sql    string
+	args   []interface{}
+	rest   *tuple.Filter
+}{
+	{
+		name:   "key range",
+		filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("a")), filter.Range{Start: filter.GT(values.UInt(1)), End: filter.LTE(values.UInt(5))}}},
+		sql:    "`k1` COLLATE utf8_bin = ? AND (`k2` > ? AND `k2` <= ?)",
+		args:   []interface{}{"a", uint64(1), uint64(5)},
+		rest:   &tuple.Filter{},
+	},
+	{
+		name: "nullable payload",
+		filter: &tuple.Filter{DataFilter: tuple.DataFilters{
+			filter.And{filter.Prefix(values.String("x")), filter.Not{Filter: filter.EQ(nil)}},
+			filter.EQ(values.Bytes("b")),
+		}},
+		sql:  "((`name` IS NULL OR `name` COLLATE utf8_bin >= ? AND `name` COLLATE utf8_bin < ?) AND NOT `name` IS NULL) AND (`data` IS NOT NULL AND `data` = ?)",
+		args: []interface{}{"x", "y", []byte("b")},
+		rest: &tuple.Filter{},
+	},
+	{
+		name: "partial",
+		filter: &tuple.Filter{
+			KeyFilter:  tuple.KeyFilters{filter.Or{filter.EQ(values.String("a")), filter.EQ(values.Int(1))}},
+			DataFilter: tuple.DataFilters{filter.LT(values.String("m")), nil},
+		},
+		sql:  "(`name` IS NULL OR `name` COLLATE utf8_bin < ?)",
+		args: []interface{}{"m"},
+		rest: &tuple.Filter{
+			KeyFilter: tuple.KeyFilters{filter.Or{filter.EQ(values.String("a")), filter.EQ(values.Int(1))}},
+		},
+	},
+}
+
+func TestWhere(t *testing.T) {
+	d := sqltuple.ByName(mysql.Name).Dialect
+	d.SetDefaults()
+	for _, c := range whereCases {
+		t.Run(c.name, func(t *testing.T) {
+			rest, where := d.Where(&whereTable, c.filter)
+			require.Equal(t, c.rest, rest)
+			if c.sql == "" {
+				require.Nil(t, where)
+				return
+			}
+			require.NotNil(t, where)
+			b := d.NewBuilder()
+			where(b)
+			require.Equal(t, c.sql, b.String())
+			require.Equal(t, c.args, b.Args())
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package postgres
 
 import (
+	"encoding/hex"
 	"strconv"
+	"strings"
 
 	"github.com/lib/pq"   // This import will be dropped if pq.QuoteIdentifier is removed.
 	_ "github.com/lib/pq" // This side-effect import must be kept.
@@ -12,11 +14,14 @@ import (
 
 const Name = "postgres"
 
+// caps are the capabilities of the driver.
+const caps = base.CapTx | base.CapConcurrentWrites
+
 func init() {
 	sqltuple.Register(sqltuple.Registration{
 		Registration: base.Registration{
 			Name: Name, Title: "PostgreSQL",
-			Local: false, Volatile: false,
+			Local: false, Volatile: false, Capabilities: caps,
 		},
 		Driver: "postgres",
 		DSN: func(addr, ns string) (string, error) {
@@ -35,9 +40,12 @@ func init() {
 				return "$" + strconv.Itoa(i+1)
 			},
 			Errors: func(err error) error {
+				if e, ok := err.(*pq.Error); ok && e.Code == "23505" && !strings.HasSuffix(e.Constraint, "_pkey") {
+					return sqltuple.ErrUnique
+				}
 				return err
 			},
-			ListColumns: `SELECT c.column_name, c.data_type, c.is_nullable, tc.constraint_type, col_description(a.attrelid, a.attnum)
+			ListColumns: `SELECT c.column_name, c.data_type, c.is_nullable, tc.constraint_type, col_description(a.attrelid, a.attnum), c.column_default
 FROM information_schema.columns c
   LEFT JOIN information_schema.constraint_column_usage AS ccu ON c.table_schema = ccu.table_schema AND c.table_name = ccu.table_name AND c.column_name = ccu.column_name
   LEFT JOIN information_schema.table_constraints AS tc ON c.table_schema = tc.constraint_schema AND tc.table_name = c.table_name AND tc.constraint_name = ccu.constraint_name
@@ -48,6 +56,25 @@ WHERE c.table_schema = $1
       AND a.attnum > 0
       AND a.attisdropped is false
       AND pg_catalog.pg_table_is_visible(pc.oid)`,
+			ListIndexes: `SELECT i.relname, a.attname, ix.indisunique
+FROM pg_catalog.pg_index AS ix
+  JOIN pg_catalog.pg_class AS t ON t.oid = ix.indrelid
+  JOIN pg_catalog.pg_class AS i ON i.oid = ix.indexrelid
+  JOIN pg_catalog.pg_namespace AS n ON n.oid = t.relnamespace
+  JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, pos) ON true
+  JOIN pg_catalog.pg_attribute AS a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = $1
+      AND t.relname = $2
+      AND NOT ix.indisprimary
+ORDER BY i.relname, k.pos`,
+			ColumnDefault: columnDefault,
+			// string columns are created with the same collation that is used in filters,
+			// so key bounds and ORDER BY can use the primary key index
+			StringTypeCollation: `COLLATE "C"`,
+			StringCollation:     `"C"`,
+			BytesLiteral: func(p []byte) string {
+				return `'\x` + hex.EncodeToString(p) + `'`
+			},
 			ColumnCommentSet: func(b *sqltuple.Builder, tbl, col, s string) {
 				b.Write(`COMMENT ON COLUMN `)
 				b.Idents(tbl)
@@ -59,3 +86,28 @@ WHERE c.table_schema = $1
 		},
 	})
 }
+
+// columnDefault converts a column default to a plain value text.
+// Postgres returns defaults as expressions, for example: 'abc'::text, '\x0102'::bytea or 42.
+func columnDefault(s string) (string, bool) {
+	if !strings.HasPrefix(s, "'") {
+		// numbers and booleans are not quoted
+		if strings.ContainsAny(s, "(:") || s == "NULL" {
+			return "", false
+		}
+		return s, true
+	}
+	i := strings.LastIndex(s, "'")
+	if i == 0 {
+		return "", false
+	}
+	v, typ := strings.Replace(s[1:i], "''", "'", -1), s[i+1:]
+	if typ == "::bytea" && strings.HasPrefix(v, `\x`) {
+		p, err := hex.DecodeString(v[2:])
+		if err != nil {
+			return "", false
+		}
+		v = string(p)
+	}
+	return v, true
+}
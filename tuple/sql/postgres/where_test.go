@@ -0,0 +1,99 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/filter"
+	"github.com/hidal-go/hidalgo/tuple"
+	sqltuple "github.com/hidal-go/hidalgo/tuple/sql"
+	"github.com/hidal-go/hidalgo/tuple/sql/postgres"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+var whereTable = tuple.Header{
+	Name: "test",
+	Key: []tuple.KeyField{
+		{Name: "k1", Type: values.StringType{}},
+		{Name: "k2", Type: values.IntType{}},
+	},
+	Data: []tuple.Field{
+		{Name: "name", Type: values.StringType{}},
+		{Name: "score", Type: values.FloatType{}, Nullable: true},
+	},
+}
+
+var whereCases = []struct {
+	name   string
+	filter *tuple.Filter
+	sql    string
+	args   []interface{}
+	rest   *tuple.Filter
+}{
+	{
+		name:   "key prefix",
+		filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("a")), filter.GTE(values.Int(2))}},
+		sql:    `"k1" COLLATE "C" = $1 AND "k2" >= $2`,
+		args:   []interface{}{"a", int64(2)},
+		rest:   &tuple.Filter{},
+	},
+	{
+		name:   "string prefix",
+		filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.Prefix(values.String("ab"))}},
+		sql:    `("k1" COLLATE "C" >= $1 AND "k1" COLLATE "C" < $2)`,
+		args:   []interface{}{"ab", "ac"},
+		rest:   &tuple.Filter{},
+	},
+	{
+		name: "nullable payload",
+		filter: &tuple.Filter{DataFilter: tuple.DataFilters{
+			nil,
+			filter.Or{filter.EQ(values.Float(1.5)), filter.Not{Filter: filter.Any{}}},
+		}},
+		sql:  `(("score" IS NOT NULL AND "score" = $1) OR NOT "score" IS NOT NULL)`,
+		args: []interface{}{1.5},
+		rest: &tuple.Filter{},
+	},
+	{
+		name: "partial",
+		filter: &tuple.Filter{
+			KeyFilter:  tuple.KeyFilters{filter.Any{}, filter.Not{Filter: filter.LT(values.Int(3))}},
+			DataFilter: tuple.DataFilters{filter.EQ(values.Int(1)), filter.GT(values.Int(0))},
+		},
+		sql:  `NOT "k2" < $1`,
+		args: []interface{}{int64(3)},
+		rest: &tuple.Filter{
+			DataFilter: tuple.DataFilters{filter.EQ(values.Int(1)), filter.GT(values.Int(0))},
+		},
+	},
+	{
+		name: "untranslated",
+		filter: &tuple.Filter{
+			KeyFilter: tuple.Keys{{values.String("a"), values.Int(1)}},
+		},
+		rest: &tuple.Filter{
+			KeyFilter: tuple.Keys{{values.String("a"), values.Int(1)}},
+		},
+	},
+}
+
+func TestWhere(t *testing.T) {
+	d := sqltuple.ByName(postgres.Name).Dialect
+	d.SetDefaults()
+	for _, c := range whereCases {
+		t.Run(c.name, func(t *testing.T) {
+			rest, where := d.Where(&whereTable, c.filter)
+			require.Equal(t, c.rest, rest)
+			if c.sql == "" {
+				require.Nil(t, where)
+				return
+			}
+			require.NotNil(t, where)
+			b := d.NewBuilder()
+			where(b)
+			require.Equal(t, c.sql, b.String())
+			require.Equal(t, c.args, b.Args())
+		})
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"reflect"
 	"strconv"
 	"strings"
 
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/tuple"
 	"github.com/hidal-go/hidalgo/values"
 )
@@ -18,6 +20,9 @@ const (
 
 var ErrTableNotFound = tuple.ErrTableNotFound
 
+// ErrUnique should be returned by the dialect when an unique index is violated.
+var ErrUnique = tuple.ErrUnique
+
 func OpenSQL(name, addr, db string) (*sql.DB, error) {
 	r := ByName(name)
 	if r == nil {
@@ -53,6 +58,11 @@ func (s *sqlStore) Close() error {
 	return s.db.Close()
 }
 
+// Capabilities implements base.Capable.
+func (s *sqlStore) Capabilities() base.Capabilities {
+	return base.CapTx | base.CapConcurrentWrites
+}
+
 func (s *sqlStore) curSchema() string {
 	if s := s.dia.DefaultSchema; s != "" {
 		return s
@@ -67,6 +77,8 @@ func (s *sqlStore) convError(err error) error {
 	switch err {
 	case ErrTableNotFound:
 		return tuple.ErrTableNotFound
+	case ErrUnique:
+		return tuple.ErrUnique
 	}
 	return err
 }
@@ -115,7 +127,8 @@ func (s *sqlStore) execb(ctx context.Context, tx *sql.Tx, b *Builder) (sql.Resul
 }
 
 func (s *sqlStore) Tx(ctx context.Context, rw bool) (tuple.Tx, error) {
-	tx, err := s.db.Begin()
+	// transaction is rolled back if the context is canceled before the commit
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -167,12 +180,13 @@ func (s *sqlStore) tableWith(ctx context.Context, tx *sql.Tx, name string) (tupl
 		Null    string         // YES/NO
 		Key     sql.NullString // PRI*
 		Comment sql.NullString
+		Default sql.NullString
 	}
 	var cols []column
 	for rows.Next() {
 		var col column
 		if err := rows.Scan(
-			&col.Name, &col.Type, &col.Null, &col.Key, &col.Comment,
+			&col.Name, &col.Type, &col.Null, &col.Key, &col.Comment, &col.Default,
 		); err != nil {
 			return nil, err
 		}
@@ -199,15 +213,76 @@ func (s *sqlStore) tableWith(ctx context.Context, tx *sql.Tx, name string) (tupl
 				Auto: auto,
 			})
 		} else {
-			header.Data = append(header.Data, tuple.Field{
-				Name: c.Name,
-				Type: typ,
-			})
+			f := tuple.Field{
+				Name:     c.Name,
+				Type:     typ,
+				Nullable: c.Null == "YES",
+			}
+			if c.Default.Valid {
+				f.Default = s.dia.parseDefault(typ, c.Default.String)
+			}
+			header.Data = append(header.Data, f)
 		}
 	}
+	if header.Indexes, err = s.listIndexes(ctx, tx, header); err != nil {
+		return nil, err
+	}
 	return &sqlTableInfo{h: header}, nil
 }
 
+func (s *sqlStore) listIndexes(ctx context.Context, tx *sql.Tx, h tuple.Header) ([]tuple.Index, error) {
+	if s.dia.ListIndexes == "" {
+		return nil, nil
+	}
+	rows, err := s.query(ctx, tx, s.dia.ListIndexes,
+		s.curSchema(),
+		h.Name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		out  []tuple.Index
+		last string
+		skip bool // index covers columns that are not in the payload
+	)
+	for rows.Next() {
+		var (
+			name, col string
+			unique    bool
+		)
+		if err := rows.Scan(&name, &col, &unique); err != nil {
+			return nil, err
+		}
+		if name != last || len(out) == 0 {
+			if skip {
+				out = out[:len(out)-1]
+			}
+			last, skip = name, false
+			out = append(out, tuple.Index{
+				Name: strings.TrimPrefix(name, h.Name+"_"), Unique: unique,
+			})
+		}
+		if f, _ := h.DataByName(col); f == nil {
+			skip = true
+		}
+		ind := &out[len(out)-1]
+		ind.Fields = append(ind.Fields, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if skip {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
 func (s *sqlStore) Table(ctx context.Context, name string) (tuple.TableInfo, error) {
 	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
@@ -261,6 +336,10 @@ type sqlTx struct {
 }
 
 func (tx *sqlTx) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		_ = tx.tx.Rollback()
+		return err
+	}
 	return tx.tx.Commit()
 }
 
@@ -313,14 +392,14 @@ func (tx *sqlTx) CreateTable(ctx context.Context, table tuple.Header) (tuple.Tab
 		if f.Auto {
 			b.Write(tbl.sqlColumnAuto())
 		} else {
-			b.Write(tbl.sqlColumnDef(f.Type, true))
+			b.Write(tbl.sqlKeyDef(f.Type))
 		}
 	}
 	for _, f := range table.Data {
 		b.Write(",\n\t")
 		b.Idents(f.Name)
 		b.Write(" ")
-		b.Write(tbl.sqlColumnDef(f.Type, false))
+		b.Write(tbl.sqlFieldDef(f, tbl.indexed(f.Name)))
 	}
 	if len(tbl.h.Key) != 0 {
 		b.Write(",\n\t")
@@ -361,9 +440,101 @@ func (tx *sqlTx) CreateTable(ctx context.Context, table tuple.Header) (tuple.Tab
 			}
 		}
 	}
+	for _, ind := range table.Indexes {
+		if err := tbl.createIndex(ctx, ind); err != nil {
+			return nil, err
+		}
+	}
 	return tbl, nil
 }
 
+func (tx *sqlTx) AlterTable(ctx context.Context, name string, changes ...tuple.Alteration) (tuple.Table, error) {
+	if !tx.rw {
+		return nil, tuple.ErrReadOnly
+	}
+	info, err := tx.db.tableWith(ctx, tx.tx, name)
+	if err != nil {
+		return nil, err
+	}
+	tbl := &sqlTable{tx: tx, h: info.Header()}
+	// check all changes before altering the table
+	if _, _, err = tbl.h.Alter(changes...); err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		if err = tbl.alter(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+	return tbl, nil
+}
+
+// alter applies a single schema change to the table.
+func (tbl *sqlTable) alter(ctx context.Context, c tuple.Alteration) error {
+	h, _, err := tbl.h.Alter(c)
+	if err != nil {
+		return err
+	}
+	dia := tbl.tx.dia
+	b := tbl.sql()
+	switch c := c.(type) {
+	case tuple.AddField:
+		b.Write("ALTER TABLE ")
+		b.Idents(tbl.h.Name)
+		b.Write(" ADD COLUMN ")
+		b.Idents(c.Field.Name)
+		// existing rows get the default value, or null
+		b.Write(" " + tbl.sqlFieldDef(c.Field, false))
+		if cm := dia.sqlColumnComment(c.Field.Type); cm != "" && dia.ColumnCommentInline == nil && dia.ColumnCommentSet != nil {
+			if _, err = tbl.tx.db.execb(ctx, tbl.tx.tx, b); err != nil {
+				return err
+			}
+			b = tbl.sql()
+			dia.ColumnCommentSet(b, tbl.h.Name, c.Field.Name, cm)
+		}
+	case tuple.DropField:
+		b.Write("ALTER TABLE ")
+		b.Idents(tbl.h.Name)
+		b.Write(" DROP COLUMN ")
+		b.Idents(c.Name)
+	case tuple.RenameField:
+		if dia.RenameColumn != nil {
+			f, _ := tbl.h.DataByName(c.Name)
+			dia.RenameColumn(b, tbl.h.Name, c.Name, c.NewName, tbl.sqlFieldDef(*f, tbl.indexed(c.Name)))
+			break
+		}
+		b.Write("ALTER TABLE ")
+		b.Idents(tbl.h.Name)
+		b.Write(" RENAME COLUMN ")
+		b.Idents(c.Name)
+		b.Write(" TO ")
+		b.Idents(c.NewName)
+	case tuple.SetDefault:
+		f, _ := h.DataByName(c.Name)
+		if dia.ModifyColumnType != nil {
+			// column type may change as well, see sqlFieldDef
+			dia.ModifyColumnType(b, tbl.h.Name, c.Name, tbl.sqlFieldDef(*f, tbl.indexed(c.Name)))
+			break
+		}
+		b.Write("ALTER TABLE ")
+		b.Idents(tbl.h.Name)
+		b.Write(" ALTER COLUMN ")
+		b.Idents(c.Name)
+		if f.Default == nil {
+			b.Write(" DROP DEFAULT")
+		} else {
+			b.Write(" SET DEFAULT " + dia.sqlLiteral(f.Default))
+		}
+	default:
+		return fmt.Errorf("unsupported alteration: %T", c)
+	}
+	if _, err = tbl.tx.db.execb(ctx, tbl.tx.tx, b); err != nil {
+		return err
+	}
+	tbl.h = h
+	return nil
+}
+
 type sqlTable struct {
 	tx *sqlTx
 	h  tuple.Header
@@ -377,18 +548,25 @@ func (tbl *sqlTable) Open(ctx context.Context, tx tuple.Tx) (tuple.Table, error)
 	return (&sqlTableInfo{h: tbl.h}).Open(ctx, tx)
 }
 
-func (tbl *sqlTable) sqlType(t values.Type, key bool) string {
-	return tbl.tx.dia.sqlType(t, key)
+func (tbl *sqlTable) sqlKeyDef(t values.Type) string {
+	return tbl.tx.dia.sqlType(t, true, false) + tbl.tx.dia.sqlColumnCommentInline(t)
 }
 
-func (tbl *sqlTable) sqlColumnDef(t values.Type, key bool) string {
-	return tbl.sqlType(t, key) + tbl.tx.dia.sqlColumnCommentInline(t)
+// sqlFieldDef returns a column definition for a payload field.
+// Columns with default values use key types, since some databases cannot set defaults for large types.
+func (tbl *sqlTable) sqlFieldDef(f tuple.Field, indexed bool) string {
+	dia := tbl.tx.dia
+	def := dia.sqlType(f.Type, indexed || f.Default != nil, f.Nullable)
+	if f.Default != nil {
+		def += " DEFAULT " + dia.sqlLiteral(f.Default)
+	}
+	return def + dia.sqlColumnCommentInline(f.Type)
 }
 
 func (tbl *sqlTable) sqlColumnAuto() string {
 	c := tbl.tx.dia.AutoType
 	if c == "" {
-		c = tbl.sqlType(values.UIntType{}, true) + " AUTO_INCREMENT"
+		c = tbl.tx.dia.sqlType(values.UIntType{}, true, false) + " AUTO_INCREMENT"
 	}
 	return c + tbl.tx.dia.sqlColumnCommentAutoInline()
 }
@@ -404,6 +582,116 @@ func (tbl *sqlTable) Drop(ctx context.Context) error {
 	return err
 }
 
+// indexed checks if a payload field is covered by any secondary index.
+func (tbl *sqlTable) indexed(name string) bool {
+	for _, ind := range tbl.h.Indexes {
+		for _, f := range ind.Fields {
+			if f == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasUnique checks if the table has at least one unique index.
+func (tbl *sqlTable) hasUnique() bool {
+	for _, ind := range tbl.h.Indexes {
+		if ind.Unique {
+			return true
+		}
+	}
+	return false
+}
+
+// indexName returns a name of the index in the database. Table name is added to avoid collisions.
+func (tbl *sqlTable) indexName(name string) string {
+	return tbl.h.Name + "_" + name
+}
+
+func (tbl *sqlTable) createIndex(ctx context.Context, ind tuple.Index) error {
+	b := tbl.sql()
+	b.Write("CREATE ")
+	if ind.Unique {
+		b.Write("UNIQUE ")
+	}
+	b.Write("INDEX ")
+	b.Idents(tbl.indexName(ind.Name))
+	b.Write(" ON ")
+	b.Idents(tbl.h.Name)
+	b.Write(" (")
+	b.Idents(ind.Fields...)
+	b.Write(")")
+	_, err := tbl.tx.db.execb(ctx, tbl.tx.tx, b)
+	return err
+}
+
+// CreateIndex implements tuple.Indexer.
+func (tbl *sqlTable) CreateIndex(ctx context.Context, ind tuple.Index) error {
+	if !tbl.tx.rw {
+		return tuple.ErrReadOnly
+	}
+	h := tbl.h.Clone()
+	h.Indexes = append(h.Indexes, ind)
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	if dia := tbl.tx.dia; dia.ModifyColumnType != nil {
+		// payload columns may use types that cannot be indexed
+		for _, name := range ind.Fields {
+			if tbl.indexed(name) {
+				continue
+			}
+			f, _ := tbl.h.DataByName(name)
+			typ := tbl.sqlFieldDef(*f, true)
+			if typ == tbl.sqlFieldDef(*f, false) {
+				continue
+			}
+			b := tbl.sql()
+			dia.ModifyColumnType(b, tbl.h.Name, name, typ)
+			if _, err := tbl.tx.db.execb(ctx, tbl.tx.tx, b); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tbl.createIndex(ctx, ind); err != nil {
+		return err
+	}
+	tbl.h = h
+	return nil
+}
+
+// DropIndex implements tuple.Indexer.
+func (tbl *sqlTable) DropIndex(ctx context.Context, name string) error {
+	if !tbl.tx.rw {
+		return tuple.ErrReadOnly
+	} else if tbl.h.IndexByName(name) == nil {
+		return tuple.ErrIndexNotFound
+	}
+	b := tbl.sql()
+	b.Write("DROP INDEX ")
+	b.Idents(tbl.indexName(name))
+	if tbl.tx.dia.DropIndexOnTable {
+		b.Write(" ON ")
+		b.Idents(tbl.h.Name)
+	}
+	if _, err := tbl.tx.db.execb(ctx, tbl.tx.tx, b); err != nil {
+		return err
+	}
+	h := tbl.h.Clone()
+	h.Indexes = h.Indexes[:0]
+	for _, ind := range tbl.h.Indexes {
+		if ind.Name != name {
+			h.Indexes = append(h.Indexes, ind)
+		}
+	}
+	if len(h.Indexes) == 0 {
+		h.Indexes = nil
+	}
+	tbl.h = h
+	return nil
+}
+
 func (tbl *sqlTable) Clear(ctx context.Context) error {
 	if !tbl.tx.rw {
 		return tuple.ErrReadOnly
@@ -415,23 +703,32 @@ func (tbl *sqlTable) Clear(ctx context.Context) error {
 	return err
 }
 
-func (tbl *sqlTable) convValue(v values.Value) interface{} {
+// convValue converts a value to an SQL query argument.
+func convValue(v values.Value) interface{} {
 	if v == nil {
 		return nil
+	} else if b, ok := v.(values.Bytes); ok && b == nil {
+		// nil slice is stored as null otherwise
+		return []byte{}
 	}
 	return v.Native()
 }
 
 func (tbl *sqlTable) appendKey(dst []interface{}, key tuple.Key) []interface{} {
 	for _, k := range key {
-		dst = append(dst, tbl.convValue(k))
+		dst = append(dst, convValue(k))
 	}
 	return dst
 }
 
+// appendData appends payload values. Null values of fields with defaults are replaced with DEFAULT.
 func (tbl *sqlTable) appendData(dst []interface{}, data tuple.Data) []interface{} {
-	for _, d := range data {
-		dst = append(dst, tbl.convValue(d))
+	for i, d := range data {
+		if d == nil && tbl.h.Data[i].Default != nil {
+			dst = append(dst, defaultValue{})
+			continue
+		}
+		dst = append(dst, convValue(d))
 	}
 	return dst
 }
@@ -461,6 +758,27 @@ func (tbl *sqlTable) keyNames() []string {
 	return names
 }
 
+// insertNames returns columns that are set by INSERT statements. Auto keys are generated by the database.
+func (tbl *sqlTable) insertNames(auto bool) []string {
+	if !auto {
+		return tbl.names()
+	} else if len(tbl.h.Data) == 0 {
+		// empty column list is not portable, thus the key is set to its default explicitly
+		return []string{tbl.h.Key[0].Name}
+	}
+	return tbl.payloadNames()
+}
+
+// appendInsert appends arguments of INSERT statement for the tuple. See insertNames.
+func (tbl *sqlTable) appendInsert(dst []interface{}, t tuple.Tuple, auto bool) []interface{} {
+	if !auto {
+		return tbl.appendTuple(dst, t)
+	} else if len(tbl.h.Data) == 0 {
+		return append(dst, defaultValue{})
+	}
+	return tbl.appendData(dst, t.Data)
+}
+
 func (tbl *sqlTable) payloadNames() []string {
 	names := make([]string, 0, len(tbl.h.Data))
 	for _, f := range tbl.h.Data {
@@ -469,13 +787,62 @@ func (tbl *sqlTable) payloadNames() []string {
 	return names
 }
 
+// payloadFields returns payload fields selected by the projection. See tuple.Header.Projection.
+func (tbl *sqlTable) payloadFields(proj []int) []tuple.Field {
+	if proj == nil {
+		return tbl.h.Data
+	}
+	out := make([]tuple.Field, 0, len(proj))
+	for _, i := range proj {
+		out = append(out, tbl.h.Data[i])
+	}
+	return out
+}
+
+func (tbl *sqlTable) projectedNames(proj []int) []string {
+	fields := tbl.payloadFields(proj)
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
 type scanner interface {
 	Scan(dst ...interface{}) error
 }
 
-func (tbl *sqlTable) scanTuple(row scanner) (tuple.Tuple, error) {
+// nullableDest allows scanning nulls to a value destination.
+type nullableDest struct {
+	dst values.ValueDest
+	ptr reflect.Value // pointer to a pointer to the native value
+}
+
+func newNullableDest(t values.Type) *nullableDest {
+	dst := t.New()
+	return &nullableDest{dst: dst, ptr: reflect.New(reflect.TypeOf(dst.NativePtr()))}
+}
+
+// NativePtr returns a destination for Scan.
+func (d *nullableDest) NativePtr() interface{} {
+	return d.ptr.Interface()
+}
+
+// Value returns a scanned value, or nil if it was null.
+func (d *nullableDest) Value() values.Value {
+	p := d.ptr.Elem()
+	if p.IsNil() {
+		return nil
+	}
+	reflect.ValueOf(d.dst.NativePtr()).Elem().Set(p.Elem())
+	return d.dst.Value()
+}
+
+// scanTuple scans a key and payload fields selected by the projection.
+func (tbl *sqlTable) scanTuple(row scanner, proj []int) (tuple.Tuple, error) {
+	fields := tbl.payloadFields(proj)
 	key := make([]values.SortableDest, 0, len(tbl.h.Key))
-	data := make([]values.ValueDest, 0, len(tbl.h.Data))
+	data := make([]*nullableDest, 0, len(fields))
 	in := make([]interface{}, 0, cap(key)+cap(data))
 
 	for _, f := range tbl.h.Key {
@@ -483,8 +850,8 @@ func (tbl *sqlTable) scanTuple(row scanner) (tuple.Tuple, error) {
 		key = append(key, v)
 		in = append(in, v.NativePtr())
 	}
-	for _, f := range tbl.h.Data {
-		v := f.Type.New()
+	for _, f := range fields {
+		v := newNullableDest(f.Type)
 		data = append(data, v)
 		in = append(in, v.NativePtr())
 	}
@@ -531,12 +898,14 @@ func (tbl *sqlTable) scanKey(row scanner) (tuple.Key, error) {
 	return key, nil
 }
 
-func (tbl *sqlTable) scanPayload(row scanner) (tuple.Data, error) {
-	dest := make([]values.ValueDest, 0, len(tbl.h.Data))
+// scanPayload scans payload fields selected by the projection.
+func (tbl *sqlTable) scanPayload(row scanner, proj []int) (tuple.Data, error) {
+	fields := tbl.payloadFields(proj)
+	dest := make([]*nullableDest, 0, len(fields))
 	in := make([]interface{}, 0, cap(dest))
 
-	for _, f := range tbl.h.Data {
-		v := f.Type.New()
+	for _, f := range fields {
+		v := newNullableDest(f.Type)
 		dest = append(dest, v)
 		in = append(in, v.NativePtr())
 	}
@@ -554,19 +923,32 @@ func (tbl *sqlTable) scanPayload(row scanner) (tuple.Data, error) {
 }
 
 func (tbl *sqlTable) GetTuple(ctx context.Context, key tuple.Key) (tuple.Data, error) {
+	return tbl.getTuple(ctx, key, nil)
+}
+
+// GetTupleFields implements tuple.Projector.
+func (tbl *sqlTable) GetTupleFields(ctx context.Context, key tuple.Key, fields []string) (tuple.Data, error) {
+	proj, err := tbl.h.Projection(fields)
+	if err != nil {
+		return nil, err
+	}
+	return tbl.getTuple(ctx, key, proj)
+}
+
+func (tbl *sqlTable) getTuple(ctx context.Context, key tuple.Key, proj []int) (tuple.Data, error) {
 	if err := tbl.h.ValidateKey(key, false); err != nil {
 		return nil, err
 	}
 	b := tbl.sql()
 	b.Write("SELECT ")
-	b.Idents(tbl.payloadNames()...)
+	b.Idents(tbl.projectedNames(proj)...)
 	b.Write(" FROM ")
 	b.Idents(tbl.h.Name)
 	b.Write(" WHERE ")
 	b.EqPlaceAnd(tbl.keyNames(), tbl.appendKey(nil, key))
 	b.Write(" LIMIT 1")
 	row := tbl.tx.db.queryRow(ctx, tbl.tx.tx, b.String(), b.Args()...)
-	data, err := tbl.scanPayload(row)
+	data, err := tbl.scanPayload(row, proj)
 	if err == sql.ErrNoRows {
 		return nil, tuple.ErrNotFound
 	} else if err != nil {
@@ -576,10 +958,23 @@ func (tbl *sqlTable) GetTuple(ctx context.Context, key tuple.Key) (tuple.Data, e
 }
 
 func (tbl *sqlTable) GetTupleBatch(ctx context.Context, keys []tuple.Key) ([]tuple.Data, error) {
+	return tbl.getTupleBatch(ctx, keys, nil)
+}
+
+// GetTupleBatchFields implements tuple.Projector.
+func (tbl *sqlTable) GetTupleBatchFields(ctx context.Context, keys []tuple.Key, fields []string) ([]tuple.Data, error) {
+	proj, err := tbl.h.Projection(fields)
+	if err != nil {
+		return nil, err
+	}
+	return tbl.getTupleBatch(ctx, keys, proj)
+}
+
+func (tbl *sqlTable) getTupleBatch(ctx context.Context, keys []tuple.Key, proj []int) ([]tuple.Data, error) {
 	out := make([]tuple.Data, 0, len(keys))
 	// TODO: batch select
 	for _, k := range keys {
-		d, err := tbl.GetTuple(ctx, k)
+		d, err := tbl.getTuple(ctx, k, proj)
 		if err != nil {
 			return out, err
 		}
@@ -602,17 +997,9 @@ func (tbl *sqlTable) InsertTuple(ctx context.Context, t tuple.Tuple) (tuple.Key,
 	b.Write("INSERT INTO ")
 	b.Idents(tbl.h.Name)
 	b.Write("(")
-	if auto {
-		b.Idents(tbl.payloadNames()...)
-	} else {
-		b.Idents(tbl.names()...)
-	}
+	b.Idents(tbl.insertNames(auto)...)
 	b.Write(") VALUES (")
-	if auto {
-		b.Place(tbl.appendData(nil, t.Data)...)
-	} else {
-		b.Place(tbl.appendTuple(nil, t)...)
-	}
+	b.Place(tbl.appendInsert(nil, t, auto)...)
 	b.Write(")")
 	if auto && tbl.tx.dia.Returning {
 		b.Write(" RETURNING ")
@@ -678,6 +1065,33 @@ func (tbl *sqlTable) UpdateTuple(ctx context.Context, t tuple.Tuple, opt *tuple.
 		_, err := tbl.tx.db.execb(ctx, tbl.tx.tx, b)
 		return err
 	}
+	if dia.ReplaceStmt && tbl.hasUnique() {
+		// REPLACE removes all rows that conflict with unique indexes,
+		// so lock the row and either update or insert it
+		b := tbl.sql()
+		b.Write(`SELECT 1 FROM `)
+		b.Idents(tbl.h.Name)
+		b.Write(` WHERE `)
+		b.EqPlaceAnd(tbl.keyNames(), tbl.appendKey(nil, t.Key))
+		b.Write(` FOR UPDATE`)
+		var one int
+		err := tbl.tx.db.querybRow(ctx, tbl.tx.tx, b).Scan(&one)
+		if err == nil {
+			return tbl.UpdateTuple(ctx, t, nil)
+		} else if err != sql.ErrNoRows {
+			return tbl.tx.db.convError(err)
+		}
+		b = tbl.sql()
+		b.Write("INSERT INTO ")
+		b.Idents(tbl.h.Name)
+		b.Write("(")
+		b.Idents(tbl.names()...)
+		b.Write(") VALUES (")
+		b.Place(tbl.appendTuple(nil, t)...)
+		b.Write(")")
+		_, err = tbl.tx.db.execb(ctx, tbl.tx.tx, b)
+		return err
+	}
 	if dia.ReplaceStmt {
 		b := tbl.sql()
 		b.Write("REPLACE INTO ")
@@ -693,9 +1107,9 @@ func (tbl *sqlTable) UpdateTuple(ctx context.Context, t tuple.Tuple, opt *tuple.
 	return fmt.Errorf("upsert is not supported")
 }
 
+// asWhere converts the filter to an SQL condition. See Dialect.Where.
 func (tbl *sqlTable) asWhere(f *tuple.Filter) (*tuple.Filter, func(*Builder)) {
-	// FIXME: optimize filters
-	return f, nil
+	return tbl.tx.dia.Where(&tbl.h, f)
 }
 
 func (tbl *sqlTable) DeleteTuples(ctx context.Context, f *tuple.Filter) error {
@@ -765,24 +1179,47 @@ func (tbl *sqlTable) DeleteTuples(ctx context.Context, f *tuple.Filter) error {
 	return it.Err()
 }
 
-func (tbl *sqlTable) scan(open rowsFunc, keysOnly bool, f *tuple.Filter) tuple.Iterator {
+func (tbl *sqlTable) scan(open rowsFunc, keysOnly bool, f *tuple.Filter) *sqlIterator {
 	return &sqlIterator{tbl: tbl, open: open, f: f, keysOnly: keysOnly}
 }
 
 func (tbl *sqlTable) scanWhere(opt *tuple.ScanOptions, where func(*Builder)) tuple.Iterator {
-	return tbl.scan(func(ctx context.Context) (*sql.Rows, error) {
+	proj, err := tbl.h.Projection(opt.Fields)
+	if err != nil {
+		return &sqlIterator{err: err}
+	}
+	// payload filters are applied to the full payload, thus all fields must be selected
+	var sel []int
+	if opt.Filter.IsAnyData() {
+		sel, proj = proj, nil
+	}
+	// remaining payload filters need the payload, even if only keys are requested
+	keysOnly := opt.KeysOnly && opt.Filter.IsAnyData()
+	dia := tbl.tx.dia
+	it := tbl.scan(func(ctx context.Context) (*sql.Rows, error) {
 		b := tbl.sql()
 		b.Write(`SELECT `)
-		if opt.KeysOnly {
-			b.Idents(tbl.keyNames()...)
-		} else {
-			b.Idents(tbl.names()...)
+		b.Idents(tbl.keyNames()...)
+		if names := tbl.projectedNames(sel); !keysOnly && len(names) != 0 {
+			b.Write(", ")
+			b.Idents(names...)
 		}
 		b.Write(` FROM `)
 		b.Idents(tbl.h.Name)
+		sep := " WHERE "
 		if where != nil {
-			b.Write(" WHERE ")
+			b.Write(sep)
 			where(b)
+			sep = " AND "
+		}
+		if opt.After != nil {
+			b.Write(sep)
+			dia.keyBound(b, &tbl.h, ">", opt.After)
+			sep = " AND "
+		}
+		if opt.Before != nil {
+			b.Write(sep)
+			dia.keyBound(b, &tbl.h, "<", opt.Before)
 		}
 		dir := ""
 		switch opt.Sort {
@@ -793,24 +1230,46 @@ func (tbl *sqlTable) scanWhere(opt *tuple.ScanOptions, where func(*Builder)) tup
 		}
 		if dir != "" {
 			b.Write(" ORDER BY ")
-			b.Idents(tbl.keyNames()...)
-			b.Write(" " + dir)
+			for i, f := range tbl.h.Key {
+				if i != 0 {
+					b.Write(", ")
+				}
+				dia.column(b, f.Name, f.Type)
+				b.Write(" " + dir)
+			}
 		}
-		if opt.Limit > 0 {
+		if opt.Limit > 0 && opt.Filter.IsAny() {
 			b.Write(" LIMIT ")
 			b.Write(strconv.Itoa(opt.Limit))
+			if opt.Offset > 0 {
+				b.Write(" OFFSET ")
+				b.Write(strconv.Itoa(opt.Offset))
+			}
 		}
 		return tbl.tx.db.queryb(ctx, tbl.tx.tx, b)
-	}, opt.KeysOnly, opt.Filter)
+	}, keysOnly, opt.Filter)
+	it.sel, it.proj = sel, proj
+	if !opt.Filter.IsAny() {
+		// remaining filters are applied to fetched rows, so the offset and the limit cannot be applied by the query
+		return tuple.LimitIterator(tuple.OffsetIterator(it, opt.Offset), opt.Limit)
+	} else if opt.Limit <= 0 {
+		// some databases do not support OFFSET without LIMIT
+		return tuple.OffsetIterator(it, opt.Offset)
+	}
+	return it
 }
 
 func (tbl *sqlTable) Scan(ctx context.Context, opt *tuple.ScanOptions) tuple.Iterator {
 	if opt == nil {
 		opt = &tuple.ScanOptions{}
 	}
-	f, where := tbl.asWhere(opt.Filter)
-	opt.Filter = f
-	return tbl.scanWhere(opt, where)
+	if err := opt.Validate(tbl.h); err != nil {
+		return &sqlIterator{err: err}
+	}
+	o := *opt
+	f, where := tbl.asWhere(o.Filter)
+	o.Filter = f
+	return tbl.scanWhere(&o, where)
 }
 
 type rowsFunc func(ctx context.Context) (*sql.Rows, error)
@@ -822,6 +1281,8 @@ type sqlIterator struct {
 	open     rowsFunc
 	keysOnly bool
 	f        *tuple.Filter
+	sel      []int // selected payload fields; all fields if nil
+	proj     []int // projection applied after filtering; see tuple.Header.Projection
 
 	t   *tuple.Tuple
 	err error
@@ -839,9 +1300,13 @@ func (it *sqlIterator) Reset() {
 func (it *sqlIterator) Next(ctx context.Context) bool {
 	if it.err != nil {
 		return false
+	} else if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
 	}
 	if it.rows == nil {
-		// TODO: this context might be captured by the iterator
+		// rows are closed when the context of the first call is canceled,
+		// contexts of the following calls are checked above
 		rows, err := it.open(ctx)
 		if err != nil {
 			it.err = err
@@ -849,7 +1314,7 @@ func (it *sqlIterator) Next(ctx context.Context) bool {
 		}
 		it.rows = rows
 	}
-	return tuple.FilterIterator(it, it.f, func() bool {
+	return tuple.FilterIterator(fullTuple{it}, it.f, func() bool {
 		it.t = nil
 		return it.rows.Next()
 	})
@@ -884,7 +1349,7 @@ func (it *sqlIterator) scan() {
 			t.Key = key
 		}
 	} else {
-		t, err = it.tbl.scanTuple(it.rows)
+		t, err = it.tbl.scanTuple(it.rows, it.sel)
 	}
 	if err != nil {
 		// TODO: user might skip this error
@@ -903,9 +1368,26 @@ func (it *sqlIterator) Key() tuple.Key {
 }
 
 func (it *sqlIterator) Data() tuple.Data {
+	return it.data().Project(it.proj)
+}
+
+func (it *sqlIterator) data() tuple.Data {
 	it.scan()
 	if it.t == nil {
 		return nil
 	}
 	return it.t.Data
 }
+
+// fullTuple exposes all selected payload fields of the current tuple, regardless of the projection.
+type fullTuple struct {
+	it *sqlIterator
+}
+
+func (t fullTuple) Key() tuple.Key {
+	return t.it.Key()
+}
+
+func (t fullTuple) Data() tuple.Data {
+	return t.it.data()
+}
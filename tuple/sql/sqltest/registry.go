@@ -76,9 +76,9 @@ func runT(t *testing.T, test func(t *testing.T, name string, run Database), name
 	}
 }
 
-func runB(b *testing.B, bench func(b *testing.B, run Database), name string) {
+func runB(b *testing.B, bench func(b *testing.B, name string, run Database), name string) {
 	for _, v := range ByName(name).Versions {
-		b.Run(v.Name, func(bb *testing.B) { bench(bb, v.Factory) })
+		b.Run(v.Name, func(bb *testing.B) { bench(bb, name, v.Factory) })
 	}
 }
 
@@ -96,7 +96,7 @@ func RunTest(t *testing.T, test func(t *testing.T, name string, run Database), n
 	}
 }
 
-func RunBenchmark(b *testing.B, bench func(b *testing.B, run Database), names ...string) {
+func RunBenchmark(b *testing.B, bench func(b *testing.B, name string, run Database), names ...string) {
 	for _, name := range names {
 		if _, ok := registry[name]; !ok {
 			panic("not registered: " + name)
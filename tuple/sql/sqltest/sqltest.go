@@ -19,12 +19,22 @@ type Database struct {
 }
 
 func TestSQL(t *testing.T, name string, gen Database) {
+	tupletest.RunTest(t, storeFunc(t, name, gen), nil)
+}
+
+func BenchmarkSQL(b *testing.B, name string, gen Database) {
+	tupletest.RunBenchmarks(b, storeFunc(b, name, gen), nil)
+}
+
+// storeFunc returns a constructor that creates a new database for each test.
+// The server is started once, unless the database must be recreated.
+func storeFunc(t testing.TB, name string, gen Database) tupletest.Func {
 	var addr string
 	recreate := gen.Recreate
 	if !recreate {
 		addr = gen.Run(t)
 	}
-	tupletest.RunTest(t, func(t testing.TB) tuple.Store {
+	return func(t testing.TB) tuple.Store {
 		db := fmt.Sprintf("db_%x", rand.Int())
 		addr := addr
 		if recreate {
@@ -57,11 +67,7 @@ func TestSQL(t *testing.T, name string, gen Database) {
 			}
 		})
 		return sqltuple.New(conn, db, sqltuple.ByName(name).Dialect)
-	}, nil)
-}
-
-func BenchmarkSQL(t *testing.B, gen Database) {
-	// TODO
+	}
 }
 
 func init() {
@@ -0,0 +1,273 @@
+package sqltuple
+
+import (
+	"github.com/hidal-go/hidalgo/filter"
+	"github.com/hidal-go/hidalgo/tuple"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+// Where converts the filter to an SQL condition for the table. It returns a part of the filter
+// that cannot be converted and must be applied to fetched tuples, and a function that writes
+// the condition. The function is nil if no filters were converted.
+//
+// Conditions follow semantics of value filters for null values, thus NOT can be applied safely.
+func (d *Dialect) Where(h *tuple.Header, f *tuple.Filter) (*tuple.Filter, func(*Builder)) {
+	if f.IsAny() {
+		return f, nil
+	}
+	var conds []func(*Builder)
+	rest := *f
+	if kf, ok := f.KeyFilter.(tuple.KeyFilters); ok && len(kf) <= len(h.Key) {
+		left := make(tuple.KeyFilters, len(kf))
+		all := true
+		for i, vf := range kf {
+			if _, ok := vf.(filter.Any); ok {
+				// keys are never null, so Any is the same as no filter
+				left[i] = vf
+				continue
+			}
+			c := d.valueCond(h.Key[i].Name, h.Key[i].Type, false, vf)
+			if c == nil {
+				left[i] = vf
+				all = false
+				continue
+			}
+			conds = append(conds, c)
+			left[i] = filter.Any{}
+		}
+		rest.KeyFilter = left
+		if all {
+			rest.KeyFilter = nil
+		}
+	}
+	if df, ok := f.DataFilter.(tuple.DataFilters); ok && len(df) == len(h.Data) {
+		left := make(tuple.DataFilters, len(df))
+		all := true
+		for i, vf := range df {
+			if vf == nil {
+				continue
+			}
+			fld := h.Data[i]
+			c := d.valueCond(fld.Name, fld.Type, true, vf)
+			if c == nil {
+				left[i] = vf
+				all = false
+				continue
+			}
+			conds = append(conds, c)
+		}
+		rest.DataFilter = left
+		if all {
+			rest.DataFilter = nil
+		}
+	}
+	if len(conds) == 0 {
+		return f, nil
+	}
+	return &rest, func(b *Builder) {
+		for i, c := range conds {
+			if i != 0 {
+				b.Write(" AND ")
+			}
+			c(b)
+		}
+	}
+}
+
+// valueCond converts a value filter for a column to an SQL condition.
+// It returns nil if the filter cannot be converted.
+func (d *Dialect) valueCond(col string, typ values.Type, null bool, f filter.ValueFilter) func(*Builder) {
+	switch f := f.(type) {
+	case filter.Any:
+		if !null {
+			return func(b *Builder) { b.Write("1 = 1") }
+		}
+		return func(b *Builder) {
+			b.Idents(col)
+			b.Write(" IS NOT NULL")
+		}
+	case filter.Equal:
+		if f.Value == nil {
+			if !null {
+				return func(b *Builder) { b.Write("1 = 0") }
+			}
+			return func(b *Builder) {
+				b.Idents(col)
+				b.Write(" IS NULL")
+			}
+		} else if f.Value.Type() != typ {
+			return nil
+		}
+		return d.compareCond(col, typ, null, false, "=", f.Value)
+	case *filter.Less:
+		if f == nil {
+			return nil
+		}
+		return d.valueCond(col, typ, null, *f)
+	case filter.Less:
+		if !sortable(typ, f.Value) {
+			return nil
+		}
+		op := "<"
+		if f.Equal {
+			op = "<="
+		}
+		// value filters accept nulls for comparisons
+		return d.compareCond(col, typ, null, true, op, f.Value)
+	case *filter.Greater:
+		if f == nil {
+			return nil
+		}
+		return d.valueCond(col, typ, null, *f)
+	case filter.Greater:
+		if !sortable(typ, f.Value) {
+			return nil
+		}
+		op := ">"
+		if f.Equal {
+			op = ">="
+		}
+		return d.compareCond(col, typ, null, true, op, f.Value)
+	case *filter.Range:
+		if f == nil {
+			return nil
+		}
+		return d.valueCond(col, typ, null, *f)
+	case filter.Range:
+		var conds []func(*Builder)
+		if f.Start != nil {
+			op := ">"
+			if f.Start.Equal {
+				op = ">="
+			}
+			if !sortable(typ, f.Start.Value) {
+				return nil
+			}
+			conds = append(conds, d.compareCond(col, typ, false, false, op, f.Start.Value))
+		}
+		if f.End != nil {
+			op := "<"
+			if f.End.Equal {
+				op = "<="
+			}
+			if !sortable(typ, f.End.Value) {
+				return nil
+			}
+			conds = append(conds, d.compareCond(col, typ, false, false, op, f.End.Value))
+		}
+		if len(conds) == 0 {
+			return d.valueCond(col, typ, null, filter.Any{})
+		}
+		return func(b *Builder) {
+			b.Write("(")
+			if null {
+				// range accepts nulls only if it has a lower bound
+				b.Idents(col)
+				if f.Start != nil {
+					b.Write(" IS NULL OR ")
+				} else {
+					b.Write(" IS NOT NULL AND ")
+				}
+			}
+			for i, c := range conds {
+				if i != 0 {
+					b.Write(" AND ")
+				}
+				c(b)
+			}
+			b.Write(")")
+		}
+	case filter.And:
+		return d.joinConds(col, typ, null, " AND ", f)
+	case filter.Or:
+		return d.joinConds(col, typ, null, " OR ", f)
+	case filter.Not:
+		c := d.valueCond(col, typ, null, f.Filter)
+		if c == nil {
+			return nil
+		}
+		return func(b *Builder) {
+			b.Write("NOT ")
+			c(b)
+		}
+	}
+	return nil
+}
+
+// joinConds converts a list of value filters and joins conditions with a given operator.
+func (d *Dialect) joinConds(col string, typ values.Type, null bool, op string, arr []filter.ValueFilter) func(*Builder) {
+	if len(arr) == 0 {
+		return nil
+	}
+	conds := make([]func(*Builder), 0, len(arr))
+	for _, f := range arr {
+		c := d.valueCond(col, typ, null, f)
+		if c == nil {
+			return nil
+		}
+		conds = append(conds, c)
+	}
+	return func(b *Builder) {
+		b.Write("(")
+		for i, c := range conds {
+			if i != 0 {
+				b.Write(op)
+			}
+			c(b)
+		}
+		b.Write(")")
+	}
+}
+
+// sortable checks if a value can be compared with a column in SQL the same way as values.Compare does.
+func sortable(typ values.Type, v values.Sortable) bool {
+	return v != nil && v.Type() == typ
+}
+
+// compareCond writes a comparison of the column with a value. For nullable columns
+// nulls either match or not, depending on the flag, so the condition is never unknown.
+func (d *Dialect) compareCond(col string, typ values.Type, null, matchNull bool, op string, v values.Value) func(*Builder) {
+	return func(b *Builder) {
+		if null {
+			b.Write("(")
+			b.Idents(col)
+			if matchNull {
+				b.Write(" IS NULL OR ")
+			} else {
+				b.Write(" IS NOT NULL AND ")
+			}
+		}
+		d.column(b, col, typ)
+		b.Write(" " + op + " ")
+		b.Place(convValue(v))
+		if null {
+			b.Write(")")
+		}
+	}
+}
+
+// column writes a column name. Strings are compared with StringCollation, if it is set.
+func (d *Dialect) column(b *Builder, col string, typ values.Type) {
+	b.Idents(col)
+	if _, ok := typ.(values.StringType); ok && d.StringCollation != "" {
+		b.Write(" COLLATE " + d.StringCollation)
+	}
+}
+
+// keyBound writes a comparison of the primary key with a full key, for example: (k1, k2) > (?, ?).
+func (d *Dialect) keyBound(b *Builder, h *tuple.Header, op string, key tuple.Key) {
+	b.Write("(")
+	for i, f := range h.Key {
+		if i != 0 {
+			b.Write(", ")
+		}
+		d.column(b, f.Name, f.Type)
+	}
+	b.Write(") " + op + " (")
+	args := make([]interface{}, 0, len(key))
+	for _, v := range key {
+		args = append(args, convValue(v))
+	}
+	b.Place(args...)
+	b.Write(")")
+}
@@ -3,6 +3,8 @@ package tuple
 import (
 	"context"
 	"errors"
+
+	"github.com/hidal-go/hidalgo/values"
 )
 
 // ErrWildGuess returned if the the size can only be randomly guessed by the backend without scanning the data.
@@ -13,10 +15,22 @@ var ErrWildGuess = errors.New("can only guess the size")
 // If estimate cannot be obtained without scanning the whole table, ErrWildGuess will be returned
 // with some random number.
 func TableSize(ctx context.Context, t Table, f *Filter, exact bool) (int64, error) {
-	// TODO: optimize for backends that can provide this functionality
 	if !exact {
 		return 1000, ErrWildGuess
 	}
+	if a, ok := t.(Aggregator); ok {
+		groups, err := a.Aggregate(ctx, &AggregateOptions{
+			Filter:     f,
+			Aggregates: []Aggregate{{Func: Count}},
+		})
+		if err != nil {
+			return 0, err
+		} else if len(groups) == 0 {
+			return 0, nil
+		}
+		n, _ := groups[0].Values[0].(values.Int)
+		return int64(n), nil
+	}
 	it := t.Scan(ctx, &ScanOptions{
 		KeysOnly: true,
 		Filter:   f,
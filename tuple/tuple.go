@@ -16,6 +16,8 @@ var (
 	ErrTableExists   = errors.New("tuple: table already exists")
 	ErrExists        = errors.New("tuple: this key already exists")
 	ErrReadOnly      = errors.New("tuple: read-only database")
+	ErrIndexNotFound = errors.New("tuple: index not found")
+	ErrUnique        = errors.New("tuple: unique index violation")
 )
 
 // Type is any value type that can be stored in tuple.
@@ -32,8 +34,10 @@ type Sortable = values.Sortable
 
 // Field is a single field used in tuple payload.
 type Field struct {
-	Name string // field name
-	Type Type   // field type
+	Name     string // field name
+	Type     Type   // field type
+	Nullable bool   // field accepts null values
+	Default  Value  // value used instead of null; optional
 }
 
 // KeyField is a single primary key field used in tuple.
@@ -43,11 +47,19 @@ type KeyField struct {
 	Auto bool    // autoincrement
 }
 
+// Index is a secondary index over payload fields.
+type Index struct {
+	Name   string   // index name
+	Fields []string // names of indexed payload fields
+	Unique bool     // reject tuples with the same values of indexed fields
+}
+
 // Header describes a schema of tuples table.
 type Header struct {
-	Name string     // name of the table
-	Key  []KeyField // primary key fields
-	Data []Field    // payload fields
+	Name    string     // name of the table
+	Key     []KeyField // primary key fields
+	Data    []Field    // payload fields
+	Indexes []Index    // secondary indexes
 }
 
 // KeyByName finds a key field by a name, or returns nil if it not exists.
@@ -72,10 +84,45 @@ func (t Header) DataByName(name string) (*Field, int) {
 	return nil, -1
 }
 
+// IndexByName finds an index by a name, or returns nil if it not exists.
+func (t Header) IndexByName(name string) *Index {
+	for i, ind := range t.Indexes {
+		if ind.Name == name {
+			return &t.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// Projection returns indexes of payload fields with given names.
+// It returns nil if no fields are specified, which indicates that all fields should be returned.
+func (t Header) Projection(fields []string) ([]int, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	out := make([]int, 0, len(fields))
+	for _, name := range fields {
+		f, i := t.DataByName(name)
+		if f == nil {
+			return nil, fmt.Errorf("no such payload field: %q", name)
+		}
+		out = append(out, i)
+	}
+	return out, nil
+}
+
 // Clone makes a copy of the header.
 func (t Header) Clone() Header {
 	t.Key = append([]KeyField{}, t.Key...)
 	t.Data = append([]Field{}, t.Data...)
+	if t.Indexes != nil {
+		inds := make([]Index, 0, len(t.Indexes))
+		for _, ind := range t.Indexes {
+			ind.Fields = append([]string{}, ind.Fields...)
+			inds = append(inds, ind)
+		}
+		t.Indexes = inds
+	}
 	return t
 }
 
@@ -112,12 +159,47 @@ func (t Header) Validate() error {
 			return fmt.Errorf("field name should not be empty")
 		} else if f.Type == nil {
 			return fmt.Errorf("value type should be specified")
+		} else if f.Default != nil && f.Default.Type() != f.Type {
+			return fmt.Errorf("field %q: expected %T default, got %T", f.Name, f.Type, f.Default.Type())
 		}
 		if _, ok := names[f.Name]; ok {
 			return fmt.Errorf("duplicate field name: %q", f.Name)
 		}
 		names[f.Name] = struct{}{}
 	}
+	inds := make(map[string]struct{})
+	for _, ind := range t.Indexes {
+		if err := t.ValidateIndex(ind); err != nil {
+			return err
+		}
+		if _, ok := inds[ind.Name]; ok {
+			return fmt.Errorf("duplicate index name: %q", ind.Name)
+		}
+		inds[ind.Name] = struct{}{}
+	}
+	return nil
+}
+
+// ValidateIndex verifies that index definition is valid for this table.
+func (t Header) ValidateIndex(ind Index) error {
+	if ind.Name == "" {
+		return fmt.Errorf("index name should not be empty")
+	} else if len(ind.Fields) == 0 {
+		return fmt.Errorf("index %q: at least one field is required", ind.Name)
+	}
+	fields := make(map[string]struct{})
+	for _, name := range ind.Fields {
+		f, _ := t.DataByName(name)
+		if f == nil {
+			return fmt.Errorf("index %q: unknown payload field: %q", ind.Name, name)
+		} else if _, ok := f.Type.(values.SortableType); !ok {
+			return fmt.Errorf("index %q: field %q is not sortable", ind.Name, name)
+		}
+		if _, ok := fields[name]; ok {
+			return fmt.Errorf("index %q: duplicate field: %q", ind.Name, name)
+		}
+		fields[name] = struct{}{}
+	}
 	return nil
 }
 
@@ -155,19 +237,39 @@ func (t Header) ValidatePref(k Key) error {
 }
 
 // ValidateData verifies that specific payload is valid for this table.
+// Null values are only allowed for nullable fields and fields with default values.
 func (t Header) ValidateData(d Data) error {
 	if len(t.Data) != len(d) {
 		return fmt.Errorf("wrong payload size")
 	}
 	for i, f := range t.Data {
 		v := d[i]
-		if v != nil && v.Type() != f.Type {
+		if v == nil && !f.Nullable && f.Default == nil {
+			return fmt.Errorf("payload %q: field is not nullable", f.Name)
+		} else if v != nil && v.Type() != f.Type {
 			return fmt.Errorf("payload %q: expected %T, got %T", f.Name, f.Type, v.Type())
 		}
 	}
 	return nil
 }
 
+// WithDefaults replaces null values in the payload with default values of the fields.
+// The payload is copied only if it was changed.
+func (t Header) WithDefaults(d Data) Data {
+	copied := false
+	for i, f := range t.Data {
+		if i >= len(d) || d[i] != nil || f.Default == nil {
+			continue
+		}
+		if !copied {
+			d = append(Data{}, d...)
+			copied = true
+		}
+		d[i] = f.Default
+	}
+	return d
+}
+
 // Key is a tuple primary key.
 type Key []Sortable
 
@@ -204,6 +306,19 @@ func AutoKey() Key {
 // Data is a tuple payload.
 type Data []Value
 
+// Project returns a payload that only contains fields with given indexes. See Header.Projection.
+// Nil projection returns the payload as-is.
+func (d Data) Project(fields []int) Data {
+	if fields == nil || d == nil {
+		return d
+	}
+	out := make(Data, len(fields))
+	for i, j := range fields {
+		out[i] = d[j]
+	}
+	return out
+}
+
 // SData creates a string payload.
 func SData(data ...string) Data {
 	out := make(Data, 0, len(data))
@@ -249,6 +364,9 @@ type Tx interface {
 	ListTables(ctx context.Context) ([]Table, error)
 	// CreateTable creates and opens a table with a specific schema.
 	CreateTable(ctx context.Context, table Header) (Table, error)
+	// AlterTable changes the schema of the table and opens it. Changes are applied in order.
+	// Existing tuples are converted to the new schema. Primary key of the table cannot be changed.
+	AlterTable(ctx context.Context, name string, changes ...Alteration) (Table, error)
 }
 
 type ScanOptions struct {
@@ -260,6 +378,50 @@ type ScanOptions struct {
 	Filter *Filter
 	// Limit limits the maximal number of tuples to return. Limit <= 0 indicates an unlimited number of results.
 	Limit int
+	// Fields is an optional list of payload fields to return. If set, iterator returns only these fields,
+	// in the specified order. Filters are still applied to the full payload.
+	Fields []string
+	// After is an optional exclusive lower bound for tuple keys. It must be a full key.
+	// Bounds do not depend on the sort order and can be used to page through the table.
+	After Key
+	// Before is an optional exclusive upper bound for tuple keys. It must be a full key.
+	Before Key
+	// Offset is a number of matching tuples to skip. It is applied before the limit.
+	Offset int
+}
+
+// Validate checks if key bounds and the offset are valid for the table.
+func (opt *ScanOptions) Validate(h Header) error {
+	if opt == nil {
+		return nil
+	} else if opt.Offset < 0 {
+		return fmt.Errorf("negative offset: %d", opt.Offset)
+	}
+	if opt.After != nil {
+		if err := h.ValidateKey(opt.After, false); err != nil {
+			return fmt.Errorf("invalid lower bound: %w", err)
+		}
+	}
+	if opt.Before != nil {
+		if err := h.ValidateKey(opt.Before, false); err != nil {
+			return fmt.Errorf("invalid upper bound: %w", err)
+		}
+	}
+	return nil
+}
+
+// InBounds checks if the key is within the After and Before bounds.
+func (opt *ScanOptions) InBounds(k Key) bool {
+	if opt == nil {
+		return true
+	}
+	if opt.After != nil && k.Compare(opt.After) <= 0 {
+		return false
+	}
+	if opt.Before != nil && k.Compare(opt.Before) >= 0 {
+		return false
+	}
+	return true
 }
 
 type Scanner interface {
@@ -291,6 +453,8 @@ type Table interface {
 	// Nil values in the returned slice indicates that specific key does not exists.
 	GetTupleBatch(ctx context.Context, keys []Key) ([]Data, error)
 	// InsertTuple creates a new tuple. If the tuple with specified key already exists it returns ErrExists.
+	// If the tuple violates a unique index, it returns ErrUnique.
+	// Null payload values are replaced with default values of the fields, both here and in UpdateTuple.
 	InsertTuple(ctx context.Context, t Tuple) (Key, error)
 	// UpdateTuple rewrites specified tuple. Options can be provided to create a tuple if ti does not exists.
 	// If this flag is not provided and the tuple is missing, it returns ErrNotFound.
@@ -302,6 +466,16 @@ type Table interface {
 	Scanner
 }
 
+// Indexer is an optional interface for tables that allow to change secondary indexes after creation.
+type Indexer interface {
+	// CreateIndex adds a secondary index to the table and fills it with existing tuples.
+	// It returns ErrUnique if existing tuples violate a unique index.
+	CreateIndex(ctx context.Context, ind Index) error
+	// DropIndex removes a secondary index from the table.
+	// It returns ErrIndexNotFound if index does not exists.
+	DropIndex(ctx context.Context, name string) error
+}
+
 type Sorting int
 
 const (
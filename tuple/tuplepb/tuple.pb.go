@@ -11,6 +11,8 @@
 		Table
 		KeyField
 		Field
+		Index
+		Value
 */
 package tuplepb
 
@@ -72,9 +74,10 @@ func (x ValueType) String() string {
 func (ValueType) EnumDescriptor() ([]byte, []int) { return fileDescriptorTuple, []int{0} }
 
 type Table struct {
-	Name string     `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Key  []KeyField `protobuf:"bytes,2,rep,name=key" json:"key"`
-	Data []Field    `protobuf:"bytes,3,rep,name=data" json:"data"`
+	Name    string     `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Key     []KeyField `protobuf:"bytes,2,rep,name=key" json:"key"`
+	Data    []Field    `protobuf:"bytes,3,rep,name=data" json:"data"`
+	Indexes []Index    `protobuf:"bytes,4,rep,name=indexes" json:"indexes"`
 }
 
 func (m *Table) Reset()                    { *m = Table{} }
@@ -103,6 +106,13 @@ func (m *Table) GetData() []Field {
 	return nil
 }
 
+func (m *Table) GetIndexes() []Index {
+	if m != nil {
+		return m.Indexes
+	}
+	return nil
+}
+
 type KeyField struct {
 	Name string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Type ValueType `protobuf:"varint,2,opt,name=type,proto3,enum=nwca.hidalgo.tuple.ValueType" json:"type,omitempty"`
@@ -136,8 +146,10 @@ func (m *KeyField) GetAuto() bool {
 }
 
 type Field struct {
-	Name string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Type ValueType `protobuf:"varint,2,opt,name=type,proto3,enum=nwca.hidalgo.tuple.ValueType" json:"type,omitempty"`
+	Name     string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type     ValueType `protobuf:"varint,2,opt,name=type,proto3,enum=nwca.hidalgo.tuple.ValueType" json:"type,omitempty"`
+	Nullable bool      `protobuf:"varint,3,opt,name=nullable,proto3" json:"nullable,omitempty"`
+	Default  *Value    `protobuf:"bytes,4,opt,name=default" json:"default,omitempty"`
 }
 
 func (m *Field) Reset()                    { *m = Field{} }
@@ -159,10 +171,74 @@ func (m *Field) GetType() ValueType {
 	return ValueType_TYPE_ANY
 }
 
+func (m *Field) GetNullable() bool {
+	if m != nil {
+		return m.Nullable
+	}
+	return false
+}
+
+func (m *Field) GetDefault() *Value {
+	if m != nil {
+		return m.Default
+	}
+	return nil
+}
+
+type Index struct {
+	Name   string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Fields []string `protobuf:"bytes,2,rep,name=fields" json:"fields,omitempty"`
+	Unique bool     `protobuf:"varint,3,opt,name=unique,proto3" json:"unique,omitempty"`
+}
+
+func (m *Index) Reset()                    { *m = Index{} }
+func (m *Index) String() string            { return proto.CompactTextString(m) }
+func (*Index) ProtoMessage()               {}
+func (*Index) Descriptor() ([]byte, []int) { return fileDescriptorTuple, []int{3} }
+
+func (m *Index) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Index) GetFields() []string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+func (m *Index) GetUnique() bool {
+	if m != nil {
+		return m.Unique
+	}
+	return false
+}
+
+type Value struct {
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *Value) Reset()                    { *m = Value{} }
+func (m *Value) String() string            { return proto.CompactTextString(m) }
+func (*Value) ProtoMessage()               {}
+func (*Value) Descriptor() ([]byte, []int) { return fileDescriptorTuple, []int{4} }
+
+func (m *Value) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Table)(nil), "nwca.hidalgo.tuple.Table")
 	proto.RegisterType((*KeyField)(nil), "nwca.hidalgo.tuple.KeyField")
 	proto.RegisterType((*Field)(nil), "nwca.hidalgo.tuple.Field")
+	proto.RegisterType((*Index)(nil), "nwca.hidalgo.tuple.Index")
+	proto.RegisterType((*Value)(nil), "nwca.hidalgo.tuple.Value")
 	proto.RegisterEnum("nwca.hidalgo.tuple.ValueType", ValueType_name, ValueType_value)
 }
 func (m *Table) Marshal() (dAtA []byte, err error) {
@@ -210,6 +286,18 @@ func (m *Table) MarshalTo(dAtA []byte) (int, error) {
 			i += n
 		}
 	}
+	if len(m.Indexes) > 0 {
+		for _, msg := range m.Indexes {
+			dAtA[i] = 0x22
+			i++
+			i = encodeVarintTuple(dAtA, i, uint64(msg.ProtoSize()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
 	return i, nil
 }
 
@@ -278,6 +366,99 @@ func (m *Field) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintTuple(dAtA, i, uint64(m.Type))
 	}
+	if m.Nullable {
+		dAtA[i] = 0x18
+		i++
+		if m.Nullable {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Default != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintTuple(dAtA, i, uint64(m.Default.ProtoSize()))
+		n1, err := m.Default.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n1
+	}
+	return i, nil
+}
+
+func (m *Index) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Index) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Name) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintTuple(dAtA, i, uint64(len(m.Name)))
+		i += copy(dAtA[i:], m.Name)
+	}
+	if len(m.Fields) > 0 {
+		for _, s := range m.Fields {
+			dAtA[i] = 0x12
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	if m.Unique {
+		dAtA[i] = 0x18
+		i++
+		if m.Unique {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *Value) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Value) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Data) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintTuple(dAtA, i, uint64(len(m.Data)))
+		i += copy(dAtA[i:], m.Data)
+	}
 	return i, nil
 }
 
@@ -309,6 +490,12 @@ func (m *Table) ProtoSize() (n int) {
 			n += 1 + l + sovTuple(uint64(l))
 		}
 	}
+	if len(m.Indexes) > 0 {
+		for _, e := range m.Indexes {
+			l = e.ProtoSize()
+			n += 1 + l + sovTuple(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -338,6 +525,42 @@ func (m *Field) ProtoSize() (n int) {
 	if m.Type != 0 {
 		n += 1 + sovTuple(uint64(m.Type))
 	}
+	if m.Nullable {
+		n += 2
+	}
+	if m.Default != nil {
+		l = m.Default.ProtoSize()
+		n += 1 + l + sovTuple(uint64(l))
+	}
+	return n
+}
+
+func (m *Index) ProtoSize() (n int) {
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovTuple(uint64(l))
+	}
+	if len(m.Fields) > 0 {
+		for _, s := range m.Fields {
+			l = len(s)
+			n += 1 + l + sovTuple(uint64(l))
+		}
+	}
+	if m.Unique {
+		n += 2
+	}
+	return n
+}
+
+func (m *Value) ProtoSize() (n int) {
+	var l int
+	_ = l
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovTuple(uint64(l))
+	}
 	return n
 }
 
@@ -474,6 +697,37 @@ func (m *Table) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Indexes", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTuple
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTuple
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Indexes = append(m.Indexes, Index{})
+			if err := m.Indexes[len(m.Indexes)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTuple(dAtA[iNdEx:])
@@ -690,6 +944,268 @@ func (m *Field) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nullable", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTuple
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Nullable = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Default", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTuple
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTuple
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Default == nil {
+				m.Default = &Value{}
+			}
+			if err := m.Default.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTuple(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTuple
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Index) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTuple
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Index: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Index: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTuple
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTuple
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Fields", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTuple
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTuple
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Fields = append(m.Fields, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Unique", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTuple
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Unique = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTuple(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTuple
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Value) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTuple
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Value: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Value: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTuple
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTuple
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTuple(dAtA[iNdEx:])
@@ -819,27 +1335,33 @@ var (
 func init() { proto.RegisterFile("tuple.proto", fileDescriptorTuple) }
 
 var fileDescriptorTuple = []byte{
-	// 340 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x91, 0x5f, 0x4a, 0xf3, 0x40,
-	0x14, 0xc5, 0x3b, 0x4d, 0xfa, 0xef, 0xf6, 0xfb, 0xea, 0x30, 0x20, 0x44, 0xd1, 0x1a, 0xfa, 0x14,
-	0x04, 0x53, 0x6c, 0xdd, 0x40, 0x03, 0xad, 0x14, 0x6b, 0x2a, 0xe9, 0x28, 0xd4, 0x17, 0x99, 0xb4,
-	0x63, 0x5a, 0x4c, 0x3b, 0x41, 0x12, 0x24, 0x0b, 0xd0, 0x75, 0xb8, 0x9c, 0x3e, 0xba, 0x02, 0x1f,
-	0xea, 0x46, 0xa4, 0x57, 0x0c, 0x82, 0x7d, 0xf4, 0xed, 0xfc, 0xe6, 0x9e, 0x73, 0xee, 0x85, 0x81,
-	0x6a, 0x9c, 0x44, 0xa1, 0xb4, 0xa3, 0x47, 0x15, 0x2b, 0xc6, 0x96, 0x4f, 0x13, 0x61, 0xcf, 0xe6,
-	0x53, 0x11, 0x06, 0xca, 0xc6, 0xc9, 0xfe, 0x49, 0x30, 0x8f, 0x67, 0x89, 0x6f, 0x4f, 0xd4, 0xa2,
-	0x19, 0xa8, 0x40, 0x35, 0xd1, 0xea, 0x27, 0xf7, 0x48, 0x08, 0xa8, 0xbe, 0x2a, 0x1a, 0x2f, 0x04,
-	0x0a, 0x5c, 0xf8, 0xa1, 0x64, 0x0c, 0xf4, 0xa5, 0x58, 0x48, 0x83, 0x98, 0xc4, 0xaa, 0x78, 0xa8,
-	0xd9, 0x19, 0x68, 0x0f, 0x32, 0x35, 0xf2, 0xa6, 0x66, 0x55, 0x5b, 0x07, 0xf6, 0xef, 0x75, 0xf6,
-	0x85, 0x4c, 0x7b, 0x73, 0x19, 0x4e, 0x1d, 0x7d, 0xf5, 0x7e, 0x94, 0xf3, 0x36, 0x76, 0xd6, 0x06,
-	0x7d, 0x2a, 0x62, 0x61, 0x68, 0x18, 0xdb, 0xdb, 0x16, 0xfb, 0x99, 0x41, 0x73, 0x43, 0x42, 0xf9,
-	0xbb, 0x6b, 0xeb, 0x29, 0xa7, 0xa0, 0xc7, 0x69, 0x24, 0x8d, 0xbc, 0x49, 0xac, 0x5a, 0xeb, 0x70,
-	0x5b, 0xe9, 0x8d, 0x08, 0x13, 0xc9, 0xd3, 0x48, 0x7a, 0x68, 0xdd, 0xd4, 0x88, 0x24, 0x56, 0x86,
-	0x66, 0x12, 0xab, 0xec, 0xa1, 0x6e, 0xb8, 0x50, 0xf8, 0xcb, 0x1d, 0xc7, 0xcf, 0x04, 0x2a, 0xd9,
-	0x1b, 0xfb, 0x07, 0x65, 0x3e, 0xbe, 0xea, 0xde, 0x75, 0xdc, 0x31, 0xcd, 0xb1, 0x1a, 0x00, 0x92,
-	0x33, 0xe6, 0xdd, 0x11, 0x25, 0x6c, 0x07, 0xaa, 0xc8, 0x23, 0xee, 0xf5, 0xdd, 0x73, 0x9a, 0x67,
-	0xff, 0xa1, 0x82, 0x0f, 0xd7, 0x7d, 0x97, 0x53, 0x2d, 0x4b, 0x6f, 0x48, 0xcf, 0x86, 0xce, 0x70,
-	0x38, 0xa0, 0x85, 0x0c, 0x79, 0xff, 0xb2, 0x4b, 0x8b, 0x59, 0x77, 0x6f, 0x30, 0xec, 0x70, 0x5a,
-	0x72, 0x76, 0x57, 0xeb, 0x3a, 0x79, 0x5b, 0xd7, 0xc9, 0xeb, 0x47, 0x9d, 0xdc, 0x96, 0xf0, 0xd8,
-	0xc8, 0xf7, 0x8b, 0xf8, 0xcb, 0xed, 0xcf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x99, 0x78, 0x22, 0xc5,
-	0x37, 0x02, 0x00, 0x00,
+	// 435 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x52, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0xee, 0xc6, 0x76, 0x7e, 0x26, 0xa5, 0x58, 0x2b, 0x81, 0x4c, 0x81, 0x60, 0xf9, 0x14, 0x21,
+	0xe1, 0x8a, 0x86, 0x0b, 0xc7, 0x46, 0x4a, 0x91, 0xd5, 0x92, 0x20, 0x77, 0x41, 0x0a, 0x17, 0xb4,
+	0xae, 0x37, 0xae, 0xc5, 0xd6, 0x36, 0xd4, 0x2b, 0xf0, 0x03, 0xf0, 0x1c, 0xf0, 0x2a, 0xdc, 0x7a,
+	0xe4, 0x09, 0x38, 0x94, 0x17, 0x41, 0x3b, 0xb5, 0x2d, 0x24, 0xdc, 0x5b, 0x6f, 0xf3, 0xed, 0x7e,
+	0xdf, 0x37, 0xb3, 0xdf, 0x2c, 0x8c, 0x4b, 0x55, 0x48, 0xe1, 0x17, 0x9f, 0xf3, 0x32, 0xa7, 0x34,
+	0xfb, 0x72, 0xca, 0xfd, 0xb3, 0x34, 0xe6, 0x32, 0xc9, 0x7d, 0xbc, 0xd9, 0x7d, 0x96, 0xa4, 0xe5,
+	0x99, 0x8a, 0xfc, 0xd3, 0xfc, 0x7c, 0x2f, 0xc9, 0x93, 0x7c, 0x0f, 0xa9, 0x91, 0xda, 0x20, 0x42,
+	0x80, 0xd5, 0xb5, 0x85, 0xf7, 0x93, 0x80, 0xc5, 0x78, 0x24, 0x05, 0xa5, 0x60, 0x66, 0xfc, 0x5c,
+	0x38, 0xc4, 0x25, 0xd3, 0x51, 0x88, 0x35, 0x7d, 0x01, 0xc6, 0x47, 0x51, 0x39, 0x3d, 0xd7, 0x98,
+	0x8e, 0xf7, 0x1f, 0xf9, 0xff, 0xb7, 0xf3, 0x8f, 0x44, 0x75, 0x98, 0x0a, 0x19, 0xcf, 0xcd, 0xcb,
+	0xdf, 0x4f, 0xb6, 0x42, 0x4d, 0xa7, 0x33, 0x30, 0x63, 0x5e, 0x72, 0xc7, 0x40, 0xd9, 0x83, 0x2e,
+	0xd9, 0xbf, 0x1a, 0x24, 0xd3, 0x97, 0x30, 0x48, 0xb3, 0x58, 0x7c, 0x15, 0x17, 0x8e, 0x79, 0xb3,
+	0x2e, 0xd0, 0x94, 0x5a, 0xd7, 0xf0, 0x3d, 0x01, 0xc3, 0x66, 0x8c, 0xce, 0x57, 0x3c, 0x07, 0xb3,
+	0xac, 0x0a, 0xe1, 0xf4, 0x5c, 0x32, 0xdd, 0xd9, 0x7f, 0xdc, 0xe5, 0xfb, 0x8e, 0x4b, 0x25, 0x58,
+	0x55, 0x88, 0x10, 0xa9, 0xda, 0x86, 0xab, 0x32, 0x77, 0x0c, 0x97, 0x4c, 0x87, 0x21, 0xd6, 0xde,
+	0x77, 0x02, 0xd6, 0xad, 0x36, 0xd9, 0x85, 0x61, 0xa6, 0xa4, 0xd4, 0xe9, 0xd7, 0x8d, 0x5a, 0x4c,
+	0x67, 0x30, 0x88, 0xc5, 0x86, 0x2b, 0x59, 0x3a, 0xa6, 0x4b, 0x6e, 0x8a, 0x03, 0x1d, 0xc3, 0x86,
+	0xe9, 0x1d, 0x81, 0x85, 0x01, 0x75, 0x0e, 0x78, 0x1f, 0xfa, 0x1b, 0x3d, 0xfd, 0x05, 0xae, 0x73,
+	0x14, 0xd6, 0x48, 0x9f, 0xab, 0x2c, 0xfd, 0xa4, 0x9a, 0x19, 0x6a, 0xe4, 0x3d, 0x04, 0x0b, 0xed,
+	0xb5, 0x19, 0xae, 0x53, 0x9b, 0x6d, 0x5f, 0x6f, 0xeb, 0xe9, 0x37, 0x02, 0xa3, 0xf6, 0x39, 0x74,
+	0x1b, 0x86, 0x6c, 0xfd, 0x66, 0xf1, 0xe1, 0x60, 0xb9, 0xb6, 0xb7, 0xe8, 0x0e, 0x00, 0xa2, 0xf9,
+	0x9a, 0x2d, 0x4e, 0x6c, 0x42, 0xef, 0xc2, 0x18, 0xf1, 0x09, 0x0b, 0x83, 0xe5, 0x2b, 0xbb, 0x47,
+	0xef, 0xc0, 0x08, 0x0f, 0xde, 0x06, 0x4b, 0x66, 0x1b, 0xad, 0x5a, 0x23, 0xb3, 0xbd, 0x9c, 0xaf,
+	0x56, 0xc7, 0xb6, 0xd5, 0x42, 0x16, 0xbc, 0x5e, 0xd8, 0xfd, 0xd6, 0xfb, 0xf0, 0x78, 0x75, 0xc0,
+	0xec, 0xc1, 0xfc, 0xde, 0xe5, 0xd5, 0x84, 0xfc, 0xba, 0x9a, 0x90, 0x1f, 0x7f, 0x26, 0xe4, 0xfd,
+	0x00, 0x53, 0x29, 0xa2, 0xa8, 0x8f, 0x9f, 0x7b, 0xf6, 0x77, 0x00, 0x26, 0xc2, 0xf1, 0x56, 0x2e,
+	0x03, 0x00, 0x00,
 }
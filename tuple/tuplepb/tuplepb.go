@@ -12,9 +12,10 @@ import (
 // Make sure that new fields will cause compilation error.
 var (
 	_ tuple.Header = struct {
-		Name string
-		Key  []tuple.KeyField
-		Data []tuple.Field
+		Name    string
+		Key     []tuple.KeyField
+		Data    []tuple.Field
+		Indexes []tuple.Index
 	}{}
 
 	_ tuple.KeyField = struct {
@@ -24,8 +25,16 @@ var (
 	}{}
 
 	_ tuple.Field = struct {
-		Name string
-		Type values.Type
+		Name     string
+		Type     values.Type
+		Nullable bool
+		Default  values.Value
+	}{}
+
+	_ tuple.Index = struct {
+		Name   string
+		Fields []string
+		Unique bool
 	}{}
 )
 
@@ -81,8 +90,21 @@ func MarshalTable(t *tuple.Header) ([]byte, error) {
 		if !ok {
 			return nil, fmt.Errorf("unsupported value type: %T", f.Type)
 		}
-		table.Data = append(table.Data, Field{
-			Name: f.Name, Type: tp,
+		pf := Field{
+			Name: f.Name, Type: tp, Nullable: f.Nullable,
+		}
+		if f.Default != nil {
+			data, err := f.Default.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			pf.Default = &Value{Data: data}
+		}
+		table.Data = append(table.Data, pf)
+	}
+	for _, ind := range t.Indexes {
+		table.Indexes = append(table.Indexes, Index{
+			Name: ind.Name, Fields: ind.Fields, Unique: ind.Unique,
 		})
 	}
 	return table.Marshal()
@@ -113,8 +135,21 @@ func UnmarshalTable(p []byte) (*tuple.Header, error) {
 		if !ok {
 			return nil, fmt.Errorf("unsupported value type: %T", f.Type)
 		}
-		table.Data = append(table.Data, tuple.Field{
-			Name: f.Name, Type: tp,
+		tf := tuple.Field{
+			Name: f.Name, Type: tp, Nullable: f.Nullable,
+		}
+		if f.Default != nil {
+			v := tp.New()
+			if err := v.UnmarshalBinary(f.Default.Data); err != nil {
+				return nil, fmt.Errorf("cannot decode default value: %v", err)
+			}
+			tf.Default = v.Value()
+		}
+		table.Data = append(table.Data, tf)
+	}
+	for _, ind := range t.Indexes {
+		table.Indexes = append(table.Indexes, tuple.Index{
+			Name: ind.Name, Fields: ind.Fields, Unique: ind.Unique,
 		})
 	}
 	return table, nil
@@ -18,8 +18,13 @@ func TestTableEncoding(t *testing.T) {
 		},
 		Data: []tuple.Field{
 			{Name: "f1", Type: values.BytesType{}},
-			{Name: "f2", Type: values.StringType{}},
-			{Name: "f2", Type: values.FloatType{}},
+			{Name: "f2", Type: values.StringType{}, Nullable: true},
+			{Name: "f2", Type: values.FloatType{}, Default: values.Float(1.5)},
+			{Name: "f3", Type: values.StringType{}, Nullable: true, Default: values.String("")},
+		},
+		Indexes: []tuple.Index{
+			{Name: "i1", Fields: []string{"f2"}},
+			{Name: "i2", Fields: []string{"f1", "f2"}, Unique: true},
 		},
 	}
 
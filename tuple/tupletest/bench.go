@@ -0,0 +1,342 @@
+package tupletest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hidal-go/hidalgo/base"
+	"github.com/hidal-go/hidalgo/filter"
+	hkv "github.com/hidal-go/hidalgo/kv"
+	"github.com/hidal-go/hidalgo/kv/flat"
+	"github.com/hidal-go/hidalgo/kv/kvtest"
+	"github.com/hidal-go/hidalgo/tuple"
+	tuplekv "github.com/hidal-go/hidalgo/tuple/kv"
+	"github.com/hidal-go/hidalgo/values"
+)
+
+const (
+	benchTable = "bench"
+	benchTx    = 100      // operations in a single transaction
+	benchGroup = 100      // tuples with the same first key field
+	benchKeys  = 10000    // tuples inserted before read benchmarks
+	benchData  = 32 << 20 // limits the number of tuples inserted before read benchmarks
+)
+
+// benchSize describes the size of keys and payloads used in benchmarks.
+type benchSize struct {
+	key int // size of the second key field; at least 8 bytes
+	val int
+}
+
+var benchSizes = []benchSize{
+	{key: 16, val: 64},
+	{key: 16, val: 1024},
+	{key: 64, val: 64 << 10}, // large values
+}
+
+func (sz benchSize) String() string {
+	return fmt.Sprintf("k%d-v%d", sz.key, sz.val)
+}
+
+// bytes returns the size of a single tuple.
+func (sz benchSize) bytes() int {
+	return len(benchGroupKey(0)) + sz.key + sz.val
+}
+
+// count returns the number of tuples to insert before read benchmarks.
+func (sz benchSize) count() int {
+	n := benchData / sz.bytes()
+	if n > benchKeys {
+		n = benchKeys
+	}
+	return n
+}
+
+func benchGroupKey(i int) values.String {
+	return values.String(fmt.Sprintf("g%06d", i/benchGroup))
+}
+
+// keyAt returns i-th key in the ascending order. Each benchGroup keys share the same first field.
+func (sz benchSize) keyAt(i int) tuple.Key {
+	id := make([]byte, sz.key)
+	binary.BigEndian.PutUint64(id[len(id)-8:], uint64(i))
+	return tuple.Key{benchGroupKey(i), values.Bytes(id)}
+}
+
+// benchValues generates random payloads of a given size.
+type benchValues struct {
+	size int
+	buf  []byte
+}
+
+func newBenchValues(size int) *benchValues {
+	buf := make([]byte, 2*size)
+	rand.New(rand.NewSource(1)).Read(buf)
+	return &benchValues{size: size, buf: buf}
+}
+
+func (v *benchValues) get(i int) tuple.Data {
+	off := i % v.size
+	return tuple.Data{values.Bytes(v.buf[off : off+v.size])}
+}
+
+var benchList = []struct {
+	name  string
+	bench func(b *testing.B, db tuple.Store, sz benchSize)
+	caps  base.Capabilities // required capabilities
+}{
+	{name: "insert-seq", bench: benchInsertSeq},
+	{name: "insert-rand", bench: benchInsertRand},
+	{name: "get", bench: benchGet},
+	{name: "get-batch", bench: benchGetBatch},
+	{name: "scan-prefix", bench: benchScanPrefix},
+	{name: "mixed", bench: benchMixed, caps: base.CapConcurrentWrites},
+}
+
+// RunBenchmarks runs all benchmarks for tuple store implementations.
+//
+// Each benchmark runs for each combination of key and payload size, and reports ops/s in addition to the throughput.
+// Key-value benchmarks are run on top of the tuple store as well.
+func RunBenchmarks(b *testing.B, fnc Func, opts *Options) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	for _, c := range benchList {
+		b.Run(c.name, func(b *testing.B) {
+			for _, sz := range benchSizes {
+				b.Run(sz.String(), func(b *testing.B) {
+					db := fnc(b)
+					if caps := opts.Capabilities(db); !caps.Has(c.caps) {
+						b.Skipf("implementation doesn't support %v", c.caps&^caps)
+					}
+					benchCreate(b, db)
+					c.bench(b, db, sz)
+				})
+			}
+		})
+	}
+	b.Run("kv", func(b *testing.B) {
+		kvtest.RunBenchmarks(b, func(t testing.TB) hkv.KV {
+			db := fnc(t)
+
+			ctx := context.Background()
+			kdb, err := tuplekv.NewKV(ctx, db, "kv")
+			if err != nil {
+				require.NoError(t, err)
+			}
+			t.Cleanup(func() {
+				_ = kdb.Close()
+			})
+			return flat.Upgrade(kdb)
+		}, &kvtest.Options{
+			NoLocks: opts.NoLocks,
+		})
+	})
+}
+
+func benchCreate(b *testing.B, db tuple.Store) {
+	ctx := context.Background()
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.CreateTable(ctx, tuple.Header{
+			Name: benchTable,
+			Key: []tuple.KeyField{
+				{Name: "g", Type: values.StringType{}},
+				{Name: "id", Type: values.BytesType{}},
+			},
+			Data: []tuple.Field{
+				{Name: "v", Type: values.BytesType{}},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+// benchView runs a function with a benchmark table opened in a read-only or read-write transaction.
+func benchView(ctx context.Context, db tuple.Store, rw bool, fnc func(tbl tuple.Table) error) error {
+	view := db.View
+	if rw {
+		view = db.Update
+	}
+	return view(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, benchTable)
+		if err != nil {
+			return err
+		}
+		return fnc(tbl)
+	})
+}
+
+// benchStart resets the benchmark timer and returns the start time.
+func benchStart(b *testing.B, sz benchSize) time.Time {
+	b.SetBytes(int64(sz.bytes()))
+	b.ResetTimer()
+	return time.Now()
+}
+
+// benchReport reports the number of operations per second.
+func benchReport(b *testing.B, start time.Time) {
+	b.StopTimer()
+	if dt := time.Since(start); dt > 0 {
+		b.ReportMetric(float64(b.N)/dt.Seconds(), "ops/s")
+	}
+}
+
+// benchFill inserts tuples for read benchmarks and returns the number of tuples.
+func benchFill(b *testing.B, db tuple.Store, sz benchSize) int {
+	n := sz.count()
+	vals := newBenchValues(sz.val)
+	benchInsert(b, db, sz, vals, n, func(i int) int { return i })
+	return n
+}
+
+func benchInsert(b *testing.B, db tuple.Store, sz benchSize, vals *benchValues, n int, order func(i int) int) {
+	ctx := context.Background()
+	for i := 0; i < n; i += benchTx {
+		err := benchView(ctx, db, true, func(tbl tuple.Table) error {
+			for j := i; j < i+benchTx && j < n; j++ {
+				_, err := tbl.InsertTuple(ctx, tuple.Tuple{Key: sz.keyAt(order(j)), Data: vals.get(j)})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchInsertSeq(b *testing.B, db tuple.Store, sz benchSize) {
+	vals := newBenchValues(sz.val)
+	start := benchStart(b, sz)
+	benchInsert(b, db, sz, vals, b.N, func(i int) int { return i })
+	benchReport(b, start)
+}
+
+func benchInsertRand(b *testing.B, db tuple.Store, sz benchSize) {
+	vals := newBenchValues(sz.val)
+	perm := rand.New(rand.NewSource(1)).Perm(b.N)
+	start := benchStart(b, sz)
+	benchInsert(b, db, sz, vals, b.N, func(i int) int { return perm[i] })
+	benchReport(b, start)
+}
+
+func benchGet(b *testing.B, db tuple.Store, sz benchSize) {
+	ctx := context.Background()
+	n := benchFill(b, db, sz)
+	rnd := rand.New(rand.NewSource(1))
+	start := benchStart(b, sz)
+	for i := 0; i < b.N; i += benchTx {
+		err := benchView(ctx, db, false, func(tbl tuple.Table) error {
+			for j := i; j < i+benchTx && j < b.N; j++ {
+				if _, err := tbl.GetTuple(ctx, sz.keyAt(rnd.Intn(n))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchReport(b, start)
+}
+
+func benchGetBatch(b *testing.B, db tuple.Store, sz benchSize) {
+	ctx := context.Background()
+	n := benchFill(b, db, sz)
+	rnd := rand.New(rand.NewSource(1))
+	keys := make([]tuple.Key, 0, benchTx)
+	start := benchStart(b, sz)
+	for i := 0; i < b.N; i += benchTx {
+		keys = keys[:0]
+		for j := i; j < i+benchTx && j < b.N; j++ {
+			keys = append(keys, sz.keyAt(rnd.Intn(n)))
+		}
+		err := benchView(ctx, db, false, func(tbl tuple.Table) error {
+			data, err := tbl.GetTupleBatch(ctx, keys)
+			if err != nil {
+				return err
+			}
+			for _, d := range data {
+				if d == nil {
+					return tuple.ErrNotFound
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchReport(b, start)
+}
+
+// benchScanPrefix counts each tuple returned by scans over the first key field as a single operation.
+func benchScanPrefix(b *testing.B, db tuple.Store, sz benchSize) {
+	ctx := context.Background()
+	n := benchFill(b, db, sz)
+	rnd := rand.New(rand.NewSource(1))
+	groups := (n + benchGroup - 1) / benchGroup
+	start := benchStart(b, sz)
+	for read := 0; read < b.N; {
+		g := benchGroupKey(rnd.Intn(groups) * benchGroup)
+		err := benchView(ctx, db, false, func(tbl tuple.Table) error {
+			it := tbl.Scan(ctx, &tuple.ScanOptions{
+				Filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(g)}},
+			})
+			defer it.Close()
+			for read < b.N && it.Next(ctx) {
+				_ = it.Data()
+				read++
+			}
+			return it.Err()
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchReport(b, start)
+}
+
+// benchMixed runs concurrent point reads and upserts in separate transactions, with one write per 10 operations.
+func benchMixed(b *testing.B, db tuple.Store, sz benchSize) {
+	ctx := context.Background()
+	n := benchFill(b, db, sz)
+	vals := newBenchValues(sz.val)
+	var seed int64
+	start := benchStart(b, sz)
+	b.RunParallel(func(pb *testing.PB) {
+		rnd := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for pb.Next() {
+			i := rnd.Intn(n)
+			var err error
+			if rnd.Intn(10) == 0 {
+				err = benchView(ctx, db, true, func(tbl tuple.Table) error {
+					return tbl.UpdateTuple(ctx, tuple.Tuple{Key: sz.keyAt(i), Data: vals.get(i)}, &tuple.UpdateOpt{Upsert: true})
+				})
+			} else {
+				err = benchView(ctx, db, false, func(tbl tuple.Table) error {
+					_, err := tbl.GetTuple(ctx, sz.keyAt(i))
+					return err
+				})
+			}
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	benchReport(b, start)
+}
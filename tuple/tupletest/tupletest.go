@@ -3,12 +3,14 @@ package tupletest
 import (
 	"context"
 	"fmt"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/hidal-go/hidalgo/base"
 	"github.com/hidal-go/hidalgo/filter"
 	hkv "github.com/hidal-go/hidalgo/kv"
 	"github.com/hidal-go/hidalgo/kv/flat"
@@ -22,8 +24,23 @@ import (
 // It returns an empty database and a function to destroy it.
 type Func func(t testing.TB) tuple.Store
 
+// Options overrides capabilities reported by the database.
 type Options struct {
-	NoLocks bool // not safe for concurrent writes
+	NoLocks  bool // not safe for concurrent writes
+	NoUnique bool // unique indexes are not supported
+}
+
+// Capabilities returns capabilities of the database that are used to select test cases.
+// Databases that don't implement base.Capable are assumed to support everything.
+func (o *Options) Capabilities(db interface{}) base.Capabilities {
+	caps, ok := base.CapabilitiesOf(db)
+	if !ok {
+		caps = base.AllCapabilities
+	}
+	if o.NoLocks {
+		caps &^= base.CapConcurrentWrites
+	}
+	return caps
 }
 
 // RunTest runs all tests for tuple store implementations.
@@ -34,6 +51,11 @@ func RunTest(t *testing.T, fnc Func, opts *Options) {
 	for _, c := range testList {
 		t.Run(c.name, func(t *testing.T) {
 			db := fnc(t)
+			if caps := opts.Capabilities(db); !caps.Has(c.caps) {
+				t.Skipf("implementation doesn't support %v", c.caps&^caps)
+			} else if c.unique && opts.NoUnique {
+				t.Skip("implementation doesn't support unique indexes")
+			}
 			c.test(t, db)
 		})
 	}
@@ -52,7 +74,6 @@ func RunTest(t *testing.T, fnc Func, opts *Options) {
 			return flat.Upgrade(kdb)
 		}, &kvtest.Options{
 			NoLocks: opts.NoLocks,
-			NoTx:    true, // FIXME
 		})
 	})
 }
@@ -60,12 +81,25 @@ func RunTest(t *testing.T, fnc Func, opts *Options) {
 var testList = []struct {
 	name string
 	test func(t *testing.T, db tuple.Store)
+	caps base.Capabilities // required capabilities
+
+	unique bool // requires unique indexes
 }{
 	{name: "basic", test: basic},
 	{name: "typed", test: typed},
 	{name: "scans", test: scans},
 	{name: "tables", test: tables},
 	{name: "auto", test: auto},
+	{name: "cancel", test: cancel},
+	{name: "cancel tx", test: cancelTx, caps: base.CapTx},
+	{name: "indexes", test: indexes},
+	{name: "unique", test: uniqueIndexes, unique: true},
+	{name: "alter", test: alter},
+	{name: "nulls", test: nulls},
+	{name: "projection", test: projection},
+	{name: "aggregate", test: aggregate},
+	{name: "pagination", test: pagination},
+	{name: "batch", test: batch},
 }
 
 func basic(t *testing.T, db tuple.Store) {
@@ -274,6 +308,15 @@ func scans(t *testing.T, db tuple.Store) {
 	insert([]string{"a", "a", "ab"}, 5)
 	insert([]string{"a", "b", "c"}, 6)
 
+	allKeys := []tuple.Key{
+		tuple.SKey("a", "a", "a"),
+		tuple.SKey("a", "a", "ab"),
+		tuple.SKey("a", "aa", "b"),
+		tuple.SKey("a", "b", "c"),
+		tuple.SKey("a", "ba", "c"),
+		tuple.SKey("b", "b", "b"),
+	}
+
 	scan(nil, 1, 5, 3, 6, 4, 2)
 	scan([]string{""}, 1, 5, 3, 6, 4, 2)
 	scan([]string{"a"}, 1, 5, 3, 6, 4)
@@ -284,6 +327,77 @@ func scans(t *testing.T, db tuple.Store) {
 	scan([]string{"a", "aa", ""}, 3)
 	scan([]string{"a", "aa", "b"}, 3)
 
+	// all combinations of options must return the same results on all backends
+	all := []int{1, 5, 3, 6, 4, 2} // in key order
+	for _, c := range []struct {
+		name   string
+		filter *tuple.Filter
+		exp    []int
+	}{
+		{name: "all", exp: all},
+		{
+			name:   "key",
+			filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("a"))}},
+			exp:    []int{1, 5, 3, 6, 4},
+		},
+		{
+			name:   "data",
+			filter: &tuple.Filter{DataFilter: tuple.DataFilters{filter.GTE(values.Int(3))}},
+			exp:    []int{5, 3, 6, 4},
+		},
+		{
+			name: "key and data",
+			filter: &tuple.Filter{
+				KeyFilter:  tuple.KeyFilters{filter.EQ(values.String("a")), filter.GT(values.String("a"))},
+				DataFilter: tuple.DataFilters{filter.LT(values.Int(6))},
+			},
+			exp: []int{3, 4},
+		},
+	} {
+		for _, sort := range []tuple.Sorting{tuple.SortAny, tuple.SortAsc, tuple.SortDesc} {
+			for _, limit := range []int{0, 1, 2, 10} {
+				for _, keysOnly := range []bool{false, true} {
+					name := fmt.Sprintf("%s, sort %v, limit %d, keys only %v", c.name, sort, limit, keysOnly)
+					exp := append([]int{}, c.exp...)
+					if sort == tuple.SortDesc {
+						for i, j := 0, len(exp)-1; i < j; i, j = i+1, j-1 {
+							exp[i], exp[j] = exp[j], exp[i]
+						}
+					}
+					if limit > 0 && limit < len(exp) {
+						exp = exp[:limit]
+					}
+					it := tbl.Scan(ctx, &tuple.ScanOptions{
+						Sort: sort, Filter: c.filter, Limit: limit, KeysOnly: keysOnly,
+					})
+					var got []int
+					for it.Next(ctx) {
+						key := it.Key()
+						n := -1
+						for i, k := range allKeys {
+							if key.Compare(k) == 0 {
+								n = all[i]
+							}
+						}
+						require.True(t, n >= 0, "%s: unexpected key: %v", name, key)
+						if !keysOnly {
+							require.Equal(t, tuple.Data{values.Int(n)}, it.Data(), name)
+						}
+						got = append(got, n)
+					}
+					require.NoError(t, it.Err(), name)
+					it.Close()
+					if sort == tuple.SortAny {
+						require.Len(t, got, len(exp), name)
+						require.Subset(t, c.exp, got, name)
+					} else {
+						require.Equal(t, exp, got, name)
+					}
+				}
+			}
+		}
+	}
+
 	tbl2, err := tx.CreateTable(ctx, tuple.Header{
 		Name: "test2",
 		Key: []tuple.KeyField{
@@ -483,3 +597,1220 @@ func auto(t *testing.T, db tuple.Store) {
 		{Key: k1, Data: v1},
 	}, tuples)
 }
+
+var cancelTable = tuple.Header{
+	Name: "test",
+	Key: []tuple.KeyField{
+		{Name: "k", Type: values.StringType{}},
+	},
+	Data: []tuple.Field{
+		{Name: "v", Type: values.StringType{}},
+	},
+}
+
+// canceled returns contexts that are already canceled and expired.
+func canceled() (context.Context, context.Context) {
+	cctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dctx, cancel := context.WithDeadline(context.Background(), time.Unix(1, 0))
+	cancel()
+	return cctx, dctx
+}
+
+// cancel checks that operations and iterators stop and return an error of the context, once it is canceled.
+func cancel(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+	cctx, dctx := canceled()
+
+	keys := []tuple.Key{tuple.SKey("a"), tuple.SKey("b"), tuple.SKey("c")}
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, cancelTable)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if _, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: k, Data: tuple.SData("1")}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = db.Tx(cctx, false)
+	require.Equal(t, context.Canceled, err)
+
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	_, err = tx.Table(cctx, cancelTable.Name)
+	require.Equal(t, context.Canceled, err)
+
+	tbl, err := tx.Table(ctx, cancelTable.Name)
+	require.NoError(t, err)
+
+	_, err = tbl.GetTuple(cctx, keys[0])
+	require.Equal(t, context.Canceled, err)
+	_, err = tbl.GetTupleBatch(dctx, keys)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	_, err = tbl.InsertTuple(cctx, tuple.Tuple{Key: tuple.SKey("d"), Data: tuple.SData("1")})
+	require.Equal(t, context.Canceled, err)
+	err = tbl.UpdateTuple(dctx, tuple.Tuple{Key: keys[0], Data: tuple.SData("2")}, nil)
+	require.Equal(t, context.DeadlineExceeded, err)
+	err = tbl.DeleteTuples(cctx, &tuple.Filter{KeyFilter: tuple.Keys{keys[0]}})
+	require.Equal(t, context.Canceled, err)
+
+	it := tbl.Scan(ctx, &tuple.ScanOptions{Sort: tuple.SortAsc})
+	require.True(t, it.Next(ctx))
+	require.False(t, it.Next(cctx))
+	require.Equal(t, context.Canceled, it.Err())
+	it.Close()
+
+	it = tbl.Scan(ctx, nil)
+	require.False(t, it.Next(dctx))
+	require.Equal(t, context.DeadlineExceeded, it.Err())
+	it.Close()
+
+	for _, k := range keys {
+		data, err := tbl.GetTuple(ctx, k)
+		require.NoError(t, err)
+		require.Equal(t, tuple.SData("1"), data)
+	}
+}
+
+// cancelTx checks that a transaction is rolled back, if it's committed with a canceled context.
+func cancelTx(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+	cctx, _ := canceled()
+
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.CreateTable(ctx, cancelTable)
+		return err
+	})
+	require.NoError(t, err)
+
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	tbl, err := tx.Table(ctx, cancelTable.Name)
+	require.NoError(t, err)
+	_, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: tuple.SKey("a"), Data: tuple.SData("1")})
+	require.NoError(t, err)
+
+	err = tx.Commit(cctx)
+	require.Equal(t, context.Canceled, err)
+
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, cancelTable.Name)
+		if err != nil {
+			return err
+		}
+		_, err = tbl.GetTuple(ctx, tuple.SKey("a"))
+		return err
+	})
+	require.Equal(t, tuple.ErrNotFound, err)
+}
+
+var indexTable = tuple.Header{
+	Name: "test",
+	Key: []tuple.KeyField{
+		{Name: "k", Type: values.StringType{}},
+	},
+	Data: []tuple.Field{
+		{Name: "name", Type: values.StringType{}},
+		{Name: "age", Type: values.IntType{}},
+		{Name: "score", Type: values.FloatType{}},
+	},
+	Indexes: []tuple.Index{
+		{Name: "age", Fields: []string{"age"}},
+		{Name: "name_age", Fields: []string{"name", "age"}},
+	},
+}
+
+func indexData(name string, age int) tuple.Data {
+	return tuple.Data{values.String(name), values.Int(age), values.Float(age) / 2}
+}
+
+// scanKeys returns sorted keys of tuples matching the filter.
+func scanKeys(t testing.TB, tbl tuple.Table, f *tuple.Filter) []string {
+	ctx := context.Background()
+	it := tbl.Scan(ctx, &tuple.ScanOptions{Filter: f})
+	defer it.Close()
+	var out []string
+	for it.Next(ctx) {
+		k := it.Key()
+		d := it.Data()
+		require.True(t, f.FilterTuple(tuple.Tuple{Key: k, Data: d}), "%v: %v", k, d)
+		out = append(out, string(k[0].(values.String)))
+	}
+	require.NoError(t, it.Err())
+	sort.Strings(out)
+	return out
+}
+
+func indexes(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+
+	bad := indexTable.Clone()
+	bad.Indexes = []tuple.Index{{Name: "score", Fields: []string{"score"}}}
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.CreateTable(ctx, bad)
+		return err
+	})
+	require.Error(t, err, "float fields cannot be indexed")
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, indexTable)
+		if err != nil {
+			return err
+		}
+		for i, d := range []tuple.Data{
+			indexData("a", 20),
+			indexData("b", 21),
+			indexData("c", 20),
+			indexData("a", 21),
+			indexData("b", 20),
+			indexData("a", 22),
+		} {
+			key := tuple.SKey(fmt.Sprintf("k%d", i+1))
+			if _, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: key, Data: d}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	tx, err := db.Tx(ctx, true)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	tbl, err := tx.Table(ctx, indexTable.Name)
+	require.NoError(t, err)
+	require.Equal(t, indexTable, tbl.Header())
+
+	byData := func(name string, age int) *tuple.Filter {
+		df := tuple.DataFilters{nil, nil, nil}
+		if name != "" {
+			df[0] = filter.EQ(values.String(name))
+		}
+		if age != 0 {
+			df[1] = filter.EQ(values.Int(age))
+		}
+		return &tuple.Filter{DataFilter: df}
+	}
+
+	require.Equal(t, []string{"k1", "k3", "k5"}, scanKeys(t, tbl, byData("", 20)))
+	require.Equal(t, []string{"k1", "k4", "k6"}, scanKeys(t, tbl, byData("a", 0)))
+	require.Equal(t, []string{"k4"}, scanKeys(t, tbl, byData("a", 21)))
+	require.Empty(t, scanKeys(t, tbl, byData("d", 0)))
+
+	// index entries should follow the payload
+	err = tbl.UpdateTuple(ctx, tuple.Tuple{Key: tuple.SKey("k1"), Data: indexData("d", 22)}, nil)
+	require.NoError(t, err)
+	err = tbl.UpdateTuple(ctx, tuple.Tuple{Key: tuple.SKey("k7"), Data: indexData("d", 20)}, &tuple.UpdateOpt{Upsert: true})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"k3", "k5", "k7"}, scanKeys(t, tbl, byData("", 20)))
+	require.Equal(t, []string{"k1", "k6"}, scanKeys(t, tbl, byData("", 22)))
+	require.Equal(t, []string{"k4", "k6"}, scanKeys(t, tbl, byData("a", 0)))
+	require.Equal(t, []string{"k1", "k7"}, scanKeys(t, tbl, byData("d", 0)))
+
+	err = tbl.DeleteTuples(ctx, byData("d", 20))
+	require.NoError(t, err)
+	err = tbl.DeleteTuples(ctx, &tuple.Filter{KeyFilter: tuple.Keys{tuple.SKey("k4")}})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"k3", "k5"}, scanKeys(t, tbl, byData("", 20)))
+	require.Equal(t, []string{"k6"}, scanKeys(t, tbl, byData("a", 0)))
+	require.Equal(t, []string{"k1"}, scanKeys(t, tbl, byData("d", 0)))
+
+	err = tx.Commit(ctx)
+	require.NoError(t, err)
+
+	tx, err = db.Tx(ctx, true)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	tbl, err = tx.Table(ctx, indexTable.Name)
+	require.NoError(t, err)
+	ind, ok := tbl.(tuple.Indexer)
+	if !ok {
+		return
+	}
+	// new indexes include existing tuples
+	err = ind.CreateIndex(ctx, tuple.Index{Name: "name", Fields: []string{"name"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"k2", "k5"}, scanKeys(t, tbl, byData("b", 0)))
+
+	err = ind.DropIndex(ctx, "name_age")
+	require.NoError(t, err)
+	err = ind.DropIndex(ctx, "name_age")
+	require.Equal(t, tuple.ErrIndexNotFound, err)
+
+	err = tx.Commit(ctx)
+	require.NoError(t, err)
+
+	exp := indexTable.Clone()
+	exp.Indexes = []tuple.Index{
+		{Name: "age", Fields: []string{"age"}},
+		{Name: "name", Fields: []string{"name"}},
+	}
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, indexTable.Name)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, exp, tbl.Header())
+		require.Equal(t, []string{"k2", "k5"}, scanKeys(t, tbl, byData("b", 0)))
+		require.Equal(t, []string{"k3", "k5"}, scanKeys(t, tbl, byData("", 20)))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func uniqueIndexes(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+	h := indexTable.Clone()
+	h.Indexes = []tuple.Index{
+		{Name: "name", Fields: []string{"name"}, Unique: true},
+	}
+	// each operation is executed in a separate transaction,
+	// since some databases abort transactions on errors
+	update := func(fnc func(tbl tuple.Table) error) error {
+		return db.Update(ctx, func(tx tuple.Tx) error {
+			tbl, err := tx.Table(ctx, h.Name)
+			if err != nil {
+				return err
+			}
+			return fnc(tbl)
+		})
+	}
+	insert := func(key, name string) error {
+		return update(func(tbl tuple.Table) error {
+			_, err := tbl.InsertTuple(ctx, tuple.Tuple{Key: tuple.SKey(key), Data: indexData(name, 20)})
+			return err
+		})
+	}
+	upsert := func(key, name string) error {
+		return update(func(tbl tuple.Table) error {
+			return tbl.UpdateTuple(ctx, tuple.Tuple{Key: tuple.SKey(key), Data: indexData(name, 21)}, &tuple.UpdateOpt{Upsert: true})
+		})
+	}
+
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.CreateTable(ctx, h)
+		return err
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, insert("k1", "a"))
+	require.NoError(t, insert("k2", "b"))
+	require.Equal(t, tuple.ErrUnique, insert("k3", "a"))
+	require.Equal(t, tuple.ErrUnique, upsert("k2", "a"))
+	require.Equal(t, tuple.ErrUnique, upsert("k3", "b"))
+
+	// tuple can be updated without changing indexed fields
+	require.NoError(t, upsert("k1", "a"))
+	require.NoError(t, upsert("k2", "c"))
+	require.NoError(t, insert("k3", "b"))
+
+	err = update(func(tbl tuple.Table) error {
+		return tbl.DeleteTuples(ctx, &tuple.Filter{KeyFilter: tuple.Keys{tuple.SKey("k1")}})
+	})
+	require.NoError(t, err)
+	require.NoError(t, insert("k4", "a"))
+
+	err = update(func(tbl tuple.Table) error {
+		ind, ok := tbl.(tuple.Indexer)
+		if !ok {
+			return nil
+		}
+		// all tuples have the same age
+		return ind.CreateIndex(ctx, tuple.Index{Name: "age", Fields: []string{"age"}, Unique: true})
+	})
+	if err != nil {
+		require.Equal(t, tuple.ErrUnique, err)
+	}
+
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, h.Name)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, h, tbl.Header())
+		require.Equal(t, []string{"k2", "k3", "k4"}, scanKeys(t, tbl, nil))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// scanAll returns all tuples of the table sorted by the key.
+func scanAll(t testing.TB, tbl tuple.Table) []tuple.Tuple {
+	ctx := context.Background()
+	it := tbl.Scan(ctx, &tuple.ScanOptions{Sort: tuple.SortAsc})
+	defer it.Close()
+	var out []tuple.Tuple
+	for it.Next(ctx) {
+		out = append(out, tuple.Tuple{Key: it.Key(), Data: it.Data()})
+	}
+	require.NoError(t, it.Err())
+	return out
+}
+
+func alter(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+	h := tuple.Header{
+		Name: "test",
+		Key: []tuple.KeyField{
+			{Name: "k", Type: values.StringType{}},
+		},
+		Data: []tuple.Field{
+			{Name: "name", Type: values.StringType{}},
+			{Name: "age", Type: values.IntType{}},
+		},
+		Indexes: []tuple.Index{
+			{Name: "age", Fields: []string{"age"}},
+		},
+	}
+	// each operation is executed in a separate transaction,
+	// since some databases abort transactions on errors
+	alterTable := func(changes ...tuple.Alteration) error {
+		return db.Update(ctx, func(tx tuple.Tx) error {
+			_, err := tx.AlterTable(ctx, h.Name, changes...)
+			return err
+		})
+	}
+	view := func(fnc func(tbl tuple.Table)) {
+		err := db.View(ctx, func(tx tuple.Tx) error {
+			tbl, err := tx.Table(ctx, h.Name)
+			if err != nil {
+				return err
+			}
+			fnc(tbl)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, h)
+		if err != nil {
+			return err
+		}
+		for _, tp := range []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: tuple.Data{values.String("a"), values.Int(20)}},
+			{Key: tuple.SKey("k2"), Data: tuple.Data{values.String("b"), values.Int(21)}},
+		} {
+			if _, err = tbl.InsertTuple(ctx, tp); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Error(t, alterTable(tuple.DropField{Name: "age"}), "indexed fields cannot be dropped")
+	require.Error(t, alterTable(tuple.DropField{Name: "k"}), "key fields cannot be dropped")
+	require.Error(t, alterTable(tuple.DropField{Name: "none"}))
+	require.Error(t, alterTable(tuple.RenameField{Name: "name", NewName: "age"}))
+	require.Error(t, alterTable(tuple.AddField{Field: tuple.Field{Name: "age", Type: values.IntType{}, Default: values.Int(0)}}))
+	require.Error(t, alterTable(tuple.AddField{Field: tuple.Field{Name: "score", Type: values.FloatType{}, Default: values.Int(0)}}))
+	view(func(tbl tuple.Table) {
+		require.Equal(t, h, tbl.Header())
+	})
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.AlterTable(ctx, h.Name,
+			tuple.AddField{Field: tuple.Field{Name: "score", Type: values.FloatType{}, Default: values.Float(1.5)}},
+			tuple.RenameField{Name: "name", NewName: "title"},
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+			Key: tuple.SKey("k3"), Data: tuple.Data{values.String("c"), values.Int(22), values.Float(2.5)},
+		})
+		return err
+	})
+	require.NoError(t, err)
+
+	h2 := h.Clone()
+	h2.Data = []tuple.Field{
+		{Name: "title", Type: values.StringType{}},
+		{Name: "age", Type: values.IntType{}},
+		{Name: "score", Type: values.FloatType{}, Default: values.Float(1.5)},
+	}
+	view(func(tbl tuple.Table) {
+		require.Equal(t, h2, tbl.Header())
+		require.Equal(t, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: tuple.Data{values.String("a"), values.Int(20), values.Float(1.5)}},
+			{Key: tuple.SKey("k2"), Data: tuple.Data{values.String("b"), values.Int(21), values.Float(1.5)}},
+			{Key: tuple.SKey("k3"), Data: tuple.Data{values.String("c"), values.Int(22), values.Float(2.5)}},
+		}, scanAll(t, tbl))
+		f := &tuple.Filter{DataFilter: tuple.DataFilters{nil, filter.EQ(values.Int(21)), nil}}
+		require.Equal(t, []string{"k2"}, scanKeys(t, tbl, f))
+	})
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.AlterTable(ctx, h.Name,
+			tuple.DropField{Name: "title"},
+			tuple.AddField{Field: tuple.Field{Name: "flag", Type: values.BoolType{}, Default: values.Bool(true)}},
+		)
+		if err != nil {
+			return err
+		}
+		return tbl.UpdateTuple(ctx, tuple.Tuple{
+			Key: tuple.SKey("k2"), Data: tuple.Data{values.Int(23), values.Float(3.5), values.Bool(false)},
+		}, nil)
+	})
+	require.NoError(t, err)
+
+	h3 := h.Clone()
+	h3.Data = []tuple.Field{
+		{Name: "age", Type: values.IntType{}},
+		{Name: "score", Type: values.FloatType{}, Default: values.Float(1.5)},
+		{Name: "flag", Type: values.BoolType{}, Default: values.Bool(true)},
+	}
+	view(func(tbl tuple.Table) {
+		require.Equal(t, h3, tbl.Header())
+		require.Equal(t, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: tuple.Data{values.Int(20), values.Float(1.5), values.Bool(true)}},
+			{Key: tuple.SKey("k2"), Data: tuple.Data{values.Int(23), values.Float(3.5), values.Bool(false)}},
+			{Key: tuple.SKey("k3"), Data: tuple.Data{values.Int(22), values.Float(2.5), values.Bool(true)}},
+		}, scanAll(t, tbl))
+		data, err := tbl.GetTuple(ctx, tuple.SKey("k1"))
+		require.NoError(t, err)
+		require.Equal(t, tuple.Data{values.Int(20), values.Float(1.5), values.Bool(true)}, data)
+	})
+
+	// table created with the same name should not inherit schema changes
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, h.Name)
+		if err != nil {
+			return err
+		}
+		if err = tbl.Drop(ctx); err != nil {
+			return err
+		}
+		tbl, err = tx.CreateTable(ctx, h)
+		if err != nil {
+			return err
+		}
+		_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+			Key: tuple.SKey("k1"), Data: tuple.Data{values.String("a"), values.Int(20)},
+		})
+		return err
+	})
+	require.NoError(t, err)
+	view(func(tbl tuple.Table) {
+		require.Equal(t, h, tbl.Header())
+		require.Equal(t, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: tuple.Data{values.String("a"), values.Int(20)}},
+		}, scanAll(t, tbl))
+	})
+}
+
+func nulls(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+	h := tuple.Header{
+		Name: "test",
+		Key: []tuple.KeyField{
+			{Name: "k", Type: values.StringType{}},
+		},
+		Data: []tuple.Field{
+			{Name: "name", Type: values.StringType{}},
+			{Name: "note", Type: values.StringType{}, Nullable: true},
+			{Name: "data", Type: values.BytesType{}, Nullable: true},
+			{Name: "age", Type: values.IntType{}, Nullable: true},
+			{Name: "score", Type: values.FloatType{}, Default: values.Float(1.5)},
+		},
+		Indexes: []tuple.Index{
+			{Name: "age", Fields: []string{"age"}},
+		},
+	}
+	// each operation is executed in a separate transaction,
+	// since some databases abort transactions on errors
+	update := func(fnc func(tbl tuple.Table) error) error {
+		return db.Update(ctx, func(tx tuple.Tx) error {
+			tbl, err := tx.Table(ctx, h.Name)
+			if err != nil {
+				return err
+			}
+			return fnc(tbl)
+		})
+	}
+	insert := func(k string, data tuple.Data) error {
+		return update(func(tbl tuple.Table) error {
+			_, err := tbl.InsertTuple(ctx, tuple.Tuple{Key: tuple.SKey(k), Data: data})
+			return err
+		})
+	}
+	view := func(fnc func(tbl tuple.Table)) {
+		err := db.View(ctx, func(tx tuple.Tx) error {
+			tbl, err := tx.Table(ctx, h.Name)
+			if err != nil {
+				return err
+			}
+			fnc(tbl)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.CreateTable(ctx, h)
+		return err
+	})
+	require.NoError(t, err)
+	view(func(tbl tuple.Table) {
+		require.Equal(t, h, tbl.Header())
+	})
+
+	require.Error(t, insert("k0", tuple.Data{nil, nil, nil, nil, nil}), "field is not nullable")
+
+	require.NoError(t, insert("k1", tuple.Data{values.String("a"), nil, nil, nil, nil}))
+	require.NoError(t, insert("k2", tuple.Data{
+		values.String("b"), values.String("x"), values.Bytes("y"), values.Int(20), values.Float(2.5),
+	}))
+	require.NoError(t, insert("k3", tuple.Data{values.String("c"), values.String(""), nil, values.Int(0), nil}))
+
+	view(func(tbl tuple.Table) {
+		require.Equal(t, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: tuple.Data{values.String("a"), nil, nil, nil, values.Float(1.5)}},
+			{Key: tuple.SKey("k2"), Data: tuple.Data{
+				values.String("b"), values.String("x"), values.Bytes("y"), values.Int(20), values.Float(2.5),
+			}},
+			{Key: tuple.SKey("k3"), Data: tuple.Data{values.String("c"), values.String(""), nil, values.Int(0), values.Float(1.5)}},
+		}, scanAll(t, tbl))
+		data, err := tbl.GetTuple(ctx, tuple.SKey("k1"))
+		require.NoError(t, err)
+		require.Equal(t, tuple.Data{values.String("a"), nil, nil, nil, values.Float(1.5)}, data)
+
+		// nulls never match value filters
+		f := &tuple.Filter{DataFilter: tuple.DataFilters{nil, nil, nil, filter.EQ(values.Int(0)), nil}}
+		require.Equal(t, []string{"k3"}, scanKeys(t, tbl, f))
+	})
+
+	err = update(func(tbl tuple.Table) error {
+		return tbl.UpdateTuple(ctx, tuple.Tuple{
+			Key: tuple.SKey("k2"), Data: tuple.Data{values.String("b"), nil, nil, nil, nil},
+		}, nil)
+	})
+	require.NoError(t, err)
+	err = update(func(tbl tuple.Table) error {
+		return tbl.UpdateTuple(ctx, tuple.Tuple{
+			Key: tuple.SKey("k2"), Data: tuple.Data{nil, nil, nil, nil, nil},
+		}, nil)
+	})
+	require.Error(t, err, "field is not nullable")
+	view(func(tbl tuple.Table) {
+		data, err := tbl.GetTuple(ctx, tuple.SKey("k2"))
+		require.NoError(t, err)
+		require.Equal(t, tuple.Data{values.String("b"), nil, nil, nil, values.Float(1.5)}, data)
+	})
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.AlterTable(ctx, h.Name, tuple.AddField{Field: tuple.Field{Name: "flag", Type: values.BoolType{}}})
+		return err
+	})
+	require.Error(t, err, "new fields must be nullable or have a default")
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		_, err := tx.AlterTable(ctx, h.Name,
+			tuple.AddField{Field: tuple.Field{Name: "flag", Type: values.BoolType{}, Nullable: true}},
+			tuple.SetDefault{Name: "note", Default: values.String("none")},
+			tuple.SetDefault{Name: "score"},
+		)
+		return err
+	})
+	require.NoError(t, err)
+
+	h2 := h.Clone()
+	h2.Data = []tuple.Field{
+		{Name: "name", Type: values.StringType{}},
+		{Name: "note", Type: values.StringType{}, Nullable: true, Default: values.String("none")},
+		{Name: "data", Type: values.BytesType{}, Nullable: true},
+		{Name: "age", Type: values.IntType{}, Nullable: true},
+		{Name: "score", Type: values.FloatType{}},
+		{Name: "flag", Type: values.BoolType{}, Nullable: true},
+	}
+	view(func(tbl tuple.Table) {
+		require.Equal(t, h2, tbl.Header())
+	})
+
+	// existing tuples are not affected by the new default
+	require.NoError(t, insert("k4", tuple.Data{values.String("d"), nil, nil, nil, values.Float(3.5), values.Bool(true)}))
+	require.Error(t, insert("k5", tuple.Data{values.String("e"), nil, nil, nil, nil, nil}), "field has no default anymore")
+	view(func(tbl tuple.Table) {
+		require.Equal(t, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: tuple.Data{values.String("a"), nil, nil, nil, values.Float(1.5), nil}},
+			{Key: tuple.SKey("k2"), Data: tuple.Data{values.String("b"), nil, nil, nil, values.Float(1.5), nil}},
+			{Key: tuple.SKey("k3"), Data: tuple.Data{values.String("c"), values.String(""), nil, values.Int(0), values.Float(1.5), nil}},
+			{Key: tuple.SKey("k4"), Data: tuple.Data{
+				values.String("d"), values.String("none"), nil, nil, values.Float(3.5), values.Bool(true),
+			}},
+		}, scanAll(t, tbl))
+	})
+}
+
+func projection(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, indexTable)
+		if err != nil {
+			return err
+		}
+		for i, d := range []tuple.Data{
+			indexData("a", 20),
+			indexData("b", 21),
+			indexData("c", 20),
+		} {
+			key := tuple.SKey(fmt.Sprintf("k%d", i+1))
+			if _, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: key, Data: d}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	scan := func(tbl tuple.Table, opt *tuple.ScanOptions) []tuple.Tuple {
+		it := tbl.Scan(ctx, opt)
+		defer it.Close()
+		var out []tuple.Tuple
+		for it.Next(ctx) {
+			out = append(out, tuple.Tuple{Key: it.Key(), Data: it.Data()})
+		}
+		require.NoError(t, it.Err())
+		sort.Slice(out, func(i, j int) bool {
+			return out[i].Key.Compare(out[j].Key) < 0
+		})
+		return out
+	}
+
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, indexTable.Name)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: tuple.Data{values.Float(10), values.String("a")}},
+			{Key: tuple.SKey("k2"), Data: tuple.Data{values.Float(10.5), values.String("b")}},
+			{Key: tuple.SKey("k3"), Data: tuple.Data{values.Float(10), values.String("c")}},
+		}, scan(tbl, &tuple.ScanOptions{Sort: tuple.SortAsc, Fields: []string{"score", "name"}}))
+
+		// filters are applied to fields that are not returned
+		require.Equal(t, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: tuple.Data{values.String("a")}},
+			{Key: tuple.SKey("k3"), Data: tuple.Data{values.String("c")}},
+		}, scan(tbl, &tuple.ScanOptions{
+			Fields: []string{"name"},
+			Filter: &tuple.Filter{DataFilter: tuple.DataFilters{nil, filter.EQ(values.Int(20)), nil}},
+		}))
+		require.Equal(t, []tuple.Tuple{
+			{Key: tuple.SKey("k2"), Data: tuple.Data{values.Int(21)}},
+		}, scan(tbl, &tuple.ScanOptions{
+			Fields: []string{"age"},
+			Filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("k2"))}},
+		}))
+
+		it := tbl.Scan(ctx, &tuple.ScanOptions{Fields: []string{"none"}})
+		defer it.Close()
+		require.False(t, it.Next(ctx))
+		require.Error(t, it.Err())
+
+		data, err := tuple.GetTupleFields(ctx, tbl, tuple.SKey("k2"), []string{"age", "name"})
+		require.NoError(t, err)
+		require.Equal(t, tuple.Data{values.Int(21), values.String("b")}, data)
+
+		_, err = tuple.GetTupleFields(ctx, tbl, tuple.SKey("k4"), []string{"age"})
+		require.Equal(t, tuple.ErrNotFound, err)
+
+		_, err = tuple.GetTupleFields(ctx, tbl, tuple.SKey("k1"), []string{"none"})
+		require.Error(t, err)
+
+		batch, err := tuple.GetTupleBatchFields(ctx, tbl, []tuple.Key{tuple.SKey("k3"), tuple.SKey("k1")}, []string{"score"})
+		require.NoError(t, err)
+		require.Equal(t, []tuple.Data{{values.Float(10)}, {values.Float(10)}}, batch)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func aggregate(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+
+	h := tuple.Header{
+		Name: "test",
+		Key: []tuple.KeyField{
+			{Name: "g", Type: values.StringType{}},
+			{Name: "k", Type: values.IntType{}},
+		},
+		Data: []tuple.Field{
+			{Name: "name", Type: values.StringType{}},
+			{Name: "n", Type: values.IntType{}, Nullable: true},
+			{Name: "f", Type: values.FloatType{}},
+		},
+	}
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, h)
+		if err != nil {
+			return err
+		}
+		for _, t := range []tuple.Tuple{
+			{Key: tuple.Key{values.String("a"), values.Int(1)}, Data: tuple.Data{values.String("x"), values.Int(10), values.Float(1.5)}},
+			{Key: tuple.Key{values.String("a"), values.Int(2)}, Data: tuple.Data{values.String("x"), nil, values.Float(2.5)}},
+			{Key: tuple.Key{values.String("b"), values.Int(1)}, Data: tuple.Data{values.String("y"), values.Int(3), values.Float(0.5)}},
+		} {
+			if _, err = tbl.InsertTuple(ctx, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// groups returns aggregation results indexed by a group key
+	groups := func(tbl tuple.Table, opt *tuple.AggregateOptions) map[string]tuple.Data {
+		res, err := tuple.AggregateTable(ctx, tbl, opt)
+		require.NoError(t, err)
+		out := make(map[string]tuple.Data, len(res))
+		for _, g := range res {
+			var id []string
+			for _, v := range g.Key {
+				id = append(id, fmt.Sprint(v))
+			}
+			for _, v := range g.Fields {
+				id = append(id, fmt.Sprint(v))
+			}
+			out[fmt.Sprint(id)] = g.Values
+		}
+		require.Len(t, out, len(res))
+		return out
+	}
+
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, h.Name)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, map[string]tuple.Data{
+			"[]": {values.Int(3), values.Int(2), values.Int(13), values.String("x"), values.Float(2.5), values.Float(6.5)},
+		}, groups(tbl, &tuple.AggregateOptions{
+			Aggregates: []tuple.Aggregate{
+				{Func: tuple.Count},
+				{Func: tuple.Count, Field: "n"},
+				{Func: tuple.Sum, Field: "n"},
+				{Func: tuple.Min, Field: "name"},
+				{Func: tuple.Max, Field: "f"},
+				{Func: tuple.Avg, Field: "n"},
+			},
+		}))
+
+		require.Equal(t, map[string]tuple.Data{
+			"[a]": {values.Int(2), values.Int(10), values.Float(4)},
+			"[b]": {values.Int(1), values.Int(3), values.Float(0.5)},
+		}, groups(tbl, &tuple.AggregateOptions{
+			GroupBy: tuple.GroupBy{KeyPrefix: 1},
+			Aggregates: []tuple.Aggregate{
+				{Func: tuple.Count},
+				{Func: tuple.Sum, Field: "n"},
+				{Func: tuple.Sum, Field: "f"},
+			},
+		}))
+
+		require.Equal(t, map[string]tuple.Data{
+			"[x]": {values.Int(2), values.Float(1.5)},
+		}, groups(tbl, &tuple.AggregateOptions{
+			Filter:  &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("a"))}},
+			GroupBy: tuple.GroupBy{Fields: []string{"name"}},
+			Aggregates: []tuple.Aggregate{
+				{Func: tuple.Count},
+				{Func: tuple.Min, Field: "f"},
+			},
+		}))
+
+		// no matching tuples
+		require.Equal(t, map[string]tuple.Data{
+			"[]": {values.Int(0), nil},
+		}, groups(tbl, &tuple.AggregateOptions{
+			Filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("c"))}},
+			Aggregates: []tuple.Aggregate{
+				{Func: tuple.Count},
+				{Func: tuple.Sum, Field: "n"},
+			},
+		}))
+		require.Empty(t, groups(tbl, &tuple.AggregateOptions{
+			Filter:     &tuple.Filter{DataFilter: tuple.DataFilters{filter.EQ(values.String("z")), nil, nil}},
+			GroupBy:    tuple.GroupBy{KeyPrefix: 2},
+			Aggregates: []tuple.Aggregate{{Func: tuple.Count}},
+		}))
+
+		n, err := tuple.TableSize(ctx, tbl, &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("a"))}}, true)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), n)
+
+		for _, opt := range []*tuple.AggregateOptions{
+			{},
+			{Aggregates: []tuple.Aggregate{{Func: tuple.Sum, Field: "name"}}},
+			{Aggregates: []tuple.Aggregate{{Func: tuple.Max, Field: "none"}}},
+			{GroupBy: tuple.GroupBy{KeyPrefix: 3}, Aggregates: []tuple.Aggregate{{Func: tuple.Count}}},
+		} {
+			_, err = tuple.AggregateTable(ctx, tbl, opt)
+			require.Error(t, err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// strings are grouped and compared byte-wise, the same way as in filters
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, h.Name)
+		if err != nil {
+			return err
+		}
+		_, err = tbl.InsertTuple(ctx, tuple.Tuple{
+			Key:  tuple.Key{values.String("B"), values.Int(1)},
+			Data: tuple.Data{values.String("X"), values.Int(1), values.Float(1)},
+		})
+		return err
+	})
+	require.NoError(t, err)
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, h.Name)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, map[string]tuple.Data{
+			"[B]": {values.Int(1), values.String("X"), values.String("X")},
+			"[a]": {values.Int(2), values.String("x"), values.String("x")},
+			"[b]": {values.Int(1), values.String("y"), values.String("y")},
+		}, groups(tbl, &tuple.AggregateOptions{
+			GroupBy: tuple.GroupBy{KeyPrefix: 1},
+			Aggregates: []tuple.Aggregate{
+				{Func: tuple.Count},
+				{Func: tuple.Min, Field: "name"},
+				{Func: tuple.Max, Field: "name"},
+			},
+		}))
+		require.Equal(t, map[string]tuple.Data{
+			"[]": {values.String("X"), values.String("y")},
+		}, groups(tbl, &tuple.AggregateOptions{
+			Aggregates: []tuple.Aggregate{
+				{Func: tuple.Min, Field: "name"},
+				{Func: tuple.Max, Field: "name"},
+			},
+		}))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func pagination(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+
+	h := tuple.Header{
+		Name: "test",
+		Key: []tuple.KeyField{
+			{Name: "g", Type: values.StringType{}},
+			{Name: "k", Type: values.IntType{}},
+		},
+		Data: []tuple.Field{
+			{Name: "n", Type: values.IntType{}},
+		},
+	}
+	// tuples in key order; n is the position of the tuple
+	var keys []tuple.Key
+	for _, g := range []string{"a", "b", "c"} {
+		for k := 1; k <= 4; k++ {
+			keys = append(keys, tuple.Key{values.String(g), values.Int(k)})
+		}
+	}
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, h)
+		if err != nil {
+			return err
+		}
+		for i, k := range keys {
+			_, err = tbl.InsertTuple(ctx, tuple.Tuple{Key: k, Data: tuple.Data{values.Int(i)}})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// scan returns positions of tuples returned by the scan
+	scan := func(tbl tuple.Table, opt *tuple.ScanOptions) []int {
+		it := tbl.Scan(ctx, opt)
+		defer it.Close()
+		var got []int
+		for it.Next(ctx) {
+			d := it.Data()
+			require.Len(t, d, 1)
+			n := int(d[0].(values.Int))
+			require.Equal(t, keys[n], it.Key())
+			got = append(got, n)
+		}
+		require.NoError(t, it.Err())
+		return got
+	}
+	seq := func(from, to int) []int {
+		var out []int
+		if from <= to {
+			for i := from; i <= to; i++ {
+				out = append(out, i)
+			}
+		} else {
+			for i := from; i >= to; i-- {
+				out = append(out, i)
+			}
+		}
+		return out
+	}
+
+	err = db.View(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.Table(ctx, h.Name)
+		if err != nil {
+			return err
+		}
+		even := &tuple.Filter{DataFilter: tuple.DataFilters{filter.Or{
+			filter.EQ(values.Int(0)), filter.EQ(values.Int(2)), filter.EQ(values.Int(4)),
+			filter.EQ(values.Int(6)), filter.EQ(values.Int(8)), filter.EQ(values.Int(10)),
+		}}}
+
+		// page through all tuples in both directions, using the last key as a bound for the next page
+		for _, c := range []struct {
+			name   string
+			filter *tuple.Filter
+			exp    []int
+		}{
+			{name: "all", exp: seq(0, 11)},
+			{name: "key", filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("b"))}}, exp: seq(4, 7)},
+			{name: "data", filter: even, exp: []int{0, 2, 4, 6, 8, 10}},
+		} {
+			for _, sort := range []tuple.Sorting{tuple.SortAsc, tuple.SortDesc} {
+				exp := append([]int{}, c.exp...)
+				if sort == tuple.SortDesc {
+					for i, j := 0, len(exp)-1; i < j; i, j = i+1, j-1 {
+						exp[i], exp[j] = exp[j], exp[i]
+					}
+				}
+				var (
+					got  []int
+					last tuple.Key
+				)
+				for pages := 0; ; pages++ {
+					require.True(t, pages <= len(exp), "%s, sort %v: too many pages", c.name, sort)
+					opt := &tuple.ScanOptions{Sort: sort, Filter: c.filter, Limit: 3}
+					if sort == tuple.SortAsc {
+						opt.After = last
+					} else {
+						opt.Before = last
+					}
+					page := scan(tbl, opt)
+					if len(page) == 0 {
+						break
+					}
+					require.True(t, len(page) <= 3)
+					got = append(got, page...)
+					last = keys[page[len(page)-1]]
+				}
+				require.Equal(t, exp, got, "%s, sort %v", c.name, sort)
+
+				// offsets must return the same pages
+				for off := 0; off <= len(exp); off += 2 {
+					page := scan(tbl, &tuple.ScanOptions{Sort: sort, Filter: c.filter, Limit: 2, Offset: off})
+					end := off + 2
+					if end > len(exp) {
+						end = len(exp)
+					}
+					var want []int
+					if off < end {
+						want = exp[off:end]
+					}
+					require.Equal(t, want, page, "%s, sort %v, offset %d", c.name, sort, off)
+				}
+			}
+		}
+
+		// bounds that are not in the table
+		require.Equal(t, seq(4, 6), scan(tbl, &tuple.ScanOptions{
+			Sort:   tuple.SortAsc,
+			After:  tuple.Key{values.String("a"), values.Int(10)},
+			Before: tuple.Key{values.String("b"), values.Int(4)},
+		}))
+		require.Equal(t, seq(6, 4), scan(tbl, &tuple.ScanOptions{
+			Sort:   tuple.SortDesc,
+			After:  tuple.Key{values.String("a"), values.Int(10)},
+			Before: tuple.Key{values.String("b"), values.Int(4)},
+		}))
+		require.Equal(t, seq(10, 11), scan(tbl, &tuple.ScanOptions{
+			Sort:   tuple.SortAsc,
+			After:  tuple.Key{values.String("c"), values.Int(1)},
+			Offset: 1,
+		}))
+		require.Len(t, scan(tbl, &tuple.ScanOptions{
+			Sort:   tuple.SortAsc,
+			After:  tuple.Key{values.String("c"), values.Int(0)},
+			Before: tuple.Key{values.String("a"), values.Int(0)},
+		}), 0)
+		// bounds within a key prefix
+		require.Equal(t, []int{7, 6}, scan(tbl, &tuple.ScanOptions{
+			Sort:   tuple.SortDesc,
+			Filter: &tuple.Filter{KeyFilter: tuple.KeyFilters{filter.EQ(values.String("b"))}},
+			After:  tuple.Key{values.String("b"), values.Int(2)},
+			Limit:  5,
+		}))
+
+		for _, opt := range []*tuple.ScanOptions{
+			{Offset: -1},
+			{After: tuple.Key{values.String("a")}},
+			{Before: tuple.Key{values.Int(1), values.Int(1)}},
+		} {
+			it := tbl.Scan(ctx, opt)
+			require.False(t, it.Next(ctx))
+			require.Error(t, it.Err())
+			it.Close()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func batch(t *testing.T, db tuple.Store) {
+	ctx := context.Background()
+
+	byName := func(name string) *tuple.Filter {
+		return &tuple.Filter{DataFilter: tuple.DataFilters{filter.EQ(values.String(name)), nil, nil}}
+	}
+	err := db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, indexTable)
+		if err != nil {
+			return err
+		}
+		keys, err := tuple.InsertTupleBatch(ctx, tbl, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: indexData("a", 20)},
+			{Key: tuple.SKey("k2"), Data: indexData("b", 21)},
+			{Key: tuple.SKey("k3"), Data: indexData("a", 22)},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []tuple.Key{tuple.SKey("k1"), tuple.SKey("k2"), tuple.SKey("k3")}, keys)
+
+		keys, err = tuple.InsertTupleBatch(ctx, tbl, nil)
+		require.NoError(t, err)
+		require.Empty(t, keys)
+
+		// the last tuple wins for repeated keys
+		err = tuple.UpsertTupleBatch(ctx, tbl, []tuple.Tuple{
+			{Key: tuple.SKey("k1"), Data: indexData("c", 20)},
+			{Key: tuple.SKey("k4"), Data: indexData("a", 23)},
+			{Key: tuple.SKey("k4"), Data: indexData("b", 24)},
+		})
+		require.NoError(t, err)
+
+		data, err := tbl.GetTupleBatch(ctx, []tuple.Key{tuple.SKey("k1"), tuple.SKey("k2"), tuple.SKey("k3"), tuple.SKey("k4")})
+		require.NoError(t, err)
+		require.Equal(t, []tuple.Data{
+			indexData("c", 20), indexData("b", 21), indexData("a", 22), indexData("b", 24),
+		}, data)
+
+		// index entries should follow the payload
+		require.Equal(t, []string{"k3"}, scanKeys(t, tbl, byName("a")))
+		require.Equal(t, []string{"k2", "k4"}, scanKeys(t, tbl, byName("b")))
+		require.Equal(t, []string{"k1"}, scanKeys(t, tbl, byName("c")))
+
+		_, err = tuple.InsertTupleBatch(ctx, tbl, []tuple.Tuple{
+			{Key: tuple.SKey("k5"), Data: indexData("a", 20)},
+			{Key: tuple.SKey("k5"), Data: indexData("b", 21)},
+		})
+		require.Equal(t, tuple.ErrExists, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "auto",
+			Key: []tuple.KeyField{
+				{Name: "k", Type: values.UIntType{}, Auto: true},
+			},
+			Data: []tuple.Field{
+				{Name: "v", Type: values.StringType{}},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		var tuples []tuple.Tuple
+		for _, v := range []string{"a", "b", "c"} {
+			tuples = append(tuples, tuple.Tuple{Key: tuple.AutoKey(), Data: tuple.SData(v)})
+		}
+		keys, err := tuple.InsertTupleBatch(ctx, tbl, tuples)
+		require.NoError(t, err)
+		require.Len(t, keys, len(tuples))
+
+		more, err := tuple.InsertTupleBatch(ctx, tbl, tuples[:1])
+		require.NoError(t, err)
+		keys = append(keys, more...)
+		tuples = append(tuples, tuples[0])
+
+		seen := make(map[string]struct{})
+		for i, k := range keys {
+			require.Len(t, k, 1)
+			require.NotNil(t, k[0])
+			seen[fmt.Sprint(k)] = struct{}{}
+
+			data, err := tbl.GetTuple(ctx, k)
+			require.NoError(t, err)
+			require.Equal(t, tuples[i].Data, data)
+		}
+		require.Len(t, seen, len(keys), "keys must be unique")
+		return nil
+	})
+	require.NoError(t, err)
+
+	// auto key without a payload
+	err = db.Update(ctx, func(tx tuple.Tx) error {
+		tbl, err := tx.CreateTable(ctx, tuple.Header{
+			Name: "auto_empty",
+			Key: []tuple.KeyField{
+				{Name: "k", Type: values.UIntType{}, Auto: true},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		tuples := []tuple.Tuple{{Key: tuple.AutoKey()}, {Key: tuple.AutoKey()}, {Key: tuple.AutoKey()}}
+		keys, err := tuple.InsertTupleBatch(ctx, tbl, tuples)
+		require.NoError(t, err)
+		require.Len(t, keys, len(tuples))
+
+		seen := make(map[string]struct{})
+		for _, k := range keys {
+			require.Len(t, k, 1)
+			seen[fmt.Sprint(k)] = struct{}{}
+		}
+		require.Len(t, seen, len(keys), "keys must be unique")
+
+		// returned keys must match the stored ones
+		it := tbl.Scan(ctx, &tuple.ScanOptions{KeysOnly: true})
+		defer it.Close()
+		for it.Next(ctx) {
+			k := fmt.Sprint(it.Key())
+			_, ok := seen[k]
+			require.True(t, ok, "unexpected key: %s", k)
+			delete(seen, k)
+		}
+		require.NoError(t, it.Err())
+		require.Empty(t, seen)
+		return nil
+	})
+	require.NoError(t, err)
+}